@@ -3,6 +3,7 @@ module OpenLinkHub
 go 1.25.0
 
 require (
+	github.com/eclipse/paho.mqtt.golang v1.5.1
 	github.com/godbus/dbus/v5 v5.1.0
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/sstallion/go-hid v0.14.1
@@ -10,4 +11,9 @@ require (
 	golang.org/x/sys v0.42.0
 )
 
-require golang.org/x/text v0.38.0 // indirect
+require (
+	github.com/gorilla/websocket v1.5.3 // indirect
+	golang.org/x/net v0.44.0 // indirect
+	golang.org/x/sync v0.21.0 // indirect
+	golang.org/x/text v0.38.0 // indirect
+)
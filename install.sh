@@ -57,6 +57,7 @@ if [ -f $SYSTEMD_FILE ]; then
   chmod -R 755 /opt/$PRODUCT/
   chown -R "$USER_TO_CHECK":"$USER_TO_CHECK" /opt/$PRODUCT/
   cp /opt/$PRODUCT/99-openlinkhub.rules /etc/udev/rules.d/
+  sed "s/\(user\|group\)=\"openlinkhub\"/\1=\"$USER_TO_CHECK\"/" /opt/$PRODUCT/org.openlinkhub.conf > /etc/dbus-1/system.d/org.openlinkhub.conf
   echo "Reloading udev..."
   sudo udevadm control --reload-rules
   sudo udevadm trigger
@@ -101,6 +102,9 @@ echo "Setting udev device permissions..."
 sudo rm -f /etc/udev/rules.d/99-corsair*.rules
 sudo cp 99-openlinkhub.rules /etc/udev/rules.d/
 
+echo "Setting D-Bus service permissions..."
+sed "s/\(user\|group\)=\"openlinkhub\"/\1=\"$USER_TO_CHECK\"/" org.openlinkhub.conf | sudo tee /etc/dbus-1/system.d/org.openlinkhub.conf > /dev/null
+
 echo "Reloading udev..."
 sudo udevadm control --reload-rules
 sudo udevadm trigger
@@ -2,6 +2,7 @@ package main
 
 import (
 	"OpenLinkHub/src/controller"
+	"OpenLinkHub/src/ctl"
 	"os"
 	"os/signal"
 	"syscall"
@@ -22,6 +23,10 @@ func waitForExit() {
 
 // main entry point
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		os.Exit(ctl.Run(os.Args[2:]))
+	}
+
 	go waitForExit()
 	controller.Start()
 }
@@ -0,0 +1,566 @@
+package capabilities
+
+// Package: capabilities
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/dispatcher"
+	"OpenLinkHub/src/inputmanager"
+	"OpenLinkHub/src/keyboards"
+	"OpenLinkHub/src/led"
+	"OpenLinkHub/src/rgb"
+)
+
+// Stoppable is implemented by every device and switches it back to hardware mode
+type Stoppable interface {
+	Stop()
+}
+
+// DirtyStoppable is implemented by devices which can be stopped without closing file handles
+type DirtyStoppable interface {
+	StopDirty() uint8
+}
+
+// Templated is implemented by devices with a WebUI template
+type Templated interface {
+	GetDeviceTemplate() string
+}
+
+// Dispatchable is implemented by devices which need to call methods of other devices
+type Dispatchable interface {
+	SetDispatcher(ds dispatcher.DeviceDispatcher)
+}
+
+// Metrics is implemented by devices exporting Prometheus metrics
+type Metrics interface {
+	UpdateDeviceMetrics()
+}
+
+// UserProfiles is implemented by devices with user profile support
+type UserProfiles interface {
+	SaveUserProfile(profileName string) uint8
+	ChangeDeviceProfile(profileName string) uint8
+}
+
+// UserProfileDeleter is implemented by devices whose user profiles can be deleted
+type UserProfileDeleter interface {
+	DeleteDeviceProfile(profileName string) uint8
+}
+
+// DeviceLabel is implemented by devices with user defined channel labels
+type DeviceLabel interface {
+	UpdateDeviceLabel(channelId int, label string) uint8
+}
+
+// RgbDeviceLabel is implemented by devices with user defined RGB channel labels
+type RgbDeviceLabel interface {
+	UpdateRGBDeviceLabel(channelId int, label string) uint8
+}
+
+// DevicePositions is implemented by devices with custom WebUI channel ordering
+type DevicePositions interface {
+	UpdateDevicePosition(positions []string) uint8
+	GetDevicePositions() []string
+}
+
+// Rgb is implemented by devices with RGB profile support
+type Rgb interface {
+	UpdateRgbProfile(channelId int, profile string) uint8
+}
+
+// RgbProfileOverride is implemented by devices able to show RGB profile without saving it
+type RgbProfileOverride interface {
+	SetRgbProfileOverride(profile string) uint8
+	ClearRgbProfileOverride()
+}
+
+// RgbBulk is implemented by devices supporting RGB profile change on multiple channels
+type RgbBulk interface {
+	UpdateRgbProfileBulk(channelIds []int, profile string) uint8
+}
+
+// RgbProfiles is implemented by devices exposing their RGB profiles
+type RgbProfiles interface {
+	GetRgbProfiles() interface{}
+}
+
+// RgbProfileReader is implemented by devices exposing a single RGB profile
+type RgbProfileReader interface {
+	GetRgbProfile(profile string) *rgb.Profile
+}
+
+// RgbProfileEditor is implemented by devices with editable RGB profiles
+type RgbProfileEditor interface {
+	UpdateRgbProfileData(profileName string, profile rgb.Profile) uint8
+	ProcessNewGradientColor(profileName string) (uint8, uint)
+	ProcessDeleteGradientColor(profileName string) (uint8, uint)
+}
+
+// RgbControl is implemented by devices whose RGB can be turned off and on
+type RgbControl interface {
+	ControlDeviceRgb(value bool)
+}
+
+// RgbAlert is implemented by devices able to flash red RGB warning on hardware failure
+type RgbAlert interface {
+	SetRgbAlert(active bool)
+}
+
+// RgbOverride is implemented by devices with per-channel RGB override
+type RgbOverride interface {
+	ProcessGetRgbOverride(channelId, subDeviceId int) interface{}
+	ProcessSetRgbOverride(channelId, subDeviceId int, enabled bool, startColor, endColor, middleColor rgb.Color, speed float64) uint8
+}
+
+// RgbTemperature is implemented by devices with temperature probe driven RGB
+type RgbTemperature interface {
+	ProcessSetRgbTemperatureProfile(channelId, subDeviceId int, probeChannelId int, minTemp, maxTemp float64) uint8
+}
+
+// RgbCluster is implemented by devices which can join the RGB cluster
+type RgbCluster interface {
+	ProcessSetRgbCluster(enabled bool) uint8
+}
+
+// OpenRgb is implemented by devices which can be exposed to OpenRGB
+type OpenRgb interface {
+	ProcessSetOpenRgbIntegration(enabled bool) uint8
+}
+
+// HardwareRgb is implemented by devices with hardware lighting modes
+type HardwareRgb interface {
+	UpdateHardwareRgbProfile(hardwareLight int) uint8
+}
+
+// LedData is implemented by devices with per-LED colors
+type LedData interface {
+	ProcessGetLedData(channelId, subDeviceId int) interface{}
+	ProcessSetLedData(channelId, subDeviceId int, zoneColors map[int]rgb.Color, save bool) uint8
+}
+
+// LedProfile is implemented by devices with LED channel profiles
+type LedProfile interface {
+	GetDeviceLedData() interface{}
+}
+
+// LedProfileEditor is implemented by devices with editable LED channel profiles
+type LedProfileEditor interface {
+	UpdateDeviceLedData(ledProfile led.Device) uint8
+}
+
+// ZoneColors is implemented by devices exposing their zone colors
+type ZoneColors interface {
+	GetZoneColors() interface{}
+}
+
+// KeyColor is implemented by keyboards with per-key or per-area colors
+type KeyColor interface {
+	UpdateDeviceColor(keyId, keyOption int, color rgb.Color, selections []int) uint8
+}
+
+// Brightness is implemented by devices with brightness levels
+type Brightness interface {
+	ChangeDeviceBrightness(mode uint8) uint8
+}
+
+// BrightnessValue is implemented by devices with brightness slider
+type BrightnessValue interface {
+	ChangeDeviceBrightnessValue(value uint8) uint8
+}
+
+// ScheduledBrightness is implemented by devices whose brightness is controlled via scheduler
+type ScheduledBrightness interface {
+	SchedulerBrightness(value uint8) uint8
+}
+
+// SpeedProfile is implemented by devices with fan or pump channels
+type SpeedProfile interface {
+	UpdateSpeedProfile(channelId int, profile string) uint8
+	ResetSpeedProfiles(profile string)
+}
+
+// SpeedProfileBulk is implemented by devices supporting speed profile change on multiple channels
+type SpeedProfileBulk interface {
+	UpdateSpeedProfileBulk(channelIds []int, profile string) uint8
+}
+
+// ManualSpeed is implemented by devices with manual channel speed control
+type ManualSpeed interface {
+	UpdateDeviceSpeed(channelId int, value uint16) uint8
+}
+
+// OperatingMode is implemented by devices with selectable PWM header operating mode
+type OperatingMode interface {
+	UpdateOperatingMode(channelId, mode int) uint8
+}
+
+// PsuFan is implemented by power supplies with fan control
+type PsuFan interface {
+	UpdatePsuFan(mode int) uint8
+}
+
+// TemperatureProbes is implemented by devices with temperature probes
+type TemperatureProbes interface {
+	GetTemperatureProbes() interface{}
+}
+
+// LiquidTemperatureSource is implemented by devices with selectable liquid temperature source
+type LiquidTemperatureSource interface {
+	SetLiquidTemperatureSource(value bool) uint8
+}
+
+// ChannelDevices is implemented by hubs exposing connected channel devices
+type ChannelDevices interface {
+	ProcessGetChannelDevice(channelId int) interface{}
+}
+
+// ExternalHub is implemented by devices with external-LED hub ports
+type ExternalHub interface {
+	UpdateExternalHubDeviceAmount(portId, externalDevices int) uint8
+	UpdateExternalHubDeviceType(portId, externalType int) uint8
+}
+
+// ExternalAdapter is implemented by devices with external LED strip adapters
+type ExternalAdapter interface {
+	UpdateExternalAdapter(channelId, stripId int) uint8
+}
+
+// ARGBDevice is implemented by devices with ARGB 3-pin ports
+type ARGBDevice interface {
+	UpdateARGBDevice(portId, deviceType int) uint8
+}
+
+// LinkAdapter is implemented by devices with iCUE LINK adapters
+type LinkAdapter interface {
+	UpdateLinkAdapter(channelId int, adapterId int) uint8
+	UpdateLinkAdapterRgbProfile(channelId, adapterId int, profile string) uint8
+	UpdateLinkAdapterRgbProfileBulk(channelId int, profile string) uint8
+}
+
+// CommanderDuoOverride is implemented by devices with COMMANDER DUO override
+type CommanderDuoOverride interface {
+	GetCommanderDuoOverride() interface{}
+	SetCommanderDuoOverride(channelId int, enabled bool, ledChannels uint8) uint8
+}
+
+// Lcd is implemented by devices with LCD screen
+type Lcd interface {
+	UpdateDeviceLcd(channelId int, mode uint8) uint8
+	UpdateDeviceLcdImage(channelId int, image string) uint8
+	UpdateDeviceLcdRotation(channelId int, rotation uint8) uint8
+}
+
+// LcdBrightness is implemented by devices with LCD backlight control
+type LcdBrightness interface {
+	UpdateDeviceLcdBrightness(channelId int, brightness uint8) uint8
+	SchedulerLcdBrightness(value uint8) uint8
+}
+
+// LcdResolution is implemented by devices with LCD resolution different from default one
+type LcdResolution interface {
+	GetLcdResolution() (int, int)
+}
+
+// LcdChanger is implemented by devices with LCD screen assignable to a channel
+type LcdChanger interface {
+	ChangeDeviceLcd(channelId int, lcdSerial string) uint8
+}
+
+// LcdProfile is implemented by devices with LCD profiles
+type LcdProfile interface {
+	UpdateDeviceLcdProfile(profileName string) uint8
+}
+
+// KeyboardProfiles is implemented by keyboards with keyboard profiles
+type KeyboardProfiles interface {
+	UpdateKeyboardProfile(profileName string) uint8
+	DeleteKeyboardProfile(profileName string) uint8
+	ChangeKeyboardLayout(layout string) uint8
+}
+
+// KeyboardProfileSaver is implemented by devices whose keyboard profile can be saved
+type KeyboardProfileSaver interface {
+	SaveDeviceProfile(profileName string, new bool) uint8
+}
+
+// KeyboardKeys is implemented by keyboards exposing key data
+type KeyboardKeys interface {
+	ProcessGetKeyboardKey(keyId int) interface{}
+}
+
+// KeyboardPerformance is implemented by keyboards with performance settings
+type KeyboardPerformance interface {
+	ProcessGetKeyboardPerformance() interface{}
+	ProcessSetKeyboardPerformance(performance common.KeyboardPerformanceData) uint8
+}
+
+// KeyboardLiveSync is implemented by keyboards with live RGB sync
+type KeyboardLiveSync interface {
+	ProcessSetKeyboardLiveSync(enabled bool) uint8
+}
+
+// KeyAssignment is implemented by devices with key assignments
+type KeyAssignment interface {
+	UpdateDeviceKeyAssignment(keyIndex int, keyAssignment inputmanager.KeyAssignment) uint8
+}
+
+// KeyAssignmentLayers is implemented by devices applying key assignment layers to hardware
+type KeyAssignmentLayers interface {
+	ApplyKeyAssignmentLayer()
+}
+
+// KeyAssignmentTypes is implemented by devices exposing key assignment types
+type KeyAssignmentTypes interface {
+	ProcessGetKeyAssignmentTypes() interface{}
+}
+
+// KeyAssignmentModifiers is implemented by devices exposing key assignment modifiers
+type KeyAssignmentModifiers interface {
+	ProcessGetKeyAssignmentModifiers() interface{}
+}
+
+// KeyActuation is implemented by keyboards with adjustable actuation point
+type KeyActuation interface {
+	UpdateDeviceKeyActuation(keyIndex int, keyActuation keyboards.KeyActuation) uint8
+}
+
+// FlashTap is implemented by keyboards with FlashTap support
+type FlashTap interface {
+	GetFlashTap() interface{}
+	ProcessGetKeyboardKeys() interface{}
+	ProcessSetKeyboardFlashTap(keys []int, flashTap keyboards.FlashTap) uint8
+}
+
+// ControlDial is implemented by keyboards with control dial
+type ControlDial interface {
+	UpdateControlDial(value int) uint8
+}
+
+// ControlDialColors is implemented by keyboards with control dial colors
+type ControlDialColors interface {
+	ProcessGetKeyboardControlDialColors() interface{}
+	ProcessSetKeyboardControlDialColors(colors map[int]rgb.Color) uint8
+}
+
+// DebounceTime is implemented by keyboards with switch debounce time
+type DebounceTime interface {
+	UpdateDebounceTime(debounceTime int) uint8
+}
+
+// AutoBrightness is implemented by devices with automatic brightness
+type AutoBrightness interface {
+	UpdateAutoBrightness(brightnessMode int) uint8
+}
+
+// PairedDevice is implemented by wireless devices connected through a receiver
+type PairedDevice interface {
+	Connect()
+	SetConnected(value bool)
+	StopInternal()
+}
+
+// BatteryLevel is implemented by wireless devices reporting battery level through a receiver
+type BatteryLevel interface {
+	ModifyBatteryLevel(batteryLevel uint16)
+}
+
+// SleepMode is implemented by wireless devices with sleep mode
+type SleepMode interface {
+	GetSleepMode() int
+}
+
+// SleepModeSwitch is implemented by wireless devices put to sleep by a receiver
+type SleepModeSwitch interface {
+	SetSleepMode()
+}
+
+// KeyboardKeyTrigger is implemented by keyboards receiving key events through a receiver
+type KeyboardKeyTrigger interface {
+	TriggerKeyAssignment(data []byte)
+}
+
+// MouseKeyTrigger is implemented by mice receiving key events through a receiver
+type MouseKeyTrigger interface {
+	TriggerKeyAssignment(value uint32)
+}
+
+// ShortKeyTrigger is implemented by mice receiving 16-bit key events through a receiver
+type ShortKeyTrigger interface {
+	TriggerKeyAssignment(value uint16)
+}
+
+// TiltTrigger is implemented by mice receiving wheel tilt events through a receiver
+type TiltTrigger interface {
+	TriggerTiltAssignment(value uint32)
+}
+
+// RgbLayout is implemented by devices with addressable LEDs
+type RgbLayout interface {
+	GetRgbLayout() RgbInfo
+}
+
+// DpiRange is implemented by mice with DPI stages
+type DpiRange interface {
+	GetDpiRange() (minDpi, maxDpi, stages int)
+	GetDpiStages() map[int]uint16
+}
+
+// PollingRateOptions is implemented by devices with selectable polling rate
+type PollingRateOptions interface {
+	GetPollingRates() map[int]string
+}
+
+// SleepModeOptions is implemented by wireless devices with selectable sleep mode
+type SleepModeOptions interface {
+	GetSleepModes() map[int]string
+}
+
+// BatteryStatus is implemented by devices reporting battery level
+type BatteryStatus interface {
+	GetBatteryLevel() uint16
+}
+
+// StateReader is implemented by devices exposing their current settings
+type StateReader interface {
+	GetDeviceState() State
+}
+
+// SniperMode is implemented by mice able to switch sniper mode on request of another device
+type SniperMode interface {
+	CallSniperMode(active bool)
+}
+
+// DpiControl is implemented by mice able to change DPI stage on request of another device
+type DpiControl interface {
+	ModifyDpi(increment bool)
+}
+
+// HapticEngine is implemented by controllers able to run haptic engine on request of input manager
+type HapticEngine interface {
+	TriggerHapticEngineExternal(left, right byte)
+}
+
+// SleepTimer is implemented by wireless devices with sleep timer
+type SleepTimer interface {
+	UpdateSleepTimer(minutes int) uint8
+}
+
+// PollingRate is implemented by devices with adjustable polling rate
+type PollingRate interface {
+	UpdatePollingRate(pullingRate int) uint8
+}
+
+// MouseDpi is implemented by mice with DPI stages
+type MouseDpi interface {
+	SaveMouseDPI(stages map[int]uint16) uint8
+}
+
+// MouseZoneColors is implemented by mice with zone colors
+type MouseZoneColors interface {
+	SaveMouseZoneColors(dpi rgb.Color, zoneColors map[int]rgb.Color) uint8
+}
+
+// MouseDpiColors is implemented by mice with DPI stage colors
+type MouseDpiColors interface {
+	SaveMouseDpiColors(dpi rgb.Color, dpiColors map[int]rgb.Color) uint8
+}
+
+// MouseSniperColors is implemented by mice with zone and sniper colors
+type MouseSniperColors interface {
+	SaveMouseZoneColorsSniper(dpi rgb.Color, zoneColors map[int]rgb.Color, sniper rgb.Color) uint8
+}
+
+// MouseGestures is implemented by mice with multi gestures
+type MouseGestures interface {
+	SaveMouseGestures(multiGesture int, zoneTilts map[int]uint8) uint8
+}
+
+// AngleSnapping is implemented by mice with angle snapping
+type AngleSnapping interface {
+	UpdateAngleSnapping(angleSnappingMode int) uint8
+}
+
+// ButtonOptimization is implemented by mice with button response optimization
+type ButtonOptimization interface {
+	UpdateButtonOptimization(buttonOptimizationMode int) uint8
+}
+
+// LiftHeight is implemented by mice with adjustable lift height
+type LiftHeight interface {
+	UpdateLiftHeight(liftHeight int) uint8
+}
+
+// LeftHandMode is implemented by mice with left hand mode
+type LeftHandMode interface {
+	UpdateLeftHandMode(leftHandMode int) uint8
+}
+
+// SurfaceSelection is implemented by mice with surface selection
+type SurfaceSelection interface {
+	UpdateSurfaceSelection(surfaceSelection int) uint8
+}
+
+// MotionSync is implemented by mice with motion sync
+type MotionSync interface {
+	UpdateMotionSync(motionSync int) uint8
+}
+
+// RippleControl is implemented by mice with ripple control
+type RippleControl interface {
+	UpdateRippleControl(rippleControl int) uint8
+}
+
+// Equalizer is implemented by headsets with equalizer
+type Equalizer interface {
+	GetEqualizers() interface{}
+	UpdateEqualizer(values map[int]float64) uint8
+}
+
+// HeadsetZoneColors is implemented by headsets with zone colors
+type HeadsetZoneColors interface {
+	SaveHeadsetZoneColors(zoneColors map[int]rgb.Color) uint8
+}
+
+// MuteIndicator is implemented by headsets with mute indicator
+type MuteIndicator interface {
+	UpdateMuteIndicator(value int) uint8
+}
+
+// Sidetone is implemented by headsets with sidetone
+type Sidetone interface {
+	UpdateSidetone(value int) uint8
+	UpdateSidetoneValue(value int) uint8
+}
+
+// NoiseCancellation is implemented by headsets with active noise cancellation
+type NoiseCancellation interface {
+	UpdateActiveNoiseCancellation(value int) uint8
+}
+
+// WheelOption is implemented by headsets with configurable wheels
+type WheelOption interface {
+	UpdateWheelOption(wheelId, wheelOption uint8) uint8
+}
+
+// ControllerZoneColors is implemented by controllers with zone colors
+type ControllerZoneColors interface {
+	SaveControllerZoneColors(zoneColors map[int]rgb.Color) uint8
+}
+
+// ControllerVibration is implemented by controllers with vibration modules
+type ControllerVibration interface {
+	ProcessControllerVibration(module, value uint8) uint8
+}
+
+// ControllerEmulation is implemented by controllers with analog stick emulation
+type ControllerEmulation interface {
+	ProcessControllerEmulation(module, mode, sensitivityX, sensitivityY uint8, invertYAxis bool) uint8
+}
+
+// ControllerGraph is implemented by controllers with analog response curves
+type ControllerGraph interface {
+	ProcessGetControllerGraph() interface{}
+	ProcessSetControllerGraph(analogDevice int, deadZoneMin, deadZoneMax uint8, curveData []common.CurveData) uint8
+}
@@ -0,0 +1,379 @@
+package capabilities
+
+// Package: capabilities
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/devices/lcd"
+	"reflect"
+	"sort"
+)
+
+// Descriptor contains everything a device is able to do
+type Descriptor struct {
+	Serial        string         `json:"serial"`
+	Product       string         `json:"product"`
+	ProductType   uint16         `json:"productType"`
+	DeviceType    uint32         `json:"deviceType"`
+	Features      []string       `json:"features"`
+	Rgb           *RgbInfo       `json:"rgb,omitempty"`
+	Channels      []ChannelInfo  `json:"channels,omitempty"`
+	Lcd           *LcdInfo       `json:"lcd,omitempty"`
+	Dpi           *DpiInfo       `json:"dpi,omitempty"`
+	PollingRates  map[int]string `json:"pollingRates,omitempty"`
+	SleepModes    map[int]string `json:"sleepModes,omitempty"`
+	Equalizer     []string       `json:"equalizer,omitempty"`
+	KeyAssignment bool           `json:"keyAssignment"`
+	Battery       bool           `json:"battery"`
+
+	UserProfile     string `json:"userProfile,omitempty"`
+	KeyboardProfile string `json:"keyboardProfile,omitempty"`
+}
+
+// RgbInfo contains RGB zone and LED layout
+type RgbInfo struct {
+	LedChannels           int `json:"ledChannels"`
+	ChangeableLedChannels int `json:"changeableLedChannels,omitempty"`
+	Zones                 int `json:"zones,omitempty"`
+}
+
+// ChannelInfo contains a single hub channel
+type ChannelInfo struct {
+	ChannelId   int    `json:"channelId"`
+	Name        string `json:"name"`
+	LedChannels int    `json:"ledChannels"`
+	Fan         bool   `json:"fan"`
+	Pump        bool   `json:"pump"`
+	Probe       bool   `json:"probe"`
+}
+
+// LcdInfo contains LCD screen details
+type LcdInfo struct {
+	Width  int            `json:"width"`
+	Height int            `json:"height"`
+	Modes  map[int]string `json:"modes,omitempty"`
+}
+
+// DpiInfo contains mouse DPI range and stages
+type DpiInfo struct {
+	Min    int      `json:"min"`
+	Max    int      `json:"max"`
+	Stages int      `json:"stages"`
+	Values []uint16 `json:"values,omitempty"`
+}
+
+var features = []struct {
+	name  string
+	check func(device interface{}) bool
+}{
+	{"rgb", func(d interface{}) bool { _, ok := d.(Rgb); return ok }},
+	{"openRgb", func(d interface{}) bool { _, ok := d.(OpenRgb); return ok }},
+	{"hardwareRgb", func(d interface{}) bool { _, ok := d.(HardwareRgb); return ok }},
+	{"brightness", func(d interface{}) bool { _, ok := d.(Brightness); return ok }},
+	{"speedProfile", func(d interface{}) bool { _, ok := d.(SpeedProfile); return ok }},
+	{"manualSpeed", func(d interface{}) bool { _, ok := d.(ManualSpeed); return ok }},
+	{"temperatureProbes", func(d interface{}) bool { _, ok := d.(TemperatureProbes); return ok }},
+	{"lcd", func(d interface{}) bool { _, ok := d.(Lcd); return ok }},
+	{"lcdProfile", func(d interface{}) bool { _, ok := d.(LcdProfile); return ok }},
+	{"keyboardProfiles", func(d interface{}) bool { _, ok := d.(KeyboardProfiles); return ok }},
+	{"keyAssignment", func(d interface{}) bool { _, ok := d.(KeyAssignment); return ok }},
+	{"pollingRate", func(d interface{}) bool { _, ok := d.(PollingRate); return ok }},
+	{"sleepTimer", func(d interface{}) bool { _, ok := d.(SleepTimer); return ok }},
+	{"dpi", func(d interface{}) bool { _, ok := d.(MouseDpi); return ok }},
+	{"equalizer", func(d interface{}) bool { _, ok := d.(Equalizer); return ok }},
+	{"sidetone", func(d interface{}) bool { _, ok := d.(Sidetone); return ok }},
+	{"noiseCancellation", func(d interface{}) bool { _, ok := d.(NoiseCancellation); return ok }},
+	{"vibration", func(d interface{}) bool { _, ok := d.(ControllerVibration); return ok }},
+	{"userProfiles", func(d interface{}) bool { _, ok := d.(UserProfiles); return ok }},
+}
+
+// Describe will build capability descriptor from what given device exposes
+func Describe(device *common.Device) *Descriptor {
+	instance := device.Instance
+	descriptor := &Descriptor{
+		Serial:      device.Serial,
+		Product:     device.Product,
+		ProductType: device.ProductType,
+		DeviceType:  device.DeviceType,
+		Features:    make([]string, 0),
+	}
+
+	for _, feature := range features {
+		if feature.check(instance) {
+			descriptor.Features = append(descriptor.Features, feature.name)
+		}
+	}
+
+	v := reflect.ValueOf(instance)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return descriptor
+	}
+
+	// RGB
+	if dev, ok := instance.(RgbLayout); ok {
+		if layout := dev.GetRgbLayout(); layout.LedChannels > 0 {
+			descriptor.Rgb = &layout
+		}
+	}
+
+	// Hub channels
+	descriptor.Channels = channels(v.FieldByName("Devices"))
+
+	// LCD
+	if boolField(v, "HasLCD") {
+		width, height := lcd.GetLcdResolution()
+		if resolution, ok := instance.(LcdResolution); ok {
+			width, height = resolution.GetLcdResolution()
+		}
+		descriptor.Lcd = &LcdInfo{
+			Width:  width,
+			Height: height,
+			Modes:  stringMap(v.FieldByName("LCDModes")),
+		}
+	}
+
+	// DPI
+	if dev, ok := instance.(DpiRange); ok {
+		if minDpi, maxDpi, stages := dev.GetDpiRange(); maxDpi > 0 {
+			descriptor.Dpi = &DpiInfo{
+				Min:    minDpi,
+				Max:    maxDpi,
+				Stages: stages,
+				Values: dpiValues(dev.GetDpiStages()),
+			}
+		}
+	}
+
+	if dev, ok := instance.(PollingRateOptions); ok {
+		descriptor.PollingRates = copyOptions(dev.GetPollingRates())
+	}
+	if dev, ok := instance.(SleepModeOptions); ok {
+		descriptor.SleepModes = copyOptions(dev.GetSleepModes())
+	}
+	descriptor.Equalizer = equalizerBands(v.FieldByName("DeviceProfile"))
+	_, descriptor.KeyAssignment = instance.(KeyAssignment)
+	_, descriptor.Battery = instance.(BatteryStatus)
+	descriptor.UserProfile = ActiveUserProfile(instance)
+	descriptor.KeyboardProfile = ActiveKeyboardProfile(instance)
+	return descriptor
+}
+
+// ActiveUserProfile will return name of currently active user profile, or empty string
+func ActiveUserProfile(instance interface{}) string {
+	v := structValue(reflect.ValueOf(instance))
+	if !v.IsValid() {
+		return ""
+	}
+
+	profiles := v.FieldByName("UserProfiles")
+	if !profiles.IsValid() || profiles.Kind() != reflect.Map || profiles.Type().Key().Kind() != reflect.String {
+		return ""
+	}
+
+	iter := profiles.MapRange()
+	for iter.Next() {
+		if profile := structValue(iter.Value()); profile.IsValid() && boolField(profile, "Active") {
+			return iter.Key().String()
+		}
+	}
+	return ""
+}
+
+// UserProfileNames will return sorted names of all device user profiles
+func UserProfileNames(instance interface{}) []string {
+	v := structValue(reflect.ValueOf(instance))
+	if !v.IsValid() {
+		return nil
+	}
+
+	profiles := v.FieldByName("UserProfiles")
+	if !profiles.IsValid() || profiles.Kind() != reflect.Map || profiles.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	names := make([]string, 0, profiles.Len())
+	for _, key := range profiles.MapKeys() {
+		names = append(names, key.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ActiveKeyboardProfile will return name of currently active keyboard profile, or empty string
+func ActiveKeyboardProfile(instance interface{}) string {
+	if _, ok := instance.(KeyboardProfiles); !ok {
+		return ""
+	}
+
+	v := structValue(reflect.ValueOf(instance))
+	if !v.IsValid() {
+		return ""
+	}
+
+	profile := structValue(v.FieldByName("DeviceProfile"))
+	if !profile.IsValid() {
+		return ""
+	}
+	return stringField(profile, "Profile")
+}
+
+// intField will return integer value of a named struct field, or 0
+func intField(v reflect.Value, name string) int {
+	field := v.FieldByName(name)
+	if !field.IsValid() {
+		return 0
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(field.Uint())
+	default:
+		return 0
+	}
+}
+
+// boolField will return boolean value of a named struct field, or false
+func boolField(v reflect.Value, name string) bool {
+	field := v.FieldByName(name)
+	if !field.IsValid() || field.Kind() != reflect.Bool {
+		return false
+	}
+	return field.Bool()
+}
+
+// stringField will return string value of a named struct field, or empty string
+func stringField(v reflect.Value, name string) string {
+	field := v.FieldByName(name)
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// stringMap will copy map[int]string field, or return nil
+func stringMap(field reflect.Value) map[int]string {
+	if !field.IsValid() || field.Kind() != reflect.Map || field.Len() == 0 {
+		return nil
+	}
+	if field.Type().Key().Kind() != reflect.Int || field.Type().Elem().Kind() != reflect.String {
+		return nil
+	}
+
+	values := make(map[int]string, field.Len())
+	iter := field.MapRange()
+	for iter.Next() {
+		values[int(iter.Key().Int())] = iter.Value().String()
+	}
+	return values
+}
+
+// structValue will dereference pointer to struct, or return invalid value
+func structValue(v reflect.Value) reflect.Value {
+	if v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return v
+}
+
+// sortedKeys will return integer map keys in ascending order
+func sortedKeys(field reflect.Value) []reflect.Value {
+	keys := field.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Int() < keys[j].Int()
+	})
+	return keys
+}
+
+// channels will build hub channel list from Devices map
+func channels(field reflect.Value) []ChannelInfo {
+	if !field.IsValid() || field.Kind() != reflect.Map || field.Type().Key().Kind() != reflect.Int {
+		return nil
+	}
+
+	var list []ChannelInfo
+	for _, key := range sortedKeys(field) {
+		channel := structValue(field.MapIndex(key))
+		if !channel.IsValid() {
+			// Devices map of peripherals holds plain names, not channels
+			return nil
+		}
+		name := channel.FieldByName("Name")
+		info := ChannelInfo{
+			ChannelId:   int(key.Int()),
+			LedChannels: intField(channel, "LedChannels"),
+			Fan:         boolField(channel, "HasSpeed") && !boolField(channel, "ContainsPump"),
+			Pump:        boolField(channel, "ContainsPump"),
+			Probe:       boolField(channel, "IsTemperatureProbe"),
+		}
+		if name.IsValid() && name.Kind() == reflect.String {
+			info.Name = name.String()
+		}
+		list = append(list, info)
+	}
+	return list
+}
+
+// dpiValues will return DPI stage values in ascending stage order
+func dpiValues(stages map[int]uint16) []uint16 {
+	keys := make([]int, 0, len(stages))
+	for key := range stages {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	var values []uint16
+	for _, key := range keys {
+		values = append(values, stages[key])
+	}
+	return values
+}
+
+// copyOptions will copy map of selectable options, or return nil when there are none
+func copyOptions(options map[int]string) map[int]string {
+	if len(options) == 0 {
+		return nil
+	}
+
+	values := make(map[int]string, len(options))
+	for key, value := range options {
+		values[key] = value
+	}
+	return values
+}
+
+// equalizerBands will return equalizer band names of active profile
+func equalizerBands(profile reflect.Value) []string {
+	profile = structValue(profile)
+	if !profile.IsValid() {
+		return nil
+	}
+
+	equalizers := profile.FieldByName("Equalizers")
+	if !equalizers.IsValid() || equalizers.Kind() != reflect.Map || equalizers.Type().Key().Kind() != reflect.Int {
+		return nil
+	}
+
+	var bands []string
+	for _, key := range sortedKeys(equalizers) {
+		band := structValue(equalizers.MapIndex(key))
+		if !band.IsValid() {
+			continue
+		}
+		if name := band.FieldByName("Name"); name.IsValid() && name.Kind() == reflect.String {
+			bands = append(bands, name.String())
+		}
+	}
+	return bands
+}
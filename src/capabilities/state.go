@@ -0,0 +1,112 @@
+package capabilities
+
+// Package: capabilities
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+// State contains current user facing settings of a device
+type State struct {
+	UserProfile     string         `json:"userProfile,omitempty"`
+	KeyboardProfile string         `json:"keyboardProfile,omitempty"`
+	RgbProfiles     map[int]string `json:"rgbProfiles,omitempty"`
+	SpeedProfiles   map[int]string `json:"speedProfiles,omitempty"`
+	Brightness      *uint8         `json:"brightness,omitempty"`
+	BrightnessMode  *uint8         `json:"brightnessMode,omitempty"`
+	LcdModes        map[int]uint8  `json:"lcdModes,omitempty"`
+}
+
+// Snapshot will read current state of a device
+func Snapshot(instance interface{}) State {
+	var state State
+	if dev, ok := instance.(StateReader); ok {
+		state = dev.GetDeviceState()
+	}
+	state.UserProfile = ActiveUserProfile(instance)
+	return state
+}
+
+// IsEmpty will return true when state holds no settings
+func (s *State) IsEmpty() bool {
+	return len(s.UserProfile) == 0 && len(s.KeyboardProfile) == 0 && len(s.RgbProfiles) == 0 && len(s.SpeedProfiles) == 0 &&
+		s.Brightness == nil && s.BrightnessMode == nil && len(s.LcdModes) == 0
+}
+
+// Restore will apply previously captured state to a device. Settings equal to current ones are skipped.
+// Returned state contains only settings which were successfully applied.
+func Restore(instance interface{}, state State) State {
+	var applied State
+	if len(state.UserProfile) > 0 && ActiveUserProfile(instance) != state.UserProfile {
+		if dev, ok := instance.(UserProfiles); ok {
+			if dev.ChangeDeviceProfile(state.UserProfile) == 1 {
+				applied.UserProfile = state.UserProfile
+			}
+		}
+	}
+
+	if len(state.KeyboardProfile) > 0 && ActiveKeyboardProfile(instance) != state.KeyboardProfile {
+		if dev, ok := instance.(KeyboardProfiles); ok {
+			if dev.UpdateKeyboardProfile(state.KeyboardProfile) == 1 {
+				applied.KeyboardProfile = state.KeyboardProfile
+			}
+		}
+	}
+
+	// User profile might have changed everything else
+	current := Snapshot(instance)
+
+	if dev, ok := instance.(Rgb); ok {
+		for channelId, profile := range state.RgbProfiles {
+			if current.RgbProfiles[channelId] != profile {
+				if dev.UpdateRgbProfile(channelId, profile) == 1 {
+					if applied.RgbProfiles == nil {
+						applied.RgbProfiles = make(map[int]string)
+					}
+					applied.RgbProfiles[channelId] = profile
+				}
+			}
+		}
+	}
+
+	if dev, ok := instance.(SpeedProfile); ok {
+		for channelId, profile := range state.SpeedProfiles {
+			if current.SpeedProfiles[channelId] != profile {
+				if dev.UpdateSpeedProfile(channelId, profile) == 1 {
+					if applied.SpeedProfiles == nil {
+						applied.SpeedProfiles = make(map[int]string)
+					}
+					applied.SpeedProfiles[channelId] = profile
+				}
+			}
+		}
+	}
+
+	if dev, ok := instance.(BrightnessValue); ok && state.Brightness != nil {
+		if current.Brightness == nil || *current.Brightness != *state.Brightness {
+			if dev.ChangeDeviceBrightnessValue(*state.Brightness) == 1 {
+				applied.Brightness = state.Brightness
+			}
+		}
+	}
+
+	if dev, ok := instance.(Brightness); ok && state.BrightnessMode != nil {
+		if current.BrightnessMode == nil || *current.BrightnessMode != *state.BrightnessMode {
+			if dev.ChangeDeviceBrightness(*state.BrightnessMode) == 1 {
+				applied.BrightnessMode = state.BrightnessMode
+			}
+		}
+	}
+
+	if dev, ok := instance.(Lcd); ok {
+		for channelId, mode := range state.LcdModes {
+			if value, found := current.LcdModes[channelId]; !found || value != mode {
+				if dev.UpdateDeviceLcd(channelId, mode) == 1 {
+					if applied.LcdModes == nil {
+						applied.LcdModes = make(map[int]uint8)
+					}
+					applied.LcdModes[channelId] = mode
+				}
+			}
+		}
+	}
+	return applied
+}
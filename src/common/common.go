@@ -5,13 +5,13 @@ package common
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/transport"
 	"bytes"
 	"crypto/md5"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"github.com/sstallion/go-hid"
 	"golang.org/x/image/draw"
 	"image"
 	"image/color"
@@ -38,8 +38,8 @@ type Display struct {
 }
 
 type Slipstream struct {
-	Dev       *hid.Device
-	Listener  *hid.Device
+	Dev       transport.Device
+	Listener  transport.Device
 	Mutex     sync.Mutex
 	Connected map[uint16]bool
 }
@@ -210,6 +210,7 @@ const (
 	ProductTypeScufEnvisionProV2WU  = 604
 	ProductTypeScufEnvisionProV2W   = 605
 	ProductTypeScufDongleV2         = 606
+	ProductTypeSimulator            = 994
 	ProductTypeXeneonEdge           = 995
 	ProductTypeMotherboard          = 996
 	ProductTypeDongle               = 997
@@ -13,39 +13,112 @@ import (
 	"strings"
 )
 
+type Authentication struct {
+	Enabled        bool     `json:"enabled"`
+	Username       string   `json:"username"`
+	Password       string   `json:"password"`
+	Tokens         []string `json:"tokens"`
+	ReadOnlyTokens []string `json:"readOnlyTokens"`
+}
+
+type Tls struct {
+	Enabled  bool   `json:"enabled"`
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+type Watchdog struct {
+	Enabled  bool   `json:"enabled"`
+	MinSpeed int    `json:"minSpeed"`
+	Delay    int    `json:"delay"`
+	Action   string `json:"action"`
+	Command  string `json:"command"`
+}
+
+type Failsafe struct {
+	Enabled             bool    `json:"enabled"`
+	SensorFailure       bool    `json:"sensorFailure"`
+	StaleTimeout        int     `json:"staleTimeout"`
+	CriticalTemperature float32 `json:"criticalTemperature"`
+}
+
+type Simulator struct {
+	Enabled bool     `json:"enabled"`
+	Devices []string `json:"devices"`
+}
+
+type Transport struct {
+	Capture      bool     `json:"capture"`
+	CaptureInput bool     `json:"captureInput"`
+	Replay       []string `json:"replay"`
+}
+
+type Mqtt struct {
+	Enabled         bool   `json:"enabled"`
+	Broker          string `json:"broker"`
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	ClientId        string `json:"clientId"`
+	BaseTopic       string `json:"baseTopic"`
+	DiscoveryPrefix string `json:"discoveryPrefix"`
+	Interval        int    `json:"interval"`
+}
+
+type HistoryTier struct {
+	Resolution int `json:"resolution"`
+	Retention  int `json:"retention"`
+}
+
+type History struct {
+	Enabled      bool          `json:"enabled"`
+	SaveInterval int           `json:"saveInterval"`
+	Tiers        []HistoryTier `json:"tiers"`
+}
+
 type Configuration struct {
-	Debug                     bool     `json:"debug"`
-	ListenPort                int      `json:"listenPort"`
-	ListenAddress             string   `json:"listenAddress"`
-	CPUSensorChip             string   `json:"cpuSensorChip"`
-	Manual                    bool     `json:"manual"`
-	Frontend                  bool     `json:"frontend"`
-	Metrics                   bool     `json:"metrics"`
-	Memory                    bool     `json:"memory"`
-	MemorySmBus               string   `json:"memorySmBus"`
-	MemoryType                int      `json:"memoryType"`
-	Exclude                   []uint16 `json:"exclude"`
-	MemorySku                 string   `json:"memorySku"`
-	ConfigPath                string   `json:",omitempty"`
-	ResumeDelay               int      `json:"resumeDelay"`
-	LogFile                   string   `json:"logFile"`
-	LogLevel                  string   `json:"logLevel"`
-	EnhancementKits           []byte   `json:"enhancementKits"`
-	TemperatureOffset         int      `json:"temperatureOffset"`
-	AMDGpuIndex               int      `json:"amdGpuIndex"`
-	AMDSmiPath                string   `json:"amdsmiPath"`
-	CheckDevicePermission     bool     `json:"checkDevicePermission"`
-	GraphProfiles             bool     `json:"graphProfiles"`
-	CpuTempFile               string   `json:"cpuTempFile"`
-	RamTempViaHwmon           bool     `json:"ramTempViaHwmon"`
-	NvidiaGpuIndex            []int    `json:"nvidiaGpuIndex"`
-	DefaultNvidiaGPU          int      `json:"defaultNvidiaGPU"`
-	OpenRGBPort               int      `json:"openRGBPort"`
-	EnableOpenRGBTargetServer bool     `json:"enableOpenRGBTargetServer"`
-	EnableGamepad             bool     `json:"enableGamepad"`
-	EnableMotherboard         bool     `json:"enableMotherboard"`
-	MotherboardBiosOnExit     bool     `json:"motherboardBiosOnExit"`
-	MemoryRegisterOverride    []byte   `json:"memoryRegisterOverride"`
+	Debug                     bool           `json:"debug"`
+	ListenPort                int            `json:"listenPort"`
+	ListenAddress             string         `json:"listenAddress"`
+	CPUSensorChip             string         `json:"cpuSensorChip"`
+	Manual                    bool           `json:"manual"`
+	Frontend                  bool           `json:"frontend"`
+	Metrics                   bool           `json:"metrics"`
+	Memory                    bool           `json:"memory"`
+	MemorySmBus               string         `json:"memorySmBus"`
+	MemoryType                int            `json:"memoryType"`
+	Exclude                   []uint16       `json:"exclude"`
+	MemorySku                 string         `json:"memorySku"`
+	ConfigPath                string         `json:",omitempty"`
+	ResumeDelay               int            `json:"resumeDelay"`
+	LogFile                   string         `json:"logFile"`
+	LogLevel                  string         `json:"logLevel"`
+	EnhancementKits           []byte         `json:"enhancementKits"`
+	TemperatureOffset         int            `json:"temperatureOffset"`
+	AMDGpuIndex               int            `json:"amdGpuIndex"`
+	AMDSmiPath                string         `json:"amdsmiPath"`
+	CheckDevicePermission     bool           `json:"checkDevicePermission"`
+	GraphProfiles             bool           `json:"graphProfiles"`
+	CpuTempFile               string         `json:"cpuTempFile"`
+	RamTempViaHwmon           bool           `json:"ramTempViaHwmon"`
+	NvidiaGpuIndex            []int          `json:"nvidiaGpuIndex"`
+	DefaultNvidiaGPU          int            `json:"defaultNvidiaGPU"`
+	OpenRGBPort               int            `json:"openRGBPort"`
+	EnableOpenRGBTargetServer bool           `json:"enableOpenRGBTargetServer"`
+	EnableGamepad             bool           `json:"enableGamepad"`
+	EnableMotherboard         bool           `json:"enableMotherboard"`
+	MotherboardBiosOnExit     bool           `json:"motherboardBiosOnExit"`
+	MemoryRegisterOverride    []byte         `json:"memoryRegisterOverride"`
+	Authentication            Authentication `json:"authentication"`
+	Tls                       Tls            `json:"tls"`
+	UnixSocket                string         `json:"unixSocket"`
+	Watchdog                  Watchdog       `json:"watchdog"`
+	Failsafe                  Failsafe       `json:"failsafe"`
+	Simulator                 Simulator      `json:"simulator"`
+	Transport                 Transport      `json:"transport"`
+	DBus                      string         `json:"dbus"`
+	Mqtt                      Mqtt           `json:"mqtt"`
+	History                   History        `json:"history"`
+	AllowCommands             bool           `json:"allowCommands"`
 }
 
 var (
@@ -72,6 +145,17 @@ var (
 		"enableMotherboard":         false,
 		"motherboardBiosOnExit":     false,
 		"memoryRegisterOverride":    make([]byte, 0),
+		"authentication":            defaultAuthentication(),
+		"tls":                       Tls{},
+		"unixSocket":                "",
+		"watchdog":                  defaultWatchdog(),
+		"failsafe":                  defaultFailsafe(),
+		"simulator":                 defaultSimulator(),
+		"transport":                 defaultTransport(),
+		"dbus":                      "auto",
+		"mqtt":                      defaultMqtt(),
+		"history":                   defaultHistory(),
+		"allowCommands":             false,
 	}
 	systemService = true
 )
@@ -132,6 +216,81 @@ func IsSystemService() bool {
 	return systemService
 }
 
+// defaultAuthentication will return disabled authentication settings
+func defaultAuthentication() Authentication {
+	return Authentication{
+		Enabled:        false,
+		Username:       "",
+		Password:       "",
+		Tokens:         make([]string, 0),
+		ReadOnlyTokens: make([]string, 0),
+	}
+}
+
+// defaultWatchdog will return default fan watchdog settings
+func defaultWatchdog() Watchdog {
+	return Watchdog{
+		Enabled:  true,
+		MinSpeed: 30,
+		Delay:    10,
+		Action:   "none",
+		Command:  "",
+	}
+}
+
+// defaultFailsafe will return default critical temperature failsafe settings
+func defaultFailsafe() Failsafe {
+	return Failsafe{
+		Enabled:             true,
+		SensorFailure:       true,
+		StaleTimeout:        30,
+		CriticalTemperature: 0,
+	}
+}
+
+// defaultSimulator will return default simulated device settings
+func defaultSimulator() Simulator {
+	return Simulator{
+		Enabled: false,
+		Devices: []string{"linkhub", "cc", "k70", "mouse"},
+	}
+}
+
+// defaultTransport will return default HID capture and replay settings
+func defaultTransport() Transport {
+	return Transport{
+		Capture:      false,
+		CaptureInput: false,
+		Replay:       make([]string, 0),
+	}
+}
+
+// defaultMqtt will return disabled MQTT bridge settings
+func defaultMqtt() Mqtt {
+	return Mqtt{
+		Enabled:         false,
+		Broker:          "tcp://127.0.0.1:1883",
+		Username:        "",
+		Password:        "",
+		ClientId:        "",
+		BaseTopic:       "openlinkhub",
+		DiscoveryPrefix: "homeassistant",
+		Interval:        10,
+	}
+}
+
+// defaultHistory will return sensor history with 1 second samples for an hour and 1 minute samples for a week
+func defaultHistory() History {
+	return History{
+		Enabled:      true,
+		SaveInterval: 300,
+		Tiers: []HistoryTier{
+			{Resolution: 10, Retention: 86400},
+			{Resolution: 300, Retention: 2592000},
+		},
+	}
+}
+
 // upgradeFile will create or upgrade config file
 func upgradeFile(cfg string) {
 	if !common.FileExists(cfg) {
@@ -167,6 +326,16 @@ func upgradeFile(cfg string) {
 			EnableMotherboard:         false,
 			MotherboardBiosOnExit:     false,
 			MemoryRegisterOverride:    make([]byte, 0),
+			Authentication:            defaultAuthentication(),
+			Tls:                       Tls{},
+			UnixSocket:                "",
+			Watchdog:                  defaultWatchdog(),
+			Failsafe:                  defaultFailsafe(),
+			Simulator:                 defaultSimulator(),
+			Transport:                 defaultTransport(),
+			DBus:                      "auto",
+			Mqtt:                      defaultMqtt(),
+			History:                   defaultHistory(),
 		}
 		saveConfigSettings(value)
 	} else {
@@ -8,9 +8,11 @@ import (
 	"OpenLinkHub/src/audio"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dashboard"
+	"OpenLinkHub/src/dbusservice"
 	"OpenLinkHub/src/devices"
 	"OpenLinkHub/src/devices/lcd"
 	"OpenLinkHub/src/display"
+	"OpenLinkHub/src/history"
 	"OpenLinkHub/src/inputmanager"
 	"OpenLinkHub/src/keyboards"
 	"OpenLinkHub/src/language"
@@ -20,7 +22,10 @@ import (
 	"OpenLinkHub/src/metrics"
 	"OpenLinkHub/src/monitor"
 	"OpenLinkHub/src/motherboards"
+	"OpenLinkHub/src/mqtt"
+	"OpenLinkHub/src/process"
 	"OpenLinkHub/src/rgb"
+	"OpenLinkHub/src/scenes"
 	"OpenLinkHub/src/scheduler"
 	"OpenLinkHub/src/server"
 	"OpenLinkHub/src/stats"
@@ -47,16 +52,23 @@ func Start() {
 	inputmanager.Init() // Input Manager
 	stats.Init()        // Statistics
 	macro.Init()        // Macro
+	scenes.Init()       // Scenes
 	motherboards.Init() // Motherboards
 	devices.Init()      // Devices
 	monitor.Init()      // Monitor
 	language.Init()     // Language
 	scheduler.Init()    // Scheduler
+	process.Init()      // Process watcher
+	dbusservice.Init()  // D-Bus service
+	mqtt.Init()         // MQTT bridge
+	history.Init()      // Sensor history
 	server.Init()       // REST & WebUI
 }
 
 // Stop will stop device control
 func Stop() {
+	mqtt.Stop()         // MQTT bridge
+	history.Stop()      // Sensor history
 	devices.Stop()      // Devices
 	inputmanager.Stop() // Cleanup virtual devices
 	audio.StopAudio()   // Virtual Audio
@@ -0,0 +1,211 @@
+package ctl
+
+// Package: ctl
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// response is a generic REST API response
+type response struct {
+	Code    int             `json:"code"`
+	Status  int             `json:"status"`
+	Message string          `json:"message"`
+	Devices json.RawMessage `json:"devices"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// client talks to a running OpenLinkHub service via TCP or unix socket
+type client struct {
+	http     *http.Client
+	baseUrl  string
+	token    string
+	username string
+	password string
+}
+
+// newClient will create API client for given options
+func newClient(opts *options) *client {
+	transport := &http.Transport{}
+	baseUrl := strings.TrimRight(opts.address, "/")
+	if len(opts.socket) > 0 {
+		socket := opts.socket
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		baseUrl = "http://unix"
+	}
+	if opts.insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &client{
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(opts.timeout) * time.Second,
+		},
+		baseUrl:  baseUrl,
+		token:    opts.token,
+		username: opts.username,
+		password: opts.password,
+	}
+}
+
+// do will send request with credentials and return raw HTTP response
+func (c *client) do(method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseUrl+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else if len(c.username) > 0 && len(c.password) > 0 {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(message)))
+	}
+	return res, nil
+}
+
+// request will send JSON request and decode API response
+func (c *client) request(method, path string, payload interface{}) (*response, error) {
+	var body io.Reader
+	contentType := ""
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	res, err := c.do(method, path, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	resp := &response{}
+	if err = json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return nil, fmt.Errorf("unable to decode response: %w", err)
+	}
+	return resp, nil
+}
+
+// get will send GET request and decode data into v
+func (c *client) get(path string, v interface{}) error {
+	resp, err := c.request(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	data := resp.Data
+	if len(resp.Devices) > 0 {
+		data = resp.Devices
+	}
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// post will send POST request and return API message. Rejected request is returned as error
+func (c *client) post(path string, payload interface{}) (string, error) {
+	resp, err := c.request(http.MethodPost, path, payload)
+	if err != nil {
+		return "", err
+	}
+	if resp.Status != 1 {
+		return "", errors.New(resp.Message)
+	}
+	return resp.Message, nil
+}
+
+// download will save response body to destination. Empty destination uses file name sent by the server
+func (c *client) download(path, destination string) (string, error) {
+	res, err := c.do(http.MethodGet, path, "", nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if len(destination) == 0 {
+		destination = "backup.zip"
+		if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil && len(params["filename"]) > 0 {
+			destination = filepath.Base(params["filename"])
+		}
+	}
+
+	file, err := os.Create(destination)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(file, res.Body); err != nil {
+		_ = file.Close()
+		return "", err
+	}
+	return destination, file.Close()
+}
+
+// upload will send file as multipart form field and return plain text response
+func (c *client) upload(path, field, source string) (string, error) {
+	file, err := os.Open(source)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(field, filepath.Base(source))
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+
+	res, err := c.do(http.MethodPost, path, writer.FormDataContentType(), body)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	message, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(message)), nil
+}
@@ -0,0 +1,321 @@
+package ctl
+
+// Package: ctl
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+var commands = []command{
+	{"devices", "", "List connected devices", listDevices},
+	{"temperatures", "", "Show CPU, GPU, storage and device temperatures", listTemperatures},
+	{"speed", "<serial> <channel> <profile>", "Set fan speed profile, channel -1 for all channels", setSpeed},
+	{"rgb", "<serial> <channel> <profile>", "Set RGB profile, channel -1 for all channels", setRgb},
+	{"brightness", "<serial> <0-100>", "Set device brightness", setBrightness},
+	{"brightness-mode", "<serial> <0-4>", "Set device brightness mode", setBrightnessMode},
+	{"profile", "<serial> <name>", "Switch device user profile", setUserProfile},
+	{"scenes", "", "List scenes", listScenes},
+	{"scene", "<id>", "Apply scene", applyScene},
+	{"backup", "[file]", "Download database backup", backup},
+	{"restore", "<file>", "Restore database backup", restore},
+}
+
+type deviceRow struct {
+	Serial   string `json:"serial"`
+	Product  string `json:"product"`
+	Firmware string `json:"firmware"`
+	Hidden   bool   `json:"hidden"`
+}
+
+type temperatureRow struct {
+	Source      string  `json:"source"`
+	Serial      string  `json:"serial,omitempty"`
+	Name        string  `json:"name"`
+	Temperature float64 `json:"temperature"`
+}
+
+type sceneRow struct {
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	Devices int    `json:"devices"`
+}
+
+// apiDevice is a device entry of /api/devices/ response
+type apiDevice struct {
+	Product   string          `json:"Product"`
+	Serial    string          `json:"Serial"`
+	Hidden    bool            `json:"Hidden"`
+	GetDevice json.RawMessage `json:"GetDevice"`
+}
+
+// getDevices will return connected devices sorted by serial
+func getDevices(c *client) ([]apiDevice, error) {
+	var list map[string]apiDevice
+	if err := c.get("/api/devices/", &list); err != nil {
+		return nil, err
+	}
+
+	result := make([]apiDevice, 0, len(list))
+	for _, device := range list {
+		result = append(result, device)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Serial < result[j].Serial })
+	return result, nil
+}
+
+// listDevices will print connected devices
+func listDevices(c *client, out *output, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	list, err := getDevices(c)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]deviceRow, 0, len(list))
+	table := make([][]string, 0, len(list))
+	for _, device := range list {
+		// Device structures differ between drivers, so only pick what is there
+		var info struct {
+			Firmware string `json:"firmware"`
+		}
+		_ = json.Unmarshal(device.GetDevice, &info)
+
+		row := deviceRow{Serial: device.Serial, Product: device.Product, Firmware: info.Firmware, Hidden: device.Hidden}
+		rows = append(rows, row)
+		table = append(table, []string{row.Serial, row.Product, row.Firmware, strconv.FormatBool(row.Hidden)})
+	}
+	return out.table(rows, []string{"SERIAL", "PRODUCT", "FIRMWARE", "HIDDEN"}, table)
+}
+
+// listTemperatures will print system and device temperatures
+func listTemperatures(c *client, out *output, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	var cpu, gpu float64
+	if err := c.get("/api/cpuTemp/clean", &cpu); err != nil {
+		return err
+	}
+	if err := c.get("/api/gpuTemp/clean", &gpu); err != nil {
+		return err
+	}
+	rows := []temperatureRow{
+		{Source: "cpu", Name: "CPU", Temperature: cpu},
+		{Source: "gpu", Name: "GPU", Temperature: gpu},
+	}
+
+	var storage []struct {
+		Model       string
+		Temperature float64
+	}
+	if err := c.get("/api/storageTemp", &storage); err != nil {
+		return err
+	}
+	for _, drive := range storage {
+		rows = append(rows, temperatureRow{Source: "storage", Name: drive.Model, Temperature: drive.Temperature})
+	}
+
+	list, err := getDevices(c)
+	if err != nil {
+		return err
+	}
+	for _, device := range list {
+		rows = append(rows, deviceTemperatures(device)...)
+	}
+
+	table := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		table = append(table, []string{row.Source, row.Serial, row.Name, strconv.FormatFloat(row.Temperature, 'f', 1, 64)})
+	}
+	return out.table(rows, []string{"SOURCE", "SERIAL", "NAME", "TEMPERATURE"}, table)
+}
+
+// deviceTemperatures will return temperatures of device channels with temperature sensor
+func deviceTemperatures(device apiDevice) []temperatureRow {
+	var info struct {
+		Devices map[int]json.RawMessage `json:"devices"`
+	}
+	if json.Unmarshal(device.GetDevice, &info) != nil {
+		return nil
+	}
+
+	channelIds := make([]int, 0, len(info.Devices))
+	for channelId := range info.Devices {
+		channelIds = append(channelIds, channelId)
+	}
+	sort.Ints(channelIds)
+
+	var rows []temperatureRow
+	for _, channelId := range channelIds {
+		var channel struct {
+			Name        string  `json:"name"`
+			Temperature float64 `json:"temperature"`
+			HasTemps    bool    `json:"HasTemps"`
+		}
+		if json.Unmarshal(info.Devices[channelId], &channel) != nil || !channel.HasTemps {
+			continue
+		}
+		rows = append(rows, temperatureRow{Source: "device", Serial: device.Serial, Name: channel.Name, Temperature: channel.Temperature})
+	}
+	return rows
+}
+
+// setSpeed will change fan speed profile
+func setSpeed(c *client, out *output, args []string) error {
+	return setChannelProfile(c, out, "/api/speed", args)
+}
+
+// setRgb will change RGB profile
+func setRgb(c *client, out *output, args []string) error {
+	return setChannelProfile(c, out, "/api/color", args)
+}
+
+// setChannelProfile will send profile change of a device channel
+func setChannelProfile(c *client, out *output, path string, args []string) error {
+	if len(args) != 3 {
+		return errUsage
+	}
+	channelId, err := strconv.Atoi(args[1])
+	if err != nil {
+		return errUsage
+	}
+
+	message, err := c.post(path, map[string]interface{}{
+		"deviceId":  args[0],
+		"channelId": channelId,
+		"profile":   args[2],
+	})
+	if err != nil {
+		return err
+	}
+	return out.message(message)
+}
+
+// setBrightness will change brightness via value from 0 to 100
+func setBrightness(c *client, out *output, args []string) error {
+	return changeBrightness(c, out, "/api/brightness/gradual", 100, args)
+}
+
+// setBrightnessMode will change brightness via predefined mode
+func setBrightnessMode(c *client, out *output, args []string) error {
+	return changeBrightness(c, out, "/api/brightness", 4, args)
+}
+
+// changeBrightness will send brightness change
+func changeBrightness(c *client, out *output, path string, limit int, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	value, err := strconv.Atoi(args[1])
+	if err != nil || value < 0 || value > limit {
+		return errUsage
+	}
+
+	message, err := c.post(path, map[string]interface{}{
+		"deviceId":   args[0],
+		"brightness": value,
+	})
+	if err != nil {
+		return err
+	}
+	return out.message(message)
+}
+
+// setUserProfile will switch device user profile
+func setUserProfile(c *client, out *output, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+
+	message, err := c.post("/api/userProfile/change", map[string]interface{}{
+		"deviceId":        args[0],
+		"userProfileName": args[1],
+	})
+	if err != nil {
+		return err
+	}
+	return out.message(message)
+}
+
+// listScenes will print saved scenes
+func listScenes(c *client, out *output, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	var list map[int]struct {
+		Name    string                     `json:"name"`
+		Devices map[string]json.RawMessage `json:"devices"`
+	}
+	if err := c.get("/api/scenes/", &list); err != nil {
+		return err
+	}
+
+	rows := make([]sceneRow, 0, len(list))
+	for id, scene := range list {
+		rows = append(rows, sceneRow{Id: id, Name: scene.Name, Devices: len(scene.Devices)})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Id < rows[j].Id })
+
+	table := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		table = append(table, []string{strconv.Itoa(row.Id), row.Name, strconv.Itoa(row.Devices)})
+	}
+	return out.table(rows, []string{"ID", "NAME", "DEVICES"}, table)
+}
+
+// applyScene will apply scene by id
+func applyScene(c *client, out *output, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	sceneId, err := strconv.Atoi(args[0])
+	if err != nil {
+		return errUsage
+	}
+
+	message, err := c.post("/api/scenes/apply", map[string]interface{}{"sceneId": sceneId})
+	if err != nil {
+		return err
+	}
+	return out.message(message)
+}
+
+// backup will download database backup archive
+func backup(c *client, out *output, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	destination := ""
+	if len(args) == 1 {
+		destination = args[0]
+	}
+
+	file, err := c.download("/api/backup", destination)
+	if err != nil {
+		return err
+	}
+	return out.message(fmt.Sprintf("Backup saved to %s", file))
+}
+
+// restore will upload database backup archive
+func restore(c *client, out *output, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	message, err := c.upload("/api/restore", "backupFile", args[0])
+	if err != nil {
+		return err
+	}
+	return out.message(message)
+}
@@ -0,0 +1,206 @@
+package ctl
+
+// Package: ctl
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultAddress = "http://127.0.0.1:27003"
+
+// options contains connection and output settings
+type options struct {
+	address  string
+	socket   string
+	token    string
+	username string
+	password string
+	config   string
+	insecure bool
+	json     bool
+	timeout  int
+}
+
+// serviceConfig contains config.json fields required to reach the service
+type serviceConfig struct {
+	ListenPort    int    `json:"listenPort"`
+	ListenAddress string `json:"listenAddress"`
+	UnixSocket    string `json:"unixSocket"`
+	Tls           struct {
+		Enabled bool `json:"enabled"`
+	} `json:"tls"`
+	Authentication struct {
+		Enabled  bool   `json:"enabled"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"authentication"`
+}
+
+// command is a single ctl sub-command
+type command struct {
+	name        string
+	args        string
+	description string
+	run         func(c *client, out *output, args []string) error
+}
+
+var errUsage = errors.New("invalid arguments")
+
+// Run will execute ctl sub-command and return process exit code
+func Run(args []string) int {
+	opts := &options{}
+	flags := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	flags.StringVar(&opts.address, "address", "", "service address, e.g. "+defaultAddress+" (env OPENLINKHUB_ADDRESS)")
+	flags.StringVar(&opts.socket, "socket", "", "service unix socket path")
+	flags.StringVar(&opts.token, "token", "", "API token (env OPENLINKHUB_TOKEN)")
+	flags.StringVar(&opts.config, "config", "", "path to config.json used to find the service")
+	flags.BoolVar(&opts.insecure, "insecure", false, "skip TLS certificate verification")
+	flags.BoolVar(&opts.json, "json", false, "print output as JSON")
+	flags.IntVar(&opts.timeout, "timeout", 30, "request timeout in seconds")
+	flags.Usage = func() { usage(flags) }
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if flags.NArg() == 0 || flags.Arg(0) == "help" {
+		usage(flags)
+		return 0
+	}
+
+	cmd := findCommand(flags.Arg(0))
+	if cmd == nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", flags.Arg(0))
+		usage(flags)
+		return 2
+	}
+
+	resolve(opts)
+	out := &output{writer: os.Stdout, json: opts.json}
+	if err := cmd.run(newClient(opts), out, flags.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			_, _ = fmt.Fprintf(os.Stderr, "Usage: OpenLinkHub ctl %s %s\n", cmd.name, cmd.args)
+			return 2
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// findCommand will return command by name
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// usage will print available options and commands
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	_, _ = fmt.Fprintln(w, "Usage: OpenLinkHub ctl [options] <command> [arguments]")
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-45s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.description)
+	}
+	_, _ = fmt.Fprintln(w, "\nOptions:")
+	flags.PrintDefaults()
+}
+
+// resolve will fill missing connection options from environment and service configuration.
+// Explicit address or socket always wins, otherwise unix socket is preferred over TCP.
+func resolve(opts *options) {
+	if len(opts.address) == 0 {
+		opts.address = os.Getenv("OPENLINKHUB_ADDRESS")
+	}
+	if len(opts.token) == 0 {
+		opts.token = os.Getenv("OPENLINKHUB_TOKEN")
+	}
+
+	cfg := loadConfig(opts.config)
+	if cfg == nil {
+		if len(opts.address) == 0 && len(opts.socket) == 0 {
+			opts.address = defaultAddress
+		}
+		return
+	}
+
+	if cfg.Authentication.Enabled && len(opts.token) == 0 {
+		opts.username = cfg.Authentication.Username
+		opts.password = cfg.Authentication.Password
+	}
+
+	if len(opts.address) > 0 || len(opts.socket) > 0 {
+		return
+	}
+
+	if len(cfg.UnixSocket) > 0 {
+		if _, err := os.Stat(cfg.UnixSocket); err == nil {
+			opts.socket = cfg.UnixSocket
+			return
+		}
+	}
+
+	if cfg.ListenPort < 1 {
+		opts.address = defaultAddress
+		return
+	}
+
+	host := strings.Trim(cfg.ListenAddress, "[]")
+	switch host {
+	case "", "0.0.0.0", "::":
+		host = "127.0.0.1"
+	}
+	scheme := "http"
+	if cfg.Tls.Enabled {
+		scheme = "https"
+	}
+	opts.address = scheme + "://" + net.JoinHostPort(host, strconv.Itoa(cfg.ListenPort))
+}
+
+// loadConfig will read service configuration from given path, or look for it next to the
+// executable, in the working directory and in atomic distribution location
+func loadConfig(path string) *serviceConfig {
+	locations := []string{path}
+	if len(path) == 0 {
+		locations = nil
+		if executable, err := os.Executable(); err == nil {
+			locations = append(locations, filepath.Join(filepath.Dir(executable), "config.json"))
+		}
+		if pwd, err := os.Getwd(); err == nil {
+			locations = append(locations, filepath.Join(pwd, "config.json"))
+		}
+		locations = append(locations, "/etc/OpenLinkHub/config.json")
+	}
+
+	for _, location := range locations {
+		file, err := os.Open(location)
+		if err != nil {
+			continue
+		}
+		cfg := &serviceConfig{}
+		err = json.NewDecoder(io.LimitReader(file, 1<<20)).Decode(cfg)
+		_ = file.Close()
+		if err == nil {
+			return cfg
+		}
+	}
+	return nil
+}
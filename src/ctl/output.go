@@ -0,0 +1,59 @@
+package ctl
+
+// Package: ctl
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// output writes command results either as aligned table or as JSON
+type output struct {
+	writer io.Writer
+	json   bool
+}
+
+// result is JSON output of commands which change device state
+type result struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// table will print header and rows. In JSON mode data is printed instead
+func (o *output) table(data interface{}, header []string, rows [][]string) error {
+	if o.json {
+		return o.print(data)
+	}
+
+	w := tabwriter.NewWriter(o.writer, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// message will print API message of successful command
+func (o *output) message(message string) error {
+	if o.json {
+		return o.print(result{Status: 1, Message: message})
+	}
+	_, err := fmt.Fprintln(o.writer, message)
+	return err
+}
+
+// print will print indented JSON
+func (o *output) print(data interface{}) error {
+	encoder := json.NewEncoder(o.writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
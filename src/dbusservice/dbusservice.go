@@ -0,0 +1,144 @@
+package dbusservice
+
+// Package: dbusservice
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/logger"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	serviceName   = "org.openlinkhub"
+	objectPath    = dbus.ObjectPath("/org/openlinkhub")
+	interfaceName = "org.openlinkhub.Manager"
+	errorName     = "org.openlinkhub.Error"
+)
+
+const (
+	BusAuto     = "auto"
+	BusSession  = "session"
+	BusSystem   = "system"
+	BusDisabled = "disabled"
+)
+
+var conn *dbus.Conn
+
+// signals are exposed via introspection, since they can't be derived from exported methods
+var signals = []introspect.Signal{
+	{Name: "DeviceAdded", Args: []introspect.Arg{{Name: "serial", Type: "s"}}},
+	{Name: "DeviceRemoved", Args: []introspect.Arg{{Name: "serial", Type: "s"}}},
+	{Name: "BatteryChanged", Args: []introspect.Arg{{Name: "serial", Type: "s"}, {Name: "level", Type: "q"}}},
+	{Name: "TemperatureAlert", Args: []introspect.Arg{{Name: "serial", Type: "s"}, {Name: "channelId", Type: "i"}, {Name: "active", Type: "b"}}},
+	{Name: "ProfileChanged", Args: []introspect.Arg{{Name: "serial", Type: "s"}, {Name: "profile", Type: "s"}}},
+}
+
+// Init will register org.openlinkhub service on configured bus and start forwarding device events as signals
+func Init() {
+	bus := config.GetConfig().DBus
+	if bus == BusAuto || len(bus) == 0 {
+		bus = BusSession
+		if config.IsSystemService() {
+			bus = BusSystem
+		}
+	}
+
+	var err error
+	switch bus {
+	case BusSession:
+		conn, err = dbus.ConnectSessionBus()
+	case BusSystem:
+		conn, err = dbus.ConnectSystemBus()
+	default:
+		logger.Log(logger.Fields{}).Info("D-Bus service is disabled")
+		return
+	}
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "bus": bus}).Warn("Unable to connect to D-Bus, D-Bus service is disabled")
+		return
+	}
+
+	m := &manager{}
+	if err = conn.Export(m, objectPath, interfaceName); err != nil {
+		logger.Log(logger.Fields{"error": err}).Error("Unable to export D-Bus object")
+		stop()
+		return
+	}
+
+	node := &introspect.Node{
+		Name: string(objectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    interfaceName,
+				Methods: introspect.Methods(m),
+				Signals: signals,
+			},
+		},
+	}
+	if err = conn.Export(introspect.NewIntrospectable(node), objectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		logger.Log(logger.Fields{"error": err}).Error("Unable to export D-Bus introspection")
+		stop()
+		return
+	}
+
+	reply, err := conn.RequestName(serviceName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		// System bus requires org.openlinkhub.conf policy in /etc/dbus-1/system.d/
+		logger.Log(logger.Fields{"error": err, "bus": bus, "name": serviceName}).Warn("Unable to acquire D-Bus name, D-Bus service is disabled")
+		stop()
+		return
+	}
+
+	logger.Log(logger.Fields{"bus": bus, "name": serviceName}).Info("D-Bus service is running")
+	go forward()
+}
+
+// stop will close D-Bus connection
+func stop() {
+	if conn == nil {
+		return
+	}
+	if err := conn.Close(); err != nil {
+		logger.Log(logger.Fields{"error": err}).Warn("Error closing D-Bus connection")
+	}
+	conn = nil
+}
+
+// forward will emit D-Bus signals for device events
+func forward() {
+	_, ch := events.Subscribe()
+	for event := range ch {
+		var err error
+		switch event.Type {
+		case events.EventDeviceAdded:
+			err = emit("DeviceAdded", event.Serial)
+		case events.EventDeviceRemoved:
+			err = emit("DeviceRemoved", event.Serial)
+		case events.EventBattery:
+			if level, ok := event.Data.(uint16); ok {
+				err = emit("BatteryChanged", event.Serial, level)
+			}
+		case events.EventFailsafe:
+			if active, ok := event.Data.(bool); ok {
+				err = emit("TemperatureAlert", event.Serial, int32(event.ChannelId), active)
+			}
+		case events.EventProfile:
+			if profile, ok := event.Data.(string); ok {
+				err = emit("ProfileChanged", event.Serial, profile)
+			}
+		}
+		if err != nil {
+			logger.Log(logger.Fields{"error": err, "event": event.Type}).Warn("Unable to emit D-Bus signal")
+		}
+	}
+}
+
+// emit will send signal of org.openlinkhub.Manager interface
+func emit(name string, values ...interface{}) error {
+	return conn.Emit(objectPath, interfaceName+"."+name, values...)
+}
@@ -0,0 +1,130 @@
+package dbusservice
+
+// Package: dbusservice
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/language"
+	"github.com/godbus/dbus/v5"
+	"sort"
+)
+
+// Device is a D-Bus representation of a connected device, signature (ssbs)
+type Device struct {
+	Serial      string
+	Product     string
+	Hidden      bool
+	UserProfile string
+}
+
+// manager contains methods of org.openlinkhub.Manager interface
+type manager struct{}
+
+// failure will return D-Bus error with translated message
+func failure(key string) *dbus.Error {
+	return dbus.NewError(errorName, []interface{}{language.GetValue(key)})
+}
+
+// getDevice will return device instance or D-Bus error when device does not exist
+func getDevice(serial string) (interface{}, *dbus.Error) {
+	device := devices.GetDevice(serial)
+	if device == nil {
+		return nil, failure("txtNonExistingDevice")
+	}
+	return device, nil
+}
+
+// ListDevices will return all connected devices sorted by serial
+func (m *manager) ListDevices() ([]Device, *dbus.Error) {
+	list := make([]Device, 0)
+	for serial, device := range devices.GetDevices() {
+		list = append(list, Device{
+			Serial:      serial,
+			Product:     device.Product,
+			Hidden:      device.Hidden,
+			UserProfile: capabilities.ActiveUserProfile(device.Instance),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Serial < list[j].Serial })
+	return list, nil
+}
+
+// ChangeUserProfile will switch device user profile
+func (m *manager) ChangeUserProfile(serial, profile string) *dbus.Error {
+	device, err := getDevice(serial)
+	if err != nil {
+		return err
+	}
+
+	if dev, ok := device.(capabilities.UserProfiles); ok && dev.ChangeDeviceProfile(profile) == 1 {
+		events.Publish(events.EventProfile, serial, 0, profile)
+		return nil
+	}
+	return failure("txtUnableToChangeUserProfile")
+}
+
+// SetBrightness will change device brightness via value from 0 to 100
+func (m *manager) SetBrightness(serial string, value uint8) *dbus.Error {
+	if value > 100 {
+		return failure("txtBrightnessTooHigh")
+	}
+
+	device, err := getDevice(serial)
+	if err != nil {
+		return err
+	}
+
+	if dev, ok := device.(capabilities.BrightnessValue); ok && dev.ChangeDeviceBrightnessValue(value) == 1 {
+		return nil
+	}
+	return failure("txtUnableToChangeBrightness")
+}
+
+// SetBrightnessMode will change device brightness via predefined mode from 0 to 4
+func (m *manager) SetBrightnessMode(serial string, mode uint8) *dbus.Error {
+	if mode > 4 {
+		return failure("txtBrightnessTooHigh")
+	}
+
+	device, err := getDevice(serial)
+	if err != nil {
+		return err
+	}
+
+	if dev, ok := device.(capabilities.Brightness); ok && dev.ChangeDeviceBrightness(mode) == 1 {
+		return nil
+	}
+	return failure("txtUnableToChangeBrightness")
+}
+
+// SetRgbProfile will change RGB profile of device channel. Channel -1 applies profile to all channels
+func (m *manager) SetRgbProfile(serial string, channelId int32, profile string) *dbus.Error {
+	device, err := getDevice(serial)
+	if err != nil {
+		return err
+	}
+
+	dev, ok := device.(capabilities.Rgb)
+	if !ok {
+		return failure("txtUnableToChangeRgbProfile")
+	}
+
+	switch dev.UpdateRgbProfile(int(channelId), profile) {
+	case 1:
+		events.Publish(events.EventRgb, serial, int(channelId), profile)
+		return nil
+	case 2:
+		return failure("txtUnableToChangeRgbProfileNoPump")
+	case 3:
+		return failure("txtUnableToChangeRgbProfileNoKeyboard")
+	case 4:
+		return failure("txtUnableToChangeRgbProfileOpenRgb")
+	case 5:
+		return failure("txtUnableToChangeRgbProfileCluster")
+	}
+	return failure("txtUnableToChangeRgbProfile")
+}
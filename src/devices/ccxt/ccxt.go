@@ -5,6 +5,7 @@ package ccxt
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/cluster"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
@@ -15,6 +16,8 @@ import (
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
+	"OpenLinkHub/src/watchdog"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -27,8 +30,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/sstallion/go-hid"
 )
 
 var (
@@ -197,7 +198,7 @@ type Devices struct {
 
 type Device struct {
 	Debug                   bool
-	dev                     *hid.Device
+	dev                     transport.Device
 	Manufacturer            string                    `json:"manufacturer"`
 	Product                 string                    `json:"product"`
 	Serial                  string                    `json:"serial"`
@@ -211,6 +212,7 @@ type Device struct {
 	DeviceProfile           *DeviceProfile
 	TemperatureProbes       *[]TemperatureProbe
 	activeRgb               *rgb.ActiveRGB
+	rgbAlert                bool
 	ExternalHub             bool
 	ExternalLedDevice       []ExternalLedDevice
 	ExternalLedDeviceAmount map[int]string
@@ -237,13 +239,47 @@ type Device struct {
 	instance                *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable           = (*Device)(nil)
+	_ capabilities.DirtyStoppable      = (*Device)(nil)
+	_ capabilities.Templated           = (*Device)(nil)
+	_ capabilities.Metrics             = (*Device)(nil)
+	_ capabilities.UserProfiles        = (*Device)(nil)
+	_ capabilities.UserProfileDeleter  = (*Device)(nil)
+	_ capabilities.DeviceLabel         = (*Device)(nil)
+	_ capabilities.RgbDeviceLabel      = (*Device)(nil)
+	_ capabilities.Rgb                 = (*Device)(nil)
+	_ capabilities.RgbBulk             = (*Device)(nil)
+	_ capabilities.RgbProfiles         = (*Device)(nil)
+	_ capabilities.RgbProfileReader    = (*Device)(nil)
+	_ capabilities.RgbProfileEditor    = (*Device)(nil)
+	_ capabilities.RgbControl          = (*Device)(nil)
+	_ capabilities.RgbAlert            = (*Device)(nil)
+	_ capabilities.RgbOverride         = (*Device)(nil)
+	_ capabilities.RgbTemperature      = (*Device)(nil)
+	_ capabilities.RgbCluster          = (*Device)(nil)
+	_ capabilities.OpenRgb             = (*Device)(nil)
+	_ capabilities.Brightness          = (*Device)(nil)
+	_ capabilities.BrightnessValue     = (*Device)(nil)
+	_ capabilities.ScheduledBrightness = (*Device)(nil)
+	_ capabilities.SpeedProfile        = (*Device)(nil)
+	_ capabilities.SpeedProfileBulk    = (*Device)(nil)
+	_ capabilities.ManualSpeed         = (*Device)(nil)
+	_ capabilities.TemperatureProbes   = (*Device)(nil)
+	_ capabilities.ChannelDevices      = (*Device)(nil)
+	_ capabilities.ExternalHub         = (*Device)(nil)
+	_ capabilities.ARGBDevice          = (*Device)(nil)
+	_ capabilities.StateReader         = (*Device)(nil)
+)
+
 // Init will initialize a new device
 func Init(vendorId, productId uint16, serial, path string) *common.Device {
 	// Set global working directory
 	pwd = config.GetConfig().ConfigPath
 
 	// Open device, return if failure
-	dev, err := hid.Open(vendorId, productId, serial)
+	dev, err := transport.Open(vendorId, productId, serial)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId}).Error("Unable to open HID device")
 		return nil
@@ -613,6 +649,37 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	for channelId, channel := range d.Devices {
+		if channel.LedChannels > 0 && len(channel.RGB) > 0 {
+			if state.RgbProfiles == nil {
+				state.RgbProfiles = make(map[int]string)
+			}
+			state.RgbProfiles[channelId] = channel.RGB
+		}
+		if channel.HasSpeed && len(channel.Profile) > 0 {
+			if state.SpeedProfiles == nil {
+				state.SpeedProfiles = make(map[int]string)
+			}
+			state.SpeedProfiles[channelId] = channel.Profile
+		}
+	}
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if d.DeviceProfile.BrightnessSlider != nil {
+		brightness := *d.DeviceProfile.BrightnessSlider
+		state.Brightness = &brightness
+	}
+	if state.Brightness == nil {
+		mode := d.DeviceProfile.Brightness
+		state.BrightnessMode = &mode
+	}
+	return state
+}
+
 // loadDeviceProfiles will load custom user profiles
 func (d *Device) loadDeviceProfiles() {
 	profileList := make(map[string]*DeviceProfile)
@@ -922,6 +989,20 @@ func (d *Device) ControlDeviceRgb(value bool) {
 	d.setDeviceColor()
 }
 
+// SetRgbAlert will enable or disable red RGB warning
+func (d *Device) SetRgbAlert(active bool) {
+	if d.DeviceProfile == nil {
+		return
+	}
+
+	d.rgbAlert = active
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
 // setDeviceColor will activate and set device RGB
 func (d *Device) setDeviceColor() {
 	// Reset
@@ -1148,14 +1229,14 @@ func (d *Device) setDeviceColor() {
 						{
 							r.MinTemp = profile.MinTemp
 							r.MaxTemp = profile.MaxTemp
-							r.Temperature(float64(d.CpuTemp))
+							r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.CpuTemp)))
 							buff = append(buff, r.Output...)
 						}
 					case "gpu-temperature":
 						{
 							r.MinTemp = profile.MinTemp
 							r.MaxTemp = profile.MaxTemp
-							r.Temperature(float64(d.GpuTemp))
+							r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.GpuTemp)))
 							buff = append(buff, r.Output...)
 						}
 					case "probe-temperature":
@@ -1408,6 +1489,7 @@ func (d *Device) getDeviceData() {
 				if rpm > 0 {
 					d.Devices[m].Rpm = rpm
 				}
+				watchdog.UpdateRpm(d.Serial, d.Devices[m].Name, d.Devices[m].ChannelId, d.Devices[m].ContainsPump, int(rpm))
 			}
 		}
 		m++
@@ -1626,7 +1708,7 @@ func (d *Device) ResetSpeedProfiles(profile string) {
 }
 
 // GetTemperatureProbes will return a list of temperature probes
-func (d *Device) GetTemperatureProbes() *[]TemperatureProbe {
+func (d *Device) GetTemperatureProbes() interface{} {
 	return d.TemperatureProbes
 }
 
@@ -1798,6 +1880,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.MiddleColor = profile.MiddleColor
 	pf.Speed = profile.Speed
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 	pf.MinTemp = profile.MinTemp
 	pf.MaxTemp = profile.MaxTemp
 
@@ -2036,7 +2119,7 @@ func (d *Device) ProcessSetRgbOverride(channelId, subDeviceId int, enabled bool,
 }
 
 // ProcessGetChannelDevice will return channel device data
-func (d *Device) ProcessGetChannelDevice(channelId int) *Devices {
+func (d *Device) ProcessGetChannelDevice(channelId int) interface{} {
 	if d.DeviceProfile == nil {
 		return nil
 	}
@@ -2877,6 +2960,10 @@ func (d *Device) setSpeed(data map[int]byte, mode uint8) {
 		return
 	}
 
+	for channelId, speed := range data {
+		watchdog.SetSpeed(d.Serial, channelId, int(speed))
+	}
+
 	buffer := make([]byte, len(data)*4+1)
 	buffer[0] = byte(len(data))
 	i := 1
@@ -2940,33 +3027,15 @@ func (d *Device) updateDeviceSpeed() {
 						profiles = temperatures.GetTemperatureProfile("Normal")
 					}
 
-					switch profiles.Sensor {
-					case temperatures.SensorTypeGPU:
+					switch {
+					case temperatures.IsHostSensor(profiles.Sensor):
 						{
-							temp = temperatures.GetNVIDIAGpuTemperature(0)
+							temp = temperatures.GetSensorTemperature(temperatures.GetProfileSensorId(profiles))
 							if temp == 0 {
-								temp = temperatures.GetAMDGpuTemperature()
-								if temp == 0 {
-									logger.Log(logger.Fields{"temperature": temp}).Warn("Unable to get sensor temperature. Going to fallback to CPU")
-									temp = temperatures.GetCpuTemperature()
-								}
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "sensor": profiles.Sensor, "device": profiles.Device}).Warn("Unable to get sensor temperature.")
 							}
 						}
-					case temperatures.SensorTypeCPU:
-						{
-							temp = temperatures.GetCpuTemperature()
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get CPU temperature.")
-							}
-						}
-					case temperatures.SensorTypeStorage:
-						{
-							temp = temperatures.GetStorageTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get storage temperature.")
-							}
-						}
-					case temperatures.SensorTypeTemperatureProbe:
+					case profiles.Sensor == temperatures.SensorTypeTemperatureProbe:
 						{
 							if strings.HasPrefix(profiles.Device, i2cPrefix) {
 								temp = temperatures.GetMemoryTemperature(profiles.ChannelId)
@@ -2980,87 +3049,34 @@ func (d *Device) updateDeviceSpeed() {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId}).Warn("Unable to get probe temperature.")
 							}
 						}
-					case temperatures.SensorTypeCpuGpu:
-						{
-							cpuTemp := temperatures.GetCpuTemperature()
-							gpuTemp := temperatures.GetNVIDIAGpuTemperature(0)
-							if gpuTemp == 0 {
-								gpuTemp = temperatures.GetAMDGpuTemperature()
-							}
-
-							if gpuTemp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get GPU temperature. Setting to 50")
-								gpuTemp = 50
-							}
-
-							temp = float32(math.Max(float64(cpuTemp), float64(gpuTemp)))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get maximum temperature value out of 2 numbers.")
-							}
-						}
-					case temperatures.SensorTypeExternalHwMon:
-						{
-							temp = temperatures.GetHwMonTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeExternalExecutable:
-						{
-							temp = temperatures.GetExternalBinaryTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "binary": profiles.Device}).Warn("Unable to get temperature from binary.")
-							}
-						}
-					case temperatures.SensorTypeMultiGPU:
-						{
-							temp = temperatures.GetGpuTemperatureIndex(int(profiles.GPUIndex))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeGlobalTemperature:
+					case profiles.Sensor == temperatures.SensorTypeGlobalTemperature:
 						{
 							temp = stats.GetDeviceTemperature(profiles.Device, profiles.ChannelId)
 							if temp == 0 {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
 							}
 						}
-					case temperatures.SensorTypeMultiGPUs:
-						{
-							maxGpuTemp := float32(0)
-
-							for _, index := range config.GetConfig().NvidiaGpuIndex {
-								gpuTemp := temperatures.GetNVIDIAGpuTemperature(index)
-								if gpuTemp == 0 {
-									logger.Log(logger.Fields{
-										"temperature": gpuTemp,
-										"serial":      d.Serial,
-										"channelId":   profiles.ChannelId,
-										"gpuIndex":    index,
-									}).Warn("Unable to get GPU temperature. Setting to 50")
-									gpuTemp = 50
-								}
-
-								if gpuTemp > maxGpuTemp {
-									maxGpuTemp = gpuTemp
-								}
-							}
-							temp = maxGpuTemp
-						}
 					}
 
+					// Critical temperature failsafe
+					temperatures.CheckFailsafe(d.Serial, device.ChannelId, profiles, temp)
+
 					// All temps failed, default to 50
 					if temp == 0 {
 						temp = 50
 					}
 
+					// Averaging and hysteresis
+					temp = temperatures.SmoothTemperature(d.Serial, device.ChannelId, profiles, temp)
+
 					if config.GetConfig().GraphProfiles {
 						pumpValue := temperatures.Interpolate(profiles.Points[0], temp)
 						fansValue := temperatures.Interpolate(profiles.Points[1], temp)
 
 						pump := int(math.Round(float64(pumpValue)))
+						pump = temperatures.RampSpeed(d.Serial, device.ChannelId, true, profiles, pump)
 						fans := int(math.Round(float64(fansValue)))
+						fans = temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, fans)
 
 						// Failsafe
 						if fans < 20 && !profiles.ZeroRpm {
@@ -3075,8 +3091,11 @@ func (d *Device) updateDeviceSpeed() {
 						if fans > 100 {
 							fans = 100
 						}
+						if temperatures.IsFailsafe() {
+							pump, fans = 100, 100
+						}
 
-						cp := fmt.Sprintf("%s-%d-%f", device.Profile, device.ChannelId, temp)
+						cp := fmt.Sprintf("%s-%d-%d-%d", device.Profile, device.ChannelId, pump, fans)
 						if ok := tmp[device.ChannelId]; ok != cp {
 							tmp[device.ChannelId] = cp
 							if device.ContainsPump {
@@ -3093,8 +3112,12 @@ func (d *Device) updateDeviceSpeed() {
 					} else {
 						for i := 0; i < len(profiles.Profiles); i++ {
 							profile := profiles.Profiles[i]
-							minimum := profile.Min + 0.1
-							if common.InBetween(temp, minimum, profile.Max) {
+							if temperatures.IsActiveStep(profiles, i, temp) {
+								profile.Fans = uint16(temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, int(profile.Fans)))
+								profile.Pump = uint16(temperatures.RampSpeed(d.Serial, device.ChannelId, true, profiles, int(profile.Pump)))
+								if temperatures.IsFailsafe() {
+									profile.Fans, profile.Pump = 100, 100
+								}
 								cp := fmt.Sprintf("%s-%d-%d-%d-%d", device.Profile, device.ChannelId, profile.Id, profile.Fans, profile.Pump)
 								if ok := tmp[device.ChannelId]; ok != cp {
 									tmp[device.ChannelId] = cp
@@ -3412,6 +3435,10 @@ func (d *Device) read(endpoint []byte, caller string) []byte {
 
 // writeColor will write color data to the device
 func (d *Device) writeColor(data []byte) {
+	if d.rgbAlert {
+		data = rgb.Alert(len(data))
+	}
+
 	d.deviceLock.Lock()
 	defer d.deviceLock.Unlock()
 
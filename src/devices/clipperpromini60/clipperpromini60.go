@@ -5,6 +5,7 @@ package clipperpromini60
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/cluster"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
@@ -15,6 +16,7 @@ import (
 	"OpenLinkHub/src/macro"
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -22,7 +24,6 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -66,13 +67,15 @@ type DeviceProfile struct {
 
 type Device struct {
 	Debug              bool
-	dev                *hid.Device
-	listener           *hid.Device
+	dev                transport.Device
+	listener           transport.Device
 	Manufacturer       string `json:"manufacturer"`
 	Product            string `json:"product"`
 	Serial             string `json:"serial"`
 	Firmware           string `json:"firmware"`
 	activeRgb          *rgb.ActiveRGB
+	rgbOverride        string
+	rgbOverrideMutex   sync.Mutex
 	UserProfiles       map[string]*DeviceProfile `json:"userProfiles"`
 	Devices            map[int]string            `json:"devices"`
 	DeviceProfile      *DeviceProfile
@@ -104,19 +107,50 @@ type Device struct {
 	ModifierIndex      *big.Int
 	KeyboardKey        *keyboards.Key
 	PressLoop          bool
-	MacroTracker       map[int]macro.Tracker
+	macroPlayer        macro.Player
 	RGBModes           []string
 	instance           *common.Device
 	mouseLoopActive    bool
 	mouseLoopMutex     sync.Mutex
 	mouseLoopStopCh    chan struct{}
-	stopRepeat         chan struct{}
-	stopRepeatMutex    sync.Mutex
 	dispatch           dispatcher.DeviceDispatcher
-	macroLoopMutex     sync.Mutex
-	macroLoops         map[int]chan struct{}
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable              = (*Device)(nil)
+	_ capabilities.DirtyStoppable         = (*Device)(nil)
+	_ capabilities.Templated              = (*Device)(nil)
+	_ capabilities.Dispatchable           = (*Device)(nil)
+	_ capabilities.UserProfiles           = (*Device)(nil)
+	_ capabilities.UserProfileDeleter     = (*Device)(nil)
+	_ capabilities.DeviceLabel            = (*Device)(nil)
+	_ capabilities.Rgb                    = (*Device)(nil)
+	_ capabilities.RgbProfileOverride     = (*Device)(nil)
+	_ capabilities.RgbProfiles            = (*Device)(nil)
+	_ capabilities.RgbProfileReader       = (*Device)(nil)
+	_ capabilities.RgbProfileEditor       = (*Device)(nil)
+	_ capabilities.RgbControl             = (*Device)(nil)
+	_ capabilities.RgbCluster             = (*Device)(nil)
+	_ capabilities.KeyColor               = (*Device)(nil)
+	_ capabilities.Brightness             = (*Device)(nil)
+	_ capabilities.KeyboardProfiles       = (*Device)(nil)
+	_ capabilities.KeyboardProfileSaver   = (*Device)(nil)
+	_ capabilities.KeyboardKeys           = (*Device)(nil)
+	_ capabilities.KeyboardPerformance    = (*Device)(nil)
+	_ capabilities.KeyAssignment          = (*Device)(nil)
+	_ capabilities.KeyAssignmentLayers    = (*Device)(nil)
+	_ capabilities.KeyAssignmentTypes     = (*Device)(nil)
+	_ capabilities.KeyAssignmentModifiers = (*Device)(nil)
+	_ capabilities.KeyActuation           = (*Device)(nil)
+	_ capabilities.FlashTap               = (*Device)(nil)
+	_ capabilities.ControlDial            = (*Device)(nil)
+	_ capabilities.PollingRate            = (*Device)(nil)
+	_ capabilities.RgbLayout              = (*Device)(nil)
+	_ capabilities.PollingRateOptions     = (*Device)(nil)
+	_ capabilities.StateReader            = (*Device)(nil)
+)
+
 var (
 	pwd                   = ""
 	cmdLogin              = []byte{0x1b, 0x01}
@@ -214,7 +248,7 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 	// Set global working directory
 	pwd = config.GetConfig().ConfigPath
 
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
@@ -280,8 +314,6 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 			17: "Screen Brightness +",
 			18: "Screen Brightness -",
 		},
-		MacroTracker: make(map[int]macro.Tracker),
-		macroLoops:   make(map[int]chan struct{}),
 	}
 
 	d.getDebugMode()           // Debug mode
@@ -345,6 +377,7 @@ func (d *Device) GetRgbProfiles() interface{} {
 func (d *Device) Stop() {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -375,6 +408,7 @@ func (d *Device) Stop() {
 func (d *Device) StopDirty() uint8 {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device (dirty)...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -599,6 +633,7 @@ func (d *Device) setupKeyAssignment() {
 
 	for _, row := range keyboard.Row {
 		for _, key := range row.Keys {
+			key = keyboards.ApplyLayer(d.Serial, key)
 			if len(key.KeyData) == 0 {
 				continue
 			}
@@ -626,6 +661,11 @@ func (d *Device) setupKeyAssignment() {
 	d.writeKeyAssignment(buf)
 }
 
+// ApplyKeyAssignmentLayer will apply key assignments of active key assignment layer
+func (d *Device) ApplyKeyAssignmentLayer() {
+	d.setupKeyAssignment()
+}
+
 func (d *Device) getModifierKeyShift(modifierKey int) byte {
 	for _, value := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
 		for keyIndex, key := range value.Keys {
@@ -740,6 +780,30 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetRgbLayout will return LED layout of a device
+func (d *Device) GetRgbLayout() capabilities.RgbInfo {
+	return capabilities.RgbInfo{LedChannels: d.LEDChannels}
+}
+
+// GetPollingRates will return polling rates supported by a device
+func (d *Device) GetPollingRates() map[int]string {
+	return d.PollingRates
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if state.RgbProfiles == nil && len(d.DeviceProfile.RGBProfile) > 0 {
+		state.RgbProfiles = map[int]string{0: d.DeviceProfile.RGBProfile}
+	}
+	mode := d.DeviceProfile.Brightness
+	state.BrightnessMode = &mode
+	return state
+}
+
 // getManufacturer will return device manufacturer
 func (d *Device) getDebugMode() {
 	d.Debug = config.GetConfig().Debug
@@ -814,7 +878,7 @@ func (d *Device) setupPerformance() {
 
 // isRgbStatic will return if RGB effect is static
 func (d *Device) isRgbStatic() bool {
-	if d.DeviceProfile.RGBProfile == "keyboard" || d.DeviceProfile.RGBProfile == "static" {
+	if d.rgbProfile() == "keyboard" || d.rgbProfile() == "static" {
 		return true
 	}
 	return false
@@ -1326,6 +1390,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.MiddleColor = profile.MiddleColor
 	pf.Speed = profile.Speed
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 
 	d.Rgb.Profiles[profileName] = *pf
 	d.saveRgbProfile()
@@ -1343,6 +1408,7 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
 		return 0
 	}
+	d.setRgbOverride("")                 // Device profile is shown again
 	d.DeviceProfile.RGBProfile = profile // Set profile
 	d.saveDeviceProfile()                // Save profile
 	if d.activeRgb != nil {
@@ -1354,6 +1420,56 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 
 }
 
+// SetRgbProfileOverride will show RGB profile without saving it to device profile
+func (d *Device) SetRgbProfileOverride(profile string) uint8 {
+	if d.GetRgbProfile(profile) == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	d.setRgbOverride(profile)
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+	return 1
+}
+
+// ClearRgbProfileOverride will show RGB profile from device profile again
+func (d *Device) ClearRgbProfileOverride() {
+	if len(d.setRgbOverride("")) == 0 {
+		return
+	}
+
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
+// setRgbOverride will set RGB profile shown instead of device profile and return previous one
+func (d *Device) setRgbOverride(profile string) string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	previous := d.rgbOverride
+	d.rgbOverride = profile
+	return previous
+}
+
+// rgbProfile will return RGB profile currently shown by a device
+func (d *Device) rgbProfile() string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	if len(d.rgbOverride) > 0 {
+		return d.rgbOverride
+	}
+	return d.DeviceProfile.RGBProfile
+}
+
 // setupClusterController will create Cluster Controller for RGB Cluster
 func (d *Device) setupClusterController() {
 	if d.DeviceProfile == nil {
@@ -2235,7 +2351,7 @@ func (d *Device) setDeviceColor() {
 		}
 	}
 
-	if d.DeviceProfile.RGBProfile == "keyboard" {
+	if d.rgbProfile() == "keyboard" {
 		var buf = make([]byte, colorPacketLength)
 		if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
 			for _, rows := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
@@ -2265,7 +2381,7 @@ func (d *Device) setDeviceColor() {
 		}
 	}
 
-	if d.DeviceProfile.RGBProfile == "static" {
+	if d.rgbProfile() == "static" {
 		// Reset
 		var buf = make([]byte, colorPacketLength)
 		profile := d.GetRgbProfile("static")
@@ -2313,7 +2429,7 @@ func (d *Device) setDeviceColor() {
 				buff := make([]byte, 0)
 
 				rgbCustomColor := true
-				profile := d.GetRgbProfile(d.DeviceProfile.RGBProfile)
+				profile := d.GetRgbProfile(d.rgbProfile())
 				if profile == nil {
 					for i := 0; i < d.LEDChannels; i++ {
 						buff = append(buff, []byte{0, 0, 0}...)
@@ -2359,7 +2475,7 @@ func (d *Device) setDeviceColor() {
 					r.RGBMiddleColor.Brightness = r.RGBBrightness
 				}
 
-				switch d.DeviceProfile.RGBProfile {
+				switch d.rgbProfile() {
 				case "off":
 					{
 						for n := 0; n < d.LEDChannels; n++ {
@@ -2390,14 +2506,14 @@ func (d *Device) setDeviceColor() {
 					{
 						r.MinTemp = profile.MinTemp
 						r.MaxTemp = profile.MaxTemp
-						r.Temperature(float64(d.CpuTemp))
+						r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.CpuTemp)))
 						buff = append(buff, r.Output...)
 					}
 				case "gpu-temperature":
 					{
 						r.MinTemp = profile.MinTemp
 						r.MaxTemp = profile.MaxTemp
-						r.Temperature(float64(d.GpuTemp))
+						r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.GpuTemp)))
 						buff = append(buff, r.Output...)
 					}
 				case "colorpulse":
@@ -2592,7 +2708,7 @@ func (d *Device) resetDeviceColor() {
 		return
 	}
 
-	if d.DeviceProfile.RGBProfile == "keyboard" {
+	if d.rgbProfile() == "keyboard" {
 		var buf = make([]byte, colorPacketLength)
 		if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
 			for _, rows := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
@@ -2610,7 +2726,7 @@ func (d *Device) resetDeviceColor() {
 			logger.Log(logger.Fields{"serial": d.Serial}).Error("Unable to set color. Unknown keyboard")
 			return
 		}
-	} else if d.DeviceProfile.RGBProfile == "static" {
+	} else if d.rgbProfile() == "static" {
 		profile := d.GetRgbProfile("static")
 		if profile == nil {
 			return
@@ -2652,7 +2768,7 @@ func (d *Device) setBrightnessLevel() {
 				logger.Log(logger.Fields{"error": err, "serial": d.Serial}).Warn("Unable to change brightness")
 			}
 		} else {
-			if d.DeviceProfile.RGBProfile == "keyboard" || d.DeviceProfile.RGBProfile == "static" {
+			if d.rgbProfile() == "keyboard" || d.rgbProfile() == "static" {
 				buf := make([]byte, 2)
 				binary.LittleEndian.PutUint16(buf[0:2], d.DeviceProfile.BrightnessLevel)
 				_, err := d.transfer(cmdBrightness, buf)
@@ -2703,58 +2819,6 @@ func (d *Device) getModifierPosition() uint8 {
 	return 0
 }
 
-// addToMacroTracker adds or updates an entry in MacroTracker
-func (d *Device) addToMacroTracker(key int, value uint16, actionType uint8) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil {
-		d.MacroTracker = make(map[int]macro.Tracker)
-	}
-	d.MacroTracker[key] = macro.Tracker{
-		Value: value,
-		Type:  actionType,
-	}
-}
-
-// deleteFromMacroTracker deletes an entry from MacroTracker
-func (d *Device) deleteFromMacroTracker(key int) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil || len(d.MacroTracker) == 0 {
-		return
-	}
-	delete(d.MacroTracker, key)
-}
-
-// releaseMacroTracker will release current MacroTracker
-func (d *Device) releaseMacroTracker() {
-	d.mutex.Lock()
-	if d.MacroTracker == nil {
-		d.mutex.Unlock()
-		return
-	}
-	keys := make([]int, 0, len(d.MacroTracker))
-	for key := range d.MacroTracker {
-		keys = append(keys, key)
-	}
-	sort.Ints(keys)
-	d.mutex.Unlock()
-
-	for _, key := range keys {
-		switch d.MacroTracker[key].Type {
-		case 1, 3:
-			inputmanager.InputControlKeyboardHold(d.MacroTracker[key].Value, false)
-			break
-		case 9:
-			inputmanager.InputControlMouseHold(d.MacroTracker[key].Value, false)
-			break
-		}
-		d.deleteFromMacroTracker(key)
-	}
-}
-
 // mouseEventLoop will send mouse action until stopped
 func mouseEventLoop(stopCh <-chan struct{}, actionCommand, actionSleep uint16) {
 	// Send input once
@@ -2797,14 +2861,10 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 		raw[i], raw[j] = raw[j], raw[i]
 	}
 	val := new(big.Int).SetBytes(raw)
+	val = inputmanager.ResolveKeyboardReport(d.Serial, val)
 
-	// Check if we have any queue in macro tracker. If yes, release those keys
-	d.macroLoopMutex.Lock()
-	macroLoopRunning := len(d.macroLoops) > 0
-	d.macroLoopMutex.Unlock()
-	if len(d.MacroTracker) > 0 && !macroLoopRunning {
-		d.releaseMacroTracker()
-	}
+	// Release keys held by macros
+	d.macroPlayer.Release()
 
 	if d.ModifierIndex != val {
 		if d.KeyboardKey != nil {
@@ -2814,7 +2874,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 				break
 			case 8:
 				if d.dispatch != nil {
-					d.dispatch(d.KeyboardKey.DeviceId, "CallSniperMode", d.PressLoop)
+					d.dispatch.CallSniperMode(d.KeyboardKey.DeviceId, d.PressLoop)
 				}
 				break
 			}
@@ -2828,6 +2888,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 		if key == nil {
 			return
 		}
+		*key = keyboards.ApplyLayer(d.Serial, *key)
 
 		// Lock
 		if key.IsLock && functionKey {
@@ -2925,7 +2986,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 				d.KeyboardKey = key
 			}
 			if d.dispatch != nil && len(key.DeviceId) > 0 {
-				d.dispatch(key.DeviceId, "CallSniperMode", key.ActionHold)
+				d.dispatch.CallSniperMode(key.DeviceId, key.ActionHold)
 			}
 			break
 		case 9:
@@ -2946,235 +3007,10 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 			}
 			break
 		case 10:
-			macroID := int(key.ActionCommand)
-			macroProfile := macro.GetProfile(macroID)
-			if macroProfile == nil {
+			if !d.macroPlayer.Press(int(key.ActionCommand)) {
 				logger.Log(logger.Fields{"serial": d.Serial}).Error("Invalid macro profile")
 				return
 			}
-
-			runMacro := func(firstRun bool) {
-				for i := 0; i < len(macroProfile.Actions); i++ {
-					if v, valid := macroProfile.Actions[i]; valid {
-						if firstRun && v.ActionHold {
-							d.addToMacroTracker(i, v.ActionCommand, v.ActionType)
-						}
-
-						switch v.ActionType {
-						case 1, 3:
-							if v.ActionHold {
-								if firstRun {
-									inputmanager.InputControlKeyboard(v.ActionCommand, true)
-								}
-							} else if v.ActionRepeat > 0 {
-								d.stopRepeatMutex.Lock()
-								if d.stopRepeat != nil {
-									close(d.stopRepeat)
-								}
-
-								d.stopRepeat = make(chan struct{})
-								localStop := d.stopRepeat
-								d.stopRepeatMutex.Unlock()
-
-								go func(action macro.Actions) {
-									for z := 0; z < int(action.ActionRepeat); z++ {
-										select {
-										case <-localStop:
-											return
-										default:
-											inputmanager.InputControlKeyboard(
-												action.ActionCommand,
-												false,
-											)
-										}
-										if action.ActionRepeatDelay > 0 &&
-											action.ActionRepeat > 1 {
-											time.Sleep(
-												time.Duration(action.ActionRepeatDelay) *
-													time.Millisecond,
-											)
-										}
-									}
-								}(v)
-							} else {
-								inputmanager.InputControlKeyboard(
-									v.ActionCommand,
-									false,
-								)
-							}
-							break
-						case 9:
-							if v.ActionHold && v.ActionRepeat == 0 {
-								if firstRun {
-									inputmanager.InputControlMouseHold(
-										v.ActionCommand,
-										true,
-									)
-								}
-							} else if v.ActionRepeat > 0 && !v.ActionHold {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									inputmanager.InputControlMouse(v.ActionCommand)
-
-									if v.ActionRepeatDelay > 0 &&
-										v.ActionRepeat > 1 {
-										time.Sleep(
-											time.Duration(v.ActionRepeatDelay) *
-												time.Millisecond,
-										)
-									}
-								}
-							} else {
-								inputmanager.InputControlMouse(v.ActionCommand)
-							}
-							break
-						case 5:
-							if v.ActionDelay > 0 {
-								time.Sleep(
-									time.Duration(v.ActionDelay) *
-										time.Millisecond,
-								)
-							}
-							break
-						case 6:
-							if v.ActionRepeat > 0 {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									inputmanager.InputControlKeyboardText(
-										v.ActionText,
-									)
-
-									if v.ActionRepeatDelay > 0 &&
-										v.ActionRepeat > 1 {
-										time.Sleep(
-											time.Duration(v.ActionRepeatDelay) *
-												time.Millisecond,
-										)
-									}
-								}
-							} else {
-								inputmanager.InputControlKeyboardText(v.ActionText)
-							}
-							break
-						case 20:
-							if v.ActionRepeat > 0 && !v.ActionHold {
-								d.stopRepeatMutex.Lock()
-								if d.stopRepeat != nil {
-									close(d.stopRepeat)
-								}
-
-								d.stopRepeat = make(chan struct{})
-								localStop := d.stopRepeat
-								d.stopRepeatMutex.Unlock()
-
-								go func(action macro.Actions) {
-									for z := 0; z < int(action.ActionRepeat); z++ {
-										select {
-										case <-localStop:
-											return
-										default:
-											if action.MousePositionAbsolute {
-												inputmanager.InputControlMoveAbsolute(
-													int32(action.MousePositionX),
-													int32(action.MousePositionY),
-												)
-											} else {
-												inputmanager.InputControlMove(
-													int32(action.MousePositionX),
-													int32(action.MousePositionY),
-												)
-											}
-										}
-										if action.ActionRepeatDelay > 0 &&
-											action.ActionRepeat > 1 {
-											time.Sleep(
-												time.Duration(action.ActionRepeatDelay) *
-													time.Millisecond,
-											)
-										}
-									}
-								}(v)
-							} else {
-								if v.MousePositionAbsolute {
-									inputmanager.InputControlMoveAbsolute(
-										int32(v.MousePositionX),
-										int32(v.MousePositionY),
-									)
-								} else {
-									inputmanager.InputControlMove(
-										int32(v.MousePositionX),
-										int32(v.MousePositionY),
-									)
-								}
-							}
-							break
-						}
-					}
-				}
-			}
-
-			if macroProfile.Repeat < 0 {
-				d.macroLoopMutex.Lock()
-
-				if stop, running := d.macroLoops[macroID]; running {
-					close(stop)
-					delete(d.macroLoops, macroID)
-					d.macroLoopMutex.Unlock()
-
-					if len(d.MacroTracker) > 0 {
-						d.releaseMacroTracker()
-					}
-					break
-				}
-				stop := make(chan struct{})
-				d.macroLoops[macroID] = stop
-				d.macroLoopMutex.Unlock()
-
-				go func(stop chan struct{}) {
-					firstRun := true
-
-					for {
-						select {
-						case <-stop:
-							return
-						default:
-						}
-
-						runMacro(firstRun)
-						firstRun = false
-
-						if macroProfile.RepeatDelay > 0 {
-							timer := time.NewTimer(
-								time.Duration(macroProfile.RepeatDelay) *
-									time.Millisecond,
-							)
-							select {
-							case <-stop:
-								if !timer.Stop() {
-									<-timer.C
-								}
-								return
-
-							case <-timer.C:
-							}
-						}
-					}
-				}(stop)
-				break
-			}
-
-			repeat := macroProfile.Repeat
-			if repeat <= 0 {
-				repeat = 1
-			}
-
-			for r := 0; r < repeat; r++ {
-				runMacro(r == 0)
-				if macroProfile.RepeatDelay > 0 && r < repeat-1 {
-					time.Sleep(
-						time.Duration(macroProfile.RepeatDelay) *
-							time.Millisecond,
-					)
-				}
-			}
 			break
 		}
 	}
@@ -3265,7 +3101,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.InterfaceNbr == 3 {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
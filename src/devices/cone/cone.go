@@ -5,6 +5,7 @@ package cone
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/cluster"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
@@ -15,6 +16,8 @@ import (
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
+	"OpenLinkHub/src/watchdog"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -126,7 +129,7 @@ type TemperatureProbe struct {
 }
 
 type Device struct {
-	dev               *hid.Device
+	dev               transport.Device
 	ProductId         uint16
 	Manufacturer      string                    `json:"manufacturer"`
 	Product           string                    `json:"product"`
@@ -142,6 +145,7 @@ type Device struct {
 	UserProfiles      map[string]*DeviceProfile `json:"userProfiles"`
 	ActiveDevice      SupportedDevice
 	activeRgb         *rgb.ActiveRGB
+	rgbAlert          bool
 	sequence          byte
 	DeviceProfile     *DeviceProfile
 	TemperatureProbes *[]TemperatureProbe
@@ -168,6 +172,35 @@ type Device struct {
 	instance          *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable               = (*Device)(nil)
+	_ capabilities.DirtyStoppable          = (*Device)(nil)
+	_ capabilities.Templated               = (*Device)(nil)
+	_ capabilities.Metrics                 = (*Device)(nil)
+	_ capabilities.UserProfiles            = (*Device)(nil)
+	_ capabilities.UserProfileDeleter      = (*Device)(nil)
+	_ capabilities.DeviceLabel             = (*Device)(nil)
+	_ capabilities.Rgb                     = (*Device)(nil)
+	_ capabilities.RgbProfiles             = (*Device)(nil)
+	_ capabilities.RgbProfileReader        = (*Device)(nil)
+	_ capabilities.RgbProfileEditor        = (*Device)(nil)
+	_ capabilities.RgbControl              = (*Device)(nil)
+	_ capabilities.RgbAlert                = (*Device)(nil)
+	_ capabilities.RgbOverride             = (*Device)(nil)
+	_ capabilities.RgbCluster              = (*Device)(nil)
+	_ capabilities.OpenRgb                 = (*Device)(nil)
+	_ capabilities.LedData                 = (*Device)(nil)
+	_ capabilities.Brightness              = (*Device)(nil)
+	_ capabilities.BrightnessValue         = (*Device)(nil)
+	_ capabilities.ScheduledBrightness     = (*Device)(nil)
+	_ capabilities.SpeedProfile            = (*Device)(nil)
+	_ capabilities.ManualSpeed             = (*Device)(nil)
+	_ capabilities.TemperatureProbes       = (*Device)(nil)
+	_ capabilities.LiquidTemperatureSource = (*Device)(nil)
+	_ capabilities.StateReader             = (*Device)(nil)
+)
+
 // https://www.3dbrew.org/wiki/CRC-8-CCITT
 var crcTable = [256]uint8{
 	0x00, 0x07, 0x0E, 0x09, 0x1C, 0x1B, 0x12, 0x15, 0x38, 0x3F, 0x36, 0x31, 0x24, 0x23, 0x2A, 0x2D,
@@ -310,7 +343,7 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 	pwd = config.GetConfig().ConfigPath
 
 	// Open device, return if failure
-	dev, err := hid.OpenFirst(vendorId, productId)
+	dev, err := transport.OpenFirst(vendorId, productId)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId}).Error("Unable to open HID device")
 		return nil
@@ -567,6 +600,37 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	for channelId, channel := range d.Devices {
+		if channel.LedChannels > 0 && len(channel.RGB) > 0 {
+			if state.RgbProfiles == nil {
+				state.RgbProfiles = make(map[int]string)
+			}
+			state.RgbProfiles[channelId] = channel.RGB
+		}
+		if channel.HasSpeed && len(channel.Profile) > 0 {
+			if state.SpeedProfiles == nil {
+				state.SpeedProfiles = make(map[int]string)
+			}
+			state.SpeedProfiles[channelId] = channel.Profile
+		}
+	}
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if d.DeviceProfile.BrightnessSlider != nil {
+		brightness := *d.DeviceProfile.BrightnessSlider
+		state.Brightness = &brightness
+	}
+	if state.Brightness == nil {
+		mode := d.DeviceProfile.Brightness
+		state.BrightnessMode = &mode
+	}
+	return state
+}
+
 // lightingControl will create an empty byte slice with length of 80.
 // After that, we fill an array with byte value from 0 to 79
 func (d *Device) lightingControl() []byte {
@@ -671,7 +735,7 @@ func (d *Device) loadDeviceProfiles() {
 }
 
 // GetTemperatureProbes will return a list of temperature probes
-func (d *Device) GetTemperatureProbes() *[]TemperatureProbe {
+func (d *Device) GetTemperatureProbes() interface{} {
 	return d.TemperatureProbes
 }
 
@@ -758,14 +822,29 @@ func (d *Device) ControlDeviceRgb(value bool) {
 	d.setDeviceColor()
 }
 
-// SetLiquidTemperatureSource will change liquid temperature source from CPU / GPU
-func (d *Device) SetLiquidTemperatureSource(value bool) {
+// SetRgbAlert will enable or disable red RGB warning
+func (d *Device) SetRgbAlert(active bool) {
 	if d.DeviceProfile == nil {
 		return
 	}
 
+	d.rgbAlert = active
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
+// SetLiquidTemperatureSource will change liquid temperature source from CPU / GPU
+func (d *Device) SetLiquidTemperatureSource(value bool) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
 	d.DeviceProfile.UseGpuTemperature = value
 	d.saveDeviceProfile()
+	return 1
 }
 
 // setDeviceColor will activate and set device RGB
@@ -1000,21 +1079,21 @@ func (d *Device) setDeviceColor() {
 						{
 							r.MinTemp = profile.MinTemp
 							r.MaxTemp = profile.MaxTemp
-							r.Temperature(float64(d.getLiquidTemperature()))
+							r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.getLiquidTemperature())))
 							buff = append(buff, r.Output...)
 						}
 					case "cpu-temperature":
 						{
 							r.MinTemp = profile.MinTemp
 							r.MaxTemp = profile.MaxTemp
-							r.Temperature(float64(d.CpuTemp))
+							r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.CpuTemp)))
 							buff = append(buff, r.Output...)
 						}
 					case "gpu-temperature":
 						{
 							r.MinTemp = profile.MinTemp
 							r.MaxTemp = profile.MaxTemp
-							r.Temperature(float64(d.GpuTemp))
+							r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.GpuTemp)))
 							buff = append(buff, r.Output...)
 						}
 					case "colorpulse":
@@ -1362,6 +1441,21 @@ func (d *Device) getPumpMode(index int, profile string) byte {
 	return 0
 }
 
+// getMaxPumpMode will return the fastest pump mode of given device
+func (d *Device) getMaxPumpMode(index int) byte {
+	var mode byte = 0
+	for device := range deviceList {
+		if deviceList[device].Index == index {
+			for pumpMode := range deviceList[device].PumpModes {
+				if pumpMode > mode {
+					mode = pumpMode
+				}
+			}
+		}
+	}
+	return mode
+}
+
 // ChangeDeviceBrightness will change device brightness
 func (d *Device) ChangeDeviceBrightness(mode uint8) uint8 {
 	d.DeviceProfile.Brightness = mode
@@ -1696,6 +1790,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.MiddleColor = profile.MiddleColor
 	pf.Speed = profile.Speed
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 
 	d.Rgb.Profiles[profileName] = *pf
 	d.saveRgbProfile()
@@ -2113,6 +2208,7 @@ func (d *Device) getDeviceData() {
 			if rpm > 0 {
 				d.Devices[deviceList[device].Index].Rpm = rpm
 			}
+			watchdog.UpdateRpm(d.Serial, deviceList[device].Name, deviceList[device].Index, deviceList[device].Pump, int(rpm))
 
 			var gpuRpm uint16 = 0
 			var gpuTemp float64 = 0
@@ -2339,6 +2435,18 @@ func (d *Device) setSpeed(data map[int]*SpeedMode) {
 		return
 	}
 
+	for channelId, speed := range data {
+		if speed == nil {
+			continue
+		}
+		if speed.Pump {
+			// Pump runs in fixed modes and never stops
+			watchdog.SetSpeed(d.Serial, channelId, 100)
+		} else {
+			watchdog.SetSpeed(d.Serial, channelId, int(speed.Value))
+		}
+	}
+
 	buffer := make(map[int]*SpeedMode, 1)
 
 	for i := 0; i < 2; i++ {
@@ -2408,26 +2516,15 @@ func (d *Device) updateDeviceSpeed() {
 						profiles = temperatures.GetTemperatureProfile("Normal")
 					}
 
-					switch profiles.Sensor {
-					case temperatures.SensorTypeGPU:
+					switch {
+					case temperatures.IsHostSensor(profiles.Sensor):
 						{
-							temp = temperatures.GetNVIDIAGpuTemperature(0)
+							temp = temperatures.GetSensorTemperature(temperatures.GetProfileSensorId(profiles))
 							if temp == 0 {
-								temp = temperatures.GetAMDGpuTemperature()
-								if temp == 0 {
-									logger.Log(logger.Fields{"temperature": temp}).Warn("Unable to get sensor temperature. Going to fallback to CPU")
-									temp = temperatures.GetCpuTemperature()
-								}
-							}
-						}
-					case temperatures.SensorTypeCPU:
-						{
-							temp = temperatures.GetCpuTemperature()
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get CPU temperature.")
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "sensor": profiles.Sensor, "device": profiles.Device}).Warn("Unable to get sensor temperature.")
 							}
 						}
-					case temperatures.SensorTypeLiquidTemperature:
+					case profiles.Sensor == temperatures.SensorTypeLiquidTemperature:
 						{
 							temp = d.getLiquidTemperature()
 							if d.DeviceProfile.UseGpuTemperature {
@@ -2437,46 +2534,7 @@ func (d *Device) updateDeviceSpeed() {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get liquid temperature.")
 							}
 						}
-					case temperatures.SensorTypeCpuGpu:
-						{
-							cpuTemp := temperatures.GetCpuTemperature()
-							gpuTemp := temperatures.GetNVIDIAGpuTemperature(0)
-							if gpuTemp == 0 {
-								gpuTemp = temperatures.GetAMDGpuTemperature()
-							}
-
-							if gpuTemp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get GPU temperature. Setting to 50")
-								gpuTemp = 50
-							}
-
-							temp = float32(math.Max(float64(cpuTemp), float64(gpuTemp)))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get maximum temperature value out of 2 numbers.")
-							}
-						}
-					case temperatures.SensorTypeExternalHwMon:
-						{
-							temp = temperatures.GetHwMonTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeExternalExecutable:
-						{
-							temp = temperatures.GetExternalBinaryTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "binary": profiles.Device}).Warn("Unable to get temperature from binary.")
-							}
-						}
-					case temperatures.SensorTypeMultiGPU:
-						{
-							temp = temperatures.GetGpuTemperatureIndex(int(profiles.GPUIndex))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeGlobalTemperature:
+					case profiles.Sensor == temperatures.SensorTypeGlobalTemperature:
 						{
 							temp = stats.GetDeviceTemperature(profiles.Device, profiles.ChannelId)
 							if d.DeviceProfile.UseGpuTemperature {
@@ -2486,41 +2544,28 @@ func (d *Device) updateDeviceSpeed() {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
 							}
 						}
-					case temperatures.SensorTypeMultiGPUs:
-						{
-							maxGpuTemp := float32(0)
-
-							for _, index := range config.GetConfig().NvidiaGpuIndex {
-								gpuTemp := temperatures.GetNVIDIAGpuTemperature(index)
-								if gpuTemp == 0 {
-									logger.Log(logger.Fields{
-										"temperature": gpuTemp,
-										"serial":      d.Serial,
-										"channelId":   profiles.ChannelId,
-										"gpuIndex":    index,
-									}).Warn("Unable to get GPU temperature. Setting to 50")
-									gpuTemp = 50
-								}
-
-								if gpuTemp > maxGpuTemp {
-									maxGpuTemp = gpuTemp
-								}
-							}
-							temp = maxGpuTemp
-						}
 					}
 
+					// Critical temperature failsafe
+					temperatures.CheckFailsafe(d.Serial, device.ChannelId, profiles, temp)
+
 					// All temps failed, default to 50
 					if temp == 0 {
 						temp = 50
 					}
 
+					// Averaging and hysteresis
+					temp = temperatures.SmoothTemperature(d.Serial, device.ChannelId, profiles, temp)
+
 					if device.ChannelId == 0 {
-						cp := fmt.Sprintf("%s-%d", device.Profile, device.ChannelId)
+						cp := fmt.Sprintf("%s-%d-%t", device.Profile, device.ChannelId, temperatures.IsFailsafe())
 						if ok := tmp[device.ChannelId]; ok != cp {
 							tmp[device.ChannelId] = cp
 							speedMode := &SpeedMode{}
 							speedMode.Value = d.getPumpMode(device.ChannelId, device.Profile)
+							if temperatures.IsFailsafe() {
+								speedMode.Value = d.getMaxPumpMode(device.ChannelId)
+							}
 							speedMode.ZeroRpm = false
 							speedMode.Pump = true
 							channelSpeeds[device.ChannelId] = speedMode
@@ -2530,6 +2575,7 @@ func (d *Device) updateDeviceSpeed() {
 						if config.GetConfig().GraphProfiles {
 							fansValue := temperatures.Interpolate(profiles.Points[1], temp)
 							fans := int(math.Round(float64(fansValue)))
+							fans = temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, fans)
 
 							// Failsafe
 							if fans < 20 {
@@ -2538,7 +2584,10 @@ func (d *Device) updateDeviceSpeed() {
 							if fans > 100 {
 								fans = 100
 							}
-							cp := fmt.Sprintf("%s-%d-%f", device.Profile, device.ChannelId, temp)
+							if temperatures.IsFailsafe() {
+								fans = 100
+							}
+							cp := fmt.Sprintf("%s-%d-%d", device.Profile, device.ChannelId, fans)
 							if ok := tmp[device.ChannelId]; ok != cp {
 								speedMode := &SpeedMode{}
 								tmp[device.ChannelId] = cp
@@ -2551,8 +2600,11 @@ func (d *Device) updateDeviceSpeed() {
 						} else {
 							for i := 0; i < len(profiles.Profiles); i++ {
 								profile := profiles.Profiles[i]
-								minimum := profile.Min + 0.1
-								if common.InBetween(temp, minimum, profile.Max) {
+								if temperatures.IsActiveStep(profiles, i, temp) {
+									profile.Fans = uint16(temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, int(profile.Fans)))
+									if temperatures.IsFailsafe() {
+										profile.Fans = 100
+									}
 									cp := fmt.Sprintf("%s-%d-%d", device.Profile, device.ChannelId, profile.Fans)
 									if ok := tmp[device.ChannelId]; ok != cp {
 										speedMode := &SpeedMode{}
@@ -2627,6 +2679,10 @@ func (d *Device) read(command byte, data []byte) []byte {
 
 // writeColor will write color data to the device
 func (d *Device) writeColor(data []byte) {
+	if d.rgbAlert {
+		data = rgb.Alert(len(data))
+	}
+
 	if d.Exit {
 		return
 	}
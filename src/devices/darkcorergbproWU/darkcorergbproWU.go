@@ -5,6 +5,7 @@ package darkcorergbproWU
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/cluster"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
@@ -15,6 +16,7 @@ import (
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -23,6 +25,7 @@ import (
 	"path/filepath"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -71,14 +74,16 @@ type DPIProfile struct {
 
 type Device struct {
 	Debug                    bool
-	dev                      *hid.Device
-	listener                 *hid.Device
+	dev                      transport.Device
+	listener                 transport.Device
 	Manufacturer             string `json:"manufacturer"`
 	Product                  string `json:"product"`
 	Serial                   string `json:"serial"`
 	Path                     string `json:"path"`
 	Firmware                 string `json:"firmware"`
 	activeRgb                *rgb.ActiveRGB
+	rgbOverride              string
+	rgbOverrideMutex         sync.Mutex
 	UserProfiles             map[string]*DeviceProfile `json:"userProfiles"`
 	ProfileOrder             []string                  `json:"profileOrder"`
 	Devices                  map[int]string            `json:"devices"`
@@ -115,7 +120,7 @@ type Device struct {
 	KeyAssignmentData        *inputmanager.KeyAssignment
 	ModifierIndex            uint32
 	SniperMode               bool
-	MacroTracker             map[int]uint16
+	macroPlayer              macro.Player
 	RGBModes                 []string
 	queue                    chan []byte
 	instance                 *common.Device
@@ -125,10 +130,44 @@ type Device struct {
 	MaxDPI                   int
 	ZoneAmount               int
 	DPIAmount                int
-	stopRepeat               chan struct{}
-	stopRepeatMutex          sync.Mutex
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable           = (*Device)(nil)
+	_ capabilities.DirtyStoppable      = (*Device)(nil)
+	_ capabilities.Templated           = (*Device)(nil)
+	_ capabilities.UserProfiles        = (*Device)(nil)
+	_ capabilities.UserProfileDeleter  = (*Device)(nil)
+	_ capabilities.Rgb                 = (*Device)(nil)
+	_ capabilities.RgbProfileOverride  = (*Device)(nil)
+	_ capabilities.RgbProfiles         = (*Device)(nil)
+	_ capabilities.RgbProfileReader    = (*Device)(nil)
+	_ capabilities.RgbProfileEditor    = (*Device)(nil)
+	_ capabilities.RgbControl          = (*Device)(nil)
+	_ capabilities.RgbCluster          = (*Device)(nil)
+	_ capabilities.OpenRgb             = (*Device)(nil)
+	_ capabilities.Brightness          = (*Device)(nil)
+	_ capabilities.BrightnessValue     = (*Device)(nil)
+	_ capabilities.ScheduledBrightness = (*Device)(nil)
+	_ capabilities.KeyAssignment       = (*Device)(nil)
+	_ capabilities.KeyAssignmentLayers = (*Device)(nil)
+	_ capabilities.SleepMode           = (*Device)(nil)
+	_ capabilities.SniperMode          = (*Device)(nil)
+	_ capabilities.DpiControl          = (*Device)(nil)
+	_ capabilities.PollingRate         = (*Device)(nil)
+	_ capabilities.MouseDpi            = (*Device)(nil)
+	_ capabilities.MouseZoneColors     = (*Device)(nil)
+	_ capabilities.AngleSnapping       = (*Device)(nil)
+	_ capabilities.ButtonOptimization  = (*Device)(nil)
+	_ capabilities.RgbLayout           = (*Device)(nil)
+	_ capabilities.DpiRange            = (*Device)(nil)
+	_ capabilities.PollingRateOptions  = (*Device)(nil)
+	_ capabilities.SleepModeOptions    = (*Device)(nil)
+	_ capabilities.BatteryStatus       = (*Device)(nil)
+	_ capabilities.StateReader         = (*Device)(nil)
+)
+
 var (
 	pwd                   = ""
 	cmdSoftwareMode       = []byte{0x01, 0x03, 0x00, 0x02}
@@ -180,7 +219,7 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 	// Set global working directory
 	pwd = config.GetConfig().ConfigPath
 
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
@@ -242,7 +281,6 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 		},
 		InputActions:      inputmanager.GetInputActions(),
 		keyAssignmentFile: "/database/key-assignments/darkcorergbproW.json",
-		MacroTracker:      make(map[int]uint16),
 		MinDPI:            minDpiValue,
 		MaxDPI:            maxDpiValue,
 		ZoneAmount:        8,
@@ -328,6 +366,7 @@ func (d *Device) getMediaInterfacePath() {
 func (d *Device) Stop() {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -360,6 +399,7 @@ func (d *Device) Stop() {
 func (d *Device) StopDirty() uint8 {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device (dirty)...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -488,6 +528,63 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetRgbLayout will return LED layout of a device
+func (d *Device) GetRgbLayout() capabilities.RgbInfo {
+	return capabilities.RgbInfo{LedChannels: d.LEDChannels, ChangeableLedChannels: d.ChangeableLedChannels, Zones: d.ZoneAmount}
+}
+
+// GetDpiRange will return DPI range and amount of DPI stages of a device
+func (d *Device) GetDpiRange() (int, int, int) {
+	return d.MinDPI, d.MaxDPI, d.DPIAmount
+}
+
+// GetDpiStages will return DPI value of each DPI stage
+func (d *Device) GetDpiStages() map[int]uint16 {
+	stages := make(map[int]uint16)
+	if d.DeviceProfile == nil {
+		return stages
+	}
+	for key, profile := range d.DeviceProfile.Profiles {
+		stages[key] = profile.Value
+	}
+	return stages
+}
+
+// GetPollingRates will return polling rates supported by a device
+func (d *Device) GetPollingRates() map[int]string {
+	return d.PollingRates
+}
+
+// GetSleepModes will return sleep modes supported by a device
+func (d *Device) GetSleepModes() map[int]string {
+	return d.SleepModes
+}
+
+// GetBatteryLevel will return battery level of a device
+func (d *Device) GetBatteryLevel() uint16 {
+	return d.BatteryLevel
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if state.RgbProfiles == nil && len(d.DeviceProfile.RGBProfile) > 0 {
+		state.RgbProfiles = map[int]string{0: d.DeviceProfile.RGBProfile}
+	}
+	if d.DeviceProfile.BrightnessSlider != nil {
+		brightness := *d.DeviceProfile.BrightnessSlider
+		state.Brightness = &brightness
+	}
+	if state.Brightness == nil {
+		mode := d.DeviceProfile.Brightness
+		state.BrightnessMode = &mode
+	}
+	return state
+}
+
 // ChangeDeviceProfile will change device profile
 func (d *Device) ChangeDeviceProfile(profileName string) uint8 {
 	if profile, ok := d.UserProfiles[profileName]; ok {
@@ -695,6 +792,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.MiddleColor = profile.MiddleColor
 	pf.Speed = profile.Speed
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 
 	d.Rgb.Profiles[profileName] = *pf
 	d.saveRgbProfile()
@@ -720,6 +818,7 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 		return 4
 	}
 
+	d.setRgbOverride("")                 // Device profile is shown again
 	d.DeviceProfile.RGBProfile = profile // Set profile
 	d.saveDeviceProfile()                // Save profile
 	if d.activeRgb != nil {
@@ -730,6 +829,63 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 	return 1
 }
 
+// SetRgbProfileOverride will show RGB profile without saving it to device profile
+func (d *Device) SetRgbProfileOverride(profile string) uint8 {
+	if d.GetRgbProfile(profile) == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	if d.DeviceProfile.RGBCluster {
+		return 5
+	}
+	if d.DeviceProfile.OpenRGBIntegration {
+		return 4
+	}
+
+	d.setRgbOverride(profile)
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+	return 1
+}
+
+// ClearRgbProfileOverride will show RGB profile from device profile again
+func (d *Device) ClearRgbProfileOverride() {
+	if len(d.setRgbOverride("")) == 0 {
+		return
+	}
+
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
+// setRgbOverride will set RGB profile shown instead of device profile and return previous one
+func (d *Device) setRgbOverride(profile string) string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	previous := d.rgbOverride
+	d.rgbOverride = profile
+	return previous
+}
+
+// rgbProfile will return RGB profile currently shown by a device
+func (d *Device) rgbProfile() string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	if len(d.rgbOverride) > 0 {
+		return d.rgbOverride
+	}
+	return d.DeviceProfile.RGBProfile
+}
+
 // setupOpenRGBController will create Cluster Controller for RGB Cluster
 func (d *Device) setupClusterController() {
 	if d.DeviceProfile == nil {
@@ -852,7 +1008,7 @@ func (d *Device) ChangeDeviceBrightnessValue(value uint8) uint8 {
 	d.DeviceProfile.BrightnessSlider = &value
 	d.saveDeviceProfile()
 
-	if d.DeviceProfile.RGBProfile == "static" || d.DeviceProfile.RGBProfile == "mouse" {
+	if d.rgbProfile() == "static" || d.rgbProfile() == "mouse" {
 		if d.activeRgb != nil {
 			d.activeRgb.Exit <- true
 			d.activeRgb = nil
@@ -872,7 +1028,7 @@ func (d *Device) SchedulerBrightness(value uint8) uint8 {
 	}
 
 	d.saveDeviceProfile()
-	if d.DeviceProfile.RGBProfile == "static" || d.DeviceProfile.RGBProfile == "mouse" {
+	if d.rgbProfile() == "static" || d.rgbProfile() == "mouse" {
 		if d.activeRgb != nil {
 			d.activeRgb.Exit <- true
 			d.activeRgb = nil
@@ -1713,7 +1869,7 @@ func (d *Device) setDeviceColor() {
 		}
 	}
 
-	if d.DeviceProfile.RGBProfile == "mouse" {
+	if d.rgbProfile() == "mouse" {
 		for _, zoneColor := range d.DeviceProfile.ZoneColors {
 			zoneColor.Color.Brightness = rgb.GetBrightnessValueFloat(*d.DeviceProfile.BrightnessSlider)
 			zoneColor.Color = rgb.ModifyBrightness(*zoneColor.Color)
@@ -1734,7 +1890,7 @@ func (d *Device) setDeviceColor() {
 		return
 	}
 
-	if d.DeviceProfile.RGBProfile == "static" {
+	if d.rgbProfile() == "static" {
 		profile := d.GetRgbProfile("static")
 		if profile == nil {
 			return
@@ -1775,7 +1931,7 @@ func (d *Device) setDeviceColor() {
 			default:
 				buff := make([]byte, 0)
 				rgbCustomColor := true
-				profile := d.GetRgbProfile(d.DeviceProfile.RGBProfile)
+				profile := d.GetRgbProfile(d.rgbProfile())
 				if profile == nil {
 					for i := 0; i < d.ChangeableLedChannels*3; i++ {
 						buff = append(buff, []byte{0, 0, 0}...)
@@ -1819,7 +1975,7 @@ func (d *Device) setDeviceColor() {
 				r.RGBEndColor.Brightness = r.RGBBrightness
 				r.RGBMiddleColor.Brightness = r.RGBBrightness
 
-				switch d.DeviceProfile.RGBProfile {
+				switch d.rgbProfile() {
 				case "off":
 					{
 						for n := 0; n < d.ChangeableLedChannels; n++ {
@@ -1850,14 +2006,14 @@ func (d *Device) setDeviceColor() {
 					{
 						r.MinTemp = profile.MinTemp
 						r.MaxTemp = profile.MaxTemp
-						r.Temperature(float64(d.CpuTemp))
+						r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.CpuTemp)))
 						buff = append(buff, r.Output...)
 					}
 				case "gpu-temperature":
 					{
 						r.MinTemp = profile.MinTemp
 						r.MaxTemp = profile.MaxTemp
-						r.Temperature(float64(d.GpuTemp))
+						r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.GpuTemp)))
 						buff = append(buff, r.Output...)
 					}
 				case "colorpulse":
@@ -2367,7 +2523,7 @@ func (d *Device) setupKeyAssignment() {
 	i := 0
 	for _, k := range keys {
 		value := d.KeyAssignment[k]
-		if value.Default {
+		if value.Default && !inputmanager.IsLayerOverride(d.Serial, strconv.Itoa(k)) {
 			buf[i] = byte(1)
 		} else {
 			buf[i] = byte(0)
@@ -2377,46 +2533,9 @@ func (d *Device) setupKeyAssignment() {
 	d.writeKeyAssignmentData(buf)
 }
 
-// addToMacroTracker adds or updates an entry in MacroTracker
-func (d *Device) addToMacroTracker(key int, value uint16) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil {
-		d.MacroTracker = make(map[int]uint16)
-	}
-	d.MacroTracker[key] = value
-}
-
-// deleteFromMacroTracker deletes an entry from MacroTracker
-func (d *Device) deleteFromMacroTracker(key int) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil || len(d.MacroTracker) == 0 {
-		return
-	}
-	delete(d.MacroTracker, key)
-}
-
-// releaseMacroTracker will release current MacroTracker
-func (d *Device) releaseMacroTracker() {
-	d.mutex.Lock()
-	if d.MacroTracker == nil {
-		d.mutex.Unlock()
-		return
-	}
-	keys := make([]int, 0, len(d.MacroTracker))
-	for key := range d.MacroTracker {
-		keys = append(keys, key)
-	}
-	sort.Ints(keys)
-	d.mutex.Unlock()
-
-	for _, key := range keys {
-		inputmanager.InputControlKeyboardHold(d.MacroTracker[key], false)
-		d.deleteFromMacroTracker(key)
-	}
+// ApplyKeyAssignmentLayer will apply key assignments of active key assignment layer
+func (d *Device) ApplyKeyAssignmentLayer() {
+	d.setupKeyAssignment()
 }
 
 // triggerKeyAssignment will trigger key assignment if defined
@@ -2435,6 +2554,7 @@ func (d *Device) triggerKeyAssignment(value uint32) {
 		isReleased := releasedKeys&mask != 0
 
 		val, ok := d.KeyAssignment[int(mask)]
+		val, ok = inputmanager.ResolveKeyAssignment(d.Serial, strconv.Itoa(int(mask)), isPressed, val, ok)
 		if !ok {
 			continue
 		}
@@ -2444,10 +2564,8 @@ func (d *Device) triggerKeyAssignment(value uint32) {
 		}
 
 		if isReleased {
-			// Check if we have any queue in macro tracker. If yes, release those keys
-			if len(d.MacroTracker) > 0 {
-				d.releaseMacroTracker()
-			}
+			// Release keys held by macros
+			d.macroPlayer.Release()
 
 			if val.Default || !val.ActionHold {
 				continue
@@ -2506,76 +2624,10 @@ func (d *Device) triggerKeyAssignment(value uint32) {
 				}
 				break
 			case 10:
-				macroProfile := macro.GetProfile(int(val.ActionCommand))
-				if macroProfile == nil {
+				if !d.macroPlayer.Press(int(val.ActionCommand)) {
 					logger.Log(logger.Fields{"serial": d.Serial}).Error("Invalid macro profile")
 					return
 				}
-				for i := 0; i < len(macroProfile.Actions); i++ {
-					if v, valid := macroProfile.Actions[i]; valid {
-						// Add to macro tracker for easier release
-						if v.ActionHold {
-							d.addToMacroTracker(i, v.ActionCommand)
-						}
-
-						switch v.ActionType {
-						case 1, 3:
-							inputmanager.InputControlKeyboard(v.ActionCommand, v.ActionHold)
-						case 9:
-							inputmanager.InputControlMouse(v.ActionCommand)
-						case 5:
-							if v.ActionDelay > 0 {
-								time.Sleep(time.Duration(v.ActionDelay) * time.Millisecond)
-							}
-						case 6:
-							if v.ActionRepeat > 0 {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									inputmanager.InputControlKeyboardText(v.ActionText)
-									if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-										time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-									}
-								}
-							} else {
-								inputmanager.InputControlKeyboardText(v.ActionText)
-							}
-						case 20:
-							if v.ActionRepeat > 0 && !v.ActionHold {
-								d.stopRepeatMutex.Lock()
-								if d.stopRepeat != nil {
-									close(d.stopRepeat)
-								}
-
-								d.stopRepeat = make(chan struct{})
-								localStop := d.stopRepeat
-								d.stopRepeatMutex.Unlock()
-
-								go func() {
-									for z := 0; z < int(v.ActionRepeat); z++ {
-										select {
-										case <-localStop:
-											return
-										default:
-											if v.MousePositionAbsolute {
-												inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-											} else {
-												inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-											}
-										}
-										if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-											time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-										}
-									}
-								}()
-							} else {
-								if v.MousePositionAbsolute {
-									inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-								} else {
-									inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-								}
-							}
-						}
-					}
-				}
 				break
 			case 11:
 				d.rotateDeviceProfile()
@@ -2628,7 +2680,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.InterfaceNbr == 2 {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
@@ -5,6 +5,7 @@ package darkcorergbseW
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/inputmanager"
@@ -13,6 +14,7 @@ import (
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -25,8 +27,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/sstallion/go-hid"
 )
 
 // DeviceProfile struct contains all device profile
@@ -70,13 +70,15 @@ type DPIProfile struct {
 
 type Device struct {
 	Debug              bool
-	dev                *hid.Device
-	listener           *hid.Device
+	dev                transport.Device
+	listener           transport.Device
 	Manufacturer       string `json:"manufacturer"`
 	Product            string `json:"product"`
 	Serial             string `json:"serial"`
 	Firmware           string `json:"firmware"`
 	activeRgb          *rgb.ActiveRGB
+	rgbOverride        string
+	rgbOverrideMutex   sync.Mutex
 	UserProfiles       map[string]*DeviceProfile `json:"userProfiles"`
 	ProfileOrder       []string                  `json:"profileOrder"`
 	Devices            map[int]string            `json:"devices"`
@@ -107,7 +109,7 @@ type Device struct {
 	KeyAssignmentData  *inputmanager.KeyAssignment
 	ModifierIndex      uint16
 	SniperMode         bool
-	MacroTracker       map[int]uint16
+	macroPlayer        macro.Player
 	RGBModes           []string
 	Connected          bool
 	BatteryLevel       uint16
@@ -116,10 +118,42 @@ type Device struct {
 	MaxDPI             int
 	ZoneAmount         int
 	DPIAmount          int
-	stopRepeat         chan struct{}
-	stopRepeatMutex    sync.Mutex
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable           = (*Device)(nil)
+	_ capabilities.DirtyStoppable      = (*Device)(nil)
+	_ capabilities.Templated           = (*Device)(nil)
+	_ capabilities.UserProfiles        = (*Device)(nil)
+	_ capabilities.UserProfileDeleter  = (*Device)(nil)
+	_ capabilities.Rgb                 = (*Device)(nil)
+	_ capabilities.RgbProfileOverride  = (*Device)(nil)
+	_ capabilities.RgbProfiles         = (*Device)(nil)
+	_ capabilities.RgbProfileReader    = (*Device)(nil)
+	_ capabilities.RgbProfileEditor    = (*Device)(nil)
+	_ capabilities.RgbControl          = (*Device)(nil)
+	_ capabilities.Brightness          = (*Device)(nil)
+	_ capabilities.BrightnessValue     = (*Device)(nil)
+	_ capabilities.ScheduledBrightness = (*Device)(nil)
+	_ capabilities.KeyAssignment       = (*Device)(nil)
+	_ capabilities.KeyAssignmentLayers = (*Device)(nil)
+	_ capabilities.PairedDevice        = (*Device)(nil)
+	_ capabilities.ShortKeyTrigger     = (*Device)(nil)
+	_ capabilities.SniperMode          = (*Device)(nil)
+	_ capabilities.SleepTimer          = (*Device)(nil)
+	_ capabilities.MouseDpi            = (*Device)(nil)
+	_ capabilities.MouseZoneColors     = (*Device)(nil)
+	_ capabilities.MouseSniperColors   = (*Device)(nil)
+	_ capabilities.AngleSnapping       = (*Device)(nil)
+	_ capabilities.LiftHeight          = (*Device)(nil)
+	_ capabilities.RgbLayout           = (*Device)(nil)
+	_ capabilities.DpiRange            = (*Device)(nil)
+	_ capabilities.SleepModeOptions    = (*Device)(nil)
+	_ capabilities.BatteryStatus       = (*Device)(nil)
+	_ capabilities.StateReader         = (*Device)(nil)
+)
+
 var (
 	pwd                   = ""
 	cmdSoftwareMode       = []byte{0x04, 0x02}
@@ -148,7 +182,7 @@ var (
 	}
 )
 
-func Init(vendorId, productId uint16, dev *hid.Device, serial string) *Device {
+func Init(vendorId, productId uint16, dev transport.Device, serial string) *Device {
 	// Set global working directory
 	pwd = config.GetConfig().ConfigPath
 
@@ -199,7 +233,6 @@ func Init(vendorId, productId uint16, dev *hid.Device, serial string) *Device {
 		RGBModes:          rgbModes,
 		InputActions:      inputmanager.GetInputActions(),
 		keyAssignmentFile: "/database/key-assignments/darkcorergbse.json",
-		MacroTracker:      make(map[int]uint16),
 		MinDPI:            minDpiValue,
 		MaxDPI:            maxDpiValue,
 		ZoneAmount:        3,
@@ -265,6 +298,7 @@ func (d *Device) Stop() {}
 func (d *Device) StopInternal() {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -286,6 +320,7 @@ func (d *Device) StopInternal() {
 func (d *Device) StopDirty() uint8 {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device (dirty)...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -393,6 +428,58 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetRgbLayout will return LED layout of a device
+func (d *Device) GetRgbLayout() capabilities.RgbInfo {
+	return capabilities.RgbInfo{LedChannels: d.LEDChannels, Zones: d.ZoneAmount}
+}
+
+// GetDpiRange will return DPI range and amount of DPI stages of a device
+func (d *Device) GetDpiRange() (int, int, int) {
+	return d.MinDPI, d.MaxDPI, d.DPIAmount
+}
+
+// GetDpiStages will return DPI value of each DPI stage
+func (d *Device) GetDpiStages() map[int]uint16 {
+	stages := make(map[int]uint16)
+	if d.DeviceProfile == nil {
+		return stages
+	}
+	for key, profile := range d.DeviceProfile.Profiles {
+		stages[key] = profile.Value
+	}
+	return stages
+}
+
+// GetSleepModes will return sleep modes supported by a device
+func (d *Device) GetSleepModes() map[int]string {
+	return d.SleepModes
+}
+
+// GetBatteryLevel will return battery level of a device
+func (d *Device) GetBatteryLevel() uint16 {
+	return d.BatteryLevel
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if state.RgbProfiles == nil && len(d.DeviceProfile.RGBProfile) > 0 {
+		state.RgbProfiles = map[int]string{0: d.DeviceProfile.RGBProfile}
+	}
+	if d.DeviceProfile.BrightnessSlider != nil {
+		brightness := *d.DeviceProfile.BrightnessSlider
+		state.Brightness = &brightness
+	}
+	if state.Brightness == nil {
+		mode := d.DeviceProfile.Brightness
+		state.BrightnessMode = &mode
+	}
+	return state
+}
+
 // getManufacturer will return device manufacturer
 func (d *Device) getDebugMode() {
 	d.Debug = config.GetConfig().Debug
@@ -1460,6 +1547,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.MiddleColor = profile.MiddleColor
 	pf.Speed = profile.Speed
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 
 	d.Rgb.Profiles[profileName] = *pf
 	d.saveRgbProfile()
@@ -1483,6 +1571,7 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
 		return 0
 	}
+	d.setRgbOverride("")                 // Device profile is shown again
 	d.DeviceProfile.RGBProfile = profile // Set profile
 	d.saveDeviceProfile()                // Save profile
 	if d.activeRgb != nil {
@@ -1493,6 +1582,60 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 	return 1
 }
 
+// SetRgbProfileOverride will show RGB profile without saving it to device profile
+func (d *Device) SetRgbProfileOverride(profile string) uint8 {
+	if !d.Connected {
+		return 0
+	}
+
+	if d.GetRgbProfile(profile) == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	d.setRgbOverride(profile)
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+	return 1
+}
+
+// ClearRgbProfileOverride will show RGB profile from device profile again
+func (d *Device) ClearRgbProfileOverride() {
+	if len(d.setRgbOverride("")) == 0 {
+		return
+	}
+
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
+// setRgbOverride will set RGB profile shown instead of device profile and return previous one
+func (d *Device) setRgbOverride(profile string) string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	previous := d.rgbOverride
+	d.rgbOverride = profile
+	return previous
+}
+
+// rgbProfile will return RGB profile currently shown by a device
+func (d *Device) rgbProfile() string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	if len(d.rgbOverride) > 0 {
+		return d.rgbOverride
+	}
+	return d.DeviceProfile.RGBProfile
+}
+
 // ChangeDeviceBrightness will change device brightness
 func (d *Device) ChangeDeviceBrightness(mode uint8) uint8 {
 	d.DeviceProfile.Brightness = mode
@@ -1517,7 +1660,7 @@ func (d *Device) ChangeDeviceBrightnessValue(value uint8) uint8 {
 	d.DeviceProfile.BrightnessSlider = &value
 	d.saveDeviceProfile()
 
-	if d.DeviceProfile.RGBProfile == "static" || d.DeviceProfile.RGBProfile == "mouse" {
+	if d.rgbProfile() == "static" || d.rgbProfile() == "mouse" {
 		if d.activeRgb != nil {
 			d.activeRgb.Exit <- true
 			d.activeRgb = nil
@@ -1537,7 +1680,7 @@ func (d *Device) SchedulerBrightness(value uint8) uint8 {
 	}
 
 	d.saveDeviceProfile()
-	if d.DeviceProfile.RGBProfile == "static" || d.DeviceProfile.RGBProfile == "mouse" {
+	if d.rgbProfile() == "static" || d.rgbProfile() == "mouse" {
 		if d.activeRgb != nil {
 			d.activeRgb.Exit <- true
 			d.activeRgb = nil
@@ -1641,7 +1784,7 @@ func (d *Device) setDeviceColor() {
 		return
 	}
 
-	if d.DeviceProfile.RGBProfile == "mouse" {
+	if d.rgbProfile() == "mouse" {
 		for _, zoneColor := range d.DeviceProfile.ZoneColors {
 			if d.DeviceProfile.Brightness != 0 {
 				zoneColor.Color.Brightness = rgb.GetBrightnessValue(d.DeviceProfile.Brightness)
@@ -1658,7 +1801,7 @@ func (d *Device) setDeviceColor() {
 		return
 	}
 
-	if d.DeviceProfile.RGBProfile == "static" {
+	if d.rgbProfile() == "static" {
 		profile := d.GetRgbProfile("static")
 		if profile == nil {
 			return
@@ -1703,7 +1846,7 @@ func (d *Device) setupKeyAssignment() {
 	i := 0
 	for _, k := range keys {
 		value := d.KeyAssignment[k]
-		if value.Default {
+		if value.Default && !inputmanager.IsLayerOverride(d.Serial, strconv.Itoa(k)) {
 			buf[i] = byte(value.ButtonIndex)
 			buf[i+1] = 0xc0
 		} else {
@@ -1715,46 +1858,9 @@ func (d *Device) setupKeyAssignment() {
 	d.writeKeyAssignmentData(buf)
 }
 
-// addToMacroTracker adds or updates an entry in MacroTracker
-func (d *Device) addToMacroTracker(key int, value uint16) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil {
-		d.MacroTracker = make(map[int]uint16)
-	}
-	d.MacroTracker[key] = value
-}
-
-// deleteFromMacroTracker deletes an entry from MacroTracker
-func (d *Device) deleteFromMacroTracker(key int) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil || len(d.MacroTracker) == 0 {
-		return
-	}
-	delete(d.MacroTracker, key)
-}
-
-// releaseMacroTracker will release current MacroTracker
-func (d *Device) releaseMacroTracker() {
-	d.mutex.Lock()
-	if d.MacroTracker == nil {
-		d.mutex.Unlock()
-		return
-	}
-	keys := make([]int, 0, len(d.MacroTracker))
-	for key := range d.MacroTracker {
-		keys = append(keys, key)
-	}
-	sort.Ints(keys)
-	d.mutex.Unlock()
-
-	for _, key := range keys {
-		inputmanager.InputControlKeyboardHold(d.MacroTracker[key], false)
-		d.deleteFromMacroTracker(key)
-	}
+// ApplyKeyAssignmentLayer will apply key assignments of active key assignment layer
+func (d *Device) ApplyKeyAssignmentLayer() {
+	d.setupKeyAssignment()
 }
 
 // TriggerKeyAssignment will trigger key assignment if defined
@@ -1773,6 +1879,7 @@ func (d *Device) TriggerKeyAssignment(value uint16) {
 		isReleased := releasedKeys&mask != 0
 
 		val, ok := d.KeyAssignment[int(mask)]
+		val, ok = inputmanager.ResolveKeyAssignment(d.Serial, strconv.Itoa(int(mask)), isPressed, val, ok)
 		if !ok {
 			continue
 		}
@@ -1782,10 +1889,8 @@ func (d *Device) TriggerKeyAssignment(value uint16) {
 		}
 
 		if isReleased {
-			// Check if we have any queue in macro tracker. If yes, release those keys
-			if len(d.MacroTracker) > 0 {
-				d.releaseMacroTracker()
-			}
+			// Release keys held by macros
+			d.macroPlayer.Release()
 
 			if val.Default || !val.ActionHold {
 				continue
@@ -1841,76 +1946,10 @@ func (d *Device) TriggerKeyAssignment(value uint16) {
 				}
 				break
 			case 10:
-				macroProfile := macro.GetProfile(int(val.ActionCommand))
-				if macroProfile == nil {
+				if !d.macroPlayer.Press(int(val.ActionCommand)) {
 					logger.Log(logger.Fields{"serial": d.Serial}).Error("Invalid macro profile")
 					return
 				}
-				for i := 0; i < len(macroProfile.Actions); i++ {
-					if v, valid := macroProfile.Actions[i]; valid {
-						// Add to macro tracker for easier release
-						if v.ActionHold {
-							d.addToMacroTracker(i, v.ActionCommand)
-						}
-
-						switch v.ActionType {
-						case 1, 3:
-							inputmanager.InputControlKeyboard(v.ActionCommand, v.ActionHold)
-						case 9:
-							inputmanager.InputControlMouse(v.ActionCommand)
-						case 5:
-							if v.ActionDelay > 0 {
-								time.Sleep(time.Duration(v.ActionDelay) * time.Millisecond)
-							}
-						case 6:
-							if v.ActionRepeat > 0 {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									inputmanager.InputControlKeyboardText(v.ActionText)
-									if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-										time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-									}
-								}
-							} else {
-								inputmanager.InputControlKeyboardText(v.ActionText)
-							}
-						case 20:
-							if v.ActionRepeat > 0 && !v.ActionHold {
-								d.stopRepeatMutex.Lock()
-								if d.stopRepeat != nil {
-									close(d.stopRepeat)
-								}
-
-								d.stopRepeat = make(chan struct{})
-								localStop := d.stopRepeat
-								d.stopRepeatMutex.Unlock()
-
-								go func() {
-									for z := 0; z < int(v.ActionRepeat); z++ {
-										select {
-										case <-localStop:
-											return
-										default:
-											if v.MousePositionAbsolute {
-												inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-											} else {
-												inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-											}
-										}
-										if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-											time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-										}
-									}
-								}()
-							} else {
-								if v.MousePositionAbsolute {
-									inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-								} else {
-									inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-								}
-							}
-						}
-					}
-				}
 				break
 			case 11:
 				d.rotateDeviceProfile()
@@ -5,10 +5,12 @@ package darkcorergbsesongle
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/darkcorergbseW"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"fmt"
 	"github.com/sstallion/go-hid"
@@ -19,8 +21,8 @@ import (
 
 type Device struct {
 	Debug         bool
-	dev           *hid.Device
-	listener      *hid.Device
+	dev           transport.Device
+	listener      transport.Device
 	Manufacturer  string `json:"manufacturer"`
 	Product       string `json:"product"`
 	Serial        string `json:"serial"`
@@ -35,6 +37,12 @@ type Device struct {
 	Exit          bool
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+)
+
 var (
 	cmdSoftwareMode = []byte{0x04, 0x02}
 	cmdHardwareMode = []byte{0x04, 0x01}
@@ -48,7 +56,7 @@ var (
 )
 
 func Init(vendorId, productId uint16, _, path string, callback func(device *common.Device)) *common.Device {
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId}).Error("Unable to open HID device")
 		return nil
@@ -155,7 +163,7 @@ func (d *Device) StopDirty() uint8 {
 }
 
 // GetDevice will return HID device
-func (d *Device) GetDevice() *hid.Device {
+func (d *Device) GetDevice() transport.Device {
 	return d.dev
 }
 
@@ -299,7 +307,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.InterfaceNbr == 0 {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
@@ -5,6 +5,7 @@ package devices
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/cluster"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
@@ -108,6 +109,7 @@ import (
 	"OpenLinkHub/src/devices/scufdongleV2"
 	"OpenLinkHub/src/devices/scufenvisionproV2WU"
 	"OpenLinkHub/src/devices/scufenvisionproWU"
+	"OpenLinkHub/src/devices/simulator"
 	"OpenLinkHub/src/devices/slipstream"
 	"OpenLinkHub/src/devices/slipstreamV2"
 	"OpenLinkHub/src/devices/st100"
@@ -124,19 +126,23 @@ import (
 	"OpenLinkHub/src/devices/voidelitedongle"
 	"OpenLinkHub/src/devices/xc7"
 	"OpenLinkHub/src/dispatcher"
+	"OpenLinkHub/src/events"
 	"OpenLinkHub/src/inputmanager"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/macro"
 	"OpenLinkHub/src/metrics"
 	"OpenLinkHub/src/motherboards"
 	"OpenLinkHub/src/openrgb"
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/smbus"
+	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
 	"OpenLinkHub/src/usb"
 	"OpenLinkHub/src/version"
+	"OpenLinkHub/src/watchdog"
 	"github.com/sstallion/go-hid"
 	"os"
 	"path/filepath"
-	"reflect"
 	"slices"
 	"strconv"
 	"sync"
@@ -176,7 +182,7 @@ var (
 	deviceList          = make(map[string]Device)
 	legacyDevices       = []uint16{3080, 3081, 3082, 3090, 3091, 3093, 7168}
 	initWG              sync.WaitGroup
-	Dispatch            dispatcher.DeviceDispatcher = CallDeviceMethod
+	Dispatch            dispatcher.DeviceDispatcher = deviceDispatcher{}
 )
 
 // Stop will stop all active devices
@@ -185,7 +191,9 @@ func Stop() {
 	cls.Stop()
 
 	for _, device := range devices {
-		CallDeviceMethod(device.Serial, "Stop")
+		if dev, ok := device.Instance.(capabilities.Stoppable); ok {
+			dev.Stop()
+		}
 		delete(devices, device.Serial)
 	}
 	err := hid.Exit()
@@ -209,11 +217,8 @@ func StopDirty(deviceId string, productId uint16) {
 	}
 	cluster.Get().RemoveDeviceControllerBySerial(device.Serial)
 
-	res := CallDeviceMethod(device.Serial, "StopDirty")
-	if res != nil {
-		val := res[0]
-		uintResult := val.Uint()
-		if uint8(uintResult) == 2 { // USB only devices, remove them from the device list
+	if dev, ok := device.Instance.(capabilities.DirtyStoppable); ok {
+		if dev.StopDirty() == 2 { // USB only devices, remove them from the device list
 			deleteDevice(device.Serial)
 		}
 	}
@@ -249,10 +254,8 @@ func GetSupportedDevices() interface{} {
 func GetRgbProfiles() map[string]interface{} {
 	profiles := make(map[string]interface{}, len(devices))
 	for _, device := range devices {
-		res := CallDeviceMethod(device.Serial, "GetRgbProfiles")
-		if res != nil {
-			val := res[0]
-			profiles[device.Serial] = val.Interface()
+		if dev, ok := device.Instance.(capabilities.RgbProfiles); ok {
+			profiles[device.Serial] = dev.GetRgbProfiles()
 		}
 	}
 	return profiles
@@ -261,21 +264,27 @@ func GetRgbProfiles() map[string]interface{} {
 // ScheduleDeviceBrightness will change device brightness level based on scheduler
 func ScheduleDeviceBrightness(mode uint8) {
 	for _, device := range GetDevices() {
-		CallDeviceMethod(device.Serial, "SchedulerBrightness", mode)
+		if dev, ok := device.Instance.(capabilities.ScheduledBrightness); ok {
+			dev.SchedulerBrightness(mode)
+		}
 	}
 }
 
 // ScheduleDeviceLcdBrightness will change LCD backlight brightness based on scheduler
 func ScheduleDeviceLcdBrightness(mode uint8) {
 	for _, device := range GetDevices() {
-		CallDeviceMethod(device.Serial, "SchedulerLcdBrightness", mode)
+		if dev, ok := device.Instance.(capabilities.LcdBrightness); ok {
+			dev.SchedulerLcdBrightness(mode)
+		}
 	}
 }
 
 // ControlDeviceRgb will disable / enable device RGB
 func ControlDeviceRgb(mode bool) {
 	for _, device := range GetDevices() {
-		CallDeviceMethod(device.Serial, "ControlDeviceRgb", mode)
+		if dev, ok := device.Instance.(capabilities.RgbControl); ok {
+			dev.ControlDeviceRgb(mode)
+		}
 	}
 }
 
@@ -283,11 +292,35 @@ func ControlDeviceRgb(mode bool) {
 func UpdateGlobalRgbProfile(profile string) uint8 {
 	channelId := -1
 	for _, device := range devices {
-		CallDeviceMethod(device.Serial, "UpdateRgbProfile", channelId, profile)
+		if dev, ok := device.Instance.(capabilities.Rgb); ok {
+			if dev.UpdateRgbProfile(channelId, profile) == 1 {
+				events.Publish(events.EventRgb, device.Serial, channelId, profile)
+			}
+		}
 	}
 	return 1
 }
 
+// ApplyState will apply state to a device and publish an event for every applied profile
+func ApplyState(serial string, state capabilities.State) capabilities.State {
+	device := GetDevice(serial)
+	if device == nil {
+		return capabilities.State{}
+	}
+
+	applied := capabilities.Restore(device, state)
+	if len(applied.UserProfile) > 0 {
+		events.Publish(events.EventProfile, serial, 0, applied.UserProfile)
+	}
+	if len(applied.KeyboardProfile) > 0 {
+		events.Publish(events.EventProfile, serial, 0, applied.KeyboardProfile)
+	}
+	for channelId, profile := range applied.RgbProfiles {
+		events.Publish(events.EventRgb, serial, channelId, profile)
+	}
+	return applied
+}
+
 // UpdateAllDevicesStaticColor will push a single static color to all registered devices
 func UpdateAllDevicesStaticColor(color rgb.Color) uint8 {
 	channelId := -1
@@ -297,8 +330,12 @@ func UpdateAllDevicesStaticColor(color rgb.Color) uint8 {
 		Brightness: 1.0,
 	}
 	for _, device := range devices {
-		CallDeviceMethod(device.Serial, "UpdateRgbProfileData", "static", profile)
-		CallDeviceMethod(device.Serial, "UpdateRgbProfile", channelId, "static")
+		if dev, ok := device.Instance.(capabilities.RgbProfileEditor); ok {
+			dev.UpdateRgbProfileData("static", profile)
+		}
+		if dev, ok := device.Instance.(capabilities.Rgb); ok {
+			dev.UpdateRgbProfile(channelId, "static")
+		}
 	}
 	return 1
 }
@@ -306,27 +343,122 @@ func UpdateAllDevicesStaticColor(color rgb.Color) uint8 {
 // ResetSpeedProfiles will reset the speed profile on each available device
 func ResetSpeedProfiles(profile string) {
 	for _, device := range devices {
-		if device.ProductType == common.ProductTypeLinkHub ||
-			device.ProductType == common.ProductTypeCC ||
-			device.ProductType == common.ProductTypeCCXT ||
-			device.ProductType == common.ProductTypeCPro ||
-			device.ProductType == common.ProductTypeElite ||
-			device.ProductType == common.ProductTypeHydro ||
-			device.ProductType == common.ProductTypeCorsairOne ||
-			device.ProductType == common.ProductTypePlatinum {
-			CallDeviceMethod(device.Serial, "ResetSpeedProfiles", profile)
+		if dev, ok := device.Instance.(capabilities.SpeedProfile); ok {
+			dev.ResetSpeedProfiles(profile)
+		}
+	}
+}
+
+// SetRgbAlert will enable or disable red RGB warning on all supported devices
+func SetRgbAlert(active bool) {
+	for _, device := range GetDevices() {
+		if dev, ok := device.Instance.(capabilities.RgbAlert); ok {
+			dev.SetRgbAlert(active)
+		}
+	}
+}
+
+// RunMacroAction will run macro action which controls devices. Without target, action applies to all devices.
+func RunMacroAction(action macro.Actions) {
+	var targets []*common.Device
+	available := GetDevices()
+	if len(action.ActionTarget) > 0 {
+		if device, ok := available[action.ActionTarget]; ok {
+			targets = append(targets, device)
+		}
+	} else {
+		for _, device := range available {
+			targets = append(targets, device)
+		}
+	}
+
+	for _, device := range targets {
+		switch action.ActionType {
+		case macro.ActionUserProfile:
+			if dev, ok := device.Instance.(capabilities.UserProfiles); ok {
+				if !slices.Contains(capabilities.UserProfileNames(device.Instance), action.ActionProfile) {
+					continue
+				}
+				if dev.ChangeDeviceProfile(action.ActionProfile) == 1 {
+					events.Publish(events.EventProfile, device.Serial, 0, action.ActionProfile)
+				}
+			}
+		case macro.ActionRgbProfile:
+			if dev, ok := device.Instance.(capabilities.Rgb); ok {
+				if dev.UpdateRgbProfile(action.ActionChannelId, action.ActionProfile) == 1 {
+					events.Publish(events.EventRgb, device.Serial, action.ActionChannelId, action.ActionProfile)
+				}
+			}
+		case macro.ActionBrightness:
+			if dev, ok := device.Instance.(capabilities.BrightnessValue); ok {
+				dev.ChangeDeviceBrightnessValue(action.ActionValue)
+			}
+		case macro.ActionSpeedToggle:
+			if dev, ok := device.Instance.(capabilities.SpeedProfile); ok {
+				profile := action.ActionProfile
+				if len(action.ActionProfileAlt) > 0 && speedProfileActive(device.Instance, action.ActionChannelId, profile) {
+					profile = action.ActionProfileAlt
+				}
+				dev.UpdateSpeedProfile(action.ActionChannelId, profile)
+			}
+		}
+	}
+}
+
+// speedProfileActive returns true when channel uses given speed profile. For negative channelId, all channels must use it.
+func speedProfileActive(instance interface{}, channelId int, profile string) bool {
+	speedProfiles := capabilities.Snapshot(instance).SpeedProfiles
+	if len(speedProfiles) == 0 {
+		return false
+	}
+
+	if channelId >= 0 {
+		return speedProfiles[channelId] == profile
+	}
+
+	for _, value := range speedProfiles {
+		if value != profile {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyKeyAssignmentLayer will apply active key assignment layer to a device. RGB profile of a
+// layer is shown without being saved and device RGB profile returns when layer without one becomes active.
+func ApplyKeyAssignmentLayer(serial string) {
+	mutex.Lock()
+	device, ok := devices[serial]
+	mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	if dev, ok := device.Instance.(capabilities.KeyAssignmentLayers); ok {
+		dev.ApplyKeyAssignmentLayer()
+	}
+
+	dev, ok := device.Instance.(capabilities.RgbProfileOverride)
+	if !ok {
+		return
+	}
+
+	profile := inputmanager.GetLayerRgbProfile(serial)
+	if len(profile) > 0 {
+		if dev.SetRgbProfileOverride(profile) != 1 {
+			logger.Log(logger.Fields{"serial": serial, "profile": profile}).Warn("Unable to apply layer RGB profile")
 		}
+		return
 	}
+	dev.ClearRgbProfileOverride()
 }
 
 // GetDevicesLedData will return led data for all devices
 func GetDevicesLedData() interface{} {
 	var leds []interface{}
 	for _, device := range devices {
-		res := CallDeviceMethod(device.Serial, "GetDeviceLedData")
-		if res != nil && len(res) > 0 {
-			val := res[0]
-			leds = append(leds, val.Interface())
+		if dev, ok := device.Instance.(capabilities.LedProfile); ok {
+			leds = append(leds, dev.GetDeviceLedData())
 		}
 	}
 	return leds
@@ -336,21 +468,8 @@ func GetDevicesLedData() interface{} {
 func GetTemperatureProbes() interface{} {
 	var probes []interface{}
 	for _, device := range devices {
-		if device.ProductType == common.ProductTypeLinkHub ||
-			device.ProductType == common.ProductTypeCC ||
-			device.ProductType == common.ProductTypeCCXT ||
-			device.ProductType == common.ProductTypeMemory ||
-			device.ProductType == common.ProductTypeCPro ||
-			device.ProductType == common.ProductTypeElite ||
-			device.ProductType == common.ProductTypeXC7 ||
-			device.ProductType == common.ProductTypeHydro ||
-			device.ProductType == common.ProductTypeCorsairOne ||
-			device.ProductType == common.ProductTypePlatinum {
-			res := CallDeviceMethod(device.Serial, "GetTemperatureProbes")
-			if res != nil && len(res) > 0 {
-				val := res[0]
-				probes = append(probes, val.Interface())
-			}
+		if dev, ok := device.Instance.(capabilities.TemperatureProbes); ok {
+			probes = append(probes, dev.GetTemperatureProbes())
 		}
 	}
 	return probes
@@ -361,8 +480,13 @@ func UpdateDeviceMetrics() {
 	metrics.PopulateDefault()
 	metrics.PopulateStorage()
 
-	for _, device := range devices {
-		CallDeviceMethod(device.Serial, "UpdateDeviceMetrics")
+	for _, device := range GetDevices() {
+		if dev, ok := device.Instance.(capabilities.Metrics); ok {
+			dev.UpdateDeviceMetrics()
+		}
+		if profile := capabilities.ActiveUserProfile(device.Instance); len(profile) > 0 {
+			metrics.PopulateUserProfile(device.Serial, profile)
+		}
 	}
 }
 
@@ -371,6 +495,10 @@ func deleteDevice(serial string) {
 	mutex.Lock()
 	defer mutex.Unlock()
 	delete(devices, serial)
+	watchdog.RemoveDevice(serial)
+	temperatures.RemoveFailsafeDevice(serial)
+	temperatures.RemoveCurveDevice(serial)
+	metrics.RemoveDevice(serial)
 }
 
 // addDevice will add device to device list
@@ -383,31 +511,39 @@ func addDevice(device *common.Device) {
 	devices[device.Serial] = device
 	mutex.Unlock()
 
-	CallDeviceMethod(device.Serial, "SetDispatcher", Dispatch)
+	if dev, ok := device.Instance.(capabilities.Dispatchable); ok {
+		dev.SetDispatcher(Dispatch)
+	}
 }
 
-// CallDeviceMethod will call device method based on method name and arguments
-func CallDeviceMethod(deviceId string, methodName string, args ...interface{}) []reflect.Value {
-	mutex.Lock()
-	defer mutex.Unlock()
+// deviceDispatcher will forward calls from devices and input manager to another device
+type deviceDispatcher struct{}
 
-	device, ok := devices[deviceId]
-	if !ok {
-		logger.Log(logger.Fields{"deviceId": deviceId}).Warn("Device not found")
-		return nil
+// CallSniperMode will toggle sniper mode of a device
+func (deviceDispatcher) CallSniperMode(deviceId string, active bool) {
+	if dev, ok := GetDevice(deviceId).(capabilities.SniperMode); ok {
+		dev.CallSniperMode(active)
+		return
 	}
+	logger.Log(logger.Fields{"deviceId": deviceId}).Warn("Device not found or sniper mode is not supported")
+}
 
-	method := reflect.ValueOf(GetDevice(device.Serial)).MethodByName(methodName)
-	if !method.IsValid() {
-		return nil
+// ModifyDpi will increase or decrease DPI of a device
+func (deviceDispatcher) ModifyDpi(deviceId string, increment bool) {
+	if dev, ok := GetDevice(deviceId).(capabilities.DpiControl); ok {
+		dev.ModifyDpi(increment)
+		return
 	}
+	logger.Log(logger.Fields{"deviceId": deviceId}).Warn("Device not found or DPI control is not supported")
+}
 
-	reflectArgs := make([]reflect.Value, len(args))
-	for i, a := range args {
-		reflectArgs[i] = reflect.ValueOf(a)
+// TriggerHapticEngine will run haptic engine of a device
+func (deviceDispatcher) TriggerHapticEngine(deviceId string, left, right byte) {
+	if dev, ok := GetDevice(deviceId).(capabilities.HapticEngine); ok {
+		dev.TriggerHapticEngineExternal(left, right)
+		return
 	}
-
-	return method.Call(reflectArgs)
+	logger.Log(logger.Fields{"deviceId": deviceId}).Warn("Device not found or haptic engine is not supported")
 }
 
 // GetProducts will return all available products
@@ -417,15 +553,32 @@ func GetProducts() map[string]Device {
 
 // GetDevice will return a device by device serial
 func GetDevice(deviceId string) interface{} {
+	mutex.Lock()
+	defer mutex.Unlock()
 	if device, ok := devices[deviceId]; ok {
 		return device.Instance
 	}
 	return nil
 }
 
-// GetDevices will return all available devices
+// GetDeviceCapabilities will return capability descriptor for given device
+func GetDeviceCapabilities(deviceId string) *capabilities.Descriptor {
+	if device, ok := GetDevices()[deviceId]; ok {
+		return capabilities.Describe(device)
+	}
+	return nil
+}
+
+// GetDevices will return a copy of all available devices, safe to iterate while devices are added or removed
 func GetDevices() map[string]*common.Device {
-	return devices
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := make(map[string]*common.Device, len(devices))
+	for serial, device := range devices {
+		list[serial] = device
+	}
+	return list
 }
 
 // GetMouse will return all available mouse devices
@@ -553,11 +706,32 @@ func Init() {
 		"build":        version.GetBuildInfo().Revision,
 	}).Info("Application build")
 
+	// Fan watchdog RGB warning
+	watchdog.SetRgbHandler(SetRgbAlert)
+
+	// Macro actions controlling devices
+	macro.SetDeviceHandler(RunMacroAction)
+
+	// Key assignment layer changes
+	inputmanager.SetLayerHandler(ApplyKeyAssignmentLayer)
+
 	// Initialize general HID interface
 	if err := hid.Init(); err != nil {
 		logger.Log(logger.Fields{"error": err}).Fatal("Unable to initialize HID interface")
 	}
 
+	// HID packet capture
+	transport.SetErrorHandler(func(err error, path string) {
+		logger.Log(logger.Fields{"error": err, "location": path}).Warn("HID transport error")
+	})
+	if err := transport.Init(
+		config.GetConfig().ConfigPath+"/captures/",
+		config.GetConfig().Transport.Capture,
+		config.GetConfig().Transport.CaptureInput,
+	); err != nil {
+		logger.Log(logger.Fields{"error": err}).Error("Unable to initialize HID packet capture")
+	}
+
 	enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 		logger.Log(
 			logger.Fields{
@@ -634,6 +808,27 @@ func Init() {
 		logger.Log(logger.Fields{"error": err, "vendorId": scufVendorId}).Fatal("Unable to enumerate devices")
 	}
 
+	// Replay recorded HID sessions instead of real hardware
+	for _, file := range config.GetConfig().Transport.Replay {
+		replay, e := transport.AddReplay(file)
+		if e != nil {
+			logger.Log(logger.Fields{"error": e, "location": file}).Error("Unable to load HID capture file")
+			continue
+		}
+
+		info := replay.Info()
+		key := info.SerialNbr
+		if len(key) == 0 {
+			key = info.Path
+		}
+		deviceList[key] = Device{
+			ProductId: info.ProductID,
+			Path:      info.Path,
+			Serial:    info.SerialNbr,
+		}
+		logger.Log(logger.Fields{"productId": info.ProductID, "serial": info.SerialNbr, "location": file}).Info("Replaying HID capture file")
+	}
+
 	// Memory
 	if config.GetConfig().Memory {
 		sm, err := smbus.GetSmBus()
@@ -659,6 +854,13 @@ func Init() {
 		}
 	}
 
+	// Simulated devices
+	if config.GetConfig().Simulator.Enabled {
+		for i, kind := range config.GetConfig().Simulator.Devices {
+			addDevice(simulator.Init(kind, i+1))
+		}
+	}
+
 	// Create dummy cluster object before any other object
 	cls = cluster.Init()
 	devices["cluster"] = &common.Device{
@@ -5,6 +5,7 @@ package headsetdongle
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/hs80rgbW"
@@ -12,6 +13,7 @@ import (
 	"OpenLinkHub/src/devices/virtuosoW"
 	"OpenLinkHub/src/devices/virtuosorgbXTW"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"fmt"
 	"github.com/sstallion/go-hid"
@@ -31,8 +33,8 @@ type Devices struct {
 }
 
 type Device struct {
-	dev            *hid.Device
-	listener       *hid.Device
+	dev            transport.Device
+	listener       transport.Device
 	Manufacturer   string `json:"manufacturer"`
 	Product        string `json:"product"`
 	Serial         string `json:"serial"`
@@ -53,6 +55,13 @@ type Device struct {
 	instance       *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+)
+
 var (
 	bufferSize       = 64
 	bufferSizeWrite  = bufferSize + 1
@@ -76,7 +85,7 @@ var (
 
 func Init(vendorId, productId uint16, _, path string, callback func(device *common.Device)) *common.Device {
 	// Open device, return if failure
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId}).Error("Unable to open HID device")
 		return nil
@@ -395,7 +404,7 @@ func (d *Device) AddPairedDevice(productId uint16, device any, dev *common.Devic
 }
 
 // GetDevice will return HID device
-func (d *Device) GetDevice() *hid.Device {
+func (d *Device) GetDevice() transport.Device {
 	return d.dev
 }
 
@@ -807,7 +816,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.UsagePage == 65346 {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
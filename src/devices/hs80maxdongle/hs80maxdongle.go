@@ -5,10 +5,12 @@ package hs80maxdongle
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/hs80maxW"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"fmt"
 	"github.com/sstallion/go-hid"
@@ -26,8 +28,8 @@ type Devices struct {
 }
 
 type Device struct {
-	dev            *hid.Device
-	listener       *hid.Device
+	dev            transport.Device
+	listener       transport.Device
 	Manufacturer   string `json:"manufacturer"`
 	Product        string `json:"product"`
 	Serial         string `json:"serial"`
@@ -47,6 +49,13 @@ type Device struct {
 	instance       *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+)
+
 var (
 	bufferSize      = 64
 	bufferSizeWrite = bufferSize + 1
@@ -62,7 +71,7 @@ var (
 
 func Init(vendorId, productId uint16, _, path string, callback func(device *common.Device)) *common.Device {
 	// Open device, return if failure
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
@@ -214,7 +223,7 @@ func (d *Device) AddPairedDevice(productId uint16, device any) {
 }
 
 // GetDevice will return HID device
-func (d *Device) GetDevice() *hid.Device {
+func (d *Device) GetDevice() transport.Device {
 	return d.dev
 }
 
@@ -400,7 +409,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.InterfaceNbr == interfaceId {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
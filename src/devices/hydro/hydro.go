@@ -5,6 +5,7 @@ package hydro
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dashboard"
@@ -13,6 +14,7 @@ import (
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/temperatures"
 	"OpenLinkHub/src/usb"
+	"OpenLinkHub/src/watchdog"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -147,6 +149,22 @@ type Device struct {
 	instance          *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable           = (*Device)(nil)
+	_ capabilities.DirtyStoppable      = (*Device)(nil)
+	_ capabilities.Templated           = (*Device)(nil)
+	_ capabilities.DeviceLabel         = (*Device)(nil)
+	_ capabilities.RgbProfiles         = (*Device)(nil)
+	_ capabilities.RgbProfileReader    = (*Device)(nil)
+	_ capabilities.RgbProfileEditor    = (*Device)(nil)
+	_ capabilities.BrightnessValue     = (*Device)(nil)
+	_ capabilities.ScheduledBrightness = (*Device)(nil)
+	_ capabilities.SpeedProfile        = (*Device)(nil)
+	_ capabilities.TemperatureProbes   = (*Device)(nil)
+	_ capabilities.StateReader         = (*Device)(nil)
+)
+
 var (
 	pwd                        = ""
 	cmdSetFanSpeed             = byte(0x11)
@@ -296,6 +314,27 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	for channelId, channel := range d.Devices {
+		if channel.HasSpeed && len(channel.Profile) > 0 {
+			if state.SpeedProfiles == nil {
+				state.SpeedProfiles = make(map[int]string)
+			}
+			state.SpeedProfiles[channelId] = channel.Profile
+		}
+	}
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if d.DeviceProfile.BrightnessSlider != nil {
+		brightness := *d.DeviceProfile.BrightnessSlider
+		state.Brightness = &brightness
+	}
+	return state
+}
+
 // Stop will stop all device operations and switch a device back to hardware mode
 func (d *Device) Stop() {
 	d.Exit = true
@@ -493,6 +532,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.MiddleColor = profile.MiddleColor
 	pf.Speed = profile.Speed
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 
 	d.Rgb.Profiles[profileName] = *pf
 	d.saveRgbProfile()
@@ -564,7 +604,7 @@ func (d *Device) saveRgbProfile() {
 }
 
 // GetTemperatureProbes will return a list of temperature probes
-func (d *Device) GetTemperatureProbes() *[]TemperatureProbe {
+func (d *Device) GetTemperatureProbes() interface{} {
 	return d.TemperatureProbes
 }
 
@@ -1014,6 +1054,7 @@ func (d *Device) getDeviceData() {
 			if rpm > 0 {
 				d.Devices[deviceList[device].Index].Rpm = rpm
 			}
+			watchdog.UpdateRpm(d.Serial, deviceList[device].Name, deviceList[device].Index, deviceList[device].Pump, int(rpm))
 
 			if temp > 0 {
 				d.Devices[deviceList[device].Index].Temperature = temp
@@ -1107,6 +1148,19 @@ func (d *Device) setSpeed(data map[int]*SpeedMode) {
 	if d.Exit {
 		return
 	}
+
+	for channelId, speed := range data {
+		if speed == nil {
+			continue
+		}
+		if speed.Pump {
+			// Pump runs in fixed modes and never stops
+			watchdog.SetSpeed(d.Serial, channelId, 100)
+		} else {
+			watchdog.SetSpeed(d.Serial, channelId, int(speed.Value))
+		}
+	}
+
 	for _, value := range data {
 		if value.Pump {
 			// Pump
@@ -1169,6 +1223,21 @@ func (d *Device) getPumpMode(index int, profile string) byte {
 	return 0
 }
 
+// getMaxPumpMode will return the fastest pump mode of given device
+func (d *Device) getMaxPumpMode(index int) byte {
+	var mode byte = 0
+	for device := range deviceList {
+		if deviceList[device].Index == index {
+			for pumpMode := range deviceList[device].PumpModes {
+				if pumpMode > mode {
+					mode = pumpMode
+				}
+			}
+		}
+	}
+	return mode
+}
+
 // updateDeviceSpeed will update device speed based on a temperature reading
 func (d *Device) updateDeviceSpeed() {
 	d.timerSpeed = time.NewTicker(time.Duration(temperaturePullingInterval) * time.Millisecond)
@@ -1188,113 +1257,50 @@ func (d *Device) updateDeviceSpeed() {
 						profiles = temperatures.GetTemperatureProfile("Normal")
 					}
 
-					switch profiles.Sensor {
-					case temperatures.SensorTypeGPU:
-						{
-							temp = temperatures.GetNVIDIAGpuTemperature(0)
-							if temp == 0 {
-								temp = temperatures.GetAMDGpuTemperature()
-								if temp == 0 {
-									logger.Log(logger.Fields{"temperature": temp}).Warn("Unable to get sensor temperature. Going to fallback to CPU")
-									temp = temperatures.GetCpuTemperature()
-								}
-							}
-						}
-					case temperatures.SensorTypeCPU:
+					switch {
+					case temperatures.IsHostSensor(profiles.Sensor):
 						{
-							temp = temperatures.GetCpuTemperature()
+							temp = temperatures.GetSensorTemperature(temperatures.GetProfileSensorId(profiles))
 							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get CPU temperature.")
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "sensor": profiles.Sensor, "device": profiles.Device}).Warn("Unable to get sensor temperature.")
 							}
 						}
-					case temperatures.SensorTypeLiquidTemperature:
+					case profiles.Sensor == temperatures.SensorTypeLiquidTemperature:
 						{
 							temp = d.getLiquidTemperature()
 							if temp == 0 {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get liquid temperature.")
 							}
 						}
-					case temperatures.SensorTypeCpuGpu:
-						{
-							cpuTemp := temperatures.GetCpuTemperature()
-							gpuTemp := temperatures.GetNVIDIAGpuTemperature(0)
-							if gpuTemp == 0 {
-								gpuTemp = temperatures.GetAMDGpuTemperature()
-							}
-
-							if gpuTemp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get GPU temperature. Setting to 50")
-								gpuTemp = 50
-							}
-
-							temp = float32(math.Max(float64(cpuTemp), float64(gpuTemp)))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get maximum temperature value out of 2 numbers.")
-							}
-						}
-					case temperatures.SensorTypeExternalHwMon:
-						{
-							temp = temperatures.GetHwMonTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeExternalExecutable:
-						{
-							temp = temperatures.GetExternalBinaryTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "binary": profiles.Device}).Warn("Unable to get temperature from binary.")
-							}
-						}
-					case temperatures.SensorTypeMultiGPU:
-						{
-							temp = temperatures.GetGpuTemperatureIndex(int(profiles.GPUIndex))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeGlobalTemperature:
+					case profiles.Sensor == temperatures.SensorTypeGlobalTemperature:
 						{
 							temp = stats.GetDeviceTemperature(profiles.Device, profiles.ChannelId)
 							if temp == 0 {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
 							}
 						}
-					case temperatures.SensorTypeMultiGPUs:
-						{
-							maxGpuTemp := float32(0)
-
-							for _, index := range config.GetConfig().NvidiaGpuIndex {
-								gpuTemp := temperatures.GetNVIDIAGpuTemperature(index)
-								if gpuTemp == 0 {
-									logger.Log(logger.Fields{
-										"temperature": gpuTemp,
-										"serial":      d.Serial,
-										"channelId":   profiles.ChannelId,
-										"gpuIndex":    index,
-									}).Warn("Unable to get GPU temperature. Setting to 50")
-									gpuTemp = 50
-								}
-
-								if gpuTemp > maxGpuTemp {
-									maxGpuTemp = gpuTemp
-								}
-							}
-							temp = maxGpuTemp
-						}
 					}
 
+					// Critical temperature failsafe
+					temperatures.CheckFailsafe(d.Serial, device.ChannelId, profiles, temp)
+
 					// All temps failed, default to 50
 					if temp == 0 {
 						temp = 50
 					}
 
+					// Averaging and hysteresis
+					temp = temperatures.SmoothTemperature(d.Serial, device.ChannelId, profiles, temp)
+
 					if device.ChannelId == 0 {
-						cp := fmt.Sprintf("%s-%d", device.Profile, device.ChannelId)
+						cp := fmt.Sprintf("%s-%d-%t", device.Profile, device.ChannelId, temperatures.IsFailsafe())
 						if ok := tmp[device.ChannelId]; ok != cp {
 							tmp[device.ChannelId] = cp
 							speedMode := &SpeedMode{}
 							speedMode.Value = d.getPumpMode(device.ChannelId, device.Profile)
+							if temperatures.IsFailsafe() {
+								speedMode.Value = d.getMaxPumpMode(device.ChannelId)
+							}
 							speedMode.ZeroRpm = false
 							speedMode.Pump = true
 							channelSpeeds[device.ChannelId] = speedMode
@@ -1304,6 +1310,7 @@ func (d *Device) updateDeviceSpeed() {
 						if config.GetConfig().GraphProfiles {
 							fansValue := temperatures.Interpolate(profiles.Points[1], temp)
 							fans := int(math.Round(float64(fansValue)))
+							fans = temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, fans)
 
 							// Failsafe
 							if fans < 20 {
@@ -1312,7 +1319,10 @@ func (d *Device) updateDeviceSpeed() {
 							if fans > 100 {
 								fans = 100
 							}
-							cp := fmt.Sprintf("%s-%d-%f", device.Profile, device.ChannelId, temp)
+							if temperatures.IsFailsafe() {
+								fans = 100
+							}
+							cp := fmt.Sprintf("%s-%d-%d", device.Profile, device.ChannelId, fans)
 							if ok := tmp[device.ChannelId]; ok != cp {
 								speedMode := &SpeedMode{}
 								tmp[device.ChannelId] = cp
@@ -1325,8 +1335,11 @@ func (d *Device) updateDeviceSpeed() {
 						} else {
 							for i := 0; i < len(profiles.Profiles); i++ {
 								profile := profiles.Profiles[i]
-								minimum := profile.Min + 0.1
-								if common.InBetween(temp, minimum, profile.Max) {
+								if temperatures.IsActiveStep(profiles, i, temp) {
+									profile.Fans = uint16(temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, int(profile.Fans)))
+									if temperatures.IsFailsafe() {
+										profile.Fans = 100
+									}
 									cp := fmt.Sprintf("%s-%d-%d", device.Profile, device.ChannelId, profile.Fans)
 									if ok := tmp[device.ChannelId]; ok != cp {
 										speedMode := &SpeedMode{}
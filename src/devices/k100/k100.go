@@ -5,6 +5,7 @@ package k100
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/cluster"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
@@ -15,6 +16,7 @@ import (
 	"OpenLinkHub/src/macro"
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -66,13 +68,15 @@ type DeviceProfile struct {
 
 type Device struct {
 	Debug              bool
-	dev                *hid.Device
-	listener           *hid.Device
+	dev                transport.Device
+	listener           transport.Device
 	Manufacturer       string `json:"manufacturer"`
 	Product            string `json:"product"`
 	Serial             string `json:"serial"`
 	Firmware           string `json:"firmware"`
 	activeRgb          *rgb.ActiveRGB
+	rgbOverride        string
+	rgbOverrideMutex   sync.Mutex
 	UserProfiles       map[string]*DeviceProfile `json:"userProfiles"`
 	Devices            map[int]string            `json:"devices"`
 	DeviceProfile      *DeviceProfile
@@ -103,17 +107,49 @@ type Device struct {
 	PressLoop          bool
 	ModifierIndex      *big.Int
 	KeyAssignmentTypes map[int]string
-	MacroTracker       map[int]macro.Tracker
+	macroPlayer        macro.Player
 	RGBModes           []string
 	instance           *common.Device
 	mouseLoopActive    bool
 	mouseLoopMutex     sync.Mutex
 	mouseLoopStopCh    chan struct{}
-	stopRepeat         chan struct{}
-	stopRepeatMutex    sync.Mutex
 	dispatch           dispatcher.DeviceDispatcher
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable              = (*Device)(nil)
+	_ capabilities.DirtyStoppable         = (*Device)(nil)
+	_ capabilities.Templated              = (*Device)(nil)
+	_ capabilities.Dispatchable           = (*Device)(nil)
+	_ capabilities.UserProfiles           = (*Device)(nil)
+	_ capabilities.DeviceLabel            = (*Device)(nil)
+	_ capabilities.Rgb                    = (*Device)(nil)
+	_ capabilities.RgbProfileOverride     = (*Device)(nil)
+	_ capabilities.RgbProfiles            = (*Device)(nil)
+	_ capabilities.RgbProfileReader       = (*Device)(nil)
+	_ capabilities.RgbProfileEditor       = (*Device)(nil)
+	_ capabilities.RgbControl             = (*Device)(nil)
+	_ capabilities.RgbCluster             = (*Device)(nil)
+	_ capabilities.KeyColor               = (*Device)(nil)
+	_ capabilities.Brightness             = (*Device)(nil)
+	_ capabilities.KeyboardProfiles       = (*Device)(nil)
+	_ capabilities.KeyboardProfileSaver   = (*Device)(nil)
+	_ capabilities.KeyboardKeys           = (*Device)(nil)
+	_ capabilities.KeyboardPerformance    = (*Device)(nil)
+	_ capabilities.KeyAssignment          = (*Device)(nil)
+	_ capabilities.KeyAssignmentLayers    = (*Device)(nil)
+	_ capabilities.KeyAssignmentTypes     = (*Device)(nil)
+	_ capabilities.KeyAssignmentModifiers = (*Device)(nil)
+	_ capabilities.ControlDial            = (*Device)(nil)
+	_ capabilities.ControlDialColors      = (*Device)(nil)
+	_ capabilities.DebounceTime           = (*Device)(nil)
+	_ capabilities.PollingRate            = (*Device)(nil)
+	_ capabilities.RgbLayout              = (*Device)(nil)
+	_ capabilities.PollingRateOptions     = (*Device)(nil)
+	_ capabilities.StateReader            = (*Device)(nil)
+)
+
 var (
 	pwd             = ""
 	cmdSoftwareMode = []byte{0x01, 0x03, 0x00, 0x02}
@@ -183,7 +219,7 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 	// Set global working directory
 	pwd = config.GetConfig().ConfigPath
 
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
@@ -255,7 +291,6 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 			8: "8ms",
 			9: "9ms",
 		},
-		MacroTracker: make(map[int]macro.Tracker),
 	}
 
 	d.getDebugMode()           // Debug mode
@@ -317,6 +352,7 @@ func (d *Device) GetRgbProfiles() interface{} {
 func (d *Device) Stop() {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -347,6 +383,7 @@ func (d *Device) Stop() {
 func (d *Device) StopDirty() uint8 {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device (dirty)...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -384,6 +421,7 @@ func (d *Device) setupKeyAssignment() {
 	}
 	for _, value := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
 		for _, key := range value.Keys {
+			key = keyboards.ApplyLayer(d.Serial, key)
 			if key.OnlyColor {
 				continue
 			}
@@ -398,6 +436,11 @@ func (d *Device) setupKeyAssignment() {
 	d.writeKeyAssignment(buf)
 }
 
+// ApplyKeyAssignmentLayer will apply key assignments of active key assignment layer
+func (d *Device) ApplyKeyAssignmentLayer() {
+	d.setupKeyAssignment()
+}
+
 // getKeyData will return key data for given key hash
 func (d *Device) getKeyData(keyHash string) *keyboards.Key {
 	for _, value := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
@@ -504,6 +547,30 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetRgbLayout will return LED layout of a device
+func (d *Device) GetRgbLayout() capabilities.RgbInfo {
+	return capabilities.RgbInfo{LedChannels: d.LEDChannels}
+}
+
+// GetPollingRates will return polling rates supported by a device
+func (d *Device) GetPollingRates() map[int]string {
+	return d.PollingRates
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if state.RgbProfiles == nil && len(d.DeviceProfile.RGBProfile) > 0 {
+		state.RgbProfiles = map[int]string{0: d.DeviceProfile.RGBProfile}
+	}
+	mode := d.DeviceProfile.Brightness
+	state.BrightnessMode = &mode
+	return state
+}
+
 // getManufacturer will return device manufacturer
 func (d *Device) getDebugMode() {
 	d.Debug = config.GetConfig().Debug
@@ -572,7 +639,7 @@ func (d *Device) setupPerformance() {
 
 // isRgbStatic will return if RGB effect is static
 func (d *Device) isRgbStatic() bool {
-	if d.DeviceProfile.RGBProfile == "keyboard" || d.DeviceProfile.RGBProfile == "static" {
+	if d.rgbProfile() == "keyboard" || d.rgbProfile() == "static" {
 		return true
 	}
 	return false
@@ -1057,6 +1124,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.MiddleColor = profile.MiddleColor
 	pf.Speed = profile.Speed
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 
 	d.Rgb.Profiles[profileName] = *pf
 	d.saveRgbProfile()
@@ -1083,6 +1151,7 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 		return 5
 	}
 
+	d.setRgbOverride("")                 // Device profile is shown again
 	d.DeviceProfile.RGBProfile = profile // Set profile
 	d.saveDeviceProfile()                // Save profile
 	if d.activeRgb != nil {
@@ -1094,6 +1163,64 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 
 }
 
+// SetRgbProfileOverride will show RGB profile without saving it to device profile
+func (d *Device) SetRgbProfileOverride(profile string) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if d.GetRgbProfile(profile) == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	if d.DeviceProfile.RGBCluster {
+		return 5
+	}
+
+	d.setRgbOverride(profile)
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+	return 1
+}
+
+// ClearRgbProfileOverride will show RGB profile from device profile again
+func (d *Device) ClearRgbProfileOverride() {
+	if len(d.setRgbOverride("")) == 0 {
+		return
+	}
+
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
+// setRgbOverride will set RGB profile shown instead of device profile and return previous one
+func (d *Device) setRgbOverride(profile string) string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	previous := d.rgbOverride
+	d.rgbOverride = profile
+	return previous
+}
+
+// rgbProfile will return RGB profile currently shown by a device
+func (d *Device) rgbProfile() string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	if len(d.rgbOverride) > 0 {
+		return d.rgbOverride
+	}
+	return d.DeviceProfile.RGBProfile
+}
+
 // setupClusterController will create Cluster Controller for RGB Cluster
 func (d *Device) setupClusterController() {
 	if d.DeviceProfile == nil {
@@ -1835,7 +1962,7 @@ func (d *Device) setDeviceColor() {
 		}
 	}
 
-	if d.DeviceProfile.RGBProfile == "keyboard" {
+	if d.rgbProfile() == "keyboard" {
 		if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
 			for _, rows := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
 				for _, keys := range rows.Keys {
@@ -1854,7 +1981,7 @@ func (d *Device) setDeviceColor() {
 		}
 	}
 
-	if d.DeviceProfile.RGBProfile == "static" {
+	if d.rgbProfile() == "static" {
 		profile := d.GetRgbProfile("static")
 		if profile == nil {
 			return
@@ -1894,7 +2021,7 @@ func (d *Device) setDeviceColor() {
 				buff := make([]byte, 0)
 
 				rgbCustomColor := true
-				profile := d.GetRgbProfile(d.DeviceProfile.RGBProfile)
+				profile := d.GetRgbProfile(d.rgbProfile())
 				if profile == nil {
 					for i := 0; i < d.LEDChannels; i++ {
 						buff = append(buff, []byte{0, 0, 0}...)
@@ -1940,7 +2067,7 @@ func (d *Device) setDeviceColor() {
 					r.RGBMiddleColor.Brightness = r.RGBBrightness
 				}
 
-				switch d.DeviceProfile.RGBProfile {
+				switch d.rgbProfile() {
 				case "off":
 					{
 						for n := 0; n < d.LEDChannels; n++ {
@@ -1971,14 +2098,14 @@ func (d *Device) setDeviceColor() {
 					{
 						r.MinTemp = profile.MinTemp
 						r.MaxTemp = profile.MaxTemp
-						r.Temperature(float64(d.CpuTemp))
+						r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.CpuTemp)))
 						buff = append(buff, r.Output...)
 					}
 				case "gpu-temperature":
 					{
 						r.MinTemp = profile.MinTemp
 						r.MaxTemp = profile.MaxTemp
-						r.Temperature(float64(d.GpuTemp))
+						r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.GpuTemp)))
 						buff = append(buff, r.Output...)
 					}
 				case "colorpulse":
@@ -2183,58 +2310,6 @@ func (d *Device) transfer(endpoint, buffer []byte) ([]byte, error) {
 	return bufferR, nil
 }
 
-// addToMacroTracker adds or updates an entry in MacroTracker
-func (d *Device) addToMacroTracker(key int, value uint16, actionType uint8) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil {
-		d.MacroTracker = make(map[int]macro.Tracker)
-	}
-	d.MacroTracker[key] = macro.Tracker{
-		Value: value,
-		Type:  actionType,
-	}
-}
-
-// deleteFromMacroTracker deletes an entry from MacroTracker
-func (d *Device) deleteFromMacroTracker(key int) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil || len(d.MacroTracker) == 0 {
-		return
-	}
-	delete(d.MacroTracker, key)
-}
-
-// releaseMacroTracker will release current MacroTracker
-func (d *Device) releaseMacroTracker() {
-	d.mutex.Lock()
-	if d.MacroTracker == nil {
-		d.mutex.Unlock()
-		return
-	}
-	keys := make([]int, 0, len(d.MacroTracker))
-	for key := range d.MacroTracker {
-		keys = append(keys, key)
-	}
-	sort.Ints(keys)
-	d.mutex.Unlock()
-
-	for _, key := range keys {
-		switch d.MacroTracker[key].Type {
-		case 1, 3:
-			inputmanager.InputControlKeyboardHold(d.MacroTracker[key].Value, false)
-			break
-		case 9:
-			inputmanager.InputControlMouseHold(d.MacroTracker[key].Value, false)
-			break
-		}
-		d.deleteFromMacroTracker(key)
-	}
-}
-
 // mouseEventLoop will send mouse action until stopped
 func mouseEventLoop(stopCh <-chan struct{}, actionCommand, actionSleep uint16) {
 	// Send input once
@@ -2274,11 +2349,10 @@ func (d *Device) triggerKeyAssignment(value []byte) {
 		raw[i], raw[j] = raw[j], raw[i]
 	}
 	val := new(big.Int).SetBytes(raw)
+	val = inputmanager.ResolveKeyboardReport(d.Serial, val)
 
-	// Check if we have any queue in macro tracker. If yes, release those keys
-	if len(d.MacroTracker) > 0 {
-		d.releaseMacroTracker()
-	}
+	// Release keys held by macros
+	d.macroPlayer.Release()
 
 	if d.ModifierIndex != val {
 		if d.KeyboardKey != nil {
@@ -2288,7 +2362,7 @@ func (d *Device) triggerKeyAssignment(value []byte) {
 				break
 			case 8:
 				if d.dispatch != nil {
-					d.dispatch(d.KeyboardKey.DeviceId, "CallSniperMode", d.PressLoop)
+					d.dispatch.CallSniperMode(d.KeyboardKey.DeviceId, d.PressLoop)
 				}
 				break
 			}
@@ -2305,6 +2379,7 @@ func (d *Device) triggerKeyAssignment(value []byte) {
 		if key == nil {
 			return
 		}
+		*key = keyboards.ApplyLayer(d.Serial, *key)
 
 		// Performance Lock
 		if key.IsLock {
@@ -2345,17 +2420,17 @@ func (d *Device) triggerKeyAssignment(value []byte) {
 				d.KeyboardKey = key
 			}
 			if d.dispatch != nil && len(key.DeviceId) > 0 {
-				d.dispatch(key.DeviceId, "CallSniperMode", key.ActionHold)
+				d.dispatch.CallSniperMode(key.DeviceId, key.ActionHold)
 			}
 			break
 		case 2:
 			if d.dispatch != nil && len(key.DeviceId) > 0 {
-				d.dispatch(key.DeviceId, "ModifyDpi", true)
+				d.dispatch.ModifyDpi(key.DeviceId, true)
 			}
 			break
 		case 4:
 			if d.dispatch != nil && len(key.DeviceId) > 0 {
-				d.dispatch(key.DeviceId, "ModifyDpi", false)
+				d.dispatch.ModifyDpi(key.DeviceId, false)
 			}
 			break
 		case 9:
@@ -2376,118 +2451,10 @@ func (d *Device) triggerKeyAssignment(value []byte) {
 			}
 			break
 		case 10:
-			macroProfile := macro.GetProfile(int(key.ActionCommand))
-			if macroProfile == nil {
+			if !d.macroPlayer.Press(int(key.ActionCommand)) {
 				logger.Log(logger.Fields{"serial": d.Serial}).Error("Invalid macro profile")
 				return
 			}
-			for i := 0; i < len(macroProfile.Actions); i++ {
-				if v, valid := macroProfile.Actions[i]; valid {
-					// Add to macro tracker for easier release
-					if v.ActionHold {
-						d.addToMacroTracker(i, v.ActionCommand, v.ActionType)
-					}
-
-					switch v.ActionType {
-					case 1, 3:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							d.stopRepeatMutex.Lock()
-							if d.stopRepeat != nil {
-								close(d.stopRepeat)
-							}
-
-							d.stopRepeat = make(chan struct{})
-							localStop := d.stopRepeat
-							d.stopRepeatMutex.Unlock()
-
-							go func() {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									select {
-									case <-localStop:
-										return
-									default:
-										inputmanager.InputControlKeyboard(v.ActionCommand, false)
-									}
-									if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-										time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-									}
-								}
-							}()
-
-						} else {
-							inputmanager.InputControlKeyboard(v.ActionCommand, v.ActionHold)
-						}
-						break
-					case 9:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							for z := 0; z < int(v.ActionRepeat); z++ {
-								inputmanager.InputControlMouse(v.ActionCommand)
-								if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-									time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-								}
-							}
-						} else if v.ActionHold && v.ActionRepeat == 0 {
-							inputmanager.InputControlMouseHold(v.ActionCommand, v.ActionHold)
-						} else {
-							inputmanager.InputControlMouse(v.ActionCommand)
-						}
-						break
-					case 5:
-						if v.ActionDelay > 0 {
-							time.Sleep(time.Duration(v.ActionDelay) * time.Millisecond)
-						}
-						break
-					case 6:
-						if v.ActionRepeat > 0 {
-							for z := 0; z < int(v.ActionRepeat); z++ {
-								inputmanager.InputControlKeyboardText(v.ActionText)
-								if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-									time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-								}
-							}
-						} else {
-							inputmanager.InputControlKeyboardText(v.ActionText)
-						}
-						break
-					case 20:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							d.stopRepeatMutex.Lock()
-							if d.stopRepeat != nil {
-								close(d.stopRepeat)
-							}
-
-							d.stopRepeat = make(chan struct{})
-							localStop := d.stopRepeat
-							d.stopRepeatMutex.Unlock()
-
-							go func() {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									select {
-									case <-localStop:
-										return
-									default:
-										if v.MousePositionAbsolute {
-											inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-										} else {
-											inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-										}
-									}
-									if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-										time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-									}
-								}
-							}()
-						} else {
-							if v.MousePositionAbsolute {
-								inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-							} else {
-								inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-							}
-						}
-						break
-					}
-				}
-			}
 			break
 		case 13:
 			inputmanager.InputControlScroll(true)
@@ -2552,7 +2519,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.InterfaceNbr == 2 {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
@@ -5,6 +5,7 @@ package k100airW
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dispatcher"
@@ -67,6 +68,8 @@ type Device struct {
 	Firmware               string `json:"firmware"`
 	DongleFirmware         string `json:"dongleFirmware"`
 	activeRgb              *rgb.ActiveRGB
+	rgbOverride            string
+	rgbOverrideMutex       sync.Mutex
 	UserProfiles           map[string]*DeviceProfile `json:"userProfiles"`
 	Devices                map[int]string            `json:"devices"`
 	DeviceProfile          *DeviceProfile
@@ -98,17 +101,52 @@ type Device struct {
 	ModifierIndex          *big.Int
 	KeyboardKey            *keyboards.Key
 	PressLoop              bool
-	MacroTracker           map[int]macro.Tracker
+	macroPlayer            macro.Player
 	mouseLoopActive        bool
 	mouseLoopMutex         sync.Mutex
 	mouseLoopStopCh        chan struct{}
 	Usb                    bool
-	stopRepeat             chan struct{}
-	stopRepeatMutex        sync.Mutex
 	dispatch               dispatcher.DeviceDispatcher
 	checkOnlineMu          sync.Mutex
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable              = (*Device)(nil)
+	_ capabilities.DirtyStoppable         = (*Device)(nil)
+	_ capabilities.Templated              = (*Device)(nil)
+	_ capabilities.Dispatchable           = (*Device)(nil)
+	_ capabilities.UserProfiles           = (*Device)(nil)
+	_ capabilities.UserProfileDeleter     = (*Device)(nil)
+	_ capabilities.DeviceLabel            = (*Device)(nil)
+	_ capabilities.Rgb                    = (*Device)(nil)
+	_ capabilities.RgbProfileOverride     = (*Device)(nil)
+	_ capabilities.RgbProfiles            = (*Device)(nil)
+	_ capabilities.RgbProfileReader       = (*Device)(nil)
+	_ capabilities.RgbProfileEditor       = (*Device)(nil)
+	_ capabilities.RgbControl             = (*Device)(nil)
+	_ capabilities.KeyColor               = (*Device)(nil)
+	_ capabilities.Brightness             = (*Device)(nil)
+	_ capabilities.KeyboardProfiles       = (*Device)(nil)
+	_ capabilities.KeyboardProfileSaver   = (*Device)(nil)
+	_ capabilities.KeyboardKeys           = (*Device)(nil)
+	_ capabilities.KeyboardPerformance    = (*Device)(nil)
+	_ capabilities.KeyAssignment          = (*Device)(nil)
+	_ capabilities.KeyAssignmentLayers    = (*Device)(nil)
+	_ capabilities.KeyAssignmentTypes     = (*Device)(nil)
+	_ capabilities.KeyAssignmentModifiers = (*Device)(nil)
+	_ capabilities.ControlDial            = (*Device)(nil)
+	_ capabilities.AutoBrightness         = (*Device)(nil)
+	_ capabilities.PairedDevice           = (*Device)(nil)
+	_ capabilities.BatteryLevel           = (*Device)(nil)
+	_ capabilities.SleepMode              = (*Device)(nil)
+	_ capabilities.KeyboardKeyTrigger     = (*Device)(nil)
+	_ capabilities.SleepTimer             = (*Device)(nil)
+	_ capabilities.SleepModeOptions       = (*Device)(nil)
+	_ capabilities.BatteryStatus          = (*Device)(nil)
+	_ capabilities.StateReader            = (*Device)(nil)
+)
+
 var (
 	pwd                       = ""
 	cmdCloseEndpoint          = []byte{0x05, 0x01, 0x01}
@@ -230,7 +268,6 @@ func Init(vendorId, slipstreamId, productId uint16, dev *common.Slipstream, endp
 		},
 		UIKeyboard:    "keyboard-7",
 		UIKeyboardRow: "keyboard-row-25",
-		MacroTracker:  make(map[int]macro.Tracker),
 	}
 
 	d.getDebugMode()       // Debug mode
@@ -269,6 +306,7 @@ func (d *Device) Stop() {
 func (d *Device) StopInternal() {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial}).Info("Stopping device...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -279,6 +317,7 @@ func (d *Device) StopInternal() {
 func (d *Device) StopDirty() uint8 {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial}).Info("Stopping device (dirty)...")
+	d.macroPlayer.Stop()
 
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
@@ -313,6 +352,7 @@ func (d *Device) setupKeyAssignment() {
 
 	for _, row := range keyboard.Row {
 		for _, key := range row.Keys {
+			key = keyboards.ApplyLayer(d.Serial, key)
 			if len(key.KeyData) == 0 {
 				continue
 			}
@@ -340,6 +380,11 @@ func (d *Device) setupKeyAssignment() {
 	d.writeKeyAssignment(buf)
 }
 
+// ApplyKeyAssignmentLayer will apply key assignments of active key assignment layer
+func (d *Device) ApplyKeyAssignmentLayer() {
+	d.setupKeyAssignment()
+}
+
 func (d *Device) getModifierKeyShift(modifierKey int) byte {
 	for _, value := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
 		for keyIndex, key := range value.Keys {
@@ -510,6 +555,30 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetSleepModes will return sleep modes supported by a device
+func (d *Device) GetSleepModes() map[int]string {
+	return d.SleepModes
+}
+
+// GetBatteryLevel will return battery level of a device
+func (d *Device) GetBatteryLevel() uint16 {
+	return d.BatteryLevel
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if state.RgbProfiles == nil && len(d.DeviceProfile.RGBProfile) > 0 {
+		state.RgbProfiles = map[int]string{0: d.DeviceProfile.RGBProfile}
+	}
+	mode := d.DeviceProfile.Brightness
+	state.BrightnessMode = &mode
+	return state
+}
+
 // setKeyAmount will set global key amount
 func (d *Device) setKeyAmount() {
 	index := 0
@@ -1142,6 +1211,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.AlternateColors = profile.AlternateColors
 	pf.RgbDirection = profile.RgbDirection
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 
 	d.Rgb.Profiles[profileName] = *pf
 	d.saveRgbProfile()
@@ -1166,6 +1236,7 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 		return 0
 	}
 
+	d.setRgbOverride("")                           // Device profile is shown again
 	d.DeviceProfile.SlipstreamRGBProfile = profile // Set profile
 	d.saveDeviceProfile()                          // Save profile
 	if d.activeRgb != nil {
@@ -1176,6 +1247,60 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 	return 1
 }
 
+// SetRgbProfileOverride will show RGB profile without saving it to device profile
+func (d *Device) SetRgbProfileOverride(profile string) uint8 {
+	if !d.Connected {
+		return 0
+	}
+
+	if _, ok := d.RGBModes[profile]; !ok {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	d.setRgbOverride(profile)
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+	return 1
+}
+
+// ClearRgbProfileOverride will show RGB profile from device profile again
+func (d *Device) ClearRgbProfileOverride() {
+	if len(d.setRgbOverride("")) == 0 {
+		return
+	}
+
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
+// setRgbOverride will set RGB profile shown instead of device profile and return previous one
+func (d *Device) setRgbOverride(profile string) string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	previous := d.rgbOverride
+	d.rgbOverride = profile
+	return previous
+}
+
+// rgbProfile will return RGB profile currently shown by a device
+func (d *Device) rgbProfile() string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	if len(d.rgbOverride) > 0 {
+		return d.rgbOverride
+	}
+	return d.DeviceProfile.SlipstreamRGBProfile
+}
+
 // ChangeDeviceBrightness will change device brightness
 func (d *Device) ChangeDeviceBrightness(mode uint8) uint8 {
 	d.DeviceProfile.Brightness = mode
@@ -1674,7 +1799,7 @@ func (d *Device) setDeviceColor() {
 		return
 	}
 
-	if !slices.Contains(rgbModes, d.DeviceProfile.SlipstreamRGBProfile) {
+	if !slices.Contains(rgbModes, d.rgbProfile()) {
 		d.DeviceProfile.SlipstreamRGBProfile = "keyboard"
 	}
 
@@ -1706,7 +1831,7 @@ func (d *Device) setDeviceColor() {
 		}
 	}
 
-	switch d.DeviceProfile.SlipstreamRGBProfile {
+	switch d.rgbProfile() {
 	case "off":
 		{
 			if keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
@@ -1775,7 +1900,7 @@ func (d *Device) setDeviceColor() {
 				dataTypeSetColor = []byte{0x7e, 0xa0, 0x02, 0x04, 0x01}
 				start := 3
 
-				rain := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				rain := d.GetRgbProfile(d.rgbProfile())
 				if rain == nil {
 					buf[2] = byte(d.KeyAmount)
 					start = 3
@@ -1837,7 +1962,7 @@ func (d *Device) setDeviceColor() {
 				dataTypeSetColor = []byte{0xf9, 0xb1, 0x02, 0x04} // Random colors
 				start := 4
 
-				tlk := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				tlk := d.GetRgbProfile(d.rgbProfile())
 				if tlk == nil {
 					logger.Log(logger.Fields{"serial": d.Serial}).Error("Unable to find tlk rgb profile definition. Using device defaults")
 					start = 4
@@ -1899,7 +2024,7 @@ func (d *Device) setDeviceColor() {
 				var buf = make([]byte, keyboard.BufferSize-4)
 				dataTypeSetColor = []byte{0xa2, 0x09, 0x02, 0x04}
 				start := 4
-				tlk := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				tlk := d.GetRgbProfile(d.rgbProfile())
 				if tlk == nil {
 					logger.Log(logger.Fields{"serial": d.Serial}).Error("Unable to find tlk rgb profile definition. Using device defaults")
 					start = 4
@@ -1959,7 +2084,7 @@ func (d *Device) setDeviceColor() {
 		{
 			if keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
 				var buf = make([]byte, keyboard.BufferSize-4)
-				spiralrainbow := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				spiralrainbow := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if spiralrainbow != nil {
 					switch spiralrainbow.Speed {
@@ -1999,7 +2124,7 @@ func (d *Device) setDeviceColor() {
 				var buf = make([]byte, keyboard.BufferSize-4)
 				dataTypeSetColor = []byte{0x4f, 0xad, 0x02, 0x04}
 				start := 4
-				colorpulse := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				colorpulse := d.GetRgbProfile(d.rgbProfile())
 				if colorpulse == nil {
 					logger.Log(logger.Fields{"serial": d.Serial}).Error("Unable to find tlk rgb profile definition. Using device defaults")
 					start = 4
@@ -2059,7 +2184,7 @@ func (d *Device) setDeviceColor() {
 				var buf = make([]byte, keyboard.BufferSize-4)
 				dataTypeSetColor = []byte{0xfa, 0xa5, 0x02, 0x04}
 				start := 4
-				colorpulse := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				colorpulse := d.GetRgbProfile(d.rgbProfile())
 				if colorpulse == nil {
 					logger.Log(logger.Fields{"serial": d.Serial}).Error("Unable to find tlk rgb profile definition. Using device defaults")
 					start = 4
@@ -2119,7 +2244,7 @@ func (d *Device) setDeviceColor() {
 				dataTypeSetColor = []byte{0xff, 0x7b, 0x02, 0x04, 0x04}
 				start := 3
 
-				colorwave := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				colorwave := d.GetRgbProfile(d.rgbProfile())
 				if colorwave == nil {
 					buf[2] = byte(d.KeyAmount)
 					start = 3
@@ -2183,7 +2308,7 @@ func (d *Device) setDeviceColor() {
 		{
 			if keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
 				var buf = make([]byte, keyboard.BufferSize-4)
-				spiralrainbow := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				spiralrainbow := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if spiralrainbow != nil {
 					switch spiralrainbow.Speed {
@@ -2225,7 +2350,7 @@ func (d *Device) setDeviceColor() {
 				dataTypeSetColor = []byte{0xc0, 0x90, 0x02, 0x04, 0x04}
 				start := 3
 
-				visor := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				visor := d.GetRgbProfile(d.rgbProfile())
 				if visor == nil {
 					buf[2] = byte(d.KeyAmount)
 					start = 3
@@ -2288,7 +2413,7 @@ func (d *Device) setDeviceColor() {
 				start := 8
 
 				var speed = byte(0x04)
-				watercolor := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				watercolor := d.GetRgbProfile(d.rgbProfile())
 				if watercolor != nil {
 					if watercolor.Speed == 1 {
 						speed = 0x05 // Fast
@@ -2356,7 +2481,7 @@ func (d *Device) setDeviceColor() {
 			if keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
 				var buf = make([]byte, keyboard.BufferSize-2)
 
-				gradient := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				gradient := d.GetRgbProfile(d.rgbProfile())
 				if gradient == nil {
 					return
 				}
@@ -2653,58 +2778,6 @@ func (d *Device) getModifierPosition() uint8 {
 	return 0
 }
 
-// addToMacroTracker adds or updates an entry in MacroTracker
-func (d *Device) addToMacroTracker(key int, value uint16, actionType uint8) {
-	d.macroMutex.Lock()
-	defer d.macroMutex.Unlock()
-
-	if d.MacroTracker == nil {
-		d.MacroTracker = make(map[int]macro.Tracker)
-	}
-	d.MacroTracker[key] = macro.Tracker{
-		Value: value,
-		Type:  actionType,
-	}
-}
-
-// deleteFromMacroTracker deletes an entry from MacroTracker
-func (d *Device) deleteFromMacroTracker(key int) {
-	d.macroMutex.Lock()
-	defer d.macroMutex.Unlock()
-
-	if d.MacroTracker == nil || len(d.MacroTracker) == 0 {
-		return
-	}
-	delete(d.MacroTracker, key)
-}
-
-// releaseMacroTracker will release current MacroTracker
-func (d *Device) releaseMacroTracker() {
-	d.macroMutex.Lock()
-	if d.MacroTracker == nil {
-		d.macroMutex.Unlock()
-		return
-	}
-	keys := make([]int, 0, len(d.MacroTracker))
-	for key := range d.MacroTracker {
-		keys = append(keys, key)
-	}
-	sort.Ints(keys)
-	d.macroMutex.Unlock()
-
-	for _, key := range keys {
-		switch d.MacroTracker[key].Type {
-		case 1, 3:
-			inputmanager.InputControlKeyboardHold(d.MacroTracker[key].Value, false)
-			break
-		case 9:
-			inputmanager.InputControlMouseHold(d.MacroTracker[key].Value, false)
-			break
-		}
-		d.deleteFromMacroTracker(key)
-	}
-}
-
 // mouseEventLoop will send mouse action until stopped
 func mouseEventLoop(stopCh <-chan struct{}, actionCommand, actionSleep uint16) {
 	// Send input once
@@ -2759,11 +2832,10 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 		raw[i], raw[j] = raw[j], raw[i]
 	}
 	val := new(big.Int).SetBytes(raw)
+	val = inputmanager.ResolveKeyboardReport(d.Serial, val)
 
-	// Check if we have any queue in macro tracker. If yes, release those keys
-	if len(d.MacroTracker) > 0 {
-		d.releaseMacroTracker()
-	}
+	// Release keys held by macros
+	d.macroPlayer.Release()
 
 	if d.ModifierIndex != val {
 		if d.KeyboardKey != nil {
@@ -2773,7 +2845,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 				break
 			case 8:
 				if d.dispatch != nil {
-					d.dispatch(d.KeyboardKey.DeviceId, "CallSniperMode", d.PressLoop)
+					d.dispatch.CallSniperMode(d.KeyboardKey.DeviceId, d.PressLoop)
 				}
 				break
 			}
@@ -2786,6 +2858,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 		if key == nil {
 			return
 		}
+		*key = keyboards.ApplyLayer(d.Serial, *key)
 
 		if key.BrightnessKey {
 			if d.DeviceProfile.BrightnessLevel >= 1000 {
@@ -2873,7 +2946,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 				d.KeyboardKey = key
 			}
 			if d.dispatch != nil && len(key.DeviceId) > 0 {
-				d.dispatch(key.DeviceId, "CallSniperMode", key.ActionHold)
+				d.dispatch.CallSniperMode(key.DeviceId, key.ActionHold)
 			}
 			break
 		case 9:
@@ -2894,118 +2967,10 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 			}
 			break
 		case 10:
-			macroProfile := macro.GetProfile(int(key.ActionCommand))
-			if macroProfile == nil {
+			if !d.macroPlayer.Press(int(key.ActionCommand)) {
 				logger.Log(logger.Fields{"serial": d.Serial}).Error("Invalid macro profile")
 				return
 			}
-			for i := 0; i < len(macroProfile.Actions); i++ {
-				if v, valid := macroProfile.Actions[i]; valid {
-					// Add to macro tracker for easier release
-					if v.ActionHold {
-						d.addToMacroTracker(i, v.ActionCommand, v.ActionType)
-					}
-
-					switch v.ActionType {
-					case 1, 3:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							d.stopRepeatMutex.Lock()
-							if d.stopRepeat != nil {
-								close(d.stopRepeat)
-							}
-
-							d.stopRepeat = make(chan struct{})
-							localStop := d.stopRepeat
-							d.stopRepeatMutex.Unlock()
-
-							go func() {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									select {
-									case <-localStop:
-										return
-									default:
-										inputmanager.InputControlKeyboard(v.ActionCommand, false)
-									}
-									if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-										time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-									}
-								}
-							}()
-
-						} else {
-							inputmanager.InputControlKeyboard(v.ActionCommand, v.ActionHold)
-						}
-						break
-					case 9:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							for z := 0; z < int(v.ActionRepeat); z++ {
-								inputmanager.InputControlMouse(v.ActionCommand)
-								if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-									time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-								}
-							}
-						} else if v.ActionHold && v.ActionRepeat == 0 {
-							inputmanager.InputControlMouseHold(v.ActionCommand, v.ActionHold)
-						} else {
-							inputmanager.InputControlMouse(v.ActionCommand)
-						}
-						break
-					case 6:
-						if v.ActionRepeat > 0 {
-							for z := 0; z < int(v.ActionRepeat); z++ {
-								inputmanager.InputControlKeyboardText(v.ActionText)
-								if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-									time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-								}
-							}
-						} else {
-							inputmanager.InputControlKeyboardText(v.ActionText)
-						}
-						break
-					case 5:
-						if v.ActionDelay > 0 {
-							time.Sleep(time.Duration(v.ActionDelay) * time.Millisecond)
-						}
-						break
-					case 20:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							d.stopRepeatMutex.Lock()
-							if d.stopRepeat != nil {
-								close(d.stopRepeat)
-							}
-
-							d.stopRepeat = make(chan struct{})
-							localStop := d.stopRepeat
-							d.stopRepeatMutex.Unlock()
-
-							go func() {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									select {
-									case <-localStop:
-										return
-									default:
-										if v.MousePositionAbsolute {
-											inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-										} else {
-											inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-										}
-									}
-									if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-										time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-									}
-								}
-							}()
-						} else {
-							if v.MousePositionAbsolute {
-								inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-							} else {
-								inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-							}
-						}
-						break
-					}
-				}
-			}
 			break
 		case 11:
 			if d.DeviceProfile.BrightnessLevel >= 1000 {
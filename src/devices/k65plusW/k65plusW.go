@@ -5,6 +5,7 @@ package k65plusW
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dispatcher"
@@ -15,6 +16,7 @@ import (
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -22,12 +24,9 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
-	"sort"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/sstallion/go-hid"
 )
 
 // DeviceProfile struct contains all device profile
@@ -60,14 +59,16 @@ type DeviceProfile struct {
 
 type Device struct {
 	Debug                  bool
-	dev                    *hid.Device
-	listener               *hid.Device
+	dev                    transport.Device
+	listener               transport.Device
 	Manufacturer           string `json:"manufacturer"`
 	Product                string `json:"product"`
 	Serial                 string `json:"serial"`
 	Firmware               string `json:"firmware"`
 	DongleFirmware         string `json:"dongleFirmware"`
 	activeRgb              *rgb.ActiveRGB
+	rgbOverride            string
+	rgbOverrideMutex       sync.Mutex
 	UserProfiles           map[string]*DeviceProfile `json:"userProfiles"`
 	Devices                map[int]string            `json:"devices"`
 	DeviceProfile          *DeviceProfile
@@ -103,18 +104,52 @@ type Device struct {
 	ModifierIndex          *big.Int
 	KeyboardKey            *keyboards.Key
 	PressLoop              bool
-	MacroTracker           map[int]macro.Tracker
+	macroPlayer            macro.Player
 	instance               *common.Device
 	mouseLoopActive        bool
 	mouseLoopMutex         sync.Mutex
 	mouseLoopStopCh        chan struct{}
 	Usb                    bool
-	stopRepeat             chan struct{}
-	stopRepeatMutex        sync.Mutex
 	dispatch               dispatcher.DeviceDispatcher
 	checkOnlineMu          sync.Mutex
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable              = (*Device)(nil)
+	_ capabilities.DirtyStoppable         = (*Device)(nil)
+	_ capabilities.Templated              = (*Device)(nil)
+	_ capabilities.Dispatchable           = (*Device)(nil)
+	_ capabilities.UserProfiles           = (*Device)(nil)
+	_ capabilities.UserProfileDeleter     = (*Device)(nil)
+	_ capabilities.DeviceLabel            = (*Device)(nil)
+	_ capabilities.Rgb                    = (*Device)(nil)
+	_ capabilities.RgbProfileOverride     = (*Device)(nil)
+	_ capabilities.RgbProfiles            = (*Device)(nil)
+	_ capabilities.RgbProfileReader       = (*Device)(nil)
+	_ capabilities.RgbProfileEditor       = (*Device)(nil)
+	_ capabilities.RgbControl             = (*Device)(nil)
+	_ capabilities.KeyColor               = (*Device)(nil)
+	_ capabilities.Brightness             = (*Device)(nil)
+	_ capabilities.KeyboardProfiles       = (*Device)(nil)
+	_ capabilities.KeyboardProfileSaver   = (*Device)(nil)
+	_ capabilities.KeyboardKeys           = (*Device)(nil)
+	_ capabilities.KeyboardPerformance    = (*Device)(nil)
+	_ capabilities.KeyAssignment          = (*Device)(nil)
+	_ capabilities.KeyAssignmentLayers    = (*Device)(nil)
+	_ capabilities.KeyAssignmentTypes     = (*Device)(nil)
+	_ capabilities.KeyAssignmentModifiers = (*Device)(nil)
+	_ capabilities.ControlDial            = (*Device)(nil)
+	_ capabilities.PairedDevice           = (*Device)(nil)
+	_ capabilities.BatteryLevel           = (*Device)(nil)
+	_ capabilities.KeyboardKeyTrigger     = (*Device)(nil)
+	_ capabilities.SleepTimer             = (*Device)(nil)
+	_ capabilities.RgbLayout              = (*Device)(nil)
+	_ capabilities.SleepModeOptions       = (*Device)(nil)
+	_ capabilities.BatteryStatus          = (*Device)(nil)
+	_ capabilities.StateReader            = (*Device)(nil)
+)
+
 var (
 	pwd                     = ""
 	cmdSoftwareMode         = []byte{0x01, 0x03, 0x00, 0x02}
@@ -161,7 +196,7 @@ var (
 	}
 )
 
-func Init(vendorId, slipstreamId, productId uint16, dev *hid.Device, endpoint byte, serial string) *Device {
+func Init(vendorId, slipstreamId, productId uint16, dev transport.Device, endpoint byte, serial string) *Device {
 	// Set global working directory
 	pwd = config.GetConfig().ConfigPath
 
@@ -223,7 +258,6 @@ func Init(vendorId, slipstreamId, productId uint16, dev *hid.Device, endpoint by
 			9:  "Mouse",
 			10: "Macro",
 		},
-		MacroTracker: make(map[int]macro.Tracker),
 	}
 
 	d.getDebugMode()       // Debug mode
@@ -272,6 +306,7 @@ func (d *Device) Stop() {}
 func (d *Device) StopInternal() {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -311,6 +346,7 @@ func (d *Device) StopInternal() {
 func (d *Device) StopDirty() uint8 {
 	d.Exit = true
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device (dirty)...")
+	d.macroPlayer.Stop()
 	if d.activeRgb != nil {
 		d.activeRgb.Stop()
 	}
@@ -354,6 +390,7 @@ func (d *Device) setupKeyAssignment() {
 
 	for _, row := range keyboard.Row {
 		for _, key := range row.Keys {
+			key = keyboards.ApplyLayer(d.Serial, key)
 			if key.Default {
 				buf[key.KeyData[0]] = byte(key.KeyData[1])
 			} else {
@@ -377,6 +414,11 @@ func (d *Device) setupKeyAssignment() {
 	d.writeKeyAssignment(buf)
 }
 
+// ApplyKeyAssignmentLayer will apply key assignments of active key assignment layer
+func (d *Device) ApplyKeyAssignmentLayer() {
+	d.setupKeyAssignment()
+}
+
 func (d *Device) getModifierKeyShift(modifierKey int) byte {
 	for _, value := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
 		for keyIndex, key := range value.Keys {
@@ -546,6 +588,35 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetRgbLayout will return LED layout of a device
+func (d *Device) GetRgbLayout() capabilities.RgbInfo {
+	return capabilities.RgbInfo{LedChannels: d.LEDChannels}
+}
+
+// GetSleepModes will return sleep modes supported by a device
+func (d *Device) GetSleepModes() map[int]string {
+	return d.SleepModes
+}
+
+// GetBatteryLevel will return battery level of a device
+func (d *Device) GetBatteryLevel() uint16 {
+	return d.BatteryLevel
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if state.RgbProfiles == nil && len(d.DeviceProfile.RGBProfile) > 0 {
+		state.RgbProfiles = map[int]string{0: d.DeviceProfile.RGBProfile}
+	}
+	mode := d.DeviceProfile.Brightness
+	state.BrightnessMode = &mode
+	return state
+}
+
 // setKeyAmount will set global key amount
 func (d *Device) setKeyAmount() {
 	index := 0
@@ -1058,6 +1129,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.AlternateColors = profile.AlternateColors
 	pf.RgbDirection = profile.RgbDirection
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 
 	d.Rgb.Profiles[profileName] = *pf
 	d.saveRgbProfile()
@@ -1082,6 +1154,7 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 		return 0
 	}
 
+	d.setRgbOverride("")                           // Device profile is shown again
 	d.DeviceProfile.SlipstreamRGBProfile = profile // Set profile
 	d.saveDeviceProfile()                          // Save profile
 	if d.activeRgb != nil {
@@ -1093,6 +1166,60 @@ func (d *Device) UpdateRgbProfile(_ int, profile string) uint8 {
 
 }
 
+// SetRgbProfileOverride will show RGB profile without saving it to device profile
+func (d *Device) SetRgbProfileOverride(profile string) uint8 {
+	if !d.Connected {
+		return 0
+	}
+
+	if _, ok := d.RGBModes[profile]; !ok {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	d.setRgbOverride(profile)
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+	return 1
+}
+
+// ClearRgbProfileOverride will show RGB profile from device profile again
+func (d *Device) ClearRgbProfileOverride() {
+	if len(d.setRgbOverride("")) == 0 {
+		return
+	}
+
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
+// setRgbOverride will set RGB profile shown instead of device profile and return previous one
+func (d *Device) setRgbOverride(profile string) string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	previous := d.rgbOverride
+	d.rgbOverride = profile
+	return previous
+}
+
+// rgbProfile will return RGB profile currently shown by a device
+func (d *Device) rgbProfile() string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	if len(d.rgbOverride) > 0 {
+		return d.rgbOverride
+	}
+	return d.DeviceProfile.SlipstreamRGBProfile
+}
+
 // ChangeDeviceBrightness will change device brightness
 func (d *Device) ChangeDeviceBrightness(mode uint8) uint8 {
 	d.DeviceProfile.Brightness = mode
@@ -1541,7 +1668,7 @@ func (d *Device) setDeviceColor() {
 		return
 	}
 
-	if !slices.Contains(rgbModes, d.DeviceProfile.SlipstreamRGBProfile) {
+	if !slices.Contains(rgbModes, d.rgbProfile()) {
 		d.DeviceProfile.SlipstreamRGBProfile = "keyboard"
 	}
 
@@ -1561,7 +1688,7 @@ func (d *Device) setDeviceColor() {
 		}
 	}
 
-	switch d.DeviceProfile.SlipstreamRGBProfile {
+	switch d.rgbProfile() {
 	case "off":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
@@ -1593,7 +1720,7 @@ func (d *Device) setDeviceColor() {
 	case "rain":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				rain := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				rain := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if rain != nil {
 					switch rain.Speed {
@@ -1617,7 +1744,7 @@ func (d *Device) setDeviceColor() {
 	case "tlk":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				tlk := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				tlk := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if tlk != nil {
 					switch tlk.Speed {
@@ -1642,7 +1769,7 @@ func (d *Device) setDeviceColor() {
 	case "tlr":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				tlr := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				tlr := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if tlr != nil {
 					switch tlr.Speed {
@@ -1667,7 +1794,7 @@ func (d *Device) setDeviceColor() {
 	case "spiralrainbow":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				spiralrainbow := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				spiralrainbow := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if spiralrainbow != nil {
 					switch spiralrainbow.Speed {
@@ -1692,7 +1819,7 @@ func (d *Device) setDeviceColor() {
 	case "colorpulse":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				colorpulse := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				colorpulse := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if colorpulse != nil {
 					if colorpulse.Speed == 1 {
@@ -1715,7 +1842,7 @@ func (d *Device) setDeviceColor() {
 	case "colorshift":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				colorshift := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				colorshift := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if colorshift != nil {
 					if colorshift.Speed == 1 {
@@ -1738,7 +1865,7 @@ func (d *Device) setDeviceColor() {
 	case "colorwave":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				colorwave := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				colorwave := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if colorwave != nil {
 					switch colorwave.Speed {
@@ -1763,7 +1890,7 @@ func (d *Device) setDeviceColor() {
 	case "rainbowwave":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				rainbowwave := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				rainbowwave := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if rainbowwave != nil {
 					switch rainbowwave.Speed {
@@ -1788,7 +1915,7 @@ func (d *Device) setDeviceColor() {
 	case "watercolor":
 		{
 			if _, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
-				watercolor := d.GetRgbProfile(d.DeviceProfile.SlipstreamRGBProfile)
+				watercolor := d.GetRgbProfile(d.rgbProfile())
 				var speed = byte(0x04)
 				if watercolor != nil {
 					if watercolor.Speed == 1 {
@@ -1890,58 +2017,6 @@ func (d *Device) getModifierPosition() uint8 {
 	return 0
 }
 
-// addToMacroTracker adds or updates an entry in MacroTracker
-func (d *Device) addToMacroTracker(key int, value uint16, actionType uint8) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil {
-		d.MacroTracker = make(map[int]macro.Tracker)
-	}
-	d.MacroTracker[key] = macro.Tracker{
-		Value: value,
-		Type:  actionType,
-	}
-}
-
-// deleteFromMacroTracker deletes an entry from MacroTracker
-func (d *Device) deleteFromMacroTracker(key int) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	if d.MacroTracker == nil || len(d.MacroTracker) == 0 {
-		return
-	}
-	delete(d.MacroTracker, key)
-}
-
-// releaseMacroTracker will release current MacroTracker
-func (d *Device) releaseMacroTracker() {
-	d.mutex.Lock()
-	if d.MacroTracker == nil {
-		d.mutex.Unlock()
-		return
-	}
-	keys := make([]int, 0, len(d.MacroTracker))
-	for key := range d.MacroTracker {
-		keys = append(keys, key)
-	}
-	sort.Ints(keys)
-	d.mutex.Unlock()
-
-	for _, key := range keys {
-		switch d.MacroTracker[key].Type {
-		case 1, 3:
-			inputmanager.InputControlKeyboardHold(d.MacroTracker[key].Value, false)
-			break
-		case 9:
-			inputmanager.InputControlMouseHold(d.MacroTracker[key].Value, false)
-			break
-		}
-		d.deleteFromMacroTracker(key)
-	}
-}
-
 // mouseEventLoop will send mouse action until stopped
 func mouseEventLoop(stopCh <-chan struct{}, actionCommand, actionSleep uint16) {
 	// Send input once
@@ -2023,11 +2098,10 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 		raw[i], raw[j] = raw[j], raw[i]
 	}
 	val := new(big.Int).SetBytes(raw)
+	val = inputmanager.ResolveKeyboardReport(d.Serial, val)
 
-	// Check if we have any queue in macro tracker. If yes, release those keys
-	if len(d.MacroTracker) > 0 {
-		d.releaseMacroTracker()
-	}
+	// Release keys held by macros
+	d.macroPlayer.Release()
 
 	if d.ModifierIndex != val {
 		if d.KeyboardKey != nil {
@@ -2037,7 +2111,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 				break
 			case 8:
 				if d.dispatch != nil {
-					d.dispatch(d.KeyboardKey.DeviceId, "CallSniperMode", d.PressLoop)
+					d.dispatch.CallSniperMode(d.KeyboardKey.DeviceId, d.PressLoop)
 				}
 				break
 			}
@@ -2050,6 +2124,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 		if key == nil {
 			return
 		}
+		*key = keyboards.ApplyLayer(d.Serial, *key)
 
 		// Function Key
 		if functionKey {
@@ -2125,7 +2200,7 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 				d.KeyboardKey = key
 			}
 			if d.dispatch != nil && len(key.DeviceId) > 0 {
-				d.dispatch(key.DeviceId, "CallSniperMode", key.ActionHold)
+				d.dispatch.CallSniperMode(key.DeviceId, key.ActionHold)
 			}
 			break
 		case 9:
@@ -2146,118 +2221,10 @@ func (d *Device) triggerKeyAssignment(value []byte, functionKey bool, modifierKe
 			}
 			break
 		case 10:
-			macroProfile := macro.GetProfile(int(key.ActionCommand))
-			if macroProfile == nil {
+			if !d.macroPlayer.Press(int(key.ActionCommand)) {
 				logger.Log(logger.Fields{"serial": d.Serial}).Error("Invalid macro profile")
 				return
 			}
-			for i := 0; i < len(macroProfile.Actions); i++ {
-				if v, valid := macroProfile.Actions[i]; valid {
-					// Add to macro tracker for easier release
-					if v.ActionHold {
-						d.addToMacroTracker(i, v.ActionCommand, v.ActionType)
-					}
-
-					switch v.ActionType {
-					case 1, 3:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							d.stopRepeatMutex.Lock()
-							if d.stopRepeat != nil {
-								close(d.stopRepeat)
-							}
-
-							d.stopRepeat = make(chan struct{})
-							localStop := d.stopRepeat
-							d.stopRepeatMutex.Unlock()
-
-							go func() {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									select {
-									case <-localStop:
-										return
-									default:
-										inputmanager.InputControlKeyboard(v.ActionCommand, false)
-									}
-									if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-										time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-									}
-								}
-							}()
-
-						} else {
-							inputmanager.InputControlKeyboard(v.ActionCommand, v.ActionHold)
-						}
-						break
-					case 9:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							for z := 0; z < int(v.ActionRepeat); z++ {
-								inputmanager.InputControlMouse(v.ActionCommand)
-								if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-									time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-								}
-							}
-						} else if v.ActionHold && v.ActionRepeat == 0 {
-							inputmanager.InputControlMouseHold(v.ActionCommand, v.ActionHold)
-						} else {
-							inputmanager.InputControlMouse(v.ActionCommand)
-						}
-						break
-					case 5:
-						if v.ActionDelay > 0 {
-							time.Sleep(time.Duration(v.ActionDelay) * time.Millisecond)
-						}
-						break
-					case 6:
-						if v.ActionRepeat > 0 {
-							for z := 0; z < int(v.ActionRepeat); z++ {
-								inputmanager.InputControlKeyboardText(v.ActionText)
-								if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-									time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-								}
-							}
-						} else {
-							inputmanager.InputControlKeyboardText(v.ActionText)
-						}
-						break
-					case 20:
-						if v.ActionRepeat > 0 && !v.ActionHold {
-							d.stopRepeatMutex.Lock()
-							if d.stopRepeat != nil {
-								close(d.stopRepeat)
-							}
-
-							d.stopRepeat = make(chan struct{})
-							localStop := d.stopRepeat
-							d.stopRepeatMutex.Unlock()
-
-							go func() {
-								for z := 0; z < int(v.ActionRepeat); z++ {
-									select {
-									case <-localStop:
-										return
-									default:
-										if v.MousePositionAbsolute {
-											inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-										} else {
-											inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-										}
-									}
-									if v.ActionRepeatDelay > 0 && v.ActionRepeat > 1 {
-										time.Sleep(time.Duration(v.ActionRepeatDelay) * time.Millisecond)
-									}
-								}
-							}()
-						} else {
-							if v.MousePositionAbsolute {
-								inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
-							} else {
-								inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
-							}
-						}
-						break
-					}
-				}
-			}
 			break
 		}
 	}
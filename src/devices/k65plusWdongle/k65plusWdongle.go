@@ -5,15 +5,16 @@ package k65plusWdongle
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/k65plusW"
 	"OpenLinkHub/src/logger"
 	"OpenLinkHub/src/stats"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"fmt"
 	"github.com/sstallion/go-hid"
-	"reflect"
 	"sync"
 	"time"
 )
@@ -27,8 +28,8 @@ type Devices struct {
 }
 
 type Device struct {
-	dev            *hid.Device
-	listener       *hid.Device
+	dev            transport.Device
+	listener       transport.Device
 	Manufacturer   string `json:"manufacturer"`
 	Product        string `json:"product"`
 	Serial         string `json:"serial"`
@@ -49,6 +50,13 @@ type Device struct {
 	instance       *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+)
+
 var (
 	bufferSize       = 64
 	bufferSizeWrite  = bufferSize + 1
@@ -71,7 +79,7 @@ var (
 
 func Init(vendorId, productId uint16, _, path string, callback func(device *common.Device)) *common.Device {
 	// Open device, return if failure
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
@@ -272,7 +280,7 @@ func (d *Device) AddPairedDevice(productId uint16, device any, dev *common.Devic
 }
 
 // GetDevice will return HID device
-func (d *Device) GetDevice() *hid.Device {
+func (d *Device) GetDevice() transport.Device {
 	return d.dev
 }
 
@@ -541,22 +549,14 @@ func (d *Device) monitorDevice() {
 
 // getSleepMode will return device sleep mode
 func (d *Device) getSleepMode(dev any) int {
-	sleepMode := 0
-	methodName := "GetSleepMode"
-	method := reflect.ValueOf(dev).MethodByName(methodName)
-	if !method.IsValid() {
+	device, ok := dev.(capabilities.SleepMode)
+	if !ok {
 		if d.Debug {
-			logger.Log(logger.Fields{"method": methodName}).Warn("Method not found or method is not supported for this device")
+			logger.Log(logger.Fields{"method": "GetSleepMode"}).Warn("Method not found or method is not supported for this device")
 		}
 		return 0
-	} else {
-		results := method.Call(nil)
-		if len(results) > 0 {
-			val := results[0]
-			sleepMode = int(val.Int())
-		}
 	}
-	return sleepMode
+	return device.GetSleepMode()
 }
 
 // getListenerData will listen for keyboard events and return data on success or nil on failure.
@@ -575,7 +575,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.InterfaceNbr == 2 {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
@@ -9,6 +9,7 @@ import (
 	"OpenLinkHub/src/dashboard"
 	"OpenLinkHub/src/logger"
 	"OpenLinkHub/src/rgb"
+	"OpenLinkHub/src/transport"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -117,7 +118,7 @@ type LCD struct {
 }
 
 type Device struct {
-	Lcd       *hid.Device
+	Lcd       transport.Device
 	ProductId uint16
 	VendorId  uint16
 	Product   string
@@ -199,7 +200,7 @@ func Init() {
 // Reconnect will reconnect to all available LCD devices
 func Reconnect() {
 	for key, device := range lcd.Devices {
-		lcdPanel, e := hid.Open(vendorId, device.ProductId, device.Serial)
+		lcdPanel, e := transport.Open(vendorId, device.ProductId, device.Serial)
 		if e != nil {
 			logger.Log(logger.Fields{"error": e, "vendorId": vendorId, "productId": device.ProductId}).Error("Unable to reconnect LCD HID device")
 			continue
@@ -224,7 +225,7 @@ func GetLcdImage(image string) *ImageData {
 }
 
 // GetLcdBySerial will return HID device by serial number
-func GetLcdBySerial(serial string) *hid.Device {
+func GetLcdBySerial(serial string) transport.Device {
 	for _, device := range lcd.Devices {
 		if device.Serial == serial {
 			return device.Lcd
@@ -234,7 +235,7 @@ func GetLcdBySerial(serial string) *hid.Device {
 }
 
 // GetLcdByProductId will return HID device by product id
-func GetLcdByProductId(productId uint16) *hid.Device {
+func GetLcdByProductId(productId uint16) transport.Device {
 	for _, device := range lcd.Devices {
 		if device.ProductId == productId {
 			return device.Lcd
@@ -291,6 +292,11 @@ func GetLcdAmount() int {
 	return len(lcd.Devices)
 }
 
+// GetLcdResolution will return LCD image resolution
+func GetLcdResolution() (int, int) {
+	return imgWidth, imgHeight
+}
+
 // GetLcdDevices will return all LCD devices
 func GetLcdDevices() []Device {
 	return lcd.Devices
@@ -1296,7 +1302,7 @@ func loadLcdDevices() {
 
 	for serial, productId := range lcdDevices {
 		logger.Log(logger.Fields{"serial": serial, "vendorId": vendorId, "productId": productId}).Info("Processing LCD device")
-		lcdPanel, e := hid.Open(vendorId, productId, serial)
+		lcdPanel, e := transport.Open(vendorId, productId, serial)
 		if e != nil {
 			logger.Log(logger.Fields{"error": e, "vendorId": vendorId, "productId": productId}).Error("Unable to open LCD HID device")
 			continue
@@ -5,6 +5,7 @@ package lsh
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/cluster"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
@@ -18,6 +19,8 @@ import (
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/systeminfo"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
+	"OpenLinkHub/src/watchdog"
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
@@ -32,8 +35,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/sstallion/go-hid"
 )
 
 type RGBOverride struct {
@@ -94,7 +95,7 @@ type CommanderDuoOverride struct {
 }
 
 type LCD struct {
-	Lcd       *hid.Device
+	Lcd       transport.Device
 	ProductId uint16
 }
 
@@ -183,7 +184,7 @@ type Devices struct {
 
 type Device struct {
 	Debug                  bool
-	dev                    *hid.Device
+	dev                    transport.Device
 	Manufacturer           string                    `json:"manufacturer"`
 	Product                string                    `json:"product"`
 	Serial                 string                    `json:"serial"`
@@ -196,6 +197,7 @@ type Device struct {
 	OriginalProfile        *DeviceProfile
 	TemperatureProbes      *[]TemperatureProbe
 	activeRgb              *rgb.ActiveRGB
+	rgbAlert               bool
 	ledProfile             *led.Device
 	Template               string
 	HasLCD                 bool
@@ -241,6 +243,46 @@ type Device struct {
 	Psu                    bool
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable            = (*Device)(nil)
+	_ capabilities.DirtyStoppable       = (*Device)(nil)
+	_ capabilities.Templated            = (*Device)(nil)
+	_ capabilities.Metrics              = (*Device)(nil)
+	_ capabilities.UserProfiles         = (*Device)(nil)
+	_ capabilities.UserProfileDeleter   = (*Device)(nil)
+	_ capabilities.DeviceLabel          = (*Device)(nil)
+	_ capabilities.DevicePositions      = (*Device)(nil)
+	_ capabilities.Rgb                  = (*Device)(nil)
+	_ capabilities.RgbBulk              = (*Device)(nil)
+	_ capabilities.RgbProfiles          = (*Device)(nil)
+	_ capabilities.RgbProfileReader     = (*Device)(nil)
+	_ capabilities.RgbProfileEditor     = (*Device)(nil)
+	_ capabilities.RgbControl           = (*Device)(nil)
+	_ capabilities.RgbAlert             = (*Device)(nil)
+	_ capabilities.RgbOverride          = (*Device)(nil)
+	_ capabilities.RgbTemperature       = (*Device)(nil)
+	_ capabilities.RgbCluster           = (*Device)(nil)
+	_ capabilities.OpenRgb              = (*Device)(nil)
+	_ capabilities.LedData              = (*Device)(nil)
+	_ capabilities.LedProfile           = (*Device)(nil)
+	_ capabilities.LedProfileEditor     = (*Device)(nil)
+	_ capabilities.Brightness           = (*Device)(nil)
+	_ capabilities.BrightnessValue      = (*Device)(nil)
+	_ capabilities.ScheduledBrightness  = (*Device)(nil)
+	_ capabilities.SpeedProfile         = (*Device)(nil)
+	_ capabilities.SpeedProfileBulk     = (*Device)(nil)
+	_ capabilities.ManualSpeed          = (*Device)(nil)
+	_ capabilities.TemperatureProbes    = (*Device)(nil)
+	_ capabilities.ChannelDevices       = (*Device)(nil)
+	_ capabilities.LinkAdapter          = (*Device)(nil)
+	_ capabilities.CommanderDuoOverride = (*Device)(nil)
+	_ capabilities.Lcd                  = (*Device)(nil)
+	_ capabilities.LcdBrightness        = (*Device)(nil)
+	_ capabilities.LcdChanger           = (*Device)(nil)
+	_ capabilities.StateReader          = (*Device)(nil)
+)
+
 var (
 	pwd                         = ""
 	cmdOpenEndpoint             = []byte{0x0d, 0x01}
@@ -351,7 +393,7 @@ func Init(vendorId, productId uint16, serial, path string) *common.Device {
 	pwd = config.GetConfig().ConfigPath
 
 	// Open device, return if failure
-	dev, err := hid.Open(vendorId, productId, serial)
+	dev, err := transport.Open(vendorId, productId, serial)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "serial": serial}).Error("Unable to open HID device")
 		return nil
@@ -908,6 +950,43 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	for channelId, channel := range d.Devices {
+		if channel.LedChannels > 0 && len(channel.RGB) > 0 {
+			if state.RgbProfiles == nil {
+				state.RgbProfiles = make(map[int]string)
+			}
+			state.RgbProfiles[channelId] = channel.RGB
+		}
+		if channel.HasSpeed && len(channel.Profile) > 0 {
+			if state.SpeedProfiles == nil {
+				state.SpeedProfiles = make(map[int]string)
+			}
+			state.SpeedProfiles[channelId] = channel.Profile
+		}
+	}
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if d.DeviceProfile.BrightnessSlider != nil {
+		brightness := *d.DeviceProfile.BrightnessSlider
+		state.Brightness = &brightness
+	}
+	if state.Brightness == nil {
+		mode := d.DeviceProfile.Brightness
+		state.BrightnessMode = &mode
+	}
+	if d.HasLCD && len(d.DeviceProfile.LCDModes) > 0 {
+		state.LcdModes = make(map[int]uint8, len(d.DeviceProfile.LCDModes))
+		for channelId, mode := range d.DeviceProfile.LCDModes {
+			state.LcdModes[channelId] = mode
+		}
+	}
+	return state
+}
+
 // loadDeviceProfiles will load custom user profiles
 func (d *Device) loadDeviceProfiles() {
 	profileList := make(map[string]*DeviceProfile)
@@ -1566,7 +1645,7 @@ func (d *Device) ResetSpeedProfiles(profile string) {
 }
 
 // GetTemperatureProbes will return a list of temperature probes
-func (d *Device) GetTemperatureProbes() *[]TemperatureProbe {
+func (d *Device) GetTemperatureProbes() interface{} {
 	return d.TemperatureProbes
 }
 
@@ -1618,7 +1697,7 @@ func (d *Device) GetDevicePositions() []string {
 }
 
 // ProcessGetChannelDevice will return channel device data
-func (d *Device) ProcessGetChannelDevice(channelId int) *Devices {
+func (d *Device) ProcessGetChannelDevice(channelId int) interface{} {
 	if d.DeviceProfile == nil {
 		return nil
 	}
@@ -2490,6 +2569,7 @@ func (d *Device) UpdateRgbProfileData(profileName string, profile rgb.Profile) u
 	pf.MiddleColor = profile.MiddleColor
 	pf.Speed = profile.Speed
 	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
 	pf.MinTemp = profile.MinTemp
 	pf.MaxTemp = profile.MaxTemp
 
@@ -3111,43 +3191,26 @@ func (d *Device) updateDeviceSpeed() {
 						profiles = temperatures.GetTemperatureProfile("Normal")
 					}
 
-					switch profiles.Sensor {
-					case temperatures.SensorTypeGPU:
+					switch {
+					case temperatures.IsHostSensor(profiles.Sensor):
 						{
-							temp = temperatures.GetNVIDIAGpuTemperature(0)
+							temp = temperatures.GetSensorTemperature(temperatures.GetProfileSensorId(profiles))
 							if temp == 0 {
-								temp = temperatures.GetAMDGpuTemperature()
-								if temp == 0 {
-									logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get GPU sensor temperature. Going to fallback to CPU")
-									temp = temperatures.GetCpuTemperature()
-									if temp == 0 {
-										temp = 70
-									}
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "sensor": profiles.Sensor, "device": profiles.Device}).Warn("Unable to get sensor temperature.")
+								if profiles.Sensor == temperatures.SensorTypeGPU {
+									// GPU and CPU fallback failed, default to 70
+									temp = 70
 								}
 							}
 						}
-					case temperatures.SensorTypeCPU:
-						{
-							temp = temperatures.GetCpuTemperature()
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get CPU sensor temperature.")
-							}
-						}
-					case temperatures.SensorTypeLiquidTemperature:
+					case profiles.Sensor == temperatures.SensorTypeLiquidTemperature:
 						{
 							temp = d.getLiquidTemperature()
 							if temp == 0 {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get liquid temperature.")
 							}
 						}
-					case temperatures.SensorTypeStorage:
-						{
-							temp = temperatures.GetStorageTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get storage temperature.")
-							}
-						}
-					case temperatures.SensorTypeTemperatureProbe:
+					case profiles.Sensor == temperatures.SensorTypeTemperatureProbe:
 						{
 							if strings.HasPrefix(profiles.Device, i2cPrefix) {
 								temp = temperatures.GetMemoryTemperature(profiles.ChannelId)
@@ -3161,95 +3224,42 @@ func (d *Device) updateDeviceSpeed() {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId}).Warn("Unable to get probe temperature.")
 							}
 						}
-					case temperatures.SensorTypeCpuGpu:
-						{
-							cpuTemp := temperatures.GetCpuTemperature()
-							gpuTemp := temperatures.GetNVIDIAGpuTemperature(0)
-							if gpuTemp == 0 {
-								gpuTemp = temperatures.GetAMDGpuTemperature()
-							}
-
-							if gpuTemp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get GPU temperature. Setting to 50")
-								gpuTemp = 50
-							}
-
-							temp = float32(math.Max(float64(cpuTemp), float64(gpuTemp)))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get maximum temperature value out of 2 numbers.")
-							}
-						}
-					case temperatures.SensorTypeExternalHwMon:
-						{
-							temp = temperatures.GetHwMonTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeExternalExecutable:
-						{
-							temp = temperatures.GetExternalBinaryTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "binary": profiles.Device}).Warn("Unable to get temperature from binary.")
-							}
-						}
-					case temperatures.SensorTypeMultiGPU:
-						{
-							temp = temperatures.GetGpuTemperatureIndex(int(profiles.GPUIndex))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeGlobalTemperature:
+					case profiles.Sensor == temperatures.SensorTypeGlobalTemperature:
 						{
 							temp = stats.GetDeviceTemperature(profiles.Device, profiles.ChannelId)
 							if temp == 0 {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
 							}
 						}
-					case temperatures.SensorTypePSU:
+					case profiles.Sensor == temperatures.SensorTypePSU:
 						{
 							temp = d.getPSUTemperature()
 							if temp == 0 {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get PSU temperature.")
 							}
 						}
-					case temperatures.SensorTypeMultiGPUs:
-						{
-							maxGpuTemp := float32(0)
-
-							for _, index := range config.GetConfig().NvidiaGpuIndex {
-								gpuTemp := temperatures.GetNVIDIAGpuTemperature(index)
-								if gpuTemp == 0 {
-									logger.Log(logger.Fields{
-										"temperature": gpuTemp,
-										"serial":      d.Serial,
-										"channelId":   profiles.ChannelId,
-										"gpuIndex":    index,
-									}).Warn("Unable to get GPU temperature. Setting to 50")
-									gpuTemp = 50
-								}
-
-								if gpuTemp > maxGpuTemp {
-									maxGpuTemp = gpuTemp
-								}
-							}
-							temp = maxGpuTemp
-						}
 					}
 
+					// Critical temperature failsafe
+					temperatures.CheckFailsafe(d.Serial, d.Devices[k].ChannelId, profiles, temp)
+
 					// All temps failed, default to 50
 					if temp == 0 {
 						temp = 50
 					}
 
+					// Averaging and hysteresis
+					temp = temperatures.SmoothTemperature(d.Serial, d.Devices[k].ChannelId, profiles, temp)
+
 					if config.GetConfig().GraphProfiles {
 						var speed byte = 0x00
 						pumpValue := temperatures.Interpolate(profiles.Points[0], temp)
 						fansValue := temperatures.Interpolate(profiles.Points[1], temp)
 
 						pump := int(math.Round(float64(pumpValue)))
+						pump = temperatures.RampSpeed(d.Serial, d.Devices[k].ChannelId, true, profiles, pump)
 						fans := int(math.Round(float64(fansValue)))
+						fans = temperatures.RampSpeed(d.Serial, d.Devices[k].ChannelId, false, profiles, fans)
 
 						// Failsafe
 						if fans < 20 && !profiles.ZeroRpm {
@@ -3276,8 +3286,11 @@ func (d *Device) updateDeviceSpeed() {
 						if fans > 100 {
 							fans = 100
 						}
+						if temperatures.IsFailsafe() {
+							pump, fans = 100, 100
+						}
 
-						cp := fmt.Sprintf("%s-%d-%f", d.Devices[k].Profile, d.Devices[k].ChannelId, temp)
+						cp := fmt.Sprintf("%s-%d-%d-%d", d.Devices[k].Profile, d.Devices[k].ChannelId, pump, fans)
 						if ok := tmp[d.Devices[k].ChannelId]; ok != cp {
 							tmp[d.Devices[k].ChannelId] = cp
 							if d.Devices[k].ContainsPump {
@@ -3296,8 +3309,12 @@ func (d *Device) updateDeviceSpeed() {
 					} else {
 						for i := 0; i < len(profiles.Profiles); i++ {
 							profile := profiles.Profiles[i]
-							minimum := profile.Min + 0.1
-							if common.InBetween(temp, minimum, profile.Max) {
+							if temperatures.IsActiveStep(profiles, i, temp) {
+								profile.Fans = uint16(temperatures.RampSpeed(d.Serial, d.Devices[k].ChannelId, false, profiles, int(profile.Fans)))
+								profile.Pump = uint16(temperatures.RampSpeed(d.Serial, d.Devices[k].ChannelId, true, profiles, int(profile.Pump)))
+								if temperatures.IsFailsafe() {
+									profile.Fans, profile.Pump = 100, 100
+								}
 								cp := fmt.Sprintf("%s-%d-%d-%d", d.Devices[k].Profile, d.Devices[k].ChannelId, profile.Fans, profile.Pump)
 								if ok := tmp[d.Devices[k].ChannelId]; ok != cp {
 									tmp[d.Devices[k].ChannelId] = cp
@@ -3363,6 +3380,10 @@ func (d *Device) setSpeed(data map[int]byte, mode uint8) {
 		return
 	}
 
+	for channelId, speed := range data {
+		watchdog.SetSpeed(d.Serial, channelId, int(speed))
+	}
+
 	buffer := make([]byte, len(data)*4+1)
 	buffer[0] = byte(len(data))
 	i := 1
@@ -3478,6 +3499,9 @@ func (d *Device) getDeviceData() {
 					if rpm > 1 {
 						d.Devices[i].Rpm = rpm
 					}
+					if d.Devices[i].HasSpeed && !d.Devices[i].IsPSU {
+						watchdog.UpdateRpm(d.Serial, d.Devices[i].Name, d.Devices[i].ChannelId, d.Devices[i].ContainsPump, int(rpm))
+					}
 
 					if d.Devices[i].IsVrmCooler {
 						d.updateVrmCoolerRpm(rpm)
@@ -4405,6 +4429,20 @@ func (d *Device) ControlDeviceRgb(value bool) {
 	d.setDeviceColor()
 }
 
+// SetRgbAlert will enable or disable red RGB warning
+func (d *Device) SetRgbAlert(active bool) {
+	if d.DeviceProfile == nil {
+		return
+	}
+
+	d.rgbAlert = active
+	if d.activeRgb != nil {
+		d.activeRgb.Exit <- true
+		d.activeRgb = nil
+	}
+	d.setDeviceColor()
+}
+
 // setDeviceColor will activate and set device RGB
 func (d *Device) setDeviceColor() {
 	// Reset
@@ -4752,17 +4790,17 @@ func (d *Device) generateRgbEffect(k int, channels uint8, startTime *time.Time,
 		}
 	case "liquid-temperature":
 		{
-			r.Temperature(float64(d.getLiquidTemperature()))
+			r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.getLiquidTemperature())))
 			buff = r.Output
 		}
 	case "cpu-temperature":
 		{
-			r.Temperature(float64(d.CpuTemp))
+			r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.CpuTemp)))
 			buff = r.Output
 		}
 	case "gpu-temperature":
 		{
-			r.Temperature(float64(d.GpuTemp))
+			r.Temperature(float64(temperatures.GetRgbTemperature(profile.Sensor, d.GpuTemp)))
 			buff = r.Output
 		}
 	case "probe-temperature":
@@ -5426,6 +5464,10 @@ func (d *Device) write(endpoint, bufferType, data []byte) []byte {
 
 // writeColor will write color data to the device
 func (d *Device) writeColor(data []byte) {
+	if d.rgbAlert {
+		data = rgb.Alert(len(data))
+	}
+
 	d.deviceLock.Lock()
 	defer d.deviceLock.Unlock()
 
@@ -5612,7 +5654,7 @@ func (d *Device) startQueueWorker() {
 }
 
 // transferToLcd will transfer data to LCD panel
-func (d *Device) transferToLcd(buffer []byte, lcdDevice *hid.Device) {
+func (d *Device) transferToLcd(buffer []byte, lcdDevice transport.Device) {
 	d.mutexLcd.Lock()
 	defer d.mutexLcd.Unlock()
 
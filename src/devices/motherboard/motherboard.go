@@ -5,6 +5,7 @@ package motherboard
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dashboard"
@@ -13,6 +14,7 @@ import (
 	"OpenLinkHub/src/motherboards"
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/watchdog"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -110,6 +112,22 @@ type Device struct {
 	instance          *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable          = (*Device)(nil)
+	_ capabilities.DirtyStoppable     = (*Device)(nil)
+	_ capabilities.Templated          = (*Device)(nil)
+	_ capabilities.Metrics            = (*Device)(nil)
+	_ capabilities.UserProfiles       = (*Device)(nil)
+	_ capabilities.UserProfileDeleter = (*Device)(nil)
+	_ capabilities.DeviceLabel        = (*Device)(nil)
+	_ capabilities.SpeedProfile       = (*Device)(nil)
+	_ capabilities.SpeedProfileBulk   = (*Device)(nil)
+	_ capabilities.ManualSpeed        = (*Device)(nil)
+	_ capabilities.OperatingMode      = (*Device)(nil)
+	_ capabilities.StateReader        = (*Device)(nil)
+)
+
 // Init will initialize a new device
 func Init() *common.Device {
 	// Set global working directory
@@ -231,6 +249,20 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	for channelId, channel := range d.Devices {
+		if channel.HasSpeed && len(channel.Profile) > 0 {
+			if state.SpeedProfiles == nil {
+				state.SpeedProfiles = make(map[int]string)
+			}
+			state.SpeedProfiles[channelId] = channel.Profile
+		}
+	}
+	return state
+}
+
 // loadDeviceProfiles will load custom user profiles
 func (d *Device) loadDeviceProfiles() {
 	profileList := make(map[string]*DeviceProfile)
@@ -360,6 +392,7 @@ func (d *Device) getDeviceData() {
 	for key, value := range d.Devices {
 		rpm := motherboards.GetMotherboardHeaderValue(value.ChannelId)
 		d.Devices[key].Rpm = rpm
+		watchdog.UpdateRpm(d.Serial, value.Name, value.ChannelId, value.ContainsPump, int(rpm))
 	}
 
 	// Update stats
@@ -712,6 +745,7 @@ func (d *Device) setSpeed(data map[int]byte) {
 			// BIOS mode can not be updated from user-space
 			continue
 		}
+		watchdog.SetSpeed(d.Serial, key, int(value))
 		motherboards.SetMotherboardHeaderValue(key, int(value))
 	}
 }
@@ -743,33 +777,15 @@ func (d *Device) updateDeviceSpeed() {
 						profiles = temperatures.GetTemperatureProfile("Normal")
 					}
 
-					switch profiles.Sensor {
-					case temperatures.SensorTypeGPU:
-						{
-							temp = temperatures.GetNVIDIAGpuTemperature(0)
-							if temp == 0 {
-								temp = temperatures.GetAMDGpuTemperature()
-								if temp == 0 {
-									logger.Log(logger.Fields{"temperature": temp}).Warn("Unable to get sensor temperature. Going to fallback to CPU")
-									temp = temperatures.GetCpuTemperature()
-								}
-							}
-						}
-					case temperatures.SensorTypeCPU:
+					switch {
+					case temperatures.IsHostSensor(profiles.Sensor):
 						{
-							temp = temperatures.GetCpuTemperature()
+							temp = temperatures.GetSensorTemperature(temperatures.GetProfileSensorId(profiles))
 							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get CPU temperature.")
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "sensor": profiles.Sensor, "device": profiles.Device}).Warn("Unable to get sensor temperature.")
 							}
 						}
-					case temperatures.SensorTypeStorage:
-						{
-							temp = temperatures.GetStorageTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get storage temperature.")
-							}
-						}
-					case temperatures.SensorTypeTemperatureProbe:
+					case profiles.Sensor == temperatures.SensorTypeTemperatureProbe:
 						{
 							if strings.HasPrefix(profiles.Device, i2cPrefix) {
 								temp = temperatures.GetMemoryTemperature(profiles.ChannelId)
@@ -783,87 +799,34 @@ func (d *Device) updateDeviceSpeed() {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId}).Warn("Unable to get probe temperature.")
 							}
 						}
-					case temperatures.SensorTypeCpuGpu:
-						{
-							cpuTemp := temperatures.GetCpuTemperature()
-							gpuTemp := temperatures.GetNVIDIAGpuTemperature(0)
-							if gpuTemp == 0 {
-								gpuTemp = temperatures.GetAMDGpuTemperature()
-							}
-
-							if gpuTemp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get GPU temperature. Setting to 50")
-								gpuTemp = 50
-							}
-
-							temp = float32(math.Max(float64(cpuTemp), float64(gpuTemp)))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId, "cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get maximum temperature value out of 2 numbers.")
-							}
-						}
-					case temperatures.SensorTypeExternalHwMon:
-						{
-							temp = temperatures.GetHwMonTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeExternalExecutable:
-						{
-							temp = temperatures.GetExternalBinaryTemperature(profiles.Device)
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "binary": profiles.Device}).Warn("Unable to get temperature from binary.")
-							}
-						}
-					case temperatures.SensorTypeMultiGPU:
-						{
-							temp = temperatures.GetGpuTemperatureIndex(int(profiles.GPUIndex))
-							if temp == 0 {
-								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
-							}
-						}
-					case temperatures.SensorTypeGlobalTemperature:
+					case profiles.Sensor == temperatures.SensorTypeGlobalTemperature:
 						{
 							temp = stats.GetDeviceTemperature(profiles.Device, profiles.ChannelId)
 							if temp == 0 {
 								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
 							}
 						}
-					case temperatures.SensorTypeMultiGPUs:
-						{
-							maxGpuTemp := float32(0)
-
-							for _, index := range config.GetConfig().NvidiaGpuIndex {
-								gpuTemp := temperatures.GetNVIDIAGpuTemperature(index)
-								if gpuTemp == 0 {
-									logger.Log(logger.Fields{
-										"temperature": gpuTemp,
-										"serial":      d.Serial,
-										"channelId":   profiles.ChannelId,
-										"gpuIndex":    index,
-									}).Warn("Unable to get GPU temperature. Setting to 50")
-									gpuTemp = 50
-								}
-
-								if gpuTemp > maxGpuTemp {
-									maxGpuTemp = gpuTemp
-								}
-							}
-							temp = maxGpuTemp
-						}
 					}
 
+					// Critical temperature failsafe
+					temperatures.CheckFailsafe(d.Serial, device.ChannelId, profiles, temp)
+
 					// All temps failed, default to 50
 					if temp == 0 {
 						temp = 50
 					}
 
+					// Averaging and hysteresis
+					temp = temperatures.SmoothTemperature(d.Serial, device.ChannelId, profiles, temp)
+
 					if config.GetConfig().GraphProfiles {
 						pumpValue := temperatures.Interpolate(profiles.Points[0], temp)
 						fansValue := temperatures.Interpolate(profiles.Points[1], temp)
 
 						pump := int(math.Round(float64(pumpValue)))
+						pump = temperatures.RampSpeed(d.Serial, device.ChannelId, true, profiles, pump)
 						fans := int(math.Round(float64(fansValue)))
+						fans = temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, fans)
 
 						// Failsafe
 						if fans < 20 && !profiles.ZeroRpm {
@@ -885,8 +848,11 @@ func (d *Device) updateDeviceSpeed() {
 						if fans > 100 {
 							fans = 100
 						}
+						if temperatures.IsFailsafe() {
+							pump, fans = 100, 100
+						}
 
-						cp := fmt.Sprintf("%s-%d-%f", device.Profile, device.ChannelId, temp)
+						cp := fmt.Sprintf("%s-%d-%d-%d", device.Profile, device.ChannelId, pump, fans)
 						if ok := tmp[device.ChannelId]; ok != cp {
 							tmp[device.ChannelId] = cp
 							if device.ContainsPump {
@@ -903,8 +869,12 @@ func (d *Device) updateDeviceSpeed() {
 					} else {
 						for i := 0; i < len(profiles.Profiles); i++ {
 							profile := profiles.Profiles[i]
-							minimum := profile.Min + 0.1
-							if common.InBetween(temp, minimum, profile.Max) {
+							if temperatures.IsActiveStep(profiles, i, temp) {
+								profile.Fans = uint16(temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, int(profile.Fans)))
+								profile.Pump = uint16(temperatures.RampSpeed(d.Serial, device.ChannelId, true, profiles, int(profile.Pump)))
+								if temperatures.IsFailsafe() {
+									profile.Fans, profile.Pump = 100, 100
+								}
 								cp := fmt.Sprintf("%s-%d-%d-%d-%d", device.Profile, device.ChannelId, profile.Id, profile.Fans, profile.Pump)
 								if ok := tmp[device.ChannelId]; ok != cp {
 									tmp[device.ChannelId] = cp
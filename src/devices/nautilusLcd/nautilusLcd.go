@@ -5,6 +5,7 @@ package nautilusLcd
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/lcd"
@@ -13,6 +14,7 @@ import (
 	"OpenLinkHub/src/rgb"
 	"OpenLinkHub/src/systeminfo"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -21,8 +23,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/sstallion/go-hid"
 )
 
 // DeviceProfile struct contains all device profile
@@ -48,7 +48,7 @@ type TemperatureProbe struct {
 
 type Device struct {
 	Debug             bool
-	dev               *hid.Device
+	dev               transport.Device
 	Manufacturer      string                    `json:"manufacturer"`
 	Product           string                    `json:"product"`
 	Serial            string                    `json:"serial"`
@@ -87,6 +87,19 @@ type Device struct {
 	instance          *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable          = (*Device)(nil)
+	_ capabilities.DirtyStoppable     = (*Device)(nil)
+	_ capabilities.Templated          = (*Device)(nil)
+	_ capabilities.UserProfiles       = (*Device)(nil)
+	_ capabilities.UserProfileDeleter = (*Device)(nil)
+	_ capabilities.DeviceLabel        = (*Device)(nil)
+	_ capabilities.Lcd                = (*Device)(nil)
+	_ capabilities.RgbLayout          = (*Device)(nil)
+	_ capabilities.StateReader        = (*Device)(nil)
+)
+
 var (
 	pwd                        = ""
 	lcdRefreshInterval         = 1000
@@ -103,7 +116,7 @@ func Init(vendorId, productId uint16, serial, _ string) *common.Device {
 	pwd = config.GetConfig().ConfigPath
 
 	// Open device, return if failure
-	dev, err := hid.Open(vendorId, productId, serial)
+	dev, err := transport.Open(vendorId, productId, serial)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "serial": serial}).Error("Unable to open HID device")
 		return nil
@@ -253,6 +266,23 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetRgbLayout will return LED layout of a device
+func (d *Device) GetRgbLayout() capabilities.RgbInfo {
+	return capabilities.RgbInfo{LedChannels: d.LEDChannels}
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Device) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if d.HasLCD {
+		state.LcdModes = map[int]uint8{0: d.DeviceProfile.LCDMode}
+	}
+	return state
+}
+
 // getManufacturer will return device manufacturer
 func (d *Device) getManufacturer() {
 	manufacturer, err := d.dev.GetMfrStr()
@@ -5,6 +5,7 @@ package nexus
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dashboard"
@@ -16,6 +17,7 @@ import (
 	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/systeminfo"
 	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -32,7 +34,6 @@ import (
 
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
-	"github.com/sstallion/go-hid"
 	"golang.org/x/image/draw"
 	"golang.org/x/image/font"
 	_ "golang.org/x/image/font"
@@ -94,8 +95,8 @@ type LCDProfiles struct {
 
 type Device struct {
 	Debug             bool
-	dev               *hid.Device
-	listener          *hid.Device
+	dev               transport.Device
+	listener          transport.Device
 	Manufacturer      string                    `json:"manufacturer"`
 	Product           string                    `json:"product"`
 	Serial            string                    `json:"serial"`
@@ -128,6 +129,16 @@ type Device struct {
 	instance          *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+	_ capabilities.Metrics        = (*Device)(nil)
+	_ capabilities.LcdResolution  = (*Device)(nil)
+	_ capabilities.LcdProfile     = (*Device)(nil)
+)
+
 var (
 	pwd                        = ""
 	lcdRefreshInterval         = 1000
@@ -147,7 +158,7 @@ func Init(vendorId, productId uint16, serial, _ string) *common.Device {
 	pwd = config.GetConfig().ConfigPath
 
 	// Open device, return if failure
-	dev, err := hid.Open(vendorId, productId, serial)
+	dev, err := transport.Open(vendorId, productId, serial)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "serial": serial}).Error("Unable to open HID device")
 		return nil
@@ -278,6 +289,11 @@ func (d *Device) UpdateDeviceMetrics() {
 	metrics.Populate(header)
 }
 
+// GetLcdResolution will return LCD image resolution
+func (d *Device) GetLcdResolution() (int, int) {
+	return imgWidth, imgHeight
+}
+
 // GetDeviceTemplate will return device template name
 func (d *Device) GetDeviceTemplate() string {
 	return d.Template
@@ -1023,7 +1039,7 @@ func (d *Device) getListenerData() []byte {
 func (d *Device) backendListener() {
 	blocked := false
 	go func() {
-		listener, err := hid.Open(d.VendorId, d.ProductId, d.Serial)
+		listener, err := transport.Open(d.VendorId, d.ProductId, d.Serial)
 		if err != nil {
 			logger.Log(logger.Fields{"error": err, "serial": d.Serial}).Error("Unable to open backend listener HID device")
 			return
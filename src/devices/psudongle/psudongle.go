@@ -5,10 +5,12 @@ package psudongle
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dashboard"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/metrics"
 	"OpenLinkHub/src/serial"
 	"bytes"
 	"encoding/json"
@@ -79,11 +81,22 @@ type Device struct {
 	GpuTemp       float32
 	FanModes      map[int]string
 	InputVoltage  float64
+	InputPower    float64
+	OutputPower   float64
 	Path          string
 	instance      *common.Device
 	IsPSU         bool
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+	_ capabilities.Metrics        = (*Device)(nil)
+	_ capabilities.PsuFan         = (*Device)(nil)
+)
+
 var (
 	pwd                   = ""
 	dataRail12V           = byte(0x00)
@@ -102,6 +115,7 @@ var (
 	dataFanModeManual     = []byte{0x01}
 	cmdTempSensors        = []byte{0x8d, 0x8e} // VRM, PSU temp
 	cmdInputVoltage       = byte(0x88)
+	cmdInputPower         = byte(0x97)
 	mutex                 sync.Mutex
 	timer                 = &time.Ticker{}
 	autoRefreshChan       = make(chan bool)
@@ -423,6 +437,36 @@ func (d *Device) getDevices() int {
 	return len(devices)
 }
 
+// UpdateDeviceMetrics will update device metrics
+func (d *Device) UpdateDeviceMetrics() {
+	for _, device := range d.Devices {
+		header := &metrics.Header{
+			Product:          d.Product,
+			Serial:           d.Serial,
+			Firmware:         d.Firmware,
+			ChannelId:        strconv.Itoa(device.ChannelId),
+			Name:             device.Name,
+			Description:      device.Description,
+			Profile:          device.Profile,
+			Label:            device.Label,
+			TemperatureProbe: strconv.FormatBool(device.IsTemperatureProbe),
+			Temperature:      device.Temperature,
+			Rpm:              int16(device.Rpm),
+			Watts:            device.Watts,
+			Volts:            device.Volts,
+			Amps:             device.Amps,
+		}
+		metrics.Populate(header)
+	}
+	metrics.PopulatePowerSupply(&metrics.PowerSupply{
+		Product:      d.Product,
+		Serial:       d.Serial,
+		InputVoltage: d.InputVoltage,
+		InputPower:   d.InputPower,
+		OutputPower:  d.OutputPower,
+	})
+}
+
 // getDeviceData will fetch device data, from temperatures, fan speed, etc...
 func (d *Device) getDeviceData() {
 	m := 0
@@ -450,12 +494,15 @@ func (d *Device) getDeviceData() {
 	}
 
 	powerOut := d.Read(cmdOutputtPower)
+	d.OutputPower = d.Byte2Float(powerOut)
 	if _, ok := d.Devices[m]; ok {
-		powerOutW := d.Byte2Float(powerOut)
-		d.Devices[m].Watts = powerOutW
+		d.Devices[m].Watts = d.OutputPower
 		d.Devices[m].HasWatts = true
 	}
 
+	// Power in
+	d.InputPower = d.Byte2Float(d.Read(cmdInputPower))
+
 	m++
 	if _, ok := d.Devices[m]; ok {
 		d.Devices[m].Rpm = d.Byte2Float(fanRpm)
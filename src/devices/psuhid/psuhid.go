@@ -5,10 +5,13 @@ package psuhid
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dashboard"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/metrics"
+	"OpenLinkHub/src/transport"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
@@ -17,11 +20,10 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/sstallion/go-hid"
 )
 
 type Devices struct {
@@ -67,7 +69,7 @@ type DeviceProfile struct {
 }
 
 type Device struct {
-	dev           *hid.Device
+	dev           transport.Device
 	Manufacturer  string                    `json:"manufacturer"`
 	Product       string                    `json:"product"`
 	Serial        string                    `json:"serial"`
@@ -80,10 +82,21 @@ type Device struct {
 	GpuTemp       float32
 	FanModes      map[int]string
 	InputVoltage  float32
+	InputPower    float32
+	OutputPower   float32
 	instance      *common.Device
 	IsPSU         bool
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+	_ capabilities.Metrics        = (*Device)(nil)
+	_ capabilities.PsuFan         = (*Device)(nil)
+)
+
 var (
 	pwd                   = ""
 	cmdInit               = byte(0xfe)
@@ -104,6 +117,7 @@ var (
 	dataGetWatts          = byte(0x96)
 	dataPowerOut          = byte(0xee)
 	dataInputVoltage      = byte(0x88)
+	dataInputPower        = byte(0x97)
 	mutex                 sync.Mutex
 	timer                 = &time.Ticker{}
 	autoRefreshChan       = make(chan bool)
@@ -119,7 +133,7 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 	pwd = config.GetConfig().ConfigPath
 
 	// Open device, return if failure
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
@@ -435,6 +449,36 @@ func (d *Device) getDevices() int {
 	return len(devices)
 }
 
+// UpdateDeviceMetrics will update device metrics
+func (d *Device) UpdateDeviceMetrics() {
+	for _, device := range d.Devices {
+		header := &metrics.Header{
+			Product:          d.Product,
+			Serial:           d.Serial,
+			Firmware:         d.Firmware,
+			ChannelId:        strconv.Itoa(device.ChannelId),
+			Name:             device.Name,
+			Description:      device.Description,
+			Profile:          device.Profile,
+			Label:            device.Label,
+			TemperatureProbe: strconv.FormatBool(device.IsTemperatureProbe),
+			Temperature:      float64(device.Temperature),
+			Rpm:              device.Rpm,
+			Watts:            float64(device.Watts),
+			Volts:            float64(device.Volts),
+			Amps:             float64(device.Amps),
+		}
+		metrics.Populate(header)
+	}
+	metrics.PopulatePowerSupply(&metrics.PowerSupply{
+		Product:      d.Product,
+		Serial:       d.Serial,
+		InputVoltage: float64(d.InputVoltage),
+		InputPower:   float64(d.InputPower),
+		OutputPower:  float64(d.OutputPower),
+	})
+}
+
 // getDeviceData will fetch device data, from temperatures, fan speed, etc...
 func (d *Device) getDeviceData() {
 	m := 0
@@ -493,6 +537,16 @@ func (d *Device) getDeviceData() {
 		d.Devices[m].Watts = powerOutWatts
 		d.Devices[m].HasWatts = true
 	}
+	d.OutputPower = powerOutWatts
+
+	// Power in
+	d.init()
+	buf = d.createPacket(cmdRead, dataInputPower, 0)
+	output, err = d.transfer(buf)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err}).Error("Unable to init to PSU device")
+	}
+	d.InputPower = common.FromLinear11(output)
 
 	m++
 	if _, ok := d.Devices[m]; ok {
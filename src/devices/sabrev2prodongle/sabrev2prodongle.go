@@ -5,12 +5,14 @@ package sabrev2prodongle
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/m55W"
 	"OpenLinkHub/src/devices/sabrev2proW"
 	"OpenLinkHub/src/inputmanager"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/transport"
 	"github.com/sstallion/go-hid"
 	"os"
 	"strconv"
@@ -27,8 +29,8 @@ type Devices struct {
 }
 
 type Device struct {
-	dev            *hid.Device
-	listener       *hid.Device
+	dev            transport.Device
+	listener       transport.Device
 	mouse          *os.File
 	Manufacturer   string `json:"manufacturer"`
 	Product        string `json:"product"`
@@ -52,6 +54,13 @@ type Device struct {
 	instance       *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+)
+
 const (
 	EvKey      = 0x01
 	BtnLeft    = 0x110
@@ -74,7 +83,7 @@ var (
 
 func Init(vendorId, productId uint16, _, path string, callback func(device *common.Device)) *common.Device {
 	// Open device, return if failure
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
@@ -296,7 +305,7 @@ func (d *Device) setDeviceBatteryByProductId(productId, batteryLevel uint16) {
 }
 
 // GetDevice will return HID device
-func (d *Device) GetDevice() *hid.Device {
+func (d *Device) GetDevice() transport.Device {
 	return d.dev
 }
 
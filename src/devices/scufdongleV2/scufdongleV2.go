@@ -5,10 +5,12 @@ package scufdongleV2
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/scufenvisionproV2W"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -27,8 +29,8 @@ type Devices struct {
 }
 
 type Device struct {
-	dev            *hid.Device
-	listener       *hid.Device
+	dev            transport.Device
+	listener       transport.Device
 	Manufacturer   string `json:"manufacturer"`
 	Product        string `json:"product"`
 	Serial         string `json:"serial"`
@@ -50,6 +52,13 @@ type Device struct {
 	Path           string
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+)
+
 var (
 	bufferSize      = 64
 	bufferSizeWrite = bufferSize + 1
@@ -69,7 +78,7 @@ var (
 
 func Init(vendorId, productId uint16, _, path string, callback func(device *common.Device)) *common.Device {
 	// Open device, return if failure
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId}).Error("Unable to open HID device")
 		return nil
@@ -229,7 +238,7 @@ func (d *Device) AddPairedDevice(productId uint16, device any, dev *common.Devic
 }
 
 // GetDevice will return HID device
-func (d *Device) GetDevice() *hid.Device {
+func (d *Device) GetDevice() transport.Device {
 	return d.dev
 }
 
@@ -429,7 +438,7 @@ func (d *Device) getListenerData() []byte {
 // backendListener will listen for events from the device
 func (d *Device) backendListener() {
 	go func() {
-		listener, err := hid.OpenPath(d.Path)
+		listener, err := transport.OpenPath(d.Path)
 		if err != nil {
 			return
 		}
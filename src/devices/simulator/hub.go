@@ -0,0 +1,936 @@
+package simulator
+
+// Package: simulator
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/dashboard"
+	"OpenLinkHub/src/devices/lcd"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/metrics"
+	"OpenLinkHub/src/rgb"
+	"OpenLinkHub/src/stats"
+	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/watchdog"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	defaultSpeedValue          = 70
+	temperaturePullingInterval = 3000
+	refreshInterval            = 1000
+	ambientTemperature         = 25.0
+	i2cPrefix                  = "i2c"
+)
+
+type DeviceProfile struct {
+	Product       string
+	Serial        string
+	Path          string
+	SpeedProfiles map[int]string
+	RGBProfiles   map[int]string
+	Labels        map[int]string
+	MultiProfile  string
+	LCDMode       uint8
+	LCDRotation   uint8
+	LCDImage      string
+}
+
+// TemperatureProbe contains information about emulated temperature probe
+type TemperatureProbe struct {
+	ChannelId int
+	Name      string
+	Label     string
+	Serial    string
+	Product   string
+}
+
+type Devices struct {
+	ChannelId          int     `json:"channelId"`
+	DeviceId           string  `json:"deviceId"`
+	Name               string  `json:"name"`
+	Rpm                int16   `json:"rpm"`
+	Speed              int     `json:"speed"`
+	Temperature        float32 `json:"temperature"`
+	TemperatureString  string  `json:"temperatureString"`
+	LedChannels        uint8   `json:"-"`
+	ContainsPump       bool    `json:"-"`
+	Description        string  `json:"description"`
+	HubId              string  `json:"-"`
+	Profile            string  `json:"profile"`
+	RGB                string  `json:"rgb"`
+	Label              string  `json:"label"`
+	AIO                bool
+	HasSpeed           bool
+	HasTemps           bool
+	IsTemperatureProbe bool
+	minRpm             int16
+	maxRpm             int16
+}
+
+type Hub struct {
+	Debug            bool
+	Kind             string           `json:"kind"`
+	Product          string           `json:"product"`
+	Serial           string           `json:"serial"`
+	Firmware         string           `json:"firmware"`
+	AIO              bool             `json:"aio"`
+	Devices          map[int]*Devices `json:"devices"`
+	DeviceProfile    *DeviceProfile
+	RGBModes         []string
+	Template         string
+	HasLCD           bool
+	LCDModes         map[int]string
+	LCDRotations     map[int]string
+	layout           Layout
+	mutex            sync.Mutex
+	autoRefreshChan  chan struct{}
+	speedRefreshChan chan struct{}
+	timer            *time.Ticker
+	timerSpeed       *time.Ticker
+	Exit             bool
+	deviceLock       sync.Mutex
+	instance         *common.Device
+}
+
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable         = (*Hub)(nil)
+	_ capabilities.DirtyStoppable    = (*Hub)(nil)
+	_ capabilities.Templated         = (*Hub)(nil)
+	_ capabilities.Metrics           = (*Hub)(nil)
+	_ capabilities.DeviceLabel       = (*Hub)(nil)
+	_ capabilities.Rgb               = (*Hub)(nil)
+	_ capabilities.SpeedProfile      = (*Hub)(nil)
+	_ capabilities.ManualSpeed       = (*Hub)(nil)
+	_ capabilities.TemperatureProbes = (*Hub)(nil)
+	_ capabilities.Lcd               = (*Hub)(nil)
+	_ capabilities.StateReader       = (*Hub)(nil)
+)
+
+// initHub will initialize a new simulated cooling hub
+func initHub(kind, serial string, layout Layout) *common.Device {
+	d := &Hub{
+		Debug:    config.GetConfig().Debug,
+		Kind:     kind,
+		Product:  layout.Product,
+		Serial:   serial,
+		Firmware: layout.Firmware,
+		AIO:      layout.Pump,
+		RGBModes: getRgbModes(),
+		Template: "simulator.html",
+		HasLCD:   layout.Pump && layout.Lcd,
+		LCDModes: map[int]string{
+			0:  "Liquid Temperature",
+			1:  "Pump Speed",
+			2:  "CPU Temperature",
+			3:  "GPU Temperature",
+			4:  "Combined",
+			6:  "CPU / GPU Temp",
+			7:  "CPU / GPU Load",
+			8:  "CPU / GPU Load/Temp",
+			9:  "Time",
+			10: "Image / GIF",
+		},
+		LCDRotations: map[int]string{
+			0: "default",
+			1: "90 degrees",
+			2: "180 degrees",
+			3: "270 degrees",
+		},
+		layout:           layout,
+		autoRefreshChan:  make(chan struct{}),
+		speedRefreshChan: make(chan struct{}),
+		timer:            &time.Ticker{},
+		timerSpeed:       &time.Ticker{},
+	}
+
+	d.loadDeviceProfile() // Load device profile
+	d.getDevices()        // Get emulated devices connected to a hub
+	d.setDefaults()       // Set default speed values for fans and pumps
+	d.setAutoRefresh()    // Set auto device refresh
+	d.saveDeviceProfile() // Save profile
+	if config.GetConfig().Manual {
+		fmt.Println(
+			fmt.Sprintf("[%s [%s]] Manual flag enabled. Process will not monitor temperature or adjust fan speed.", d.Serial, d.Product),
+		)
+	} else {
+		d.updateDeviceSpeed()
+	}
+	d.createDevice() // Device register
+	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Simulated device successfully initialized")
+
+	return d.instance
+}
+
+// createDevice will create new device register object
+func (d *Hub) createDevice() {
+	d.instance = &common.Device{
+		ProductType: common.ProductTypeSimulator,
+		Product:     d.Product,
+		Serial:      d.Serial,
+		Firmware:    d.Firmware,
+		Image:       d.layout.Image,
+		Instance:    d,
+		GetDevice:   d,
+		DeviceType:  d.layout.DeviceType,
+	}
+}
+
+// Stop will stop all device operations
+func (d *Hub) Stop() {
+	d.Exit = true
+	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device...")
+
+	d.timer.Stop()
+	var once sync.Once
+	go func() {
+		once.Do(func() {
+			if !config.GetConfig().Manual {
+				d.timerSpeed.Stop()
+				if d.speedRefreshChan != nil {
+					close(d.speedRefreshChan)
+				}
+			}
+			if d.autoRefreshChan != nil {
+				close(d.autoRefreshChan)
+			}
+		})
+	}()
+	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Device stopped")
+}
+
+// StopDirty will stop device in a dirty way
+func (d *Hub) StopDirty() uint8 {
+	d.Stop()
+	return 1
+}
+
+// GetDeviceTemplate will return device template name
+func (d *Hub) GetDeviceTemplate() string {
+	return d.Template
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Hub) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	for channelId, channel := range d.Devices {
+		if channel.LedChannels > 0 && len(channel.RGB) > 0 {
+			if state.RgbProfiles == nil {
+				state.RgbProfiles = make(map[int]string)
+			}
+			state.RgbProfiles[channelId] = channel.RGB
+		}
+		if channel.HasSpeed && len(channel.Profile) > 0 {
+			if state.SpeedProfiles == nil {
+				state.SpeedProfiles = make(map[int]string)
+			}
+			state.SpeedProfiles[channelId] = channel.Profile
+		}
+	}
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if d.HasLCD {
+		state.LcdModes = map[int]uint8{0: d.DeviceProfile.LCDMode}
+	}
+	return state
+}
+
+// loadDeviceProfile will load persistent device configuration
+func (d *Hub) loadDeviceProfile() {
+	profilePath := pwd + "/database/profiles/" + d.Serial + ".json"
+	if !common.FileExists(profilePath) {
+		logger.Log(logger.Fields{"serial": d.Serial}).Warn("No profile found for device. Probably initial start")
+		return
+	}
+
+	file, err := os.Open(profilePath)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": profilePath}).Warn("Unable to load profile")
+		return
+	}
+	defer func(file *os.File) {
+		if err = file.Close(); err != nil {
+			logger.Log(logger.Fields{"location": profilePath, "serial": d.Serial}).Warn("Failed to close file handle")
+		}
+	}(file)
+
+	pf := &DeviceProfile{}
+	if err = json.NewDecoder(file).Decode(pf); err != nil {
+		logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": profilePath}).Warn("Unable to decode profile")
+		return
+	}
+	d.DeviceProfile = pf
+}
+
+// saveDeviceProfile will save device profile for persistent configuration
+func (d *Hub) saveDeviceProfile() {
+	d.deviceLock.Lock()
+	defer d.deviceLock.Unlock()
+
+	deviceProfile := &DeviceProfile{
+		Product:       d.Product,
+		Serial:        d.Serial,
+		Path:          pwd + "/database/profiles/" + d.Serial + ".json",
+		SpeedProfiles: make(map[int]string, len(d.Devices)),
+		RGBProfiles:   make(map[int]string, len(d.Devices)),
+		Labels:        make(map[int]string, len(d.Devices)),
+	}
+
+	for _, device := range d.Devices {
+		if device.HasSpeed {
+			deviceProfile.SpeedProfiles[device.ChannelId] = device.Profile
+		}
+		if device.LedChannels > 0 {
+			deviceProfile.RGBProfiles[device.ChannelId] = device.RGB
+		}
+		deviceProfile.Labels[device.ChannelId] = device.Label
+	}
+
+	if d.DeviceProfile != nil {
+		deviceProfile.MultiProfile = d.DeviceProfile.MultiProfile
+		deviceProfile.LCDMode = d.DeviceProfile.LCDMode
+		deviceProfile.LCDRotation = d.DeviceProfile.LCDRotation
+		deviceProfile.LCDImage = d.DeviceProfile.LCDImage
+	}
+	d.DeviceProfile = deviceProfile
+
+	if err := common.SaveJsonData(deviceProfile.Path, deviceProfile); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": deviceProfile.Path}).Error("Unable to write device profile data")
+	}
+}
+
+// getDevices will build all emulated devices connected to a hub
+func (d *Hub) getDevices() {
+	devices := make(map[int]*Devices)
+	channelId := d.layout.PumpChannel
+
+	if d.layout.Pump {
+		devices[channelId] = &Devices{
+			ChannelId:    channelId,
+			DeviceId:     fmt.Sprintf("%s-%v", "Pump", channelId),
+			Name:         "AIO Pump",
+			Description:  "Pump",
+			LedChannels:  d.layout.PumpLeds,
+			ContainsPump: true,
+			AIO:          true,
+			HasSpeed:     true,
+			HasTemps:     true,
+			Temperature:  float32(ambientTemperature),
+			minRpm:       d.layout.PumpMinRpm,
+			maxRpm:       d.layout.PumpMaxRpm,
+		}
+		channelId++
+	}
+
+	for i := 0; i < d.layout.Fans; i++ {
+		devices[channelId] = &Devices{
+			ChannelId:   channelId,
+			DeviceId:    fmt.Sprintf("%s-%v", "Fan", channelId),
+			Name:        fmt.Sprintf("Fan %d", i+1),
+			Description: "Fan",
+			LedChannels: d.layout.FanLeds,
+			HasSpeed:    true,
+			minRpm:      d.layout.FanMinRpm,
+			maxRpm:      d.layout.FanMaxRpm,
+		}
+		channelId++
+	}
+
+	for i := 0; i < d.layout.TemperatureProbes; i++ {
+		devices[channelId] = &Devices{
+			ChannelId:          channelId,
+			DeviceId:           fmt.Sprintf("%s-%v", "Probe", channelId),
+			Name:               fmt.Sprintf("Temperature Probe %d", i+1),
+			Description:        "Probe",
+			HasTemps:           true,
+			IsTemperatureProbe: true,
+			Temperature:        float32(ambientTemperature),
+		}
+		channelId++
+	}
+
+	for _, device := range devices {
+		device.HubId = d.Serial
+		device.Label = "Set Label"
+		if device.HasSpeed {
+			device.Profile = "Normal"
+		}
+		if device.LedChannels > 0 {
+			device.RGB = "static"
+		}
+
+		if d.DeviceProfile == nil {
+			continue
+		}
+		if sp, ok := d.DeviceProfile.SpeedProfiles[device.ChannelId]; ok && device.HasSpeed {
+			if temperatures.GetTemperatureProfile(sp) != nil {
+				device.Profile = sp
+			} else {
+				logger.Log(logger.Fields{"serial": d.Serial, "profile": sp}).Warn("Tried to apply non-existing profile")
+			}
+		}
+		if rp, ok := d.DeviceProfile.RGBProfiles[device.ChannelId]; ok && device.LedChannels > 0 {
+			if rgb.GetRgbProfile(rp) != nil {
+				device.RGB = rp
+			}
+		}
+		if lb, ok := d.DeviceProfile.Labels[device.ChannelId]; ok && len(lb) > 0 {
+			device.Label = lb
+		}
+	}
+	d.Devices = devices
+}
+
+// setDefaults will set default speed for all fans and pumps
+func (d *Hub) setDefaults() {
+	channelDefaults := map[int]byte{}
+	for _, device := range d.Devices {
+		if device.HasSpeed {
+			channelDefaults[device.ChannelId] = byte(defaultSpeedValue)
+		}
+	}
+	d.setSpeed(channelDefaults)
+}
+
+// setSpeed will store commanded speed of emulated channels
+func (d *Hub) setSpeed(data map[int]byte) {
+	if d.Exit {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key, value := range data {
+		if device, ok := d.Devices[key]; ok && device.HasSpeed {
+			device.Speed = int(value)
+			watchdog.SetSpeed(d.Serial, key, int(value))
+		}
+	}
+}
+
+// simulate will move RPM towards commanded speed and liquid temperature towards thermal equilibrium
+func (d *Hub) simulate() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	fans, pump, fanCount := 0.0, 0.0, 0
+	for _, device := range d.Devices {
+		if !device.HasSpeed {
+			continue
+		}
+
+		target := 0.0
+		if device.Speed > 0 {
+			target = float64(device.minRpm) + float64(device.maxRpm-device.minRpm)*float64(device.Speed)/100
+		}
+
+		// Spin up and down with inertia and a bit of sensor noise
+		rpm := float64(device.Rpm) + (target-float64(device.Rpm))*0.4
+		if target > 0 {
+			rpm += (rand.Float64()*2 - 1) * target * 0.01
+		}
+		device.Rpm = int16(math.Max(0, math.Round(rpm)))
+
+		if device.ContainsPump {
+			pump = float64(device.Speed) / 100
+		} else {
+			fans += float64(device.Speed) / 100
+			fanCount++
+		}
+	}
+	if fanCount > 0 {
+		fans = fans / float64(fanCount)
+	}
+
+	// Heat load follows CPU temperature when available
+	load := float64(temperatures.GetCpuTemperature())
+	if load == 0 {
+		load = 60
+	}
+	equilibrium := ambientTemperature + math.Max(0, load-ambientTemperature)*0.45*(1-0.35*fans-0.25*pump)
+
+	for _, device := range d.Devices {
+		if !device.HasTemps {
+			continue
+		}
+		target := equilibrium
+		if device.IsTemperatureProbe {
+			// Case probes sit between ambient and coolant temperature
+			target = (ambientTemperature + equilibrium) / 2
+		}
+		value := float64(device.Temperature) + (target-float64(device.Temperature))*0.1
+		device.Temperature = float32(math.Round(value*10) / 10)
+		device.TemperatureString = dashboard.GetDashboard().TemperatureToString(device.Temperature)
+	}
+}
+
+// getDeviceData will update device stats and report RPM to watchdog
+func (d *Hub) getDeviceData() {
+	if d.Exit {
+		return
+	}
+
+	d.simulate()
+	for key, value := range d.Devices {
+		if value.HasSpeed {
+			watchdog.UpdateRpm(d.Serial, value.Name, value.ChannelId, value.ContainsPump, int(value.Rpm))
+		}
+
+		temperatureString := ""
+		rpmString := ""
+		if value.Temperature > 0 {
+			temperatureString = dashboard.GetDashboard().TemperatureToString(value.Temperature)
+		}
+		if value.Rpm > 0 {
+			rpmString = fmt.Sprintf("%v RPM", value.Rpm)
+		}
+		stats.UpdateDeviceStats(d.Serial, value.Name, temperatureString, rpmString, value.Label, key, value.Temperature)
+	}
+}
+
+// setAutoRefresh will refresh device data
+func (d *Hub) setAutoRefresh() {
+	d.timer = time.NewTicker(time.Duration(refreshInterval) * time.Millisecond)
+	go func() {
+		for {
+			select {
+			case <-d.timer.C:
+				if d.Exit {
+					return
+				}
+				d.getDeviceData()
+			case <-d.autoRefreshChan:
+				d.timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// getLiquidTemperature will fetch temperature from AIO pump
+func (d *Hub) getLiquidTemperature() float32 {
+	for _, device := range d.Devices {
+		if device.AIO {
+			return device.Temperature
+		}
+	}
+	return 0
+}
+
+// validateSpeedProfile will check if speed profile can be applied to this device
+func (d *Hub) validateSpeedProfile(profiles *temperatures.TemperatureProfileData) uint8 {
+	// If the profile is liquid temperature, check for the presence of AIOs
+	if profiles.Sensor == temperatures.SensorTypeLiquidTemperature && !d.AIO {
+		return 2
+	}
+
+	// Block PSU profile type
+	if profiles.Sensor == temperatures.SensorTypePSU {
+		return 6
+	}
+
+	if profiles.Sensor == temperatures.SensorTypeTemperatureProbe {
+		if strings.HasPrefix(profiles.Device, i2cPrefix) {
+			if temperatures.GetMemoryTemperature(profiles.ChannelId) == 0 {
+				return 5
+			}
+		} else {
+			if profiles.Device != d.Serial {
+				return 3
+			}
+
+			if device, ok := d.Devices[profiles.ChannelId]; !ok || !device.IsTemperatureProbe {
+				return 4
+			}
+		}
+	}
+	return 1
+}
+
+// UpdateSpeedProfile will update device channel speed.
+// If channelId is -1, all device channels will be updated
+func (d *Hub) UpdateSpeedProfile(channelId int, profile string) uint8 {
+	// Check if the profile exists
+	profiles := temperatures.GetTemperatureProfile(profile)
+	if profiles == nil {
+		return 0
+	}
+
+	if status := d.validateSpeedProfile(profiles); status != 1 {
+		return status
+	}
+
+	if channelId < 0 {
+		d.DeviceProfile.MultiProfile = profile
+		for _, device := range d.Devices {
+			if device.HasSpeed {
+				device.Profile = profile
+			}
+		}
+	} else {
+		device, ok := d.Devices[channelId]
+		if !ok || !device.HasSpeed {
+			return 0
+		}
+		device.Profile = profile
+	}
+
+	d.saveDeviceProfile()
+	return 1
+}
+
+// ResetSpeedProfiles will reset channel speed profile if it matches with the current speed profile
+// This is used when speed profile is deleted from the UI
+func (d *Hub) ResetSpeedProfiles(profile string) {
+	i := 0
+	for _, device := range d.Devices {
+		if device.HasSpeed && device.Profile == profile {
+			device.Profile = "Normal"
+			i++
+		}
+	}
+
+	if i > 0 {
+		d.saveDeviceProfile()
+	}
+}
+
+// UpdateDeviceSpeed will update device channel speed.
+func (d *Hub) UpdateDeviceSpeed(channelId int, value uint16) uint8 {
+	device, ok := d.Devices[channelId]
+	if !ok || !device.HasSpeed {
+		return 0
+	}
+
+	if value < 20 {
+		value = 20
+	}
+	if device.ContainsPump && value < 50 {
+		value = 50
+	}
+	if value > 100 {
+		value = 100
+	}
+	d.setSpeed(map[int]byte{channelId: byte(value)})
+	return 1
+}
+
+// UpdateDeviceLabel will set / update device label
+func (d *Hub) UpdateDeviceLabel(channelId int, label string) uint8 {
+	if _, ok := d.Devices[channelId]; !ok {
+		return 0
+	}
+
+	d.Devices[channelId].Label = label
+	d.saveDeviceProfile()
+	return 1
+}
+
+// UpdateRgbProfile will update device RGB profile.
+// If channelId is -1, all device channels will be updated
+func (d *Hub) UpdateRgbProfile(channelId int, profile string) uint8 {
+	if rgb.GetRgbProfile(profile) == nil {
+		return 0
+	}
+
+	if channelId < 0 {
+		for _, device := range d.Devices {
+			if device.LedChannels > 0 {
+				device.RGB = profile
+			}
+		}
+	} else {
+		device, ok := d.Devices[channelId]
+		if !ok || device.LedChannels == 0 {
+			return 0
+		}
+		device.RGB = profile
+	}
+
+	d.saveDeviceProfile()
+	return 1
+}
+
+// GetTemperatureProbes will return a list of temperature probes
+func (d *Hub) GetTemperatureProbes() interface{} {
+	keys := make([]int, 0)
+	for k, device := range d.Devices {
+		if device.IsTemperatureProbe {
+			keys = append(keys, k)
+		}
+	}
+	sort.Ints(keys)
+
+	probes := make([]TemperatureProbe, 0, len(keys))
+	for _, k := range keys {
+		probes = append(probes, TemperatureProbe{
+			ChannelId: d.Devices[k].ChannelId,
+			Name:      d.Devices[k].Name,
+			Label:     d.Devices[k].Label,
+			Serial:    d.Serial,
+			Product:   d.Product,
+		})
+	}
+	return &probes
+}
+
+// isLcdChannel will return true when LCD is mounted on a given channel
+func (d *Hub) isLcdChannel(channelId int) bool {
+	if !d.HasLCD {
+		return false
+	}
+	device, ok := d.Devices[channelId]
+	return ok && device.ContainsPump
+}
+
+// UpdateDeviceLcd will update device LCD
+func (d *Hub) UpdateDeviceLcd(channelId int, mode uint8) uint8 {
+	if !d.isLcdChannel(channelId) {
+		return 2
+	}
+
+	if _, ok := d.LCDModes[int(mode)]; !ok {
+		return 0
+	}
+
+	if mode == lcd.DisplayImage {
+		images := lcd.GetLcdImages()
+		if len(images) == 0 {
+			return 0
+		}
+		if lcd.GetLcdImage(d.DeviceProfile.LCDImage) == nil {
+			d.DeviceProfile.LCDImage = images[0].Name
+		}
+	}
+
+	d.DeviceProfile.LCDMode = mode
+	d.saveDeviceProfile()
+	return 1
+}
+
+// UpdateDeviceLcdImage will update device LCD image
+func (d *Hub) UpdateDeviceLcdImage(channelId int, image string) uint8 {
+	if !d.isLcdChannel(channelId) {
+		return 0
+	}
+
+	if !common.AlphanumericRegex.MatchString(image) {
+		return 0
+	}
+
+	if lcd.GetLcdImage(image) == nil {
+		return 0
+	}
+
+	d.DeviceProfile.LCDImage = image
+	d.saveDeviceProfile()
+	return 1
+}
+
+// UpdateDeviceLcdRotation will update device LCD rotation
+func (d *Hub) UpdateDeviceLcdRotation(channelId int, rotation uint8) uint8 {
+	if !d.isLcdChannel(channelId) {
+		return 2
+	}
+
+	if _, ok := d.LCDRotations[int(rotation)]; !ok {
+		return 0
+	}
+
+	d.DeviceProfile.LCDRotation = rotation
+	d.saveDeviceProfile()
+	return 1
+}
+
+// updateDeviceSpeed will update device speed based on a temperature reading
+func (d *Hub) updateDeviceSpeed() {
+	d.timerSpeed = time.NewTicker(time.Duration(temperaturePullingInterval) * time.Millisecond)
+	go func() {
+		tmp := make(map[int]string)
+		channelSpeeds := map[int]byte{}
+
+		for {
+			select {
+			case <-d.timerSpeed.C:
+				var temp float32 = 0
+				for _, device := range d.Devices {
+					if !device.HasSpeed {
+						continue
+					}
+
+					profiles := temperatures.GetTemperatureProfile(device.Profile)
+					if profiles == nil {
+						profiles = temperatures.GetTemperatureProfile("Normal")
+					}
+
+					switch {
+					case temperatures.IsHostSensor(profiles.Sensor):
+						{
+							temp = temperatures.GetSensorTemperature(temperatures.GetProfileSensorId(profiles))
+							if temp == 0 {
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "sensor": profiles.Sensor, "device": profiles.Device}).Warn("Unable to get sensor temperature.")
+							}
+						}
+					case profiles.Sensor == temperatures.SensorTypeLiquidTemperature:
+						{
+							temp = d.getLiquidTemperature()
+							if temp == 0 {
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial}).Warn("Unable to get liquid temperature.")
+							}
+						}
+					case profiles.Sensor == temperatures.SensorTypeTemperatureProbe:
+						{
+							if strings.HasPrefix(profiles.Device, i2cPrefix) {
+								temp = temperatures.GetMemoryTemperature(profiles.ChannelId)
+							} else if probe, ok := d.Devices[profiles.ChannelId]; ok && probe.IsTemperatureProbe {
+								temp = probe.Temperature
+							}
+
+							if temp == 0 {
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "channelId": profiles.ChannelId}).Warn("Unable to get probe temperature.")
+							}
+						}
+					case profiles.Sensor == temperatures.SensorTypeGlobalTemperature:
+						{
+							temp = stats.GetDeviceTemperature(profiles.Device, profiles.ChannelId)
+							if temp == 0 {
+								logger.Log(logger.Fields{"temperature": temp, "serial": d.Serial, "hwmonDeviceId": profiles.Device}).Warn("Unable to get hwmon temperature.")
+							}
+						}
+					}
+
+					// Critical temperature failsafe
+					temperatures.CheckFailsafe(d.Serial, device.ChannelId, profiles, temp)
+
+					// All temps failed, default to 50
+					if temp == 0 {
+						temp = 50
+					}
+
+					// Averaging and hysteresis
+					temp = temperatures.SmoothTemperature(d.Serial, device.ChannelId, profiles, temp)
+
+					if config.GetConfig().GraphProfiles {
+						pumpValue := temperatures.Interpolate(profiles.Points[0], temp)
+						fansValue := temperatures.Interpolate(profiles.Points[1], temp)
+
+						pump := int(math.Round(float64(pumpValue)))
+						pump = temperatures.RampSpeed(d.Serial, device.ChannelId, true, profiles, pump)
+						fans := int(math.Round(float64(fansValue)))
+						fans = temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, fans)
+
+						// Failsafe
+						if fans < 20 && !profiles.ZeroRpm {
+							fans = 20
+						}
+						if pump < 50 {
+							pump = 70
+						}
+						if pump > 100 {
+							pump = 100
+						}
+						if fans > 100 {
+							fans = 100
+						}
+						if temperatures.IsFailsafe() {
+							pump, fans = 100, 100
+						}
+
+						cp := fmt.Sprintf("%s-%d-%d-%d", device.Profile, device.ChannelId, pump, fans)
+						if ok := tmp[device.ChannelId]; ok != cp {
+							tmp[device.ChannelId] = cp
+							if device.ContainsPump {
+								channelSpeeds[device.ChannelId] = byte(pump)
+							} else {
+								channelSpeeds[device.ChannelId] = byte(fans)
+							}
+							d.setSpeed(channelSpeeds)
+						}
+
+						if d.Debug {
+							logger.Log(logger.Fields{"serial": d.Serial, "pump": pump, "fans": fans, "temp": temp, "device": device.Name, "zeroRpm": profiles.ZeroRpm}).Info("updateDeviceSpeed()")
+						}
+					} else {
+						for i := 0; i < len(profiles.Profiles); i++ {
+							profile := profiles.Profiles[i]
+							if temperatures.IsActiveStep(profiles, i, temp) {
+								profile.Fans = uint16(temperatures.RampSpeed(d.Serial, device.ChannelId, false, profiles, int(profile.Fans)))
+								profile.Pump = uint16(temperatures.RampSpeed(d.Serial, device.ChannelId, true, profiles, int(profile.Pump)))
+								if temperatures.IsFailsafe() {
+									profile.Fans, profile.Pump = 100, 100
+								}
+								cp := fmt.Sprintf("%s-%d-%d-%d-%d", device.Profile, device.ChannelId, profile.Id, profile.Fans, profile.Pump)
+								if ok := tmp[device.ChannelId]; ok != cp {
+									tmp[device.ChannelId] = cp
+
+									if profile.Fans < 20 && !profiles.ZeroRpm {
+										profile.Fans = 20
+									}
+									if profile.Pump < 50 {
+										profile.Pump = 50
+									}
+									if profile.Pump > 100 {
+										profile.Pump = 100
+									}
+
+									if device.ContainsPump {
+										channelSpeeds[device.ChannelId] = byte(profile.Pump)
+									} else {
+										channelSpeeds[device.ChannelId] = byte(profile.Fans)
+									}
+									d.setSpeed(channelSpeeds)
+								}
+							}
+						}
+					}
+				}
+			case <-d.speedRefreshChan:
+				d.timerSpeed.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// UpdateDeviceMetrics will update device metrics
+func (d *Hub) UpdateDeviceMetrics() {
+	if d.Exit {
+		return
+	}
+	for _, device := range d.Devices {
+		header := &metrics.Header{
+			Product:          d.Product,
+			Serial:           d.Serial,
+			Firmware:         d.Firmware,
+			ChannelId:        strconv.Itoa(device.ChannelId),
+			Name:             device.Name,
+			Description:      device.Description,
+			Profile:          device.Profile,
+			Label:            device.Label,
+			RGB:              device.RGB,
+			AIO:              strconv.FormatBool(device.AIO),
+			ContainsPump:     strconv.FormatBool(device.ContainsPump),
+			Temperature:      float64(device.Temperature),
+			LedChannels:      strconv.Itoa(int(device.LedChannels)),
+			Rpm:              device.Rpm,
+			TemperatureProbe: strconv.FormatBool(device.IsTemperatureProbe),
+		}
+		metrics.Populate(header)
+	}
+}
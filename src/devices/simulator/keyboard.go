@@ -0,0 +1,870 @@
+package simulator
+
+// Package: simulator
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/inputmanager"
+	"OpenLinkHub/src/keyboards"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/rgb"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	keyboardKey           = "k70coretklW-default"
+	keyboardDefaultLayout = "k70coretklW-default-US"
+	keyAssignmentLength   = 123
+	keyboardRgbModes      = []string{
+		"watercolor",
+		"visor",
+		"rainbowwave",
+		"colorwave",
+		"colorshift",
+		"colorpulse",
+		"spiralrainbow",
+		"tlr",
+		"tlk",
+		"rain",
+		"keyboard",
+		"gradient",
+		"off",
+	}
+)
+
+// KeyboardProfile struct contains simulated keyboard profile
+type KeyboardProfile struct {
+	Active               bool
+	Path                 string
+	Product              string
+	Serial               string
+	Brightness           uint8
+	RGBProfile           string
+	SlipstreamRGBProfile string
+	Label                string
+	Layout               string
+	Keyboards            map[string]*keyboards.Keyboard
+	Profile              string
+	PollingRate          int
+	Profiles             []string
+	ControlDial          int
+	BrightnessLevel      uint16
+	SleepMode            int
+	DisableAltTab        bool
+	DisableAltF4         bool
+	DisableShiftTab      bool
+	DisableWinKey        bool
+	Performance          bool
+	RgbOff               bool
+}
+
+// Keyboard is a simulated K70 CORE TKL WIRELESS keyboard
+type Keyboard struct {
+	Peripheral
+	UserProfiles       map[string]*KeyboardProfile `json:"userProfiles"`
+	DeviceProfile      *KeyboardProfile
+	Brightness         map[int]string
+	Layouts            []string
+	ControlDialOptions map[int]string
+	RGBModes           map[string]string
+	rgbOverride        string
+	rgbOverrideMutex   sync.Mutex
+	SleepModes         map[int]string
+	PollingRates       map[int]string
+	KeyAmount          int
+	UIKeyboard         string
+	UIKeyboardRow      string
+	KeyAssignmentTypes map[int]string
+}
+
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable              = (*Keyboard)(nil)
+	_ capabilities.DirtyStoppable         = (*Keyboard)(nil)
+	_ capabilities.Templated              = (*Keyboard)(nil)
+	_ capabilities.UserProfiles           = (*Keyboard)(nil)
+	_ capabilities.UserProfileDeleter     = (*Keyboard)(nil)
+	_ capabilities.DeviceLabel            = (*Keyboard)(nil)
+	_ capabilities.Rgb                    = (*Keyboard)(nil)
+	_ capabilities.RgbProfileOverride     = (*Keyboard)(nil)
+	_ capabilities.RgbProfiles            = (*Keyboard)(nil)
+	_ capabilities.RgbProfileReader       = (*Keyboard)(nil)
+	_ capabilities.RgbProfileEditor       = (*Keyboard)(nil)
+	_ capabilities.RgbControl             = (*Keyboard)(nil)
+	_ capabilities.KeyColor               = (*Keyboard)(nil)
+	_ capabilities.Brightness             = (*Keyboard)(nil)
+	_ capabilities.KeyboardProfiles       = (*Keyboard)(nil)
+	_ capabilities.KeyboardProfileSaver   = (*Keyboard)(nil)
+	_ capabilities.KeyboardKeys           = (*Keyboard)(nil)
+	_ capabilities.KeyboardPerformance    = (*Keyboard)(nil)
+	_ capabilities.KeyAssignment          = (*Keyboard)(nil)
+	_ capabilities.KeyAssignmentLayers    = (*Keyboard)(nil)
+	_ capabilities.KeyAssignmentTypes     = (*Keyboard)(nil)
+	_ capabilities.KeyAssignmentModifiers = (*Keyboard)(nil)
+	_ capabilities.ControlDial            = (*Keyboard)(nil)
+	_ capabilities.SleepTimer             = (*Keyboard)(nil)
+	_ capabilities.PollingRate            = (*Keyboard)(nil)
+	_ capabilities.PollingRateOptions     = (*Keyboard)(nil)
+	_ capabilities.SleepModeOptions       = (*Keyboard)(nil)
+	_ capabilities.BatteryStatus          = (*Keyboard)(nil)
+	_ capabilities.StateReader            = (*Keyboard)(nil)
+)
+
+// GetPollingRates will return polling rates supported by a device
+func (d *Keyboard) GetPollingRates() map[int]string {
+	return d.PollingRates
+}
+
+// GetSleepModes will return sleep modes supported by a device
+func (d *Keyboard) GetSleepModes() map[int]string {
+	return d.SleepModes
+}
+
+// GetBatteryLevel will return battery level of a device
+func (d *Keyboard) GetBatteryLevel() uint16 {
+	return d.BatteryLevel
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Keyboard) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if state.RgbProfiles == nil && len(d.DeviceProfile.RGBProfile) > 0 {
+		state.RgbProfiles = map[int]string{0: d.DeviceProfile.RGBProfile}
+	}
+	mode := d.DeviceProfile.Brightness
+	state.BrightnessMode = &mode
+	return state
+}
+
+// initKeyboard will initialize a new simulated keyboard
+func initKeyboard(kind, serial string, layout Layout) *common.Device {
+	d := &Keyboard{
+		Peripheral: newPeripheral(kind, serial, "k70coretklW.html", layout, keyboardRgbModes),
+		Brightness: map[int]string{
+			0: "RGB Profile",
+			1: "33 %",
+			2: "66 %",
+			3: "100 %",
+		},
+		ControlDialOptions: map[int]string{
+			1: "Volume Control",
+			2: "Brightness",
+			3: "Scroll",
+			4: "Zoom",
+			5: "Screen Brightness",
+			6: "Media Control",
+			7: "Horizontal Scroll",
+		},
+		Layouts: keyboards.GetLayouts(keyboardKey),
+		RGBModes: map[string]string{
+			"watercolor":    "Watercolor",
+			"colorpulse":    "Color Pulse",
+			"colorshift":    "Color Shift",
+			"colorwave":     "Color Wave",
+			"rain":          "Rain",
+			"rainbowwave":   "Rainbow Wave",
+			"spiralrainbow": "Spiral Rainbow",
+			"tlk":           "Type Lighting - Key",
+			"tlr":           "Type Lighting - Ripple",
+			"keyboard":      "Keyboard",
+			"off":           "Off",
+			"visor":         "Visor",
+		},
+		SleepModes: map[int]string{
+			1:  "1 minute",
+			5:  "5 minutes",
+			10: "10 minutes",
+			15: "15 minutes",
+			30: "30 minutes",
+			60: "1 hour",
+		},
+		PollingRates: map[int]string{
+			0: "Not Set",
+			1: "125 Hz / 8 msec",
+			2: "250 Hz / 4 msec",
+			3: "500 Hz / 2 msec",
+			4: "1000 Hz / 1 msec",
+		},
+		UIKeyboard:    "keyboard-6",
+		UIKeyboardRow: "keyboard-row-20",
+		KeyAssignmentTypes: map[int]string{
+			0:  "None",
+			1:  "Media Keys",
+			3:  "Keyboard",
+			8:  "Sniper",
+			9:  "Mouse",
+			10: "Macro",
+			11: "Brightness +",
+			12: "Brightness -",
+			13: "Scroll Up",
+			14: "Scroll Down",
+			15: "Zoom In",
+			16: "Zoom Out",
+			17: "Screen Brightness +",
+			18: "Screen Brightness -",
+		},
+	}
+
+	d.loadRgb()            // Load RGB
+	d.loadDeviceProfiles() // Load all device profiles
+	d.saveDeviceProfile()  // Save profile
+	if d.DeviceProfile == nil {
+		logger.Log(logger.Fields{"serial": d.Serial}).Error("Unable to load keyboard layout")
+		return nil
+	}
+	d.setKeyAmount()       // Set number of keys
+	d.setupKeyAssignment() // Key assignments
+	d.setAutoRefresh()     // Set battery discharge
+	d.createDevice(d)      // Device register
+	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Simulated device successfully initialized")
+
+	return d.instance
+}
+
+// setKeyAmount will set global key amount
+func (d *Keyboard) setKeyAmount() {
+	index := 0
+	for _, row := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
+		for _, key := range row.Keys {
+			index += len(key.PacketIndex)
+		}
+	}
+	d.KeyAmount = index
+}
+
+// setupKeyAssignment will emulate upload of keyboard key assignments
+func (d *Keyboard) setupKeyAssignment() {
+	d.deviceLock.Lock()
+	defer d.deviceLock.Unlock()
+
+	if d.DeviceProfile == nil {
+		return
+	}
+
+	keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]
+	if !ok {
+		return
+	}
+
+	buf := make([]byte, keyAssignmentLength)
+	for i := range buf {
+		buf[i] = 0x01
+	}
+
+	for _, row := range keyboard.Row {
+		for _, key := range row.Keys {
+			key = keyboards.ApplyLayer(d.Serial, key)
+			if len(key.KeyData) < 3 || int(key.KeyData[0]) >= len(buf) {
+				continue
+			}
+
+			if key.Default || key.RetainOriginal {
+				buf[key.KeyData[0]] = byte(key.KeyData[1])
+			} else {
+				buf[key.KeyData[0]] = byte(key.KeyData[2])
+			}
+		}
+	}
+	d.transfer("keyAssignment", buf)
+}
+
+// ApplyKeyAssignmentLayer will apply key assignments of active key assignment layer
+func (d *Keyboard) ApplyKeyAssignmentLayer() {
+	d.setupKeyAssignment()
+}
+
+// saveDeviceProfile will save device profile for persistent configuration
+func (d *Keyboard) saveDeviceProfile() {
+	profilePath := pwd + "/database/profiles/" + d.Serial + ".json"
+	keyboardMap := make(map[string]*keyboards.Keyboard)
+
+	deviceProfile := &KeyboardProfile{
+		Product: d.Product,
+		Serial:  d.Serial,
+		Path:    profilePath,
+	}
+
+	if d.DeviceProfile == nil {
+		keyboard := keyboards.GetKeyboard(keyboardDefaultLayout)
+		if keyboard == nil {
+			return
+		}
+		deviceProfile.RGBProfile = "keyboard"
+		deviceProfile.SlipstreamRGBProfile = "keyboard"
+		deviceProfile.Label = "Keyboard"
+		deviceProfile.Active = true
+		keyboardMap["default"] = keyboard
+		deviceProfile.Keyboards = keyboardMap
+		deviceProfile.Profile = "default"
+		deviceProfile.Profiles = []string{"default"}
+		deviceProfile.Layout = "US"
+		deviceProfile.ControlDial = 1
+		deviceProfile.BrightnessLevel = 1000
+		deviceProfile.SleepMode = 15
+		deviceProfile.PollingRate = 4
+	} else {
+		if len(d.DeviceProfile.Layout) == 0 {
+			deviceProfile.Layout = "US"
+		} else {
+			deviceProfile.Layout = d.DeviceProfile.Layout
+		}
+
+		if d.DeviceProfile.SleepMode == 0 {
+			deviceProfile.SleepMode = 15
+		} else {
+			deviceProfile.SleepMode = d.DeviceProfile.SleepMode
+		}
+
+		if d.DeviceProfile.PollingRate == 0 {
+			deviceProfile.PollingRate = 4
+		} else {
+			deviceProfile.PollingRate = d.DeviceProfile.PollingRate
+		}
+
+		deviceProfile.Active = d.DeviceProfile.Active
+		deviceProfile.Brightness = d.DeviceProfile.Brightness
+		deviceProfile.RGBProfile = d.DeviceProfile.RGBProfile
+		deviceProfile.SlipstreamRGBProfile = d.DeviceProfile.SlipstreamRGBProfile
+		deviceProfile.Label = d.DeviceProfile.Label
+		deviceProfile.Profile = d.DeviceProfile.Profile
+		deviceProfile.Profiles = d.DeviceProfile.Profiles
+		deviceProfile.Keyboards = d.DeviceProfile.Keyboards
+		deviceProfile.ControlDial = d.DeviceProfile.ControlDial
+		deviceProfile.BrightnessLevel = d.DeviceProfile.BrightnessLevel
+		deviceProfile.DisableAltTab = d.DeviceProfile.DisableAltTab
+		deviceProfile.DisableAltF4 = d.DeviceProfile.DisableAltF4
+		deviceProfile.DisableShiftTab = d.DeviceProfile.DisableShiftTab
+		deviceProfile.DisableWinKey = d.DeviceProfile.DisableWinKey
+		deviceProfile.Performance = d.DeviceProfile.Performance
+		deviceProfile.RgbOff = d.DeviceProfile.RgbOff
+		if len(d.DeviceProfile.Path) > 0 {
+			deviceProfile.Path = d.DeviceProfile.Path
+		}
+	}
+
+	// Fix profile paths if folder database/ folder is moved
+	filename := filepath.Base(deviceProfile.Path)
+	deviceProfile.Path = fmt.Sprintf("%s/database/profiles/%s", pwd, filename)
+
+	// Save profile
+	if err := common.SaveJsonData(deviceProfile.Path, deviceProfile); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": deviceProfile.Path}).Error("Unable to write device profile data")
+		return
+	}
+
+	d.loadDeviceProfiles()
+}
+
+// loadDeviceProfiles will load custom user profiles
+func (d *Keyboard) loadDeviceProfiles() {
+	profileList := make(map[string]*KeyboardProfile)
+	userProfileDirectory := pwd + "/database/profiles/"
+
+	files, err := os.ReadDir(userProfileDirectory)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "location": userProfileDirectory, "serial": d.Serial}).Error("Unable to read content of a folder")
+		return
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue // Exclude folders if any
+		}
+
+		// Define a full path of filename
+		profileLocation := userProfileDirectory + fi.Name()
+
+		// Check if filename has .json extension
+		if !common.IsValidExtension(profileLocation, ".json") {
+			continue
+		}
+
+		fileName := strings.Split(fi.Name(), ".")[0]
+		if !common.AlphanumericDashRegex.MatchString(fileName) {
+			continue
+		}
+
+		if strings.Split(fileName, "-")[0] != d.Serial {
+			continue
+		}
+
+		pf := &KeyboardProfile{}
+		file, err := os.Open(profileLocation)
+		if err != nil {
+			logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": profileLocation}).Warn("Unable to load profile")
+			continue
+		}
+		if err = json.NewDecoder(file).Decode(pf); err != nil {
+			logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": profileLocation}).Warn("Unable to decode profile")
+		}
+		if err = file.Close(); err != nil {
+			logger.Log(logger.Fields{"location": profileLocation, "serial": d.Serial}).Warn("Failed to close file handle")
+		}
+
+		if pf.Serial != d.Serial {
+			continue
+		}
+		if fileName == d.Serial {
+			profileList["default"] = pf
+		} else {
+			profileList[strings.Split(fileName, "-")[1]] = pf
+		}
+	}
+	d.UserProfiles = profileList
+
+	for _, pf := range d.UserProfiles {
+		if pf.Active {
+			d.DeviceProfile = pf
+		}
+	}
+}
+
+// UpdateDeviceLabel will set / update device label
+func (d *Keyboard) UpdateDeviceLabel(_ int, label string) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+	d.DeviceProfile.Label = label
+	d.saveDeviceProfile()
+	return 1
+}
+
+// UpdateRgbProfile will update device RGB profile
+func (d *Keyboard) UpdateRgbProfile(_ int, profile string) uint8 {
+	if _, ok := d.RGBModes[profile]; !ok {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	d.setRgbOverride("") // Device profile is shown again
+	d.DeviceProfile.SlipstreamRGBProfile = profile
+	d.saveDeviceProfile()
+	return 1
+}
+
+// SetRgbProfileOverride will show RGB profile without saving it to device profile
+func (d *Keyboard) SetRgbProfileOverride(profile string) uint8 {
+	if _, ok := d.RGBModes[profile]; !ok {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	d.setRgbOverride(profile)
+	return 1
+}
+
+// ClearRgbProfileOverride will show RGB profile from device profile again
+func (d *Keyboard) ClearRgbProfileOverride() {
+	d.setRgbOverride("")
+}
+
+// setRgbOverride will set RGB profile shown instead of device profile and return previous one
+func (d *Keyboard) setRgbOverride(profile string) string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	previous := d.rgbOverride
+	d.rgbOverride = profile
+	return previous
+}
+
+// ChangeDeviceBrightness will change device brightness
+func (d *Keyboard) ChangeDeviceBrightness(mode uint8) uint8 {
+	d.DeviceProfile.Brightness = mode
+	d.DeviceProfile.BrightnessLevel = 1000
+
+	switch mode {
+	case 1:
+		d.DeviceProfile.BrightnessLevel = 300
+	case 2:
+		d.DeviceProfile.BrightnessLevel = 600
+	case 3:
+		d.DeviceProfile.BrightnessLevel = 1000
+	case 4:
+		d.DeviceProfile.BrightnessLevel = 0
+	}
+
+	d.saveDeviceProfile()
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf[0:2], d.DeviceProfile.BrightnessLevel)
+	d.transfer("brightness", buf)
+	return 1
+}
+
+// ControlDeviceRgb will turn device RGB on or off
+func (d *Keyboard) ControlDeviceRgb(value bool) {
+	if d.DeviceProfile == nil {
+		return
+	}
+
+	d.DeviceProfile.RgbOff = value
+	d.saveDeviceProfile()
+}
+
+// UpdateDeviceColor will update device color based on selected input
+func (d *Keyboard) UpdateDeviceColor(_, keyOption int, color rgb.Color, _ []int) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if keyOption != 2 {
+		return 0
+	}
+
+	keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]
+	if !ok {
+		return 0
+	}
+	keyboard.Color = color
+	d.saveDeviceProfile()
+	return 1
+}
+
+// ChangeDeviceProfile will change device profile
+func (d *Keyboard) ChangeDeviceProfile(profileName string) uint8 {
+	profile, ok := d.UserProfiles[profileName]
+	if !ok {
+		return 0
+	}
+
+	d.DeviceProfile.Active = false
+	d.saveDeviceProfile()
+
+	profile.Active = true
+	d.DeviceProfile = profile
+	d.saveDeviceProfile()
+	d.setKeyAmount()
+	d.setupKeyAssignment()
+	return 1
+}
+
+// DeleteDeviceProfile deletes a device profile and its JSON file
+func (d *Keyboard) DeleteDeviceProfile(profileName string) uint8 {
+	profile, ok := d.UserProfiles[profileName]
+	if !ok {
+		return 0
+	}
+
+	if !common.IsValidExtension(profile.Path, ".json") {
+		return 0
+	}
+
+	if profile.Active {
+		return 2
+	}
+
+	if err := os.Remove(profile.Path); err != nil {
+		return 3
+	}
+
+	delete(d.UserProfiles, profileName)
+	return 1
+}
+
+// SaveUserProfile will generate a new user profile configuration and save it to a file
+func (d *Keyboard) SaveUserProfile(profileName string) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	newProfile := *d.DeviceProfile
+	newProfile.Path = pwd + "/database/profiles/" + d.Serial + "-" + profileName + ".json"
+	newProfile.Active = false
+
+	if err := common.SaveJsonData(newProfile.Path, newProfile); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": newProfile.Path}).Error("Unable to create new device profile")
+		return 0
+	}
+	d.loadDeviceProfiles()
+	return 1
+}
+
+// ChangeKeyboardLayout will change keyboard layout
+func (d *Keyboard) ChangeKeyboardLayout(layout string) uint8 {
+	if !slices.Contains(d.Layouts, layout) {
+		logger.Log(logger.Fields{"serial": d.Serial}).Warn("No such layout")
+		return 2
+	}
+
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	keyboardLayout := keyboards.GetKeyboard(fmt.Sprintf("%s-%s", keyboardKey, layout))
+	if keyboardLayout == nil {
+		logger.Log(logger.Fields{"serial": d.Serial}).Error("Trying to apply non-existing keyboard layout")
+		return 2
+	}
+
+	d.DeviceProfile.Keyboards["default"] = keyboardLayout
+	d.DeviceProfile.Layout = layout
+	d.saveDeviceProfile()
+	d.setKeyAmount()
+	d.setupKeyAssignment()
+	return 1
+}
+
+// SaveDeviceProfile will save a new keyboard profile
+func (d *Keyboard) SaveDeviceProfile(profileName string, new bool) uint8 {
+	if new {
+		if d.DeviceProfile == nil {
+			return 0
+		}
+
+		if slices.Contains(d.DeviceProfile.Profiles, profileName) {
+			return 2
+		}
+
+		if _, ok := d.DeviceProfile.Keyboards[profileName]; ok {
+			return 2
+		}
+
+		keyboard := *d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]
+		d.DeviceProfile.Profiles = append(d.DeviceProfile.Profiles, profileName)
+		d.DeviceProfile.Keyboards[profileName] = &keyboard
+	}
+	d.saveDeviceProfile()
+	return 1
+}
+
+// UpdateKeyboardProfile will change keyboard profile
+func (d *Keyboard) UpdateKeyboardProfile(profileName string) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if !slices.Contains(d.DeviceProfile.Profiles, profileName) {
+		return 2
+	}
+
+	if _, ok := d.DeviceProfile.Keyboards[profileName]; !ok {
+		return 2
+	}
+
+	d.DeviceProfile.Profile = profileName
+	d.saveDeviceProfile()
+	d.setupKeyAssignment()
+	return 1
+}
+
+// DeleteKeyboardProfile will delete keyboard profile
+func (d *Keyboard) DeleteKeyboardProfile(profileName string) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if profileName == "default" {
+		return 3
+	}
+
+	index := common.IndexOfString(d.DeviceProfile.Profiles, profileName)
+	if index < 0 {
+		return 2
+	}
+
+	if _, ok := d.DeviceProfile.Keyboards[profileName]; !ok {
+		return 2
+	}
+
+	d.DeviceProfile.Profile = "default"
+	d.DeviceProfile.Profiles = append(d.DeviceProfile.Profiles[:index], d.DeviceProfile.Profiles[index+1:]...)
+	delete(d.DeviceProfile.Keyboards, profileName)
+	d.saveDeviceProfile()
+	d.setupKeyAssignment()
+	return 1
+}
+
+// UpdateControlDial will update control dial function
+func (d *Keyboard) UpdateControlDial(value int) uint8 {
+	if _, ok := d.ControlDialOptions[value]; !ok {
+		return 0
+	}
+	d.DeviceProfile.ControlDial = value
+	d.saveDeviceProfile()
+	return 1
+}
+
+// UpdateSleepTimer will update device sleep timer
+func (d *Keyboard) UpdateSleepTimer(minutes int) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if _, ok := d.SleepModes[minutes]; !ok {
+		return 0
+	}
+
+	d.DeviceProfile.SleepMode = minutes
+	d.saveDeviceProfile()
+
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(minutes*60*1000))
+	d.transfer("sleep", buf)
+	return 1
+}
+
+// UpdatePollingRate will set device polling rate
+func (d *Keyboard) UpdatePollingRate(pullingRate int) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if _, ok := d.PollingRates[pullingRate]; !ok {
+		return 0
+	}
+
+	d.DeviceProfile.PollingRate = pullingRate
+	d.saveDeviceProfile()
+	d.transfer("pollingRate", []byte{byte(pullingRate)})
+	return 1
+}
+
+// ProcessGetKeyboardKey will get key data
+func (d *Keyboard) ProcessGetKeyboardKey(keyId int) interface{} {
+	for _, row := range d.DeviceProfile.Keyboards[d.DeviceProfile.Profile].Row {
+		if key, ok := row.Keys[keyId]; ok {
+			return key
+		}
+	}
+	return nil
+}
+
+// ProcessGetKeyAssignmentTypes will get KeyAssignmentTypes
+func (d *Keyboard) ProcessGetKeyAssignmentTypes() interface{} {
+	return d.KeyAssignmentTypes
+}
+
+// ProcessGetKeyAssignmentModifiers will get key assignment modifiers
+func (d *Keyboard) ProcessGetKeyAssignmentModifiers() interface{} {
+	if d.DeviceProfile == nil {
+		return nil
+	}
+
+	modifiers := make(map[int]string)
+	modifiers[0] = "None"
+	if keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
+		for _, rows := range keyboard.Row {
+			for keyId, key := range rows.Keys {
+				if key.Modifier {
+					modifiers[keyId] = key.KeyNameInternal
+					if len(key.KeyNameInternal) == 0 {
+						modifiers[keyId] = key.KeyName
+					}
+				}
+			}
+		}
+	}
+	return modifiers
+}
+
+// ProcessGetKeyboardPerformance will get keyboard performance values
+func (d *Keyboard) ProcessGetKeyboardPerformance() interface{} {
+	values := []common.KeyboardPerformance{
+		{
+			Name:     "Disable Win Key",
+			Type:     "checkbox",
+			Value:    d.DeviceProfile.DisableWinKey,
+			Internal: "perf_winKey",
+		},
+		{
+			Name:     "Disable Shift + Tab",
+			Type:     "checkbox",
+			Value:    d.DeviceProfile.DisableShiftTab,
+			Internal: "perf_shiftTab",
+		},
+		{
+			Name:     "Disable Alt + Tab",
+			Type:     "checkbox",
+			Value:    d.DeviceProfile.DisableAltTab,
+			Internal: "perf_altTab",
+		},
+		{
+			Name:     "Disable Alt + F4",
+			Type:     "checkbox",
+			Value:    d.DeviceProfile.DisableAltF4,
+			Internal: "perf_altF4",
+		},
+	}
+	return values
+}
+
+// ProcessSetKeyboardPerformance will set keyboard performance values
+func (d *Keyboard) ProcessSetKeyboardPerformance(performance common.KeyboardPerformanceData) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	d.DeviceProfile.DisableWinKey = performance.WinKey
+	d.DeviceProfile.DisableShiftTab = performance.ShiftTab
+	d.DeviceProfile.DisableAltF4 = performance.AltF4
+	d.DeviceProfile.DisableAltTab = performance.AltTab
+	d.saveDeviceProfile()
+	return 1
+}
+
+// isFunctionKey will check if given modifier key is Function Key
+func (d *Keyboard) isFunctionKey(keyIndex int) bool {
+	if keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]; ok {
+		for _, row := range keyboard.Row {
+			if key, found := row.Keys[keyIndex]; found && key.FunctionKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpdateDeviceKeyAssignment will update device key assignments
+func (d *Keyboard) UpdateDeviceKeyAssignment(keyIndex int, keyAssignment inputmanager.KeyAssignment) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	keyboard, ok := d.DeviceProfile.Keyboards[d.DeviceProfile.Profile]
+	if !ok {
+		return 0
+	}
+
+	for rowId, row := range keyboard.Row {
+		key, found := row.Keys[keyIndex]
+		if !found {
+			continue
+		}
+
+		if key.OnlyColor {
+			return 2
+		}
+		key.Default = keyAssignment.Default
+		key.ActionType = keyAssignment.ActionType
+		key.ActionCommand = keyAssignment.ActionCommand
+		key.DeviceId = keyAssignment.DeviceId
+		key.ActionHold = keyAssignment.ActionHold
+		key.ModifierKey = keyAssignment.ModifierKey
+		key.RetainOriginal = keyAssignment.RetainOriginal
+
+		if key.Default {
+			key.ColorOffOnFunctionKey = key.ColorOffOnFunctionKeyInternal
+		} else {
+			key.ColorOffOnFunctionKey = !d.isFunctionKey(int(keyAssignment.ModifierKey))
+		}
+
+		keyboard.Row[rowId].Keys[keyIndex] = key
+		d.saveDeviceProfile()
+		d.setupKeyAssignment()
+		return 1
+	}
+	return 0
+}
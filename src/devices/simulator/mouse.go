@@ -0,0 +1,942 @@
+package simulator
+
+// Package: simulator
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/inputmanager"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/rgb"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	mouseKeyAmount = 8
+	minDpiValue    = 100
+	maxDpiValue    = 26000
+	mouseRgbModes  = []string{
+		"colorpulse",
+		"colorshift",
+		"colorwarp",
+		"cpu-temperature",
+		"flickering",
+		"gpu-temperature",
+		"gradient",
+		"mouse",
+		"off",
+		"rainbow",
+		"pastelrainbow",
+		"rotator",
+		"static",
+		"storm",
+		"watercolor",
+		"wave",
+	}
+)
+
+// MouseZoneColors contains color of a mouse LED zone
+type MouseZoneColors struct {
+	Color      *rgb.Color
+	ColorIndex []int
+	Name       string
+}
+
+// DPIProfile contains a single mouse DPI stage
+type DPIProfile struct {
+	Name        string `json:"name"`
+	Value       uint16
+	PackerIndex int
+	ColorIndex  map[int][]int
+	Color       *rgb.Color
+	Sniper      bool
+}
+
+// MouseProfile struct contains simulated mouse profile
+type MouseProfile struct {
+	Active             bool
+	Path               string
+	Product            string
+	Serial             string
+	Brightness         uint8
+	RGBProfile         string
+	BrightnessSlider   *uint8
+	OriginalBrightness uint8
+	Label              string
+	Profile            int
+	PollingRate        int
+	DPIColor           *rgb.Color
+	ZoneColors         map[int]MouseZoneColors
+	Profiles           map[int]DPIProfile
+	SleepMode          int
+	AngleSnapping      int
+	ButtonOptimization int
+	LiftHeight         int
+	KeyAssignmentHash  string
+	LeftHandMode       int
+	RgbOff             bool
+}
+
+// Mouse is a simulated M75 WIRELESS mouse
+type Mouse struct {
+	Peripheral
+	UserProfiles       map[string]*MouseProfile `json:"userProfiles"`
+	DeviceProfile      *MouseProfile
+	Brightness         map[int]string
+	PollingRates       map[int]string
+	SleepModes         map[int]string
+	LiftHeights        map[int]string
+	KeyAssignmentTypes map[int]string
+	KeyAssignment      map[int]inputmanager.KeyAssignment
+	InputActions       map[uint16]inputmanager.InputAction
+	RGBModes           []string
+	rgbOverride        string
+	rgbOverrideMutex   sync.Mutex
+	SniperMode         bool
+	MinDPI             int
+	MaxDPI             int
+	ZoneAmount         int
+	DPIAmount          int
+}
+
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable           = (*Mouse)(nil)
+	_ capabilities.DirtyStoppable      = (*Mouse)(nil)
+	_ capabilities.Templated           = (*Mouse)(nil)
+	_ capabilities.UserProfiles        = (*Mouse)(nil)
+	_ capabilities.UserProfileDeleter  = (*Mouse)(nil)
+	_ capabilities.DeviceLabel         = (*Mouse)(nil)
+	_ capabilities.Rgb                 = (*Mouse)(nil)
+	_ capabilities.RgbProfileOverride  = (*Mouse)(nil)
+	_ capabilities.RgbProfiles         = (*Mouse)(nil)
+	_ capabilities.RgbProfileReader    = (*Mouse)(nil)
+	_ capabilities.RgbProfileEditor    = (*Mouse)(nil)
+	_ capabilities.RgbControl          = (*Mouse)(nil)
+	_ capabilities.Brightness          = (*Mouse)(nil)
+	_ capabilities.BrightnessValue     = (*Mouse)(nil)
+	_ capabilities.ScheduledBrightness = (*Mouse)(nil)
+	_ capabilities.KeyAssignment       = (*Mouse)(nil)
+	_ capabilities.KeyAssignmentLayers = (*Mouse)(nil)
+	_ capabilities.SniperMode          = (*Mouse)(nil)
+	_ capabilities.SleepTimer          = (*Mouse)(nil)
+	_ capabilities.PollingRate         = (*Mouse)(nil)
+	_ capabilities.MouseDpi            = (*Mouse)(nil)
+	_ capabilities.MouseZoneColors     = (*Mouse)(nil)
+	_ capabilities.MouseDpiColors      = (*Mouse)(nil)
+	_ capabilities.AngleSnapping       = (*Mouse)(nil)
+	_ capabilities.ButtonOptimization  = (*Mouse)(nil)
+	_ capabilities.LiftHeight          = (*Mouse)(nil)
+	_ capabilities.LeftHandMode        = (*Mouse)(nil)
+	_ capabilities.DpiRange            = (*Mouse)(nil)
+	_ capabilities.PollingRateOptions  = (*Mouse)(nil)
+	_ capabilities.SleepModeOptions    = (*Mouse)(nil)
+	_ capabilities.BatteryStatus       = (*Mouse)(nil)
+	_ capabilities.StateReader         = (*Mouse)(nil)
+)
+
+// GetDpiRange will return DPI range and amount of DPI stages of a device
+func (d *Mouse) GetDpiRange() (int, int, int) {
+	return d.MinDPI, d.MaxDPI, d.DPIAmount
+}
+
+// GetDpiStages will return DPI value of each DPI stage
+func (d *Mouse) GetDpiStages() map[int]uint16 {
+	stages := make(map[int]uint16)
+	if d.DeviceProfile == nil {
+		return stages
+	}
+	for key, profile := range d.DeviceProfile.Profiles {
+		stages[key] = profile.Value
+	}
+	return stages
+}
+
+// GetPollingRates will return polling rates supported by a device
+func (d *Mouse) GetPollingRates() map[int]string {
+	return d.PollingRates
+}
+
+// GetSleepModes will return sleep modes supported by a device
+func (d *Mouse) GetSleepModes() map[int]string {
+	return d.SleepModes
+}
+
+// GetBatteryLevel will return battery level of a device
+func (d *Mouse) GetBatteryLevel() uint16 {
+	return d.BatteryLevel
+}
+
+// GetDeviceState will return current RGB, speed, brightness and LCD settings of a device
+func (d *Mouse) GetDeviceState() capabilities.State {
+	var state capabilities.State
+	if d.DeviceProfile == nil {
+		return state
+	}
+	if state.RgbProfiles == nil && len(d.DeviceProfile.RGBProfile) > 0 {
+		state.RgbProfiles = map[int]string{0: d.DeviceProfile.RGBProfile}
+	}
+	if d.DeviceProfile.BrightnessSlider != nil {
+		brightness := *d.DeviceProfile.BrightnessSlider
+		state.Brightness = &brightness
+	}
+	if state.Brightness == nil {
+		mode := d.DeviceProfile.Brightness
+		state.BrightnessMode = &mode
+	}
+	return state
+}
+
+// initMouse will initialize a new simulated mouse
+func initMouse(kind, serial string, layout Layout) *common.Device {
+	d := &Mouse{
+		Peripheral: newPeripheral(kind, serial, "m75W.html", layout, mouseRgbModes),
+		Brightness: map[int]string{
+			0: "RGB Profile",
+			1: "33 %",
+			2: "66 %",
+			3: "100 %",
+		},
+		SleepModes: map[int]string{
+			1:  "1 minute",
+			5:  "5 minutes",
+			10: "10 minutes",
+			15: "15 minutes",
+			30: "30 minutes",
+			60: "1 hour",
+		},
+		LiftHeights: map[int]string{
+			2: "Low",
+			3: "Medium",
+			4: "High",
+			6: "Calibrated",
+		},
+		PollingRates: map[int]string{
+			0: "Not Set",
+			1: "125 Hz / 8 msec",
+			2: "250 Hz / 4 msec",
+			3: "500 Hz / 2 msec",
+			4: "1000 Hz / 1 msec",
+			5: "2000 Hz / 0.5 msec",
+			6: "4000 Hz / 0.25 msec",
+			7: "8000 Hz / 0.125 msec",
+		},
+		KeyAssignmentTypes: map[int]string{
+			0:  "None",
+			1:  "Media Keys",
+			2:  "DPI",
+			3:  "Keyboard",
+			8:  "Sniper",
+			9:  "Mouse",
+			10: "Macro",
+			11: "Profile Switch",
+		},
+		InputActions: inputmanager.GetInputActions(),
+		RGBModes:     mouseRgbModes,
+		MinDPI:       minDpiValue,
+		MaxDPI:       maxDpiValue,
+		ZoneAmount:   2,
+		DPIAmount:    6,
+	}
+
+	d.loadRgb()            // Load RGB
+	d.loadDeviceProfiles() // Load all device profiles
+	d.saveDeviceProfile()  // Save profile
+	d.loadKeyAssignments() // Key Assignments
+	d.setupKeyAssignment() // Upload key assignments
+	d.toggleDPI()          // DPI
+	d.setAutoRefresh()     // Set battery discharge
+	d.createDevice(d)      // Device register
+	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Simulated device successfully initialized")
+
+	return d.instance
+}
+
+// newDpiProfile will return DPI stage with default colors
+func newDpiProfile(name string, value uint16, packerIndex int, red, green, blue float64) DPIProfile {
+	return DPIProfile{
+		Name:        name,
+		Value:       value,
+		PackerIndex: packerIndex,
+		ColorIndex: map[int][]int{
+			0: {1, 3, 5},
+			1: {0, 2, 4},
+		},
+		Color: &rgb.Color{
+			Red:        red,
+			Green:      green,
+			Blue:       blue,
+			Brightness: 1,
+			Hex:        fmt.Sprintf("#%02x%02x%02x", int(red), int(green), int(blue)),
+		},
+	}
+}
+
+// saveDeviceProfile will save device profile for persistent configuration
+func (d *Mouse) saveDeviceProfile() {
+	var defaultBrightness = uint8(100)
+	profilePath := pwd + "/database/profiles/" + d.Serial + ".json"
+
+	deviceProfile := &MouseProfile{
+		Product:            d.Product,
+		Serial:             d.Serial,
+		Path:               profilePath,
+		BrightnessSlider:   &defaultBrightness,
+		OriginalBrightness: 100,
+	}
+
+	if d.DeviceProfile == nil {
+		sniper := newDpiProfile("Sniper", 200, 3, 255, 255, 0)
+		sniper.Sniper = true
+
+		deviceProfile.RGBProfile = "mouse"
+		deviceProfile.Label = "Mouse"
+		deviceProfile.Active = true
+		deviceProfile.ZoneColors = map[int]MouseZoneColors{
+			0: {
+				ColorIndex: []int{1, 3, 5},
+				Color:      &rgb.Color{Red: 255, Green: 0, Blue: 0, Brightness: 1, Hex: "#ff0000"},
+				Name:       "Bottom",
+			},
+			1: {
+				ColorIndex: []int{0, 2, 4},
+				Color:      &rgb.Color{Red: 255, Green: 255, Blue: 0, Brightness: 1, Hex: "#ffff00"},
+				Name:       "Logo",
+			},
+		}
+		deviceProfile.Profiles = map[int]DPIProfile{
+			0: newDpiProfile("Stage 1", 400, 1, 255, 0, 0),
+			1: newDpiProfile("Stage 2", 800, 2, 255, 165, 0),
+			2: newDpiProfile("Stage 3", 1200, 3, 255, 255, 0),
+			3: newDpiProfile("Stage 4", 1600, 3, 0, 255, 0),
+			4: newDpiProfile("Stage 5", 3200, 3, 0, 0, 255),
+			5: sniper,
+		}
+		deviceProfile.Profile = 1
+		deviceProfile.SleepMode = 15
+		deviceProfile.PollingRate = 4
+		deviceProfile.LiftHeight = 2
+	} else {
+		if d.DeviceProfile.LiftHeight == 0 {
+			deviceProfile.LiftHeight = 2
+		} else {
+			deviceProfile.LiftHeight = d.DeviceProfile.LiftHeight
+		}
+
+		if d.DeviceProfile.BrightnessSlider != nil {
+			deviceProfile.BrightnessSlider = d.DeviceProfile.BrightnessSlider
+		}
+
+		if d.DeviceProfile.SleepMode == 0 {
+			deviceProfile.SleepMode = 15
+		} else {
+			deviceProfile.SleepMode = d.DeviceProfile.SleepMode
+		}
+
+		if d.DeviceProfile.PollingRate == 0 {
+			deviceProfile.PollingRate = 4
+		} else {
+			deviceProfile.PollingRate = d.DeviceProfile.PollingRate
+		}
+
+		deviceProfile.Active = d.DeviceProfile.Active
+		deviceProfile.Brightness = d.DeviceProfile.Brightness
+		deviceProfile.OriginalBrightness = d.DeviceProfile.OriginalBrightness
+		deviceProfile.RGBProfile = d.DeviceProfile.RGBProfile
+		deviceProfile.Label = d.DeviceProfile.Label
+		deviceProfile.Profiles = d.DeviceProfile.Profiles
+		deviceProfile.Profile = d.DeviceProfile.Profile
+		deviceProfile.DPIColor = d.DeviceProfile.DPIColor
+		deviceProfile.ZoneColors = d.DeviceProfile.ZoneColors
+		deviceProfile.AngleSnapping = d.DeviceProfile.AngleSnapping
+		deviceProfile.ButtonOptimization = d.DeviceProfile.ButtonOptimization
+		deviceProfile.KeyAssignmentHash = d.DeviceProfile.KeyAssignmentHash
+		deviceProfile.LeftHandMode = d.DeviceProfile.LeftHandMode
+		deviceProfile.RgbOff = d.DeviceProfile.RgbOff
+		if len(d.DeviceProfile.Path) > 0 {
+			deviceProfile.Path = d.DeviceProfile.Path
+		}
+	}
+
+	// Fix profile paths if folder database/ folder is moved
+	filename := filepath.Base(deviceProfile.Path)
+	deviceProfile.Path = fmt.Sprintf("%s/database/profiles/%s", pwd, filename)
+
+	// Save profile
+	if err := common.SaveJsonData(deviceProfile.Path, deviceProfile); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": deviceProfile.Path}).Error("Unable to write device profile data")
+		return
+	}
+
+	d.loadDeviceProfiles()
+}
+
+// loadDeviceProfiles will load custom user profiles
+func (d *Mouse) loadDeviceProfiles() {
+	profileList := make(map[string]*MouseProfile)
+	userProfileDirectory := pwd + "/database/profiles/"
+
+	files, err := os.ReadDir(userProfileDirectory)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "location": userProfileDirectory, "serial": d.Serial}).Error("Unable to read content of a folder")
+		return
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue // Exclude folders if any
+		}
+
+		// Define a full path of filename
+		profileLocation := userProfileDirectory + fi.Name()
+
+		// Check if filename has .json extension
+		if !common.IsValidExtension(profileLocation, ".json") {
+			continue
+		}
+
+		fileName := strings.Split(fi.Name(), ".")[0]
+		if !common.AlphanumericDashRegex.MatchString(fileName) {
+			continue
+		}
+
+		if strings.Split(fileName, "-")[0] != d.Serial {
+			continue
+		}
+
+		pf := &MouseProfile{}
+		file, err := os.Open(profileLocation)
+		if err != nil {
+			logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": profileLocation}).Warn("Unable to load profile")
+			continue
+		}
+		if err = json.NewDecoder(file).Decode(pf); err != nil {
+			logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": profileLocation}).Warn("Unable to decode profile")
+		}
+		if err = file.Close(); err != nil {
+			logger.Log(logger.Fields{"location": profileLocation, "serial": d.Serial}).Warn("Failed to close file handle")
+		}
+
+		if pf.Serial != d.Serial {
+			continue
+		}
+		if fileName == d.Serial {
+			profileList["default"] = pf
+		} else {
+			profileList[strings.Split(fileName, "-")[1]] = pf
+		}
+	}
+	d.UserProfiles = profileList
+
+	for _, pf := range d.UserProfiles {
+		if pf.Active {
+			d.DeviceProfile = pf
+		}
+	}
+}
+
+// getKeyAssignmentFile will return location of key assignments for current profile
+func (d *Mouse) getKeyAssignmentFile() string {
+	if len(d.DeviceProfile.KeyAssignmentHash) > 0 {
+		return fmt.Sprintf("%s/database/key-assignments/%s.json", pwd, d.DeviceProfile.KeyAssignmentHash)
+	}
+	return fmt.Sprintf("%s/database/key-assignments/%s.json", pwd, d.Serial)
+}
+
+// saveKeyAssignments will save new key assignments
+func (d *Mouse) saveKeyAssignments() {
+	keyAssignmentsFile := d.getKeyAssignmentFile()
+	if err := common.SaveJsonData(keyAssignmentsFile, d.KeyAssignment); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": keyAssignmentsFile}).Error("Unable to write key assignment data")
+	}
+}
+
+// loadKeyAssignments will load custom key assignments
+func (d *Mouse) loadKeyAssignments() {
+	if d.DeviceProfile == nil {
+		return
+	}
+
+	keyAssignmentsFile := d.getKeyAssignmentFile()
+	if common.FileExists(keyAssignmentsFile) {
+		file, err := os.Open(keyAssignmentsFile)
+		if err != nil {
+			logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": keyAssignmentsFile}).Warn("Unable to load JSON file")
+			return
+		}
+		defer func(file *os.File) {
+			if err = file.Close(); err != nil {
+				logger.Log(logger.Fields{"location": keyAssignmentsFile, "serial": d.Serial}).Warn("Failed to close file handle")
+			}
+		}(file)
+
+		if err = json.NewDecoder(file).Decode(&d.KeyAssignment); err != nil {
+			logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": keyAssignmentsFile}).Warn("Unable to decode key assignments JSON")
+			return
+		}
+
+		// Prevent left click modifications
+		if val, ok := d.KeyAssignment[1]; ok && !val.Default {
+			logger.Log(logger.Fields{"serial": d.Serial}).Warn("Restoring left button to original value")
+			val.Default = true
+			d.KeyAssignment[1] = val
+		}
+		return
+	}
+
+	names := map[int]string{
+		128: "DPI Button",
+		64:  "Right Back",
+		32:  "Right Forward",
+		16:  "Left Back",
+		8:   "Left Forward",
+		4:   "Middle Button",
+		2:   "Right Button",
+		1:   "Left Button",
+	}
+	keyAssignment := make(map[int]inputmanager.KeyAssignment, len(names))
+	for key, name := range names {
+		keyAssignment[key] = inputmanager.KeyAssignment{Name: name, Default: true}
+	}
+
+	if err := common.SaveJsonData(keyAssignmentsFile, keyAssignment); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": keyAssignmentsFile}).Error("Unable to save key assignments data")
+		return
+	}
+	d.KeyAssignment = keyAssignment
+}
+
+// setupKeyAssignment will emulate upload of mouse key assignments
+func (d *Mouse) setupKeyAssignment() {
+	if len(d.KeyAssignment) != mouseKeyAmount {
+		logger.Log(logger.Fields{"serial": d.Serial, "keys": len(d.KeyAssignment), "expected": mouseKeyAmount}).Warn("Expected key amount does not match the expected key amount.")
+		return
+	}
+
+	keys := make([]int, 0, len(d.KeyAssignment))
+	for k := range d.KeyAssignment {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	buf := make([]byte, mouseKeyAmount)
+	for i, k := range keys {
+		if d.KeyAssignment[k].Default && !inputmanager.IsLayerOverride(d.Serial, strconv.Itoa(k)) {
+			buf[i] = 0x01
+		}
+	}
+	d.transfer("keyAssignment", buf)
+}
+
+// ApplyKeyAssignmentLayer will apply key assignments of active key assignment layer
+func (d *Mouse) ApplyKeyAssignmentLayer() {
+	d.setupKeyAssignment()
+}
+
+// UpdateDeviceKeyAssignment will update device key assignments
+func (d *Mouse) UpdateDeviceKeyAssignment(keyIndex int, keyAssignment inputmanager.KeyAssignment) uint8 {
+	val, ok := d.KeyAssignment[keyIndex]
+	if !ok {
+		return 0
+	}
+
+	// Left button is always kept to its original value
+	if keyIndex == 1 && !keyAssignment.Default {
+		return 0
+	}
+
+	val.Default = keyAssignment.Default
+	val.ActionHold = keyAssignment.ActionHold
+	val.ActionType = keyAssignment.ActionType
+	val.ActionCommand = keyAssignment.ActionCommand
+	val.IsMacro = keyAssignment.IsMacro
+	val.OnRelease = keyAssignment.OnRelease
+	d.KeyAssignment[keyIndex] = val
+	d.saveKeyAssignments()
+	d.setupKeyAssignment()
+	return 1
+}
+
+// setDpi will emulate upload of DPI value
+func (d *Mouse) setDpi(value uint16) {
+	if value < uint16(minDpiValue) {
+		value = uint16(minDpiValue)
+	}
+	if value > uint16(maxDpiValue) {
+		value = uint16(maxDpiValue)
+	}
+
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf[0:2], value)
+	d.transfer("dpi", buf)
+}
+
+// toggleDPI will apply DPI of current stage
+func (d *Mouse) toggleDPI() {
+	if d.Exit || d.DeviceProfile == nil {
+		return
+	}
+
+	if profile, ok := d.DeviceProfile.Profiles[d.DeviceProfile.Profile]; ok {
+		d.setDpi(profile.Value)
+	}
+}
+
+// CallSniperMode will switch mouse DPI to sniper stage while active
+func (d *Mouse) CallSniperMode(active bool) {
+	if d.DeviceProfile == nil {
+		return
+	}
+
+	d.SniperMode = active
+	if !active {
+		d.toggleDPI()
+		return
+	}
+
+	for _, profile := range d.DeviceProfile.Profiles {
+		if profile.Sniper {
+			d.setDpi(profile.Value)
+			return
+		}
+	}
+}
+
+// SaveMouseDPI will save mouse DPI
+func (d *Mouse) SaveMouseDPI(stages map[int]uint16) uint8 {
+	if d.DeviceProfile == nil || len(stages) == 0 {
+		return 0
+	}
+
+	i := 0
+	for key, stage := range stages {
+		profile, ok := d.DeviceProfile.Profiles[key]
+		if !ok {
+			continue
+		}
+		if stage > uint16(maxDpiValue) || stage < uint16(minDpiValue) {
+			continue
+		}
+		profile.Value = stage
+		d.DeviceProfile.Profiles[key] = profile
+		i++
+	}
+
+	if i > 0 {
+		d.saveDeviceProfile()
+		d.toggleDPI()
+		return 1
+	}
+	return 0
+}
+
+// isValidColor will return true when color components are within range
+func isValidColor(color rgb.Color) bool {
+	return color.Red >= 0 && color.Red <= 255 &&
+		color.Green >= 0 && color.Green <= 255 &&
+		color.Blue >= 0 && color.Blue <= 255
+}
+
+// SaveMouseDpiColors will save mouse dpi colors
+func (d *Mouse) SaveMouseDpiColors(dpi rgb.Color, dpiColors map[int]rgb.Color) uint8 {
+	if d.DeviceProfile == nil || !isValidColor(dpi) {
+		return 0
+	}
+
+	i := 0
+	for key, color := range dpiColors {
+		profile, ok := d.DeviceProfile.Profiles[key]
+		if !ok || !isValidColor(color) {
+			continue
+		}
+		profile.Color.Red = color.Red
+		profile.Color.Green = color.Green
+		profile.Color.Blue = color.Blue
+		profile.Color.Hex = fmt.Sprintf("#%02x%02x%02x", int(color.Red), int(color.Green), int(color.Blue))
+		i++
+	}
+
+	if i > 0 {
+		d.saveDeviceProfile()
+		return 1
+	}
+	return 0
+}
+
+// SaveMouseZoneColors will save mouse zone colors
+func (d *Mouse) SaveMouseZoneColors(_ rgb.Color, zoneColors map[int]rgb.Color) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	i := 0
+	for key, color := range zoneColors {
+		zone, ok := d.DeviceProfile.ZoneColors[key]
+		if !ok || !isValidColor(color) {
+			continue
+		}
+		zone.Color.Red = color.Red
+		zone.Color.Green = color.Green
+		zone.Color.Blue = color.Blue
+		zone.Color.Hex = fmt.Sprintf("#%02x%02x%02x", int(color.Red), int(color.Green), int(color.Blue))
+		i++
+	}
+
+	if i > 0 {
+		d.saveDeviceProfile()
+		return 1
+	}
+	return 0
+}
+
+// UpdatePollingRate will set device polling rate
+func (d *Mouse) UpdatePollingRate(pullingRate int) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if _, ok := d.PollingRates[pullingRate]; !ok {
+		return 0
+	}
+
+	d.DeviceProfile.PollingRate = pullingRate
+	d.saveDeviceProfile()
+	d.transfer("pollingRate", []byte{byte(pullingRate)})
+	return 1
+}
+
+// UpdateSleepTimer will update device sleep timer
+func (d *Mouse) UpdateSleepTimer(minutes int) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if _, ok := d.SleepModes[minutes]; !ok {
+		return 0
+	}
+
+	d.DeviceProfile.SleepMode = minutes
+	d.saveDeviceProfile()
+
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(minutes*60*1000))
+	d.transfer("sleep", buf)
+	return 1
+}
+
+// UpdateAngleSnapping will update angle snapping mode
+func (d *Mouse) UpdateAngleSnapping(angleSnappingMode int) uint8 {
+	if d.DeviceProfile == nil || d.DeviceProfile.AngleSnapping == angleSnappingMode {
+		return 0
+	}
+
+	d.DeviceProfile.AngleSnapping = angleSnappingMode
+	d.saveDeviceProfile()
+	d.transfer("angleSnapping", []byte{byte(angleSnappingMode)})
+	return 1
+}
+
+// UpdateButtonOptimization will update button response optimization mode
+func (d *Mouse) UpdateButtonOptimization(buttonOptimizationMode int) uint8 {
+	if d.DeviceProfile == nil || d.DeviceProfile.ButtonOptimization == buttonOptimizationMode {
+		return 0
+	}
+
+	d.DeviceProfile.ButtonOptimization = buttonOptimizationMode
+	d.saveDeviceProfile()
+	d.transfer("buttonOptimization", []byte{byte(buttonOptimizationMode)})
+	return 1
+}
+
+// UpdateLiftHeight will update lift height
+func (d *Mouse) UpdateLiftHeight(liftHeight int) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	if _, ok := d.LiftHeights[liftHeight]; !ok || d.DeviceProfile.LiftHeight == liftHeight {
+		return 0
+	}
+
+	d.DeviceProfile.LiftHeight = liftHeight
+	d.saveDeviceProfile()
+	d.transfer("liftHeight", []byte{byte(liftHeight)})
+	return 1
+}
+
+// UpdateLeftHandMode will update button left hand mode
+func (d *Mouse) UpdateLeftHandMode(leftHandMode int) uint8 {
+	if d.DeviceProfile == nil || d.DeviceProfile.LeftHandMode == leftHandMode {
+		return 0
+	}
+
+	d.DeviceProfile.LeftHandMode = leftHandMode
+	d.saveDeviceProfile()
+	d.transfer("leftHandMode", []byte{byte(leftHandMode)})
+	return 1
+}
+
+// UpdateDeviceLabel will set / update device label
+func (d *Mouse) UpdateDeviceLabel(_ int, label string) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+	d.DeviceProfile.Label = label
+	d.saveDeviceProfile()
+	return 1
+}
+
+// UpdateRgbProfile will update device RGB profile
+func (d *Mouse) UpdateRgbProfile(_ int, profile string) uint8 {
+	if d.GetRgbProfile(profile) == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+	d.setRgbOverride("") // Device profile is shown again
+	d.DeviceProfile.RGBProfile = profile
+	d.saveDeviceProfile()
+	return 1
+}
+
+// SetRgbProfileOverride will show RGB profile without saving it to device profile
+func (d *Mouse) SetRgbProfileOverride(profile string) uint8 {
+	if d.GetRgbProfile(profile) == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profile}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	d.setRgbOverride(profile)
+	return 1
+}
+
+// ClearRgbProfileOverride will show RGB profile from device profile again
+func (d *Mouse) ClearRgbProfileOverride() {
+	d.setRgbOverride("")
+}
+
+// setRgbOverride will set RGB profile shown instead of device profile and return previous one
+func (d *Mouse) setRgbOverride(profile string) string {
+	d.rgbOverrideMutex.Lock()
+	defer d.rgbOverrideMutex.Unlock()
+
+	previous := d.rgbOverride
+	d.rgbOverride = profile
+	return previous
+}
+
+// ChangeDeviceBrightness will change device brightness
+func (d *Mouse) ChangeDeviceBrightness(mode uint8) uint8 {
+	d.DeviceProfile.Brightness = mode
+	d.saveDeviceProfile()
+	return 1
+}
+
+// ChangeDeviceBrightnessValue will change device brightness via slider
+func (d *Mouse) ChangeDeviceBrightnessValue(value uint8) uint8 {
+	if value > 100 {
+		return 0
+	}
+
+	d.DeviceProfile.BrightnessSlider = &value
+	d.saveDeviceProfile()
+	return 1
+}
+
+// SchedulerBrightness will change device brightness via scheduler
+func (d *Mouse) SchedulerBrightness(value uint8) uint8 {
+	if value == 0 {
+		d.DeviceProfile.OriginalBrightness = *d.DeviceProfile.BrightnessSlider
+		d.DeviceProfile.BrightnessSlider = &value
+	} else {
+		d.DeviceProfile.BrightnessSlider = &d.DeviceProfile.OriginalBrightness
+	}
+	d.saveDeviceProfile()
+	return 1
+}
+
+// ControlDeviceRgb will turn device RGB on or off
+func (d *Mouse) ControlDeviceRgb(value bool) {
+	if d.DeviceProfile == nil {
+		return
+	}
+
+	d.DeviceProfile.RgbOff = value
+	d.saveDeviceProfile()
+}
+
+// ChangeDeviceProfile will change device profile
+func (d *Mouse) ChangeDeviceProfile(profileName string) uint8 {
+	profile, ok := d.UserProfiles[profileName]
+	if !ok {
+		return 0
+	}
+
+	d.DeviceProfile.Active = false
+	d.saveDeviceProfile()
+
+	profile.Active = true
+	d.DeviceProfile = profile
+	d.saveDeviceProfile()
+	d.toggleDPI()
+	d.loadKeyAssignments()
+	d.setupKeyAssignment()
+	return 1
+}
+
+// DeleteDeviceProfile deletes a device profile and its JSON file
+func (d *Mouse) DeleteDeviceProfile(profileName string) uint8 {
+	profile, ok := d.UserProfiles[profileName]
+	if !ok {
+		return 0
+	}
+
+	if !common.IsValidExtension(profile.Path, ".json") {
+		return 0
+	}
+
+	if profile.Active {
+		return 2
+	}
+
+	if err := os.Remove(profile.Path); err != nil {
+		return 3
+	}
+
+	delete(d.UserProfiles, profileName)
+	return 1
+}
+
+// SaveUserProfile will generate a new user profile configuration and save it to a file
+func (d *Mouse) SaveUserProfile(profileName string) uint8 {
+	if d.DeviceProfile == nil {
+		return 0
+	}
+
+	newProfile := *d.DeviceProfile
+	newProfile.Path = pwd + "/database/profiles/" + d.Serial + "-" + profileName + ".json"
+	newProfile.Active = false
+	newProfile.KeyAssignmentHash = common.GenerateRandomMD5()
+
+	if err := common.SaveJsonData(newProfile.Path, newProfile); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": newProfile.Path}).Error("Unable to create new device profile")
+		return 0
+	}
+
+	keyAssignmentsFile := fmt.Sprintf("%s/database/key-assignments/%s.json", pwd, newProfile.KeyAssignmentHash)
+	if err := common.SaveJsonData(keyAssignmentsFile, d.KeyAssignment); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": keyAssignmentsFile}).Error("Unable to write key assignment data")
+		return 0
+	}
+	d.loadDeviceProfiles()
+	return 1
+}
@@ -0,0 +1,319 @@
+package simulator
+
+// Package: simulator
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/rgb"
+	"OpenLinkHub/src/stats"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	batteryInterval = 60000
+	batteryLow      = uint16(5)
+)
+
+// Peripheral contains state shared by all simulated wireless peripherals
+type Peripheral struct {
+	Debug           bool
+	Kind            string `json:"kind"`
+	Product         string `json:"product"`
+	Serial          string `json:"serial"`
+	Firmware        string `json:"firmware"`
+	Template        string
+	Connected       bool
+	Usb             bool
+	BatteryLevel    uint16
+	Charging        bool
+	Rgb             *rgb.RGB
+	Exit            bool
+	rgbModes        []string
+	layout          Layout
+	autoRefreshChan chan struct{}
+	timer           *time.Ticker
+	rgbMutex        sync.RWMutex
+	deviceLock      sync.Mutex
+	instance        *common.Device
+}
+
+// newPeripheral will initialize state of a simulated wireless peripheral
+func newPeripheral(kind, serial, template string, layout Layout, rgbModes []string) Peripheral {
+	return Peripheral{
+		Debug:           config.GetConfig().Debug,
+		Kind:            kind,
+		Product:         layout.Product,
+		Serial:          serial,
+		Firmware:        layout.Firmware,
+		Template:        template,
+		Connected:       true,
+		BatteryLevel:    100,
+		rgbModes:        rgbModes,
+		layout:          layout,
+		autoRefreshChan: make(chan struct{}),
+		timer:           &time.Ticker{},
+	}
+}
+
+// createDevice will create new device register object
+func (d *Peripheral) createDevice(instance interface{}) {
+	d.instance = &common.Device{
+		ProductType: common.ProductTypeSimulator,
+		Product:     d.Product,
+		Serial:      d.Serial,
+		Firmware:    d.Firmware,
+		Image:       d.layout.Image,
+		Instance:    instance,
+		GetDevice:   instance,
+		DeviceType:  d.layout.DeviceType,
+	}
+}
+
+// Stop will stop all device operations
+func (d *Peripheral) Stop() {
+	d.Exit = true
+	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Stopping device...")
+
+	d.timer.Stop()
+	var once sync.Once
+	go func() {
+		once.Do(func() {
+			if d.autoRefreshChan != nil {
+				close(d.autoRefreshChan)
+			}
+		})
+	}()
+	d.Connected = false
+	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Device stopped")
+}
+
+// StopDirty will stop device in a dirty way
+func (d *Peripheral) StopDirty() uint8 {
+	d.Stop()
+	return 1
+}
+
+// GetDeviceTemplate will return device template name
+func (d *Peripheral) GetDeviceTemplate() string {
+	return d.Template
+}
+
+// transfer will emulate a write of device settings
+func (d *Peripheral) transfer(command string, buffer []byte) {
+	if d.Debug {
+		logger.Log(logger.Fields{"serial": d.Serial, "command": command, "data": hex.EncodeToString(buffer)}).Info("Simulated transfer")
+	}
+}
+
+// loadRgb will load RGB file if found, or create the default.
+func (d *Peripheral) loadRgb() {
+	rgbFilename := pwd + "/database/rgb/" + d.Serial + ".json"
+
+	if !common.FileExists(rgbFilename) {
+		profile := rgb.GetRGB()
+		profile.Device = d.Product
+
+		if err := common.SaveJsonData(rgbFilename, profile); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": rgbFilename}).Error("Unable to write rgb profile data")
+			return
+		}
+	}
+
+	file, err := os.Open(rgbFilename)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": rgbFilename}).Warn("Unable to load RGB")
+		return
+	}
+	defer func(file *os.File) {
+		if err = file.Close(); err != nil {
+			logger.Log(logger.Fields{"location": rgbFilename, "serial": d.Serial}).Warn("Failed to close file handle")
+		}
+	}(file)
+
+	if err = json.NewDecoder(file).Decode(&d.Rgb); err != nil {
+		logger.Log(logger.Fields{"error": err, "serial": d.Serial, "location": rgbFilename}).Warn("Unable to decode profile")
+	}
+}
+
+// saveRgbProfile will save rgb profile data
+func (d *Peripheral) saveRgbProfile() {
+	rgbFilename := pwd + "/database/rgb/" + d.Serial + ".json"
+	if common.FileExists(rgbFilename) {
+		if err := common.SaveJsonData(rgbFilename, d.Rgb); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": rgbFilename}).Error("Unable to write rgb profile data")
+		}
+	}
+}
+
+// GetRgbProfiles will return RGB profiles for a target device
+func (d *Peripheral) GetRgbProfiles() interface{} {
+	if d.Rgb == nil {
+		return nil
+	}
+	tmp := *d.Rgb
+
+	// Filter unsupported modes out
+	profiles := make(map[string]rgb.Profile, len(tmp.Profiles))
+	for key, value := range tmp.Profiles {
+		if slices.Contains(d.rgbModes, key) {
+			profiles[key] = value
+		}
+	}
+	tmp.Profiles = profiles
+	return tmp
+}
+
+// GetRgbProfile will return rgb.Profile struct
+func (d *Peripheral) GetRgbProfile(profile string) *rgb.Profile {
+	if d.Rgb == nil {
+		return nil
+	}
+
+	if val, ok := d.Rgb.Profiles[profile]; ok {
+		return &val
+	}
+	return nil
+}
+
+// ProcessNewGradientColor will create new gradient color
+func (d *Peripheral) ProcessNewGradientColor(profileName string) (uint8, uint) {
+	pf := d.GetRgbProfile(profileName)
+	if pf == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profileName}).Warn("Non-existing RGB profile")
+		return 0, 0
+	}
+
+	if pf.Gradients == nil {
+		return 0, 0
+	}
+
+	// find next available key
+	nextID := 0
+	for k := range pf.Gradients {
+		if k >= nextID {
+			nextID = k + 1
+		}
+	}
+	pf.Gradients[nextID] = rgb.Color{Red: 0, Green: 255, Blue: 255}
+
+	d.Rgb.Profiles[profileName] = *pf
+	d.saveRgbProfile()
+	return 1, uint(nextID)
+}
+
+// ProcessDeleteGradientColor will delete gradient color
+func (d *Peripheral) ProcessDeleteGradientColor(profileName string) (uint8, uint) {
+	pf := d.GetRgbProfile(profileName)
+	if pf == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profileName}).Warn("Non-existing RGB profile")
+		return 0, 0
+	}
+
+	if len(pf.Gradients) < 3 {
+		return 2, 0
+	}
+
+	maxKey := -1
+	for k := range pf.Gradients {
+		if k > maxKey {
+			maxKey = k
+		}
+	}
+	delete(pf.Gradients, maxKey)
+
+	d.Rgb.Profiles[profileName] = *pf
+	d.saveRgbProfile()
+	return 1, uint(maxKey)
+}
+
+// UpdateRgbProfileData will update RGB profile data
+func (d *Peripheral) UpdateRgbProfileData(profileName string, profile rgb.Profile) uint8 {
+	d.rgbMutex.Lock()
+	defer d.rgbMutex.Unlock()
+
+	pf := d.GetRgbProfile(profileName)
+	if pf == nil {
+		logger.Log(logger.Fields{"serial": d.Serial, "profile": profileName}).Warn("Non-existing RGB profile")
+		return 0
+	}
+
+	if profile.StartColor.Temperature < 0 || profile.StartColor.Temperature > 105 {
+		return 0
+	}
+
+	if profile.MiddleColor.Temperature < 0 || profile.MiddleColor.Temperature > 105 {
+		return 0
+	}
+
+	if profile.EndColor.Temperature < 0 || profile.EndColor.Temperature > 105 {
+		return 0
+	}
+
+	profile.StartColor.Brightness = pf.StartColor.Brightness
+	profile.EndColor.Brightness = pf.EndColor.Brightness
+	profile.MiddleColor.Brightness = pf.MiddleColor.Brightness
+	pf.StartColor = profile.StartColor
+	pf.EndColor = profile.EndColor
+	pf.MiddleColor = profile.MiddleColor
+	pf.Speed = profile.Speed
+	pf.Gradients = profile.Gradients
+	pf.Sensor = profile.Sensor
+
+	d.Rgb.Profiles[profileName] = *pf
+	d.saveRgbProfile()
+	return 1
+}
+
+// setBatteryLevel will report current battery level
+func (d *Peripheral) setBatteryLevel() {
+	stats.UpdateBatteryStats(d.Serial, d.Product, d.BatteryLevel, d.layout.BatteryType)
+}
+
+// discharge will drain battery and recharge it once it runs low
+func (d *Peripheral) discharge() {
+	d.deviceLock.Lock()
+	defer d.deviceLock.Unlock()
+
+	if d.Charging {
+		d.BatteryLevel += 5
+		if d.BatteryLevel >= 100 {
+			d.BatteryLevel = 100
+			d.Charging = false
+		}
+	} else {
+		d.BatteryLevel--
+		if d.BatteryLevel <= batteryLow {
+			d.Charging = true
+		}
+	}
+}
+
+// setAutoRefresh will periodically discharge battery
+func (d *Peripheral) setAutoRefresh() {
+	d.setBatteryLevel()
+	d.timer = time.NewTicker(time.Duration(batteryInterval) * time.Millisecond)
+	go func() {
+		for {
+			select {
+			case <-d.timer.C:
+				if d.Exit {
+					return
+				}
+				d.discharge()
+				d.setBatteryLevel()
+			case <-d.autoRefreshChan:
+				d.timer.Stop()
+				return
+			}
+		}
+	}()
+}
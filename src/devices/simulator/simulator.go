@@ -0,0 +1,128 @@
+package simulator
+
+// Package: simulator
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/rgb"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	KindLinkHub = "linkhub"
+	KindCC      = "cc"
+	KindK70     = "k70"
+	KindMouse   = "mouse"
+)
+
+// Layout defines hardware emulated by a simulated device
+type Layout struct {
+	Product           string
+	Image             string
+	DeviceType        uint32
+	Firmware          string
+	Pump              bool
+	PumpChannel       int
+	Lcd               bool
+	Fans              int
+	FanLeds           uint8
+	PumpLeds          uint8
+	LedChannels       int
+	BatteryType       uint8
+	FanMinRpm         int16
+	FanMaxRpm         int16
+	PumpMinRpm        int16
+	PumpMaxRpm        int16
+	TemperatureProbes int
+}
+
+var (
+	pwd     = ""
+	layouts = map[string]Layout{
+		KindLinkHub: {
+			Product:     "iCUE LINK System Hub (Simulated)",
+			Image:       "icon-device.svg",
+			DeviceType:  common.DeviceTypeCooler,
+			Firmware:    "2.6.201",
+			Pump:        true,
+			PumpChannel: 1,
+			Fans:        3,
+			FanLeds:     34,
+			PumpLeds:    20,
+			FanMinRpm:   350,
+			FanMaxRpm:   2400,
+			PumpMinRpm:  1200,
+			PumpMaxRpm:  2800,
+		},
+		KindCC: {
+			Product:           "iCUE COMMANDER CORE (Simulated)",
+			Image:             "icon-device.svg",
+			DeviceType:        common.DeviceTypeCooler,
+			Firmware:          "2.10.219",
+			Pump:              true,
+			PumpChannel:       0,
+			Lcd:               true,
+			Fans:              6,
+			FanLeds:           34,
+			PumpLeds:          29,
+			FanMinRpm:         400,
+			FanMaxRpm:         2100,
+			PumpMinRpm:        1000,
+			PumpMaxRpm:        2500,
+			TemperatureProbes: 2,
+		},
+		KindK70: {
+			Product:     "K70 CORE TKL WIRELESS (Simulated)",
+			Image:       "icon-keyboard.svg",
+			DeviceType:  common.DeviceTypeKeyboard,
+			Firmware:    "1.12.45",
+			LedChannels: 87,
+			BatteryType: 0,
+		},
+		KindMouse: {
+			Product:     "M75 WIRELESS (Simulated)",
+			Image:       "icon-mouse.svg",
+			DeviceType:  common.DeviceTypeMouse,
+			Firmware:    "3.4.12",
+			LedChannels: 2,
+			BatteryType: 1,
+		},
+	}
+)
+
+// Init will initialize a new simulated device of a given kind
+func Init(kind string, index int) *common.Device {
+	// Set global working directory
+	pwd = config.GetConfig().ConfigPath
+
+	layout, ok := layouts[kind]
+	if !ok {
+		logger.Log(logger.Fields{"kind": kind}).Warn("Unknown simulated device kind")
+		return nil
+	}
+
+	serial := fmt.Sprintf("SIM%s%02d", strings.ToUpper(kind), index)
+	switch kind {
+	case KindK70:
+		return initKeyboard(kind, serial, layout)
+	case KindMouse:
+		return initMouse(kind, serial, layout)
+	}
+	return initHub(kind, serial, layout)
+}
+
+// getRgbModes will return all available RGB profile names
+func getRgbModes() []string {
+	modes := make([]string, 0)
+	for key := range rgb.GetRgbProfiles() {
+		modes = append(modes, key)
+	}
+	sort.Strings(modes)
+	return modes
+}
@@ -5,6 +5,7 @@ package slipstream
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/darkcorergbproW"
@@ -27,6 +28,7 @@ import (
 	"OpenLinkHub/src/devices/scimitarSEW"
 	"OpenLinkHub/src/devices/scimitarW"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/transport"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -34,7 +36,6 @@ import (
 	"github.com/sstallion/go-hid"
 	"os"
 	"path/filepath"
-	"reflect"
 	"slices"
 	"sync"
 	"time"
@@ -73,6 +74,15 @@ type Device struct {
 	PollingRates   map[int]string
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable          = (*Device)(nil)
+	_ capabilities.DirtyStoppable     = (*Device)(nil)
+	_ capabilities.Templated          = (*Device)(nil)
+	_ capabilities.PollingRate        = (*Device)(nil)
+	_ capabilities.PollingRateOptions = (*Device)(nil)
+)
+
 type DeviceProfile struct {
 	Active      bool
 	Path        string
@@ -111,7 +121,7 @@ func Init(vendorId, productId uint16, _, path string, callback func(device *comm
 	pwd = config.GetConfig().ConfigPath
 
 	// Open device, return if failure
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
@@ -690,12 +700,12 @@ func (d *Device) StopDirty() uint8 {
 	}()
 
 	for _, value := range d.PairedDevices {
-		stopDirty := reflect.ValueOf(value).MethodByName("StopDirty")
-		if !stopDirty.IsValid() {
+		stopDirty, ok := value.(capabilities.DirtyStoppable)
+		if !ok {
 			logger.Log(logger.Fields{"productId": d.ProductId}).Warn("Invalid or non-existing method called")
 			continue
 		}
-		stopDirty.Call(nil)
+		stopDirty.StopDirty()
 	}
 	logger.Log(logger.Fields{"serial": d.Serial, "product": d.Product}).Info("Device stopped")
 	return 2
@@ -721,12 +731,12 @@ func (d *Device) Stop() {
 	}()
 
 	for _, value := range d.PairedDevices {
-		stopInternal := reflect.ValueOf(value).MethodByName("StopInternal")
-		if !stopInternal.IsValid() {
+		stopInternal, ok := value.(capabilities.PairedDevice)
+		if !ok {
 			logger.Log(logger.Fields{"productId": d.ProductId}).Warn("Invalid or non-existing method called")
 			continue
 		}
-		stopInternal.Call(nil)
+		stopInternal.StopInternal()
 	}
 
 	time.Sleep(500 * time.Millisecond)
@@ -750,6 +760,11 @@ func (d *Device) GetDeviceTemplate() string {
 	return d.Template
 }
 
+// GetPollingRates will return polling rates supported by a device
+func (d *Device) GetPollingRates() map[int]string {
+	return d.PollingRates
+}
+
 // AddPairedDevice will add a paired device
 func (d *Device) AddPairedDevice(productId uint16, device any, dev *common.Device) {
 	d.PairedDevices[productId] = device
@@ -757,7 +772,7 @@ func (d *Device) AddPairedDevice(productId uint16, device any, dev *common.Devic
 }
 
 // GetDevice will return HID device
-func (d *Device) GetDevice() *hid.Device {
+func (d *Device) GetDevice() transport.Device {
 	return d.slipstream.Dev
 }
 
@@ -1013,12 +1028,12 @@ func (d *Device) initAvailableDevices() {
 		}
 
 		if _, ok := d.PairedDevices[value.ProductId]; ok {
-			connect := reflect.ValueOf(d.PairedDevices[value.ProductId]).MethodByName("Connect")
-			if !connect.IsValid() {
+			connect, ok := d.PairedDevices[value.ProductId].(capabilities.PairedDevice)
+			if !ok {
 				logger.Log(logger.Fields{"endpoint": value.Endpoint, "productId": value.ProductId}).Warn("Invalid or non-existing method called")
 				return
 			}
-			connect.Call(nil)
+			connect.Connect()
 			d.slipstream.Connected[value.ProductId] = true
 		}
 	}
@@ -1027,15 +1042,13 @@ func (d *Device) initAvailableDevices() {
 // setDeviceOnlineByProductId will online device by given productId
 func (d *Device) setDeviceBatteryLevelByProductId(productId, batteryLevel uint16) {
 	if _, ok := d.PairedDevices[productId]; ok {
-		battery := reflect.ValueOf(d.PairedDevices[productId]).MethodByName("ModifyBatteryLevel")
-		if !battery.IsValid() {
+		battery, ok := d.PairedDevices[productId].(capabilities.BatteryLevel)
+		if !ok {
 			logger.Log(logger.Fields{"productId": productId}).Warn("Invalid or non-existing method called")
 			return
 		}
 
-		reflectArgs := make([]reflect.Value, 1)
-		reflectArgs[0] = reflect.ValueOf(batteryLevel)
-		battery.Call(reflectArgs)
+		battery.ModifyBatteryLevel(batteryLevel)
 	}
 }
 
@@ -1044,28 +1057,20 @@ func (d *Device) setDeviceStatus(status byte, productId uint16) {
 	for _, v := range d.Devices {
 		if productId == v.ProductId {
 			if _, ok := d.PairedDevices[productId]; ok {
-				setConnected := reflect.ValueOf(d.PairedDevices[productId]).MethodByName("SetConnected")
-				if !setConnected.IsValid() {
+				paired, ok := d.PairedDevices[productId].(capabilities.PairedDevice)
+				if !ok {
 					logger.Log(logger.Fields{"productId": v.ProductId, "method": "SetConnected"}).Warn("Invalid or non-existing method called")
 					return
 				}
 
-				connect := reflect.ValueOf(d.PairedDevices[productId]).MethodByName("Connect")
-				if !connect.IsValid() {
-					logger.Log(logger.Fields{"productId": v.ProductId, "method": "Connect"}).Warn("Invalid or non-existing method called")
-					return
-				}
-
 				switch status {
 				case 0x00:
-					reflectArgs := make([]reflect.Value, 1)
-					reflectArgs[0] = reflect.ValueOf(false)
-					setConnected.Call(reflectArgs)
+					paired.SetConnected(false)
 					d.slipstream.Connected[v.ProductId] = false
 				case 0x02:
 					go func() {
 						time.Sleep(time.Duration(connectDelay) * time.Millisecond)
-						connect.Call(nil)
+						paired.Connect()
 						d.slipstream.Connected[v.ProductId] = true
 					}()
 				}
@@ -1073,23 +1078,15 @@ func (d *Device) setDeviceStatus(status byte, productId uint16) {
 		} else {
 			for _, val := range d.DeviceList {
 				if _, ok := d.PairedDevices[val.ProductId]; ok {
-					setConnected := reflect.ValueOf(d.PairedDevices[val.ProductId]).MethodByName("SetConnected")
-					if !setConnected.IsValid() {
+					paired, ok := d.PairedDevices[val.ProductId].(capabilities.PairedDevice)
+					if !ok {
 						logger.Log(logger.Fields{"productId": val.ProductId, "method": "SetConnected"}).Warn("Invalid or non-existing method called")
 						return
 					}
 
-					connect := reflect.ValueOf(d.PairedDevices[val.ProductId]).MethodByName("Connect")
-					if !connect.IsValid() {
-						logger.Log(logger.Fields{"productId": val.ProductId, "method": "Connect"}).Warn("Invalid or non-existing method called")
-						return
-					}
-
 					switch status {
 					case 0x00:
-						reflectArgs := make([]reflect.Value, 1)
-						reflectArgs[0] = reflect.ValueOf(false)
-						setConnected.Call(reflectArgs)
+						paired.SetConnected(false)
 						d.slipstream.Connected[val.ProductId] = false
 					case 0x02:
 						//
@@ -1097,32 +1094,28 @@ func (d *Device) setDeviceStatus(status byte, productId uint16) {
 						if val.DeviceType == common.DeviceTypeMouse {
 							go func() {
 								time.Sleep(time.Duration(connectDelay) * time.Millisecond)
-								connect.Call(nil)
+								paired.Connect()
 								d.slipstream.Connected[val.ProductId] = true
 							}()
 						} else {
-							reflectArgs := make([]reflect.Value, 1)
-							reflectArgs[0] = reflect.ValueOf(false)
-							setConnected.Call(reflectArgs)
+							paired.SetConnected(false)
 							d.slipstream.Connected[val.ProductId] = false
 						}
 					case 0x08:
 						if val.DeviceType == common.DeviceTypeKeyboard {
 							go func() {
 								time.Sleep(time.Duration(connectDelay) * time.Millisecond)
-								connect.Call(nil)
+								paired.Connect()
 								d.slipstream.Connected[val.ProductId] = true
 							}()
 						} else {
-							reflectArgs := make([]reflect.Value, 1)
-							reflectArgs[0] = reflect.ValueOf(false)
-							setConnected.Call(reflectArgs)
+							paired.SetConnected(false)
 							d.slipstream.Connected[val.ProductId] = false
 						}
 					case 0x0c:
 						go func() {
 							time.Sleep(time.Duration(connectDelay) * time.Millisecond)
-							connect.Call(nil)
+							paired.Connect()
 							d.slipstream.Connected[val.ProductId] = true
 						}()
 					}
@@ -1180,22 +1173,14 @@ func (d *Device) monitorDevice() {
 
 // getSleepMode will return device sleep mode
 func (d *Device) getSleepMode(dev any) int {
-	sleepMode := 0
-	methodName := "GetSleepMode"
-	method := reflect.ValueOf(dev).MethodByName(methodName)
-	if !method.IsValid() {
+	device, ok := dev.(capabilities.SleepMode)
+	if !ok {
 		if d.Debug {
-			logger.Log(logger.Fields{"method": methodName}).Warn("Method not found or method is not supported for this device")
+			logger.Log(logger.Fields{"method": "GetSleepMode"}).Warn("Method not found or method is not supported for this device")
 		}
 		return 0
-	} else {
-		results := method.Call(nil)
-		if len(results) > 0 {
-			val := results[0]
-			sleepMode = int(val.Int())
-		}
 	}
-	return sleepMode
+	return device.GetSleepMode()
 }
 
 // sleepMonitor will monitor for device inactivity
@@ -1224,17 +1209,16 @@ func (d *Device) sleepMonitor() {
 									if dev, ok := d.PairedDevices[value.ProductId]; ok {
 										sleepMode := d.getSleepMode(dev) * 60
 										if sleepMode > 0 {
-											methodName := "SetSleepMode"
-											method := reflect.ValueOf(dev).MethodByName(methodName)
-											if !method.IsValid() {
+											device, ok := dev.(capabilities.SleepModeSwitch)
+											if !ok {
 												if d.Debug {
-													logger.Log(logger.Fields{"method": methodName}).Warn("Method not found or method is not supported for this device type")
+													logger.Log(logger.Fields{"method": "SetSleepMode"}).Warn("Method not found or method is not supported for this device type")
 												}
 												continue
 											} else {
 												if inactive >= sleepMode {
 													d.slipstream.Connected[value.ProductId] = false
-													method.Call(nil)
+													device.SetSleepMode()
 												}
 											}
 										}
@@ -1266,15 +1250,13 @@ func (d *Device) TriggerKeyboardKeyAssignment(data []byte) {
 	for _, val := range d.DeviceList {
 		if _, ok := d.PairedDevices[val.ProductId]; ok {
 			if val.DeviceType == common.DeviceTypeKeyboard {
-				keyAssignment := reflect.ValueOf(d.PairedDevices[val.ProductId]).MethodByName("TriggerKeyAssignment")
-				if !keyAssignment.IsValid() {
+				keyAssignment, ok := d.PairedDevices[val.ProductId].(capabilities.KeyboardKeyTrigger)
+				if !ok {
 					logger.Log(logger.Fields{"productId": val.ProductId, "method": "TriggerKeyAssignment"}).Warn("Invalid or non-existing method called")
 					return
 				}
 
-				reflectArgs := make([]reflect.Value, 1)
-				reflectArgs[0] = reflect.ValueOf(data)
-				keyAssignment.Call(reflectArgs)
+				keyAssignment.TriggerKeyAssignment(data)
 			}
 		}
 	}
@@ -1284,16 +1266,14 @@ func (d *Device) TriggerMousedKeyAssignment(data []byte) {
 	for _, val := range d.DeviceList {
 		if device, ok := d.PairedDevices[val.ProductId]; ok {
 			if val.DeviceType == common.DeviceTypeMouse {
-				keyAssignment := reflect.ValueOf(d.PairedDevices[val.ProductId]).MethodByName("TriggerKeyAssignment")
-				if !keyAssignment.IsValid() {
+				keyAssignment, ok := d.PairedDevices[val.ProductId].(capabilities.MouseKeyTrigger)
+				if !ok {
 					logger.Log(logger.Fields{"productId": val.ProductId, "method": "TriggerKeyAssignment"}).Warn("Invalid or non-existing method called")
 					return
 				}
 
 				value := binary.LittleEndian.Uint32(data[2:6])
-				reflectArgs := make([]reflect.Value, 1)
-				reflectArgs[0] = reflect.ValueOf(value)
-				keyAssignment.Call(reflectArgs)
+				keyAssignment.TriggerKeyAssignment(value)
 
 				// Tilt
 				if data[1] == 0x09 {
@@ -1320,15 +1300,13 @@ func (d *Device) TriggerMousedKeyAssignment(data []byte) {
 						break
 					}
 
-					tilt := reflect.ValueOf(d.PairedDevices[val.ProductId]).MethodByName("TriggerTiltAssignment")
-					if !tilt.IsValid() {
+					tilt, ok := d.PairedDevices[val.ProductId].(capabilities.TiltTrigger)
+					if !ok {
 						logger.Log(logger.Fields{"productId": val.ProductId, "method": "TriggerTiltAssignment"}).Warn("Invalid or non-existing method called")
 						return
 					}
 
-					args := make([]reflect.Value, 1)
-					args[0] = reflect.ValueOf(v)
-					tilt.Call(args)
+					tilt.TriggerTiltAssignment(v)
 				}
 			}
 		}
@@ -1340,7 +1318,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.InterfaceNbr == 2 && info.SerialNbr == d.Serial {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
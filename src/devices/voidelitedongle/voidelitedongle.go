@@ -5,10 +5,12 @@ package voidelitedongle
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices/voideliteW"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/transport"
 	"github.com/sstallion/go-hid"
 	"strconv"
 	"sync"
@@ -24,8 +26,8 @@ type Devices struct {
 }
 
 type Device struct {
-	dev           *hid.Device
-	listener      *hid.Device
+	dev           transport.Device
+	listener      transport.Device
 	Manufacturer  string `json:"manufacturer"`
 	Product       string `json:"product"`
 	Serial        string `json:"serial"`
@@ -45,6 +47,13 @@ type Device struct {
 	MuteStatus    byte
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+)
+
 var (
 	bufferSize      = 32
 	cmdSoftwareMode = []byte{0x01, 0x00}
@@ -55,7 +64,7 @@ var (
 
 func Init(vendorId, productId uint16, _, path string, callback func(device *common.Device)) *common.Device {
 	// Open device, return if failure
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId}).Error("Unable to open HID device")
 		return nil
@@ -183,7 +192,7 @@ func (d *Device) AddPairedDevice(productId uint16, device any) {
 }
 
 // GetDevice will return HID device
-func (d *Device) GetDevice() *hid.Device {
+func (d *Device) GetDevice() transport.Device {
 	return d.dev
 }
 
@@ -316,7 +325,7 @@ func (d *Device) backendListener() {
 	go func() {
 		enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
 			if info.InterfaceNbr == 3 {
-				listener, err := hid.OpenPath(info.Path)
+				listener, err := transport.OpenPath(info.Path)
 				if err != nil {
 					return err
 				}
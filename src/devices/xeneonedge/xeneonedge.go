@@ -5,12 +5,13 @@ package xeneonedge
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/transport"
 	"encoding/json"
 	"fmt"
-	"github.com/sstallion/go-hid"
 	"os"
 	"strings"
 )
@@ -31,7 +32,7 @@ type WidgetArea struct {
 	Widget   *Widget `json:"widget"`
 }
 type Device struct {
-	dev             *hid.Device
+	dev             transport.Device
 	Debug           bool
 	Manufacturer    string                    `json:"manufacturer"`
 	Product         string                    `json:"product"`
@@ -48,6 +49,14 @@ type Device struct {
 	instance        *common.Device
 }
 
+// Capabilities implemented by a device
+var (
+	_ capabilities.Stoppable      = (*Device)(nil)
+	_ capabilities.DirtyStoppable = (*Device)(nil)
+	_ capabilities.Templated      = (*Device)(nil)
+	_ capabilities.UserProfiles   = (*Device)(nil)
+)
+
 type Widget struct {
 	Id          int     `json:"id"`
 	Name        string  `json:"name"`
@@ -74,7 +83,7 @@ func Init(vendorId, productId uint16, _, path string) *common.Device {
 	// Set global working directory
 	pwd = config.GetConfig().ConfigPath
 
-	dev, err := hid.OpenPath(path)
+	dev, err := transport.OpenPath(path)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "vendorId": vendorId, "productId": productId, "path": path}).Error("Unable to open HID device")
 		return nil
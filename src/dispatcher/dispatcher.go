@@ -4,10 +4,9 @@ package dispatcher
 // Author: Nikola Jurkovic
 // License: GPL-3.0 or later
 
-import "reflect"
-
-type DeviceDispatcher func(
-	deviceId string,
-	methodName string,
-	args ...interface{},
-) []reflect.Value
+// DeviceDispatcher is used by devices and input manager to call another device by its serial
+type DeviceDispatcher interface {
+	CallSniperMode(deviceId string, active bool)
+	ModifyDpi(deviceId string, increment bool)
+	TriggerHapticEngine(deviceId string, left, right byte)
+}
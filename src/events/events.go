@@ -0,0 +1,86 @@
+package events
+
+// Package: events
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventTemperature   = "temperature"
+	EventRpm           = "rpm"
+	EventBattery       = "battery"
+	EventDeviceAdded   = "deviceAdded"
+	EventDeviceRemoved = "deviceRemoved"
+	EventProfile       = "profile"
+	EventRgb           = "rgb"
+	EventAlert         = "alert"
+	EventFailsafe      = "failsafe"
+)
+
+// subscriberBuffer is amount of events queued per subscriber before new events are dropped
+const subscriberBuffer = 64
+
+type Event struct {
+	Type      string      `json:"type"`
+	Serial    string      `json:"serial"`
+	ChannelId int         `json:"channelId"`
+	Data      interface{} `json:"data"`
+	Time      int64       `json:"time"`
+}
+
+var (
+	mutex       sync.RWMutex
+	subscribers = map[uint64]chan Event{}
+	nextId      uint64
+)
+
+// Subscribe will register new event subscriber
+func Subscribe() (uint64, <-chan Event) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	nextId++
+	ch := make(chan Event, subscriberBuffer)
+	subscribers[nextId] = ch
+	return nextId, ch
+}
+
+// Unsubscribe will remove event subscriber and close its channel
+func Unsubscribe(id uint64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if ch, ok := subscribers[id]; ok {
+		delete(subscribers, id)
+		close(ch)
+	}
+}
+
+// Publish will send event to all subscribers. Slow subscribers will miss events instead of blocking the caller.
+func Publish(eventType, serial string, channelId int, data interface{}) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if len(subscribers) == 0 {
+		return
+	}
+
+	event := Event{
+		Type:      eventType,
+		Serial:    serial,
+		ChannelId: channelId,
+		Data:      data,
+		Time:      time.Now().UnixMilli(),
+	}
+
+	for _, ch := range subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package history
+
+// Package: history
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/metrics"
+	"OpenLinkHub/src/stats"
+	"encoding/gob"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	KindTemperature = "temperature"
+	KindRpm         = "rpm"
+	KindWatts       = "watts"
+	KindVolts       = "volts"
+	KindAmps        = "amps"
+	KindBattery     = "battery"
+
+	// SystemSerial is serial of CPU, GPU and storage series
+	SystemSerial = "system"
+)
+
+// Series describes a single recorded sensor
+type Series struct {
+	Serial    string `json:"serial"`
+	ChannelId string `json:"channelId"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Product   string `json:"product"`
+	Updated   int64  `json:"updated"`
+}
+
+// Tier describes resolution and retention of stored points, in seconds
+type Tier struct {
+	Resolution int64 `json:"resolution"`
+	Retention  int64 `json:"retention"`
+}
+
+// Data is a result of history query
+type Data struct {
+	Series     Series  `json:"series"`
+	Resolution int64   `json:"resolution"`
+	Points     []Point `json:"points"`
+}
+
+// snapshot is on-disk representation of history
+type snapshot struct {
+	Series map[string]Series
+	Tiers  map[int64]map[string][]Point
+}
+
+var (
+	location = ""
+	enabled  = false
+	mutex    sync.Mutex
+	series   = map[string]Series{}
+	tiers    []*tier
+	stop     = make(chan struct{})
+	done     = make(chan struct{})
+)
+
+// Init will load stored history and start sampling sensors
+func Init() {
+	settings := config.GetConfig().History
+	if !settings.Enabled {
+		return
+	}
+
+	for _, value := range settings.Tiers {
+		if value.Resolution < 1 || value.Retention < value.Resolution {
+			logger.Log(logger.Fields{"resolution": value.Resolution, "retention": value.Retention}).Warn("Invalid history tier, skipping")
+			continue
+		}
+		tiers = append(tiers, newTier(int64(value.Resolution), int64(value.Retention)))
+	}
+	if len(tiers) == 0 {
+		logger.Log(logger.Fields{}).Warn("No valid history tiers, history is disabled")
+		return
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].resolution < tiers[j].resolution })
+
+	location = config.GetConfig().ConfigPath + "/database/history/"
+	if !common.FileExists(location) {
+		if err := os.MkdirAll(location, 0755); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": location}).Error("Unable to create history directory")
+			return
+		}
+	}
+	load()
+
+	saveInterval := settings.SaveInterval
+	if saveInterval < 1 {
+		saveInterval = 300
+	}
+
+	enabled = true
+	go run(time.Duration(tiers[0].resolution)*time.Second, time.Duration(saveInterval)*time.Second)
+}
+
+// Stop will stop sampling and save history to disk
+func Stop() {
+	if !enabled {
+		return
+	}
+	enabled = false
+	close(stop)
+	<-done
+
+	// Keep incomplete intervals
+	mutex.Lock()
+	for _, t := range tiers {
+		for key, b := range t.pending {
+			t.flush(key, b)
+		}
+	}
+	mutex.Unlock()
+	save()
+}
+
+// run will sample sensors at the finest resolution and periodically save history
+func run(interval, saveInterval time.Duration) {
+	sampler := time.NewTicker(interval)
+	saver := time.NewTicker(saveInterval)
+	defer func() {
+		sampler.Stop()
+		saver.Stop()
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-sampler.C:
+			sample()
+		case <-saver.C:
+			save()
+		}
+	}
+}
+
+// sample will record current value of every sensor
+func sample() {
+	devices.UpdateDeviceMetrics()
+	now := time.Now().Unix()
+	connected := devices.GetDevices()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	record := func(meta Series, value float64) {
+		key := meta.Serial + "|" + meta.ChannelId + "|" + meta.Kind
+		if _, ok := series[key]; !ok && value <= 0 {
+			// Sensor is not present until it reports a value once
+			return
+		}
+		meta.Updated = now
+		series[key] = meta
+		for _, t := range tiers {
+			t.add(key, now, value)
+		}
+	}
+
+	for _, value := range metrics.GetDefaultMetrics() {
+		if len(value.Model) > 0 {
+			record(Series{Serial: SystemSerial, ChannelId: value.Model, Kind: KindTemperature, Name: value.Model}, value.Temperature)
+		}
+	}
+
+	for key, value := range metrics.GetStorageMetrics() {
+		record(Series{Serial: SystemSerial, ChannelId: key, Kind: KindTemperature, Name: value.Model}, value.Temperature)
+	}
+
+	for _, header := range metrics.GetDeviceMetrics() {
+		if _, ok := connected[header.Serial]; !ok {
+			continue
+		}
+		values := map[string]float64{
+			KindTemperature: header.Temperature,
+			KindRpm:         float64(header.Rpm),
+			KindWatts:       header.Watts,
+			KindVolts:       header.Volts,
+			KindAmps:        header.Amps,
+		}
+		for kind, value := range values {
+			record(Series{Serial: header.Serial, ChannelId: header.ChannelId, Kind: kind, Name: header.Name, Product: header.Product}, value)
+		}
+	}
+
+	for serial, battery := range stats.GetBatteryStats() {
+		if _, ok := connected[serial]; !ok {
+			continue
+		}
+		record(Series{Serial: serial, ChannelId: "0", Kind: KindBattery, Name: "Battery", Product: battery.Device}, float64(battery.Level))
+	}
+
+	for _, t := range tiers {
+		t.expire(now)
+	}
+
+	// Forget sensors which have no data left in any tier
+	retention := int64(0)
+	for _, t := range tiers {
+		retention = max(retention, t.retention)
+	}
+	for key, meta := range series {
+		if now-meta.Updated > retention {
+			delete(series, key)
+			for _, t := range tiers {
+				t.remove(key)
+			}
+		}
+	}
+}
+
+// GetTiers returns all configured tiers
+func GetTiers() []Tier {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := make([]Tier, 0, len(tiers))
+	for _, t := range tiers {
+		list = append(list, Tier{Resolution: t.resolution, Retention: t.retention})
+	}
+	return list
+}
+
+// GetSeries returns all recorded series sorted by serial, channel and kind
+func GetSeries() []Series {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := make([]Series, 0, len(series))
+	for _, value := range series {
+		list = append(list, value)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Serial != list[j].Serial {
+			return list[i].Serial < list[j].Serial
+		}
+		if list[i].ChannelId != list[j].ChannelId {
+			a, errA := strconv.Atoi(list[i].ChannelId)
+			b, errB := strconv.Atoi(list[j].ChannelId)
+			if errA == nil && errB == nil {
+				return a < b
+			}
+			return list[i].ChannelId < list[j].ChannelId
+		}
+		return list[i].Kind < list[j].Kind
+	})
+	return list
+}
+
+// GetData returns points of a series between from and to. When resolution is 0, the finest tier
+// covering whole range is used.
+func GetData(serial, channelId, kind string, from, to, resolution int64) *Data {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	key := serial + "|" + channelId + "|" + kind
+	meta, ok := series[key]
+	if !ok || len(tiers) == 0 {
+		return nil
+	}
+
+	var selected *tier
+	if resolution > 0 {
+		for _, t := range tiers {
+			if t.resolution == resolution {
+				selected = t
+				break
+			}
+		}
+		if selected == nil {
+			return nil
+		}
+	} else {
+		now := time.Now().Unix()
+		selected = tiers[len(tiers)-1]
+		for _, t := range tiers {
+			if now-from <= t.retention {
+				selected = t
+				break
+			}
+		}
+	}
+
+	return &Data{
+		Series:     meta,
+		Resolution: selected.resolution,
+		Points:     selected.query(key, from, to),
+	}
+}
+
+// load will restore history from disk. Tiers which are no longer configured are dropped.
+func load() {
+	filename := location + "history.gob"
+	if !common.FileExists(filename) {
+		return
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "location": filename}).Error("Unable to read history")
+		return
+	}
+	defer func(file *os.File) {
+		if err = file.Close(); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": filename}).Warn("Failed to close file handle")
+		}
+	}(file)
+
+	var data snapshot
+	if err = gob.NewDecoder(file).Decode(&data); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": filename}).Error("Unable to decode history")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if data.Series != nil {
+		series = data.Series
+	}
+	for _, t := range tiers {
+		for key, points := range data.Tiers[t.resolution] {
+			r := newRing(t.capacity())
+			for _, point := range points {
+				r.push(point)
+			}
+			t.rings[key] = r
+		}
+	}
+}
+
+// save will write history to disk
+func save() {
+	mutex.Lock()
+	data := snapshot{
+		Series: make(map[string]Series, len(series)),
+		Tiers:  make(map[int64]map[string][]Point, len(tiers)),
+	}
+	for key, value := range series {
+		data.Series[key] = value
+	}
+	for _, t := range tiers {
+		points := make(map[string][]Point, len(t.rings))
+		for key, r := range t.rings {
+			points[key] = r.ordered()
+		}
+		data.Tiers[t.resolution] = points
+	}
+	mutex.Unlock()
+
+	filename := location + "history.gob"
+	file, err := os.Create(filename + ".tmp")
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "location": filename}).Error("Unable to save history")
+		return
+	}
+
+	if err = gob.NewEncoder(file).Encode(&data); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": filename}).Error("Unable to encode history")
+		_ = file.Close()
+		return
+	}
+
+	if err = file.Close(); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": filename}).Error("Unable to save history")
+		return
+	}
+
+	if err = os.Rename(filename+".tmp", filename); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": filename}).Error("Unable to save history")
+	}
+}
@@ -0,0 +1,154 @@
+package history
+
+// Package: history
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"math"
+)
+
+// Point is a single aggregated value of a series at given resolution
+type Point struct {
+	Time int64   `json:"time"`
+	Avg  float32 `json:"avg"`
+	Min  float32 `json:"min"`
+	Max  float32 `json:"max"`
+}
+
+// ring is a fixed size buffer of points, oldest points are overwritten
+type ring struct {
+	points []Point
+	start  int
+	size   int
+}
+
+// bucket accumulates samples until resolution interval is complete
+type bucket struct {
+	start int64
+	sum   float64
+	count int
+	min   float32
+	max   float32
+}
+
+// tier holds all series at single resolution
+type tier struct {
+	resolution int64
+	retention  int64
+	rings      map[string]*ring
+	pending    map[string]*bucket
+}
+
+// newRing will create ring buffer with given capacity
+func newRing(size int) *ring {
+	if size < 1 {
+		size = 1
+	}
+	return &ring{points: make([]Point, 0, size), size: size}
+}
+
+// push will add point to ring, replacing the oldest one when ring is full
+func (r *ring) push(point Point) {
+	if len(r.points) < r.size {
+		r.points = append(r.points, point)
+		return
+	}
+	r.points[r.start] = point
+	r.start = (r.start + 1) % r.size
+}
+
+// ordered returns all points from oldest to newest
+func (r *ring) ordered() []Point {
+	points := make([]Point, 0, len(r.points))
+	points = append(points, r.points[r.start:]...)
+	points = append(points, r.points[:r.start]...)
+	return points
+}
+
+// point will convert bucket to point
+func (b *bucket) point() Point {
+	return Point{
+		Time: b.start,
+		Avg:  float32(b.sum / float64(b.count)),
+		Min:  b.min,
+		Max:  b.max,
+	}
+}
+
+// newTier will create tier with given resolution and retention in seconds
+func newTier(resolution, retention int64) *tier {
+	return &tier{
+		resolution: resolution,
+		retention:  retention,
+		rings:      make(map[string]*ring),
+		pending:    make(map[string]*bucket),
+	}
+}
+
+// capacity returns amount of points a single series can hold
+func (t *tier) capacity() int {
+	return int(t.retention / t.resolution)
+}
+
+// add will accumulate sample into series bucket, completing previous bucket when interval has passed
+func (t *tier) add(key string, now int64, value float64) {
+	start := now - now%t.resolution
+	b, ok := t.pending[key]
+	if ok && b.start != start {
+		t.flush(key, b)
+		ok = false
+	}
+	if !ok {
+		b = &bucket{start: start, min: math.MaxFloat32, max: -math.MaxFloat32}
+		t.pending[key] = b
+	}
+
+	b.sum += value
+	b.count++
+	b.min = min(b.min, float32(value))
+	b.max = max(b.max, float32(value))
+}
+
+// expire will complete buckets of series that did not receive samples in current interval
+func (t *tier) expire(now int64) {
+	start := now - now%t.resolution
+	for key, b := range t.pending {
+		if b.start < start {
+			t.flush(key, b)
+		}
+	}
+}
+
+// flush will store bucket as a point and remove it from pending buckets
+func (t *tier) flush(key string, b *bucket) {
+	r, ok := t.rings[key]
+	if !ok {
+		r = newRing(t.capacity())
+		t.rings[key] = r
+	}
+	r.push(b.point())
+	delete(t.pending, key)
+}
+
+// remove will delete series from tier
+func (t *tier) remove(key string) {
+	delete(t.rings, key)
+	delete(t.pending, key)
+}
+
+// query returns points of series between from and to, including incomplete bucket
+func (t *tier) query(key string, from, to int64) []Point {
+	points := make([]Point, 0)
+	if r, ok := t.rings[key]; ok {
+		for _, point := range r.ordered() {
+			if point.Time >= from && point.Time <= to {
+				points = append(points, point)
+			}
+		}
+	}
+	if b, ok := t.pending[key]; ok && b.start >= from && b.start <= to {
+		points = append(points, b.point())
+	}
+	return points
+}
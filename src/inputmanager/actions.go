@@ -0,0 +1,48 @@
+package inputmanager
+
+// Package: inputmanager
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"sync"
+)
+
+var (
+	actionMutex    sync.RWMutex
+	actionHandlers = map[uint16]func(){}
+)
+
+// RegisterAction will add keyboard action which runs handler instead of emitting input event
+func RegisterAction(actionType uint16, name string, handler func()) {
+	actionMutex.Lock()
+	defer actionMutex.Unlock()
+
+	inputActions[actionType] = InputAction{Name: name}
+	actionHandlers[actionType] = handler
+}
+
+// UnregisterAction will remove keyboard action added via RegisterAction
+func UnregisterAction(actionType uint16) {
+	actionMutex.Lock()
+	defer actionMutex.Unlock()
+
+	delete(inputActions, actionType)
+	delete(actionHandlers, actionType)
+}
+
+// runAction will run registered action handler once key is released. Returns true when action
+// type belongs to registered action.
+func runAction(actionType uint16, release bool) bool {
+	actionMutex.RLock()
+	handler, ok := actionHandlers[actionType]
+	actionMutex.RUnlock()
+
+	if !ok {
+		return false
+	}
+	if release {
+		go handler()
+	}
+	return true
+}
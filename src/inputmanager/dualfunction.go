@@ -0,0 +1,286 @@
+package inputmanager
+
+// Package: inputmanager
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/logger"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHoldThreshold   = 200 // Milliseconds
+	defaultDoubleTapWindow = 250 // Milliseconds
+	maxDualFunctionTime    = 5000
+	dualQueueSize          = 64
+	dualInterruptWait      = 100 // Milliseconds
+)
+
+// dualKey is a dual-function key waiting for its action to be resolved
+type dualKey struct {
+	value    KeyAssignment
+	pressed  bool
+	held     bool
+	resolved bool
+	timer    *time.Timer
+}
+
+var (
+	dualMutex    sync.Mutex
+	dualKeys     = map[string]*dualKey{}
+	dualQueues   = map[string]chan func(){}
+	macroHandler func(macroId int) bool
+)
+
+// SetMacroHandler will set function used to run macros from dual-function keys
+func SetMacroHandler(handler func(macroId int) bool) {
+	macroHandler = handler
+}
+
+// IsDualFunction will return true when key assignment has hold or double-tap action
+func IsDualFunction(value KeyAssignment) bool {
+	return !value.Default && value.LayerShift == 0 && (value.HoldActionType > 0 || value.DoubleTapActionType > 0)
+}
+
+// IsValidDualFunction will return true when dual-function actions and timings of key assignment are supported
+func IsValidDualFunction(value KeyAssignment) bool {
+	if value.HoldThreshold > maxDualFunctionTime || value.DoubleTapWindow > maxDualFunctionTime {
+		return false
+	}
+	if !IsDualFunction(value) {
+		return true
+	}
+
+	// Sniper mode is only available while key is held
+	if value.ActionType == 8 || value.DoubleTapActionType == 8 {
+		return false
+	}
+	for _, actionType := range []uint8{value.ActionType, value.HoldActionType, value.DoubleTapActionType} {
+		switch actionType {
+		case 0, 1, 3, 8, 9, 10:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveKeyAssignment will resolve key assignment of a pressed or released key through key assignment
+// layers and dual-function actions. Returns false when key is handled by input manager.
+func ResolveKeyAssignment(serial, key string, pressed bool, base KeyAssignment, found bool) (KeyAssignment, bool) {
+	value, ok := layerKeyAssignment(serial, key, pressed, base, found)
+	if (ok && IsDualFunction(value)) || (!pressed && isDualFunctionPressed(serial, key)) {
+		resolveDualFunction(serial, key, pressed, value)
+		return value, false
+	}
+	if ok && pressed {
+		interruptDualFunction(serial, key)
+	}
+	return value, ok
+}
+
+// interruptDualFunction will resolve pending dual-function keys of a device when another key is pressed,
+// and wait for their actions to run, so they come before action of pressed key.
+func interruptDualFunction(serial, key string) {
+	dualMutex.Lock()
+	done := resolvePendingDualKeys(serial, key)
+	dualMutex.Unlock()
+
+	if done == nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(dualInterruptWait * time.Millisecond):
+	}
+}
+
+// resolvePendingDualKeys will resolve dual-function keys of a device, except given key, that are waiting for
+// their action. Held keys resolve to hold action and released keys waiting for a second tap resolve to tap
+// action. Returns channel closed once last resolved action has run, or nil. Caller must hold dualMutex.
+func resolvePendingDualKeys(serial, key string) chan struct{} {
+	prefix := serial + ":"
+	ids := make([]string, 0, len(dualKeys))
+	for id := range dualKeys {
+		if strings.HasPrefix(id, prefix) && id != prefix+key {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var done chan struct{}
+	for _, id := range ids {
+		state := dualKeys[id]
+		if state.resolved {
+			continue
+		}
+
+		if state.pressed {
+			if state.value.HoldActionType == 0 {
+				continue
+			}
+			state.timer.Stop()
+			state.held = true
+			state.resolved = true
+			done = queueDualAction(serial, state.value.HoldActionType, state.value.HoldActionCommand, state.value.DeviceId, true, true)
+			continue
+		}
+
+		state.timer.Stop()
+		delete(dualKeys, id)
+		done = queueDualAction(serial, state.value.ActionType, state.value.ActionCommand, state.value.DeviceId, false, false)
+	}
+	return done
+}
+
+// resolveDualFunction will process press or release of a dual-function key. Tap action runs when key
+// is released before hold threshold, hold action runs while key is held past it, and double-tap
+// action runs when key is pressed again within double-tap window. Actions of a device run in order they
+// are resolved, and pressing another key resolves pending keys of a device.
+func resolveDualFunction(serial, key string, pressed bool, value KeyAssignment) {
+	id := serial + ":" + key
+
+	dualMutex.Lock()
+	defer dualMutex.Unlock()
+
+	state, ok := dualKeys[id]
+	if pressed {
+		resolvePendingDualKeys(serial, key)
+		if ok && !state.pressed && state.timer != nil {
+			// Second press within double-tap window
+			state.timer.Stop()
+			state.pressed = true
+			state.resolved = true
+			queueDualAction(serial, state.value.DoubleTapActionType, state.value.DoubleTapActionCommand, state.value.DeviceId, false, false)
+			return
+		}
+
+		state = &dualKey{value: value, pressed: true}
+		dualKeys[id] = state
+		if value.HoldActionType > 0 {
+			state.timer = time.AfterFunc(dualFunctionTime(value.HoldThreshold, defaultHoldThreshold), func() {
+				dualMutex.Lock()
+				defer dualMutex.Unlock()
+				if dualKeys[id] != state || !state.pressed || state.resolved {
+					return
+				}
+				state.held = true
+				state.resolved = true
+				queueDualAction(serial, value.HoldActionType, value.HoldActionCommand, value.DeviceId, true, true)
+			})
+		}
+		return
+	}
+
+	if !ok || !state.pressed {
+		return
+	}
+	state.pressed = false
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+
+	if state.held {
+		delete(dualKeys, id)
+		queueDualAction(serial, state.value.HoldActionType, state.value.HoldActionCommand, state.value.DeviceId, true, false)
+		return
+	}
+
+	if state.resolved {
+		delete(dualKeys, id)
+		return
+	}
+
+	if state.value.DoubleTapActionType > 0 {
+		// Wait for a second tap
+		state.timer = time.AfterFunc(dualFunctionTime(state.value.DoubleTapWindow, defaultDoubleTapWindow), func() {
+			dualMutex.Lock()
+			defer dualMutex.Unlock()
+			if dualKeys[id] != state || state.pressed {
+				return
+			}
+			delete(dualKeys, id)
+			queueDualAction(serial, state.value.ActionType, state.value.ActionCommand, state.value.DeviceId, false, false)
+		})
+		return
+	}
+
+	delete(dualKeys, id)
+	queueDualAction(serial, state.value.ActionType, state.value.ActionCommand, state.value.DeviceId, false, false)
+}
+
+// isDualFunctionPressed will return true when dual-function key is waiting for its release
+func isDualFunctionPressed(serial, key string) bool {
+	dualMutex.Lock()
+	defer dualMutex.Unlock()
+
+	state, ok := dualKeys[serial+":"+key]
+	return ok && state.pressed
+}
+
+// queueDualAction will queue action of a dual-function key. Actions of a device run one at a time, in order
+// they are queued. Returns channel closed once action has run. Caller must hold dualMutex.
+func queueDualAction(serial string, actionType uint8, actionCommand uint16, deviceId string, hold, press bool) chan struct{} {
+	queue, ok := dualQueues[serial]
+	if !ok {
+		queue = make(chan func(), dualQueueSize)
+		dualQueues[serial] = queue
+		go func() {
+			for action := range queue {
+				action()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	queue <- func() {
+		defer close(done)
+		runDualAction(serial, actionType, actionCommand, deviceId, hold, press)
+	}
+	return done
+}
+
+// runDualAction will run action of a dual-function key. When hold is true, press or release of the action
+// is sent, otherwise action is pressed and released at once.
+func runDualAction(serial string, actionType uint8, actionCommand uint16, deviceId string, hold, press bool) {
+	switch actionType {
+	case 1, 3:
+		if hold {
+			InputControlKeyboardHold(actionCommand, press)
+		} else {
+			InputControlKeyboard(actionCommand, false)
+		}
+	case 8:
+		if len(deviceId) == 0 {
+			deviceId = serial
+		}
+		if dispatch != nil {
+			dispatch.CallSniperMode(deviceId, hold && press)
+		}
+	case 9:
+		if hold {
+			InputControlMouseHold(actionCommand, press)
+		} else {
+			InputControlMouse(actionCommand)
+		}
+	case 10:
+		if hold && !press {
+			return
+		}
+		if macroHandler != nil && !macroHandler(int(actionCommand)) {
+			logger.Log(logger.Fields{"serial": serial, "macroId": actionCommand}).Warn("Invalid macro profile")
+		}
+	}
+}
+
+// dualFunctionTime will return duration of given milliseconds, or fallback when value is not set
+func dualFunctionTime(value uint16, fallback int) time.Duration {
+	if value == 0 {
+		return time.Duration(fallback) * time.Millisecond
+	}
+	return time.Duration(value) * time.Millisecond
+}
@@ -322,7 +322,7 @@ func createVirtualGamepad(vendorId, productId uint16) error {
 		}
 
 		if len(gamepadSerial) > 0 {
-			dispatch(gamepadSerial, "TriggerHapticEngineExternal", left, right)
+			dispatch.TriggerHapticEngine(gamepadSerial, left, right)
 		}
 
 		rumbleMutex.Lock()
@@ -346,7 +346,7 @@ func createVirtualGamepad(vendorId, productId uint16) error {
 				rumbleMutex.Unlock()
 
 				if sc && len(gamepadSerial) > 0 {
-					dispatch(gamepadSerial, "TriggerHapticEngineExternal", byte(0), byte(0))
+					dispatch.TriggerHapticEngine(gamepadSerial, byte(0), byte(0))
 
 					rumbleMutex.Lock()
 					lastLeft, lastRight = 0, 0
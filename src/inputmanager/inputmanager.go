@@ -12,9 +12,12 @@ import (
 	"OpenLinkHub/src/logger"
 	"encoding/binary"
 	"errors"
+	"maps"
 	"os"
+	"path/filepath"
 	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"unsafe"
@@ -37,6 +40,16 @@ type KeyAssignment struct {
 	TiltToggle     bool   `json:"tiltToggle"`
 	TiltIndex      int    `json:"tiltIndex"`
 	DeviceId       string `json:"deviceId"`
+	LayerShift     uint8  `json:"layerShift"`
+	Layer          int    `json:"layer"`
+
+	// Dual-function actions
+	HoldActionType         uint8  `json:"holdActionType"`
+	HoldActionCommand      uint16 `json:"holdActionCommand"`
+	HoldThreshold          uint16 `json:"holdThreshold"`
+	DoubleTapActionType    uint8  `json:"doubleTapActionType"`
+	DoubleTapActionCommand uint16 `json:"doubleTapActionCommand"`
+	DoubleTapWindow        uint16 `json:"doubleTapWindow"`
 }
 
 type InputAction struct {
@@ -716,6 +729,7 @@ func Init() {
 	screenWidth = int32(display.GetScreenResolution().Width)
 	screenHeight = int32(display.GetScreenResolution().Height)
 	buildInputActions()
+	loadLayers()
 	CreateVirtualKeyboard()
 	CreateVirtualMouse()
 }
@@ -777,6 +791,9 @@ func Stop() {
 
 // GetMediaKeys will return a map of InputAction for Media keys
 func GetMediaKeys() map[uint16]InputAction {
+	actionMutex.RLock()
+	defer actionMutex.RUnlock()
+
 	keys := make(map[uint16]InputAction)
 	for key, value := range inputActions {
 		if value.Media {
@@ -788,6 +805,9 @@ func GetMediaKeys() map[uint16]InputAction {
 
 // GetControllerKeys will return a map of InputAction for Controller keys
 func GetControllerKeys() map[uint16]InputAction {
+	actionMutex.RLock()
+	defer actionMutex.RUnlock()
+
 	keys := make(map[uint16]InputAction)
 	for key, value := range inputActions {
 		if value.Controller {
@@ -799,6 +819,9 @@ func GetControllerKeys() map[uint16]InputAction {
 
 // GetInputKeys will return a map of InputAction for non-media keys
 func GetInputKeys() map[uint16]InputAction {
+	actionMutex.RLock()
+	defer actionMutex.RUnlock()
+
 	keys := make(map[uint16]InputAction)
 	for key, value := range inputActions {
 		if value.Media {
@@ -811,6 +834,9 @@ func GetInputKeys() map[uint16]InputAction {
 
 // GetMouseButtons will return a map of InputAction for mouse buttons
 func GetMouseButtons() map[uint16]InputAction {
+	actionMutex.RLock()
+	defer actionMutex.RUnlock()
+
 	keys := make(map[uint16]InputAction)
 	for key, value := range inputActions {
 		if value.Mouse {
@@ -821,6 +847,9 @@ func GetMouseButtons() map[uint16]InputAction {
 }
 
 func GetKeyName(keyIndex uint16) string {
+	actionMutex.RLock()
+	defer actionMutex.RUnlock()
+
 	if key, ok := inputActions[keyIndex]; ok {
 		return key.Name
 	}
@@ -829,7 +858,49 @@ func GetKeyName(keyIndex uint16) string {
 
 // GetInputActions will return a map of InputAction
 func GetInputActions() map[uint16]InputAction {
-	return inputActions
+	actionMutex.RLock()
+	defer actionMutex.RUnlock()
+	return maps.Clone(inputActions)
+}
+
+// GetInputActionByCode will return action type which emits given input event code
+func GetInputActionByCode(code uint16) (uint16, *InputAction) {
+	actionMutex.RLock()
+	defer actionMutex.RUnlock()
+
+	var actionType uint16
+	var action *InputAction
+	for key, value := range inputActions {
+		if value.CommandCode != code || value.Controller || value.Scroll {
+			continue
+		}
+		if action == nil || key < actionType {
+			actionType = key
+			action = &value
+		}
+	}
+	return actionType, action
+}
+
+// FindVirtualEvents will return input event nodes of virtual keyboard and mouse
+func FindVirtualEvents() []string {
+	names := []string{"OpenLinkHub Virtual Keyboard", "OpenLinkHub Virtual Mouse"}
+	sysClassEvents, err := filepath.Glob("/sys/class/input/event*")
+	if err != nil {
+		return nil
+	}
+
+	var out []string
+	for _, sysClassEvent := range sysClassEvents {
+		name, err := os.ReadFile(filepath.Join(sysClassEvent, "device", "name"))
+		if err != nil {
+			continue
+		}
+		if slices.Contains(names, strings.TrimSpace(string(name))) {
+			out = append(out, filepath.Join("/dev/input", filepath.Base(sysClassEvent)))
+		}
+	}
+	return out
 }
 
 // FindKeyAssignment will find nearest KeyAssignment by input value and given offset
@@ -866,6 +937,9 @@ func SetGamepadSerial(serial string) {
 
 // getInputAction will return InputAction based on actionType
 func getInputAction(actionType uint16) *InputAction {
+	actionMutex.RLock()
+	defer actionMutex.RUnlock()
+
 	if action, ok := inputActions[actionType]; ok {
 		return &action
 	}
@@ -95,6 +95,10 @@ func createVirtualKeyboard(vendorId, productId uint16) error {
 
 // InputControlKeyboard will emulate input events based on virtual keyboard
 func InputControlKeyboard(controlType uint16, hold bool) {
+	if runAction(controlType, !hold) {
+		return
+	}
+
 	if virtualKeyboardFile == nil {
 		logger.Log(logger.Fields{}).Error("Virtual keyboard is not present")
 		return
@@ -126,6 +130,10 @@ func InputControlKeyboard(controlType uint16, hold bool) {
 
 // InputControlKeyboardHold will emulate input events based on virtual keyboard
 func InputControlKeyboardHold(controlType uint16, press bool) {
+	if runAction(controlType, !press) {
+		return
+	}
+
 	if virtualKeyboardFile == nil {
 		logger.Log(logger.Fields{}).Error("Virtual keyboard is not present")
 		return
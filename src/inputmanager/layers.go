@@ -0,0 +1,514 @@
+package inputmanager
+
+// Package: inputmanager
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/logger"
+	"encoding/json"
+	"math/big"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+)
+
+const (
+	LayerShiftMomentary = 1 // Layer is active while key is held
+	LayerShiftToggle    = 2 // Layer is toggled on and off with each key press
+	maxLayers           = 16
+)
+
+// KeyLayer is a set of key assignments used while layer is active. Keys not assigned in a layer
+// fall through to layer 0, and then to device key assignments.
+type KeyLayer struct {
+	Name       string                   `json:"name"`
+	RgbProfile string                   `json:"rgbProfile"`
+	Keys       map[string]KeyAssignment `json:"keys"`
+}
+
+// DeviceLayers contains key assignment layers of a device
+type DeviceLayers struct {
+	Serial string           `json:"serial"`
+	Layers map[int]KeyLayer `json:"layers"`
+}
+
+// LayerStatus contains device layers and currently active layer
+type LayerStatus struct {
+	Active int              `json:"active"`
+	Layers map[int]KeyLayer `json:"layers"`
+}
+
+// layerShift is a momentary layer held by a key
+type layerShift struct {
+	key   string
+	layer int
+}
+
+// layerState contains active layers of a device
+type layerState struct {
+	toggled   int
+	momentary []layerShift
+	pressedIn map[string]int
+	held      map[string]bool
+	report    *big.Int
+}
+
+var (
+	layersLocation = ""
+	layers         = map[string]*DeviceLayers{}
+	layerStates    = map[string]*layerState{}
+	layerMutex     sync.Mutex
+	layerHandler   func(serial string)
+	layerKeyRegex  = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// loadLayers will load key assignment layers of all devices
+func loadLayers() {
+	layersLocation = config.GetConfig().ConfigPath + "/database/layers/"
+	if !common.FileExists(layersLocation) {
+		if err := os.MkdirAll(layersLocation, 0755); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": layersLocation}).Error("Unable to create layers directory")
+			return
+		}
+	}
+
+	files, err := os.ReadDir(layersLocation)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "location": layersLocation}).Error("Unable to read content of a folder")
+		return
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue
+		}
+
+		layerLocation := layersLocation + fi.Name()
+		if !common.IsValidExtension(layerLocation, ".json") {
+			continue
+		}
+
+		file, err := os.Open(layerLocation)
+		if err != nil {
+			logger.Log(logger.Fields{"error": err, "location": layerLocation}).Error("Unable to read key assignment layers")
+			continue
+		}
+
+		var deviceLayers DeviceLayers
+		if err = json.NewDecoder(file).Decode(&deviceLayers); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": layerLocation}).Error("Unable to decode key assignment layers")
+		} else if len(deviceLayers.Serial) > 0 {
+			layers[deviceLayers.Serial] = &deviceLayers
+		}
+
+		if err = file.Close(); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": layerLocation}).Warn("Failed to close file handle")
+		}
+	}
+}
+
+// SetLayerHandler will set function called when active layer of a device changes
+func SetLayerHandler(handler func(serial string)) {
+	layerHandler = handler
+}
+
+// GetLayers will return key assignment layers and active layer of a device
+func GetLayers(serial string) LayerStatus {
+	layerMutex.Lock()
+	defer layerMutex.Unlock()
+
+	status := LayerStatus{Layers: map[int]KeyLayer{}}
+	if deviceLayers, ok := layers[serial]; ok {
+		// Copy layers, since they are encoded after layerMutex is released
+		for id, layer := range deviceLayers.Layers {
+			keys := make(map[string]KeyAssignment, len(layer.Keys))
+			for key, value := range layer.Keys {
+				keys[key] = value
+			}
+			layer.Keys = keys
+			status.Layers[id] = layer
+		}
+		status.Active = activeLayer(serial)
+	}
+	return status
+}
+
+// GetActiveLayer will return active layer of a device
+func GetActiveLayer(serial string) int {
+	layerMutex.Lock()
+	defer layerMutex.Unlock()
+	return activeLayer(serial)
+}
+
+// GetToggledLayer will return layer toggled on a device, ignoring layers held by a key
+func GetToggledLayer(serial string) int {
+	layerMutex.Lock()
+	defer layerMutex.Unlock()
+
+	if state, ok := layerStates[serial]; ok {
+		return state.toggled
+	}
+	return 0
+}
+
+// SetToggledLayer will toggle given layer on a device, same as a toggle layer shift key.
+// Returns 2 on non-existing layer
+func SetToggledLayer(serial string, layerId int) uint8 {
+	layerMutex.Lock()
+	if layerId != 0 {
+		deviceLayers, ok := layers[serial]
+		if !ok {
+			layerMutex.Unlock()
+			return 2
+		}
+		if _, ok = deviceLayers.Layers[layerId]; !ok {
+			layerMutex.Unlock()
+			return 2
+		}
+	}
+
+	state := getLayerState(serial)
+	previous := activeLayer(serial)
+	state.toggled = layerId
+	changed := previous != activeLayer(serial)
+	layerMutex.Unlock()
+
+	if changed {
+		notifyLayerChange(serial)
+	}
+	return 1
+}
+
+// GetLayerRgbProfile will return RGB profile of active layer, or empty string when layer has none
+func GetLayerRgbProfile(serial string) string {
+	layerMutex.Lock()
+	defer layerMutex.Unlock()
+
+	if deviceLayers, ok := layers[serial]; ok {
+		return deviceLayers.Layers[activeLayer(serial)].RgbProfile
+	}
+	return ""
+}
+
+// SaveLayer will create or update key assignment layer of a device
+func SaveLayer(serial string, layerId int, layer KeyLayer) uint8 {
+	if layerId < 0 || layerId >= maxLayers {
+		return 2
+	}
+
+	for key, value := range layer.Keys {
+		if len(key) == 0 || !layerKeyRegex.MatchString(key) {
+			return 3
+		}
+		if value.LayerShift > LayerShiftToggle {
+			return 4
+		}
+		if value.LayerShift > 0 && (value.Layer < 0 || value.Layer >= maxLayers || value.Layer == layerId) {
+			return 4
+		}
+		if !IsValidDualFunction(value) {
+			return 5
+		}
+	}
+
+	layerMutex.Lock()
+	deviceLayers, ok := layers[serial]
+	if !ok {
+		deviceLayers = &DeviceLayers{Serial: serial, Layers: map[int]KeyLayer{}}
+	}
+	if layer.Keys == nil {
+		layer.Keys = map[string]KeyAssignment{}
+	}
+	deviceLayers.Layers[layerId] = layer
+	layers[serial] = deviceLayers
+
+	if !saveLayers(deviceLayers) {
+		layerMutex.Unlock()
+		return 0
+	}
+	layerMutex.Unlock()
+
+	notifyLayerChange(serial)
+	return 1
+}
+
+// DeleteLayer will delete key assignment layer of a device. Shift keys pointing to deleted layer are kept.
+func DeleteLayer(serial string, layerId int) uint8 {
+	layerMutex.Lock()
+	deviceLayers, ok := layers[serial]
+	if !ok {
+		layerMutex.Unlock()
+		return 2
+	}
+	if _, ok = deviceLayers.Layers[layerId]; !ok {
+		layerMutex.Unlock()
+		return 2
+	}
+	delete(deviceLayers.Layers, layerId)
+
+	// Return to base layer if deleted layer was active
+	if state, ok := layerStates[serial]; ok {
+		if state.toggled == layerId {
+			state.toggled = 0
+		}
+		state.momentary = slices.DeleteFunc(state.momentary, func(shift layerShift) bool {
+			return shift.layer == layerId
+		})
+	}
+
+	if !saveLayers(deviceLayers) {
+		layerMutex.Unlock()
+		return 0
+	}
+	layerMutex.Unlock()
+
+	notifyLayerChange(serial)
+	return 1
+}
+
+// layerKeyAssignment will resolve key assignment of a key in active layer. base and found are
+// device key assignment and its lookup result, returned when key is not assigned in any layer.
+// Returns false when key is consumed by layer shift. Release is resolved in same layer as press.
+func layerKeyAssignment(serial, key string, pressed bool, base KeyAssignment, found bool) (KeyAssignment, bool) {
+	layerMutex.Lock()
+	deviceLayers, ok := layers[serial]
+	if !ok {
+		layerMutex.Unlock()
+		return base, found
+	}
+
+	state := getLayerState(serial)
+	layerId := activeLayer(serial)
+	if pressed {
+		state.pressedIn[key] = layerId
+	} else if value, ok := state.pressedIn[key]; ok {
+		layerId = value
+		delete(state.pressedIn, key)
+	}
+
+	value, ok := lookupLayer(deviceLayers, layerId, key)
+	if !ok {
+		layerMutex.Unlock()
+		return base, found
+	}
+
+	if value.LayerShift > 0 {
+		changed := shiftLayer(state, key, value, pressed)
+		layerMutex.Unlock()
+		if changed {
+			notifyLayerChange(serial)
+		}
+		return value, false
+	}
+	layerMutex.Unlock()
+	return value, true
+}
+
+// GetLayerKeyAssignment will return key assignment of the first key found in active layer
+func GetLayerKeyAssignment(serial string, keys []string) (KeyAssignment, bool) {
+	layerMutex.Lock()
+	defer layerMutex.Unlock()
+
+	deviceLayers, ok := layers[serial]
+	if !ok {
+		return KeyAssignment{}, false
+	}
+
+	layerId := activeLayer(serial)
+	for _, key := range keys {
+		if value, ok := lookupLayer(deviceLayers, layerId, key); ok {
+			return value, true
+		}
+	}
+	return KeyAssignment{}, false
+}
+
+// IsLayerOverride will return true when key is handled by software in active layer, and its
+// default hardware action needs to be disabled
+func IsLayerOverride(serial, key string) bool {
+	value, ok := GetLayerKeyAssignment(serial, []string{key})
+	return ok && (!value.Default || value.LayerShift > 0)
+}
+
+// ResolveKeyboardReport will process layer shift and dual-function keys found in keyboard report, and
+// return report without keys handled by input manager. Keyboard layer keys are defined by their key hash.
+// Other keys pressed in a report resolve pending dual-function keys before report is returned.
+func ResolveKeyboardReport(serial string, report *big.Int) *big.Int {
+	layerMutex.Lock()
+	deviceLayers, ok := layers[serial]
+	if !ok {
+		layerMutex.Unlock()
+		return report
+	}
+
+	state := getLayerState(serial)
+	layerId := activeLayer(serial)
+
+	// Shift and dual-function keys of active layer and layer 0, plus keys held from previous layers
+	candidates := map[string]bool{}
+	for _, id := range []int{0, layerId} {
+		for key, value := range deviceLayers.Layers[id].Keys {
+			if value.LayerShift > 0 || IsDualFunction(value) {
+				candidates[key] = true
+			}
+		}
+	}
+	for key := range state.held {
+		candidates[key] = true
+	}
+
+	keys := make([]string, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changed := false
+	result := new(big.Int).Set(report)
+	for _, key := range keys {
+		mask, ok := new(big.Int).SetString(key, 10)
+		if !ok || mask.Sign() == 0 {
+			continue
+		}
+
+		pressed := new(big.Int).And(report, mask).Cmp(mask) == 0
+		if pressed != state.held[key] {
+			id := activeLayer(serial)
+			if pressed {
+				state.held[key] = true
+				state.pressedIn[key] = id
+			} else {
+				delete(state.held, key)
+				if value, ok := state.pressedIn[key]; ok {
+					id = value
+					delete(state.pressedIn, key)
+				}
+			}
+
+			value, ok := lookupLayer(deviceLayers, id, key)
+			if ok && value.LayerShift > 0 {
+				if shiftLayer(state, key, value, pressed) {
+					changed = true
+				}
+			} else if !pressed || (ok && IsDualFunction(value)) {
+				resolveDualFunction(serial, key, pressed, value)
+			}
+		}
+
+		if pressed {
+			result.AndNot(result, mask)
+		}
+	}
+
+	// Keys pressed since previous report resolve pending dual-function keys
+	interrupted := state.report != nil && new(big.Int).AndNot(result, state.report).Sign() != 0
+	state.report = new(big.Int).Set(result)
+	layerMutex.Unlock()
+
+	if interrupted {
+		interruptDualFunction(serial, "")
+	}
+	if changed {
+		notifyLayerChange(serial)
+	}
+	return result
+}
+
+// activeLayer will return active layer of a device. Caller must hold layerMutex.
+func activeLayer(serial string) int {
+	state, ok := layerStates[serial]
+	if !ok {
+		return 0
+	}
+	if len(state.momentary) > 0 {
+		return state.momentary[len(state.momentary)-1].layer
+	}
+	return state.toggled
+}
+
+// getLayerState will return layer state of a device, creating it when needed. Caller must hold layerMutex.
+func getLayerState(serial string) *layerState {
+	state, ok := layerStates[serial]
+	if !ok {
+		state = &layerState{
+			pressedIn: map[string]int{},
+			held:      map[string]bool{},
+		}
+		layerStates[serial] = state
+	}
+	return state
+}
+
+// lookupLayer will find key assignment in given layer, falling through to layer 0
+func lookupLayer(deviceLayers *DeviceLayers, layerId int, key string) (KeyAssignment, bool) {
+	if layer, ok := deviceLayers.Layers[layerId]; ok {
+		if value, ok := layer.Keys[key]; ok {
+			return value, true
+		}
+	}
+	if layerId != 0 {
+		if layer, ok := deviceLayers.Layers[0]; ok {
+			if value, ok := layer.Keys[key]; ok {
+				return value, true
+			}
+		}
+	}
+	return KeyAssignment{}, false
+}
+
+// shiftLayer will process layer shift key. Returns true when active layer has changed. Caller must hold layerMutex.
+func shiftLayer(state *layerState, key string, value KeyAssignment, pressed bool) bool {
+	active := func() int {
+		if len(state.momentary) > 0 {
+			return state.momentary[len(state.momentary)-1].layer
+		}
+		return state.toggled
+	}
+	previous := active()
+
+	switch value.LayerShift {
+	case LayerShiftMomentary:
+		if pressed {
+			state.momentary = append(state.momentary, layerShift{key: key, layer: value.Layer})
+		} else {
+			state.momentary = slices.DeleteFunc(state.momentary, func(shift layerShift) bool {
+				return shift.key == key
+			})
+		}
+	case LayerShiftToggle:
+		if pressed {
+			if state.toggled == value.Layer {
+				state.toggled = 0
+			} else {
+				state.toggled = value.Layer
+			}
+		}
+	}
+
+	return previous != active()
+}
+
+// saveLayers will save device layers to a file. Caller must hold layerMutex.
+func saveLayers(deviceLayers *DeviceLayers) bool {
+	location := layersLocation + deviceLayers.Serial + ".json"
+	if !common.IsValidExtension(location, ".json") {
+		return false
+	}
+	if err := common.SaveJsonData(location, deviceLayers); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": location}).Error("Unable to save key assignment layers")
+		return false
+	}
+	return true
+}
+
+// notifyLayerChange will notify layer handler about layer change of a device
+func notifyLayerChange(serial string) {
+	if layerHandler != nil {
+		go layerHandler(serial)
+	}
+}
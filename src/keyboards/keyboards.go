@@ -7,6 +7,7 @@ package keyboards
 import (
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/inputmanager"
 	"OpenLinkHub/src/logger"
 	"OpenLinkHub/src/rgb"
 	"encoding/json"
@@ -203,3 +204,28 @@ func GetLayouts(key string) []string {
 	}
 	return layouts
 }
+
+// ApplyLayer will return key with its assignment from active key assignment layer, if key is assigned in it
+func ApplyLayer(serial string, key Key) Key {
+	value, ok := inputmanager.GetLayerKeyAssignment(serial, key.KeyHash)
+	if !ok {
+		return key
+	}
+
+	key.Default = value.Default
+	key.ActionType = value.ActionType
+	key.ActionCommand = value.ActionCommand
+	key.ActionHold = value.ActionHold
+	key.ToggleDelay = value.ToggleDelay
+	key.ModifierKey = value.ModifierKey
+	key.RetainOriginal = value.RetainOriginal
+	key.DeviceId = value.DeviceId
+	if value.LayerShift > 0 {
+		// Layer shift keys are handled by input manager
+		key.Default = false
+		key.ActionType = 0
+		key.ModifierKey = 0
+		key.RetainOriginal = false
+	}
+	return key
+}
@@ -0,0 +1,108 @@
+package macro
+
+// Package: macro
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/logger"
+	"context"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	handlerMutex   sync.Mutex
+	deviceHandler  func(action Actions)
+	commandTimeout = 30 * time.Second
+)
+
+// SetDeviceHandler will set function used to run actions which control devices
+func SetDeviceHandler(handler func(action Actions)) {
+	handlerMutex.Lock()
+	defer handlerMutex.Unlock()
+	deviceHandler = handler
+}
+
+// runDeviceAction will pass action to device handler
+func runDeviceAction(v Actions) {
+	handlerMutex.Lock()
+	handler := deviceHandler
+	handlerMutex.Unlock()
+
+	if handler == nil {
+		logger.Log(logger.Fields{"actionType": v.ActionType}).Warn("Device actions are not available")
+		return
+	}
+	handler(v)
+}
+
+// CommandsAllowed will return true when launch and command actions can run. Both require allowCommands in config,
+// and launch requires user-space install, since system service user has no access to desktop session.
+func CommandsAllowed(actionType uint8) bool {
+	if !config.GetConfig().AllowCommands {
+		return false
+	}
+	if actionType == ActionLaunch && config.IsSystemService() {
+		return false
+	}
+	return true
+}
+
+// launch will start application in its own process group without waiting for it to exit
+func launch(application string) {
+	if len(application) == 0 {
+		return
+	}
+
+	if !CommandsAllowed(ActionLaunch) {
+		logger.Log(logger.Fields{"application": application}).Warn("Macro launch requires allowCommands in config.json and user-space install")
+		return
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", application)
+	cmd.Env = os.Environ()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		logger.Log(logger.Fields{"error": err, "application": application}).Error("Unable to launch application")
+		return
+	}
+
+	// Collect exit status once application is closed
+	go func() {
+		_ = cmd.Wait()
+	}()
+}
+
+// command will run shell command and wait for it to finish, so next actions run after it. Command is
+// killed when macro playback is stopped or command runs too long.
+func command(line string, quit chan struct{}) {
+	if len(line) == 0 {
+		return
+	}
+
+	if !CommandsAllowed(ActionCommand) {
+		logger.Log(logger.Fields{"command": line}).Warn("Macro commands are disabled. Set allowCommands in config.json to enable them")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", line)
+	cmd.Env = os.Environ()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logger.Log(logger.Fields{"error": err, "command": line, "output": string(output)}).Error("Unable to run macro command")
+	}
+}
@@ -7,6 +7,7 @@ package macro
 import (
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/inputmanager"
 	"OpenLinkHub/src/logger"
 	"encoding/json"
 	"fmt"
@@ -28,12 +29,18 @@ type Actions struct {
 	ActionCommand         uint16 `json:"actionCommand"`
 	ActionDelay           uint16 `json:"actionDelay"`
 	ActionHold            bool   `json:"actionHold"`
+	ActionRelease         bool   `json:"actionRelease"`
 	ActionRepeat          uint8  `json:"actionRepeat"`
 	ActionRepeatDelay     uint16 `json:"actionRepeatDelay"`
 	ActionText            string `json:"actionText"`
 	MousePositionX        int    `json:"mousePositionX"`
 	MousePositionY        int    `json:"mousePositionY"`
 	MousePositionAbsolute bool   `json:"mousePositionAbsolute"`
+	ActionTarget          string `json:"actionTarget"`
+	ActionChannelId       int    `json:"actionChannelId"`
+	ActionProfile         string `json:"actionProfile"`
+	ActionProfileAlt      string `json:"actionProfileAlt"`
+	ActionValue           uint8  `json:"actionValue"`
 }
 
 type Tracker struct {
@@ -53,6 +60,9 @@ func Init() {
 	pwd = config.GetConfig().ConfigPath
 	location = pwd + "/database/macros/"
 
+	// Macros bound to dual-function keys
+	inputmanager.SetMacroHandler(Run)
+
 	files, err := os.ReadDir(location)
 	if err != nil {
 		logger.Log(logger.Fields{"error": err, "location": location}).Error("Unable to read content of a folder")
@@ -209,6 +219,21 @@ func NewMacroProfile(macroName string) uint8 {
 	mutex.Lock()
 	defer mutex.Unlock()
 
+	if createProfile(macroName, map[int]Actions{}) == 0 {
+		return 0
+	}
+	return 1
+}
+
+// ProfileExists will check if macro profile with given name exists
+func ProfileExists(macroName string) bool {
+	profile := fmt.Sprintf("%s/database/macros/%s.json", config.GetConfig().ConfigPath, strings.ToLower(macroName))
+	return common.FileExists(profile)
+}
+
+// createProfile will create and save macro profile with given actions. Caller must hold mutex.
+// Returns new macro ID, or 0 when profile already exists.
+func createProfile(macroName string, actions map[int]Actions) int {
 	profile := fmt.Sprintf("%s/database/macros/%s.json", config.GetConfig().ConfigPath, strings.ToLower(macroName))
 	if common.FileExists(profile) {
 		return 0
@@ -228,11 +253,11 @@ func NewMacroProfile(macroName string) uint8 {
 	macro := Macro{
 		Id:      macroId,
 		Name:    macroName,
-		Actions: map[int]Actions{},
+		Actions: actions,
 	}
 	macros[macroId] = macro
 	SaveProfile(profile, macro)
-	return 1
+	return macroId
 }
 
 // NewMacroProfileValue will create new macro profile value
@@ -252,6 +277,11 @@ func NewMacroProfileValue(macroId int, macroAction *Actions) uint8 {
 				MousePositionX:        macroAction.MousePositionX,
 				MousePositionY:        macroAction.MousePositionY,
 				MousePositionAbsolute: macroAction.MousePositionAbsolute,
+				ActionTarget:          macroAction.ActionTarget,
+				ActionChannelId:       macroAction.ActionChannelId,
+				ActionProfile:         macroAction.ActionProfile,
+				ActionProfileAlt:      macroAction.ActionProfileAlt,
+				ActionValue:           macroAction.ActionValue,
 			}
 			macros[macroId] = val
 			SaveProfile(profile, macros[macroId])
@@ -0,0 +1,338 @@
+package macro
+
+// Package: macro
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/inputmanager"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ActionKeyboard      = 1
+	ActionMedia         = 3
+	ActionDelay         = 5
+	ActionText          = 6
+	ActionMouse         = 9
+	ActionMouseMovement = 20
+	ActionLaunch        = 21
+	ActionCommand       = 22
+	ActionUserProfile   = 23
+	ActionRgbProfile    = 24
+	ActionBrightness    = 25
+	ActionSpeedToggle   = 26
+)
+
+// pressOwner owns actions held by macros which are not looped. Looped macros own their actions by macro id.
+const pressOwner = -1
+
+// Player plays macros of a single device. Drivers report macro key press and release, and Player
+// takes care of hold, repeat and cancellation. Zero value is ready to use.
+//
+// Macro is played in background and macros of the same device are played in order they were
+// pressed, while looping macros run alongside them. Actions with hold flag stay pressed until
+// macro key is released or macro finishes, whichever comes later, or until macro reaches action
+// with release flag for the same key. Macro with negative repeat
+// value is looped until its key is pressed again, and its held actions stay pressed while it
+// runs. Delays and repeats are cancelled by Stop.
+type Player struct {
+	mutex   sync.Mutex
+	playing sync.Mutex
+	held    map[int]map[int]Tracker
+	loops   map[int]chan struct{}
+	quit    chan struct{}
+	pressed bool
+}
+
+// Run will play macro once and release all held keys when done. It blocks until macro is played.
+func Run(macroId int) bool {
+	profile := GetProfile(macroId)
+	if profile == nil {
+		return false
+	}
+
+	p := &Player{}
+	p.play(profile, 1, nil, p.cancel())
+	return true
+}
+
+// Press will start playing macro bound to a pressed key. Pressing key of a looping macro again stops the loop.
+func (p *Player) Press(macroId int) bool {
+	profile := GetProfile(macroId)
+	if profile == nil {
+		return false
+	}
+
+	p.mutex.Lock()
+	p.pressed = true
+	quit := p.cancelLocked()
+
+	if profile.Repeat >= 0 {
+		p.mutex.Unlock()
+		go p.play(profile, max(profile.Repeat, 1), nil, quit)
+		return true
+	}
+
+	if p.loops == nil {
+		p.loops = make(map[int]chan struct{})
+	}
+	if stop, running := p.loops[macroId]; running {
+		close(stop)
+		delete(p.loops, macroId)
+		p.mutex.Unlock()
+		return true
+	}
+	stop := make(chan struct{})
+	p.loops[macroId] = stop
+	p.mutex.Unlock()
+
+	go p.play(profile, -1, stop, quit)
+	return true
+}
+
+// Release will release keys held by macros once macro key is released
+func (p *Player) Release() {
+	p.mutex.Lock()
+	p.pressed = false
+	p.mutex.Unlock()
+	p.releaseHeld(pressOwner)
+}
+
+// Stop will stop all loops, repeats and delays and release held keys
+func (p *Player) Stop() {
+	p.mutex.Lock()
+	if p.quit != nil {
+		close(p.quit)
+		p.quit = nil
+	}
+	owners := make([]int, 0, len(p.held))
+	for owner := range p.held {
+		owners = append(owners, owner)
+	}
+	p.loops = nil
+	p.pressed = false
+	p.mutex.Unlock()
+
+	for _, owner := range owners {
+		p.releaseHeld(owner)
+	}
+}
+
+// cancel returns channel closed by Stop
+func (p *Player) cancel() chan struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.cancelLocked()
+}
+
+// cancelLocked returns channel closed by Stop, caller must hold mutex
+func (p *Player) cancelLocked() chan struct{} {
+	if p.quit == nil {
+		p.quit = make(chan struct{})
+	}
+	return p.quit
+}
+
+// play will play macro given amount of times, or until stopped when times is negative
+func (p *Player) play(profile *Macro, times int, stop, quit chan struct{}) {
+	owner := profile.Id
+	if times >= 0 {
+		// Loops run alongside other macros
+		owner = pressOwner
+		p.playing.Lock()
+		defer p.playing.Unlock()
+	}
+
+	for i := 0; times < 0 || i < times; i++ {
+		if i > 0 && !wait(profile.RepeatDelay, stop, quit) {
+			break
+		}
+		if !p.playActions(profile, owner, i == 0, stop, quit) {
+			break
+		}
+	}
+
+	// Loop was stopped or key was released while macro was playing
+	p.mutex.Lock()
+	pressed := p.pressed
+	p.mutex.Unlock()
+	if owner != pressOwner || !pressed {
+		p.releaseHeld(owner)
+	}
+}
+
+// playActions will play all macro actions in order. Actions with hold flag are pressed only on first run,
+// unless macro releases them itself.
+func (p *Player) playActions(profile *Macro, owner int, firstRun bool, stop, quit chan struct{}) bool {
+	for i := 0; i < len(profile.Actions); i++ {
+		v, valid := profile.Actions[i]
+		if !valid {
+			continue
+		}
+
+		if v.ActionRelease {
+			p.unhold(owner, v)
+			continue
+		}
+
+		if v.ActionHold {
+			if !firstRun && !releasedLater(profile, i) {
+				continue
+			}
+			switch v.ActionType {
+			case ActionKeyboard, ActionMedia:
+				p.hold(owner, i, v)
+				inputmanager.InputControlKeyboard(v.ActionCommand, true)
+				continue
+			case ActionMouse:
+				if v.ActionRepeat == 0 {
+					p.hold(owner, i, v)
+					inputmanager.InputControlMouseHold(v.ActionCommand, true)
+					continue
+				}
+			}
+		}
+
+		if v.ActionType == ActionDelay {
+			if !wait(int(v.ActionDelay), stop, quit) {
+				return false
+			}
+			continue
+		}
+
+		repeat := max(int(v.ActionRepeat), 1)
+		for z := 0; z < repeat; z++ {
+			if z > 0 && !wait(int(v.ActionRepeatDelay), stop, quit) {
+				return false
+			}
+			if cancelled(stop, quit) {
+				return false
+			}
+			runAction(v, quit)
+		}
+	}
+	return true
+}
+
+// runAction will run a single macro action once. Quit channel stops running command.
+func runAction(v Actions, quit chan struct{}) {
+	switch v.ActionType {
+	case ActionKeyboard, ActionMedia:
+		inputmanager.InputControlKeyboard(v.ActionCommand, false)
+	case ActionMouse:
+		inputmanager.InputControlMouse(v.ActionCommand)
+	case ActionText:
+		inputmanager.InputControlKeyboardText(v.ActionText)
+	case ActionMouseMovement:
+		if v.MousePositionAbsolute {
+			inputmanager.InputControlMoveAbsolute(int32(v.MousePositionX), int32(v.MousePositionY))
+		} else {
+			inputmanager.InputControlMove(int32(v.MousePositionX), int32(v.MousePositionY))
+		}
+	case ActionLaunch:
+		launch(v.ActionText)
+	case ActionCommand:
+		command(v.ActionText, quit)
+	case ActionUserProfile, ActionRgbProfile, ActionBrightness, ActionSpeedToggle:
+		runDeviceAction(v)
+	}
+}
+
+// hold will track action pressed until its owner releases it
+func (p *Player) hold(owner, index int, v Actions) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.held == nil {
+		p.held = make(map[int]map[int]Tracker)
+	}
+	if p.held[owner] == nil {
+		p.held[owner] = make(map[int]Tracker)
+	}
+	p.held[owner][index] = Tracker{Value: v.ActionCommand, Type: v.ActionType}
+}
+
+// unhold will release held action before macro ends
+func (p *Player) unhold(owner int, v Actions) {
+	p.mutex.Lock()
+	for key, value := range p.held[owner] {
+		if value.Value == v.ActionCommand && value.Type == v.ActionType {
+			delete(p.held[owner], key)
+		}
+	}
+	p.mutex.Unlock()
+
+	switch v.ActionType {
+	case ActionKeyboard, ActionMedia:
+		inputmanager.InputControlKeyboardHold(v.ActionCommand, false)
+	case ActionMouse:
+		inputmanager.InputControlMouseHold(v.ActionCommand, false)
+	}
+}
+
+// releasedLater returns true when macro releases held action at given index
+func releasedLater(profile *Macro, index int) bool {
+	for i := index + 1; i < len(profile.Actions); i++ {
+		v, valid := profile.Actions[i]
+		if valid && v.ActionRelease && v.ActionType == profile.Actions[index].ActionType && v.ActionCommand == profile.Actions[index].ActionCommand {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseHeld will release actions held by owner in order they were defined
+func (p *Player) releaseHeld(owner int) {
+	p.mutex.Lock()
+	held := p.held[owner]
+	delete(p.held, owner)
+	p.mutex.Unlock()
+
+	keys := make([]int, 0, len(held))
+	for key := range held {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	for _, key := range keys {
+		switch held[key].Type {
+		case ActionKeyboard, ActionMedia:
+			inputmanager.InputControlKeyboardHold(held[key].Value, false)
+		case ActionMouse:
+			inputmanager.InputControlMouseHold(held[key].Value, false)
+		}
+	}
+}
+
+// wait will sleep for given amount of milliseconds. Returns false when playback was cancelled.
+func wait(delay int, stop, quit chan struct{}) bool {
+	if delay <= 0 {
+		return !cancelled(stop, quit)
+	}
+
+	timer := time.NewTimer(time.Duration(delay) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-stop:
+		return false
+	case <-quit:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// cancelled returns true when playback was cancelled
+func cancelled(stop, quit chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	case <-quit:
+		return true
+	default:
+		return false
+	}
+}
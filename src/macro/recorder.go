@@ -0,0 +1,340 @@
+package macro
+
+// Package: macro
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/inputmanager"
+	"OpenLinkHub/src/logger"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sstallion/go-hid"
+)
+
+const (
+	evKey           = 0x01
+	keyRelease      = 0
+	keyPress        = 1
+	corsairVendorId = uint16(6940)
+	scufVendorId    = uint16(11925)
+)
+
+// pairWindow is maximum time between physical key event and virtual event emitted for it by software key handling
+var pairWindow = int64(50 * time.Millisecond)
+
+// RecordingStatus describes macro recording in progress
+type RecordingStatus struct {
+	Recording bool     `json:"recording"`
+	Started   int64    `json:"started"`
+	Events    int      `json:"events"`
+	Inputs    []string `json:"inputs"`
+}
+
+// recordedEvent is a single key or button event captured during recording
+type recordedEvent struct {
+	time    int64
+	code    uint16
+	press   bool
+	virtual bool
+}
+
+// recorder captures key and button events from input event nodes
+type recorder struct {
+	mutex   sync.Mutex
+	wg      sync.WaitGroup
+	files   []*os.File
+	inputs  []string
+	events  []recordedEvent
+	started time.Time
+}
+
+var (
+	recordMutex sync.Mutex
+	recording   *recorder
+)
+
+// StartRecording will start capturing key presses, releases and mouse buttons from connected
+// keyboards and mice, and from virtual keyboard and mouse. Returns 1 when recording is started,
+// 2 when recording is already running and 0 when no input could be opened.
+func StartRecording() uint8 {
+	recordMutex.Lock()
+	defer recordMutex.Unlock()
+
+	if recording != nil {
+		return 2
+	}
+
+	r := &recorder{started: time.Now()}
+	events := findInputEvents()
+	virtual := make(map[*os.File]bool, len(events))
+	inputs := make([]string, 0, len(events))
+	for input := range events {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+
+	for _, input := range inputs {
+		f, err := os.OpenFile(input, os.O_RDONLY, 0)
+		if err != nil {
+			logger.Log(logger.Fields{"error": err, "input": input}).Warn("Unable to open input event for macro recording")
+			continue
+		}
+		r.files = append(r.files, f)
+		r.inputs = append(r.inputs, input)
+		virtual[f] = events[input]
+	}
+
+	if len(r.files) == 0 {
+		logger.Log(logger.Fields{}).Warn("No input events available for macro recording")
+		return 0
+	}
+
+	for _, f := range r.files {
+		r.wg.Add(1)
+		go r.listen(f, virtual[f])
+	}
+	recording = r
+	logger.Log(logger.Fields{"inputs": r.inputs}).Info("Macro recording started")
+	return 1
+}
+
+// StopRecording will stop capturing events and save them as a new macro profile. When quantize
+// is above 0, delays are rounded to its nearest multiple in milliseconds. Returns macro ID and 1
+// on success, 2 when recording is not running, 3 when profile already exists and 4 when nothing
+// was recorded. Recording keeps running when profile already exists.
+func StopRecording(macroName string, quantize int) (int, uint8) {
+	recordMutex.Lock()
+	defer recordMutex.Unlock()
+
+	if recording == nil {
+		return 0, 2
+	}
+
+	if ProfileExists(macroName) {
+		return 0, 3
+	}
+
+	events := recording.stop()
+	recording = nil
+
+	actions := buildActions(events, quantize)
+	if len(actions) == 0 {
+		return 0, 4
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	macroId := createProfile(macroName, actions)
+	if macroId == 0 {
+		return 0, 3
+	}
+	logger.Log(logger.Fields{"macroId": macroId, "actions": len(actions)}).Info("Macro recording saved")
+	return macroId, 1
+}
+
+// CancelRecording will stop capturing events and discard them
+func CancelRecording() uint8 {
+	recordMutex.Lock()
+	defer recordMutex.Unlock()
+
+	if recording == nil {
+		return 2
+	}
+	recording.stop()
+	recording = nil
+	logger.Log(logger.Fields{}).Info("Macro recording cancelled")
+	return 1
+}
+
+// GetRecordingStatus will return status of macro recording
+func GetRecordingStatus() RecordingStatus {
+	recordMutex.Lock()
+	defer recordMutex.Unlock()
+
+	if recording == nil {
+		return RecordingStatus{}
+	}
+
+	recording.mutex.Lock()
+	defer recording.mutex.Unlock()
+	return RecordingStatus{
+		Recording: true,
+		Started:   recording.started.Unix(),
+		Events:    len(recording.events),
+		Inputs:    recording.inputs,
+	}
+}
+
+// findInputEvents will return input event nodes of connected Corsair and Scuf devices, and of virtual
+// keyboard and mouse, mapped to true for virtual ones. Virtual devices are required for devices whose
+// input events are grabbed by driver, and for keys handled in software.
+func findInputEvents() map[string]bool {
+	inputs := map[string]bool{}
+	enum := hid.EnumFunc(func(info *hid.DeviceInfo) error {
+		events, err := common.FindEventsByHidraw(info.Path)
+		if err != nil {
+			return nil
+		}
+		for _, event := range events {
+			inputs[event] = true
+		}
+		return nil
+	})
+
+	for _, vendorId := range []uint16{corsairVendorId, scufVendorId} {
+		if err := hid.Enumerate(vendorId, hid.ProductIDAny, enum); err != nil {
+			logger.Log(logger.Fields{"error": err, "vendorId": vendorId}).Warn("Unable to enumerate devices")
+		}
+	}
+
+	for input := range inputs {
+		inputs[input] = false
+	}
+	for _, event := range inputmanager.FindVirtualEvents() {
+		inputs[event] = true
+	}
+	return inputs
+}
+
+// listen will read key events from input until input is closed
+func (r *recorder) listen(f *os.File, virtual bool) {
+	defer r.wg.Done()
+	for {
+		ev, err := inputmanager.ReadEvent(f)
+		if err != nil {
+			return
+		}
+
+		// Auto repeat is not recorded
+		if ev.Type != evKey || (ev.Value != keyPress && ev.Value != keyRelease) {
+			continue
+		}
+
+		r.mutex.Lock()
+		r.events = append(r.events, recordedEvent{
+			time:    ev.Time.Nano(),
+			code:    ev.Code,
+			press:   ev.Value == keyPress,
+			virtual: virtual,
+		})
+		r.mutex.Unlock()
+	}
+}
+
+// stop will close all inputs and return recorded events in order they happened
+func (r *recorder) stop() []recordedEvent {
+	for _, f := range r.files {
+		if err := f.Close(); err != nil {
+			logger.Log(logger.Fields{"error": err}).Warn("Failed to close input event")
+		}
+	}
+	r.wg.Wait()
+
+	sort.SliceStable(r.events, func(i, j int) bool { return r.events[i].time < r.events[j].time })
+	return r.events
+}
+
+// softwareHandled will drop physical events followed by virtual event which software key handling
+// emitted for them. Only virtual event is kept, since physical device reports original key.
+func softwareHandled(events []recordedEvent) []recordedEvent {
+	paired := make([]bool, len(events))
+	for i, event := range events {
+		if !event.virtual {
+			continue
+		}
+		for j := i - 1; j >= 0 && event.time-events[j].time <= pairWindow; j-- {
+			if !events[j].virtual && !paired[j] && events[j].press == event.press {
+				paired[j] = true
+				break
+			}
+		}
+	}
+
+	filtered := make([]recordedEvent, 0, len(events))
+	for i, event := range events {
+		if !paired[i] {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// buildActions will convert recorded events to macro actions. Key released before any other event
+// becomes a single key press, otherwise key is held until its release.
+func buildActions(events []recordedEvent, quantize int) map[int]Actions {
+	// Keys pressed before recording started are released during it. Keep only events which change key state.
+	pressed := map[uint16]bool{}
+	filtered := make([]recordedEvent, 0, len(events))
+	for _, event := range softwareHandled(events) {
+		if pressed[event.code] == event.press {
+			continue
+		}
+		pressed[event.code] = event.press
+		filtered = append(filtered, event)
+	}
+
+	// Presses at the end are never released, e.g. click on button which stopped recording
+	for len(filtered) > 0 && filtered[len(filtered)-1].press {
+		filtered = filtered[:len(filtered)-1]
+	}
+
+	actions := map[int]Actions{}
+	add := func(action Actions) {
+		actions[len(actions)] = action
+	}
+
+	var last int64
+	for i := 0; i < len(filtered); i++ {
+		event := filtered[i]
+		actionCommand, inputAction := inputmanager.GetInputActionByCode(event.code)
+		if inputAction == nil {
+			continue
+		}
+
+		actionType := uint8(ActionKeyboard)
+		if inputAction.Media {
+			actionType = ActionMedia
+		} else if inputAction.Mouse {
+			actionType = ActionMouse
+		}
+
+		if len(actions) > 0 {
+			delay := quantizeDelay((event.time-last)/int64(time.Millisecond), quantize)
+			for delay > 0 {
+				value := min(delay, math.MaxUint16)
+				add(Actions{ActionType: ActionDelay, ActionDelay: uint16(value)})
+				delay -= value
+			}
+		}
+		last = event.time
+
+		action := Actions{ActionType: actionType, ActionCommand: actionCommand}
+		if event.press {
+			if i+1 < len(filtered) && filtered[i+1].code == event.code && !filtered[i+1].press {
+				i++
+			} else {
+				action.ActionHold = true
+			}
+		} else {
+			action.ActionRelease = true
+		}
+		add(action)
+	}
+	return actions
+}
+
+// quantizeDelay will round delay to the nearest multiple of quantize
+func quantizeDelay(delay int64, quantize int) int64 {
+	if quantize <= 0 {
+		return delay
+	}
+	step := int64(quantize)
+	return (delay + step/2) / step * step
+}
@@ -5,10 +5,13 @@ package metrics
 // License: GPL-3.0 or later
 
 import (
+	"OpenLinkHub/src/stats"
 	"OpenLinkHub/src/systeminfo"
 	"OpenLinkHub/src/temperatures"
-	"fmt"
+	"OpenLinkHub/src/watchdog"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -30,6 +33,9 @@ type Header struct {
 	HwmonDevice      string
 	Temperature      float64
 	Rpm              int16
+	Watts            float64
+	Volts            float64
+	Amps             float64
 }
 
 type DeviceMetric struct {
@@ -48,6 +54,20 @@ type DefaultTemp struct {
 	Temperature float64
 }
 
+type PowerSupply struct {
+	Product      string
+	Serial       string
+	InputVoltage float64
+	InputPower   float64
+	OutputPower  float64
+}
+
+// sample is a single value of a metric family with label name and value pairs
+type sample struct {
+	labels []string
+	value  float64
+}
+
 var (
 	mu sync.RWMutex
 
@@ -55,28 +75,43 @@ var (
 	deviceMetrics  = make(map[string]Header)      // key: serial:channel
 	storageMetrics = make(map[string]StorageTemp) // key: hwmonDevice
 	defaultMetrics = make(map[string]DefaultTemp) // key: model
+	psuMetrics     = make(map[string]PowerSupply) // key: serial
+	userProfiles   = make(map[string]string)      // key: serial
 )
 
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
 // Init initializes internal maps (optional in Go, but for symmetry)
 func Init() {
 	productMetrics = make(map[string]Header)
 	deviceMetrics = make(map[string]Header)
 	storageMetrics = make(map[string]StorageTemp)
 	defaultMetrics = make(map[string]DefaultTemp)
+	psuMetrics = make(map[string]PowerSupply)
+	userProfiles = make(map[string]string)
 }
 
-// PopulateDefault adds default temperature metrics (e.g., CPU, GPU)
+// PopulateDefault adds default temperature metrics (e.g., CPU, GPU). Temperatures come from sensor cache,
+// so frequent calls do not run nvidia-smi or scan hwmon each time
 func PopulateDefault() {
 	cpu := systeminfo.GetInfo().CPU.Model
+	cpuTemp := temperatures.GetSensorTemperature(temperatures.SensorId{Sensor: temperatures.SensorTypeCPU})
+
+	gpus := make(map[string]float32)
+	for key, val := range systeminfo.GetInfo().GPU {
+		gpus[val.Model] = temperatures.GetSensorTemperature(temperatures.SensorId{Sensor: temperatures.SensorTypeMultiGPU, Index: key})
+	}
+
 	mu.Lock()
 	defaultMetrics[cpu] = DefaultTemp{
 		Model:       cpu,
-		Temperature: float64(temperatures.GetCpuTemperature()),
+		Temperature: float64(cpuTemp),
 	}
-	for key, val := range systeminfo.GetInfo().GPU {
-		defaultMetrics[val.Model] = DefaultTemp{
-			Model:       val.Model,
-			Temperature: float64(temperatures.GetGpuTemperatureIndex(key)),
+	for model, temp := range gpus {
+		defaultMetrics[model] = DefaultTemp{
+			Model:       model,
+			Temperature: float64(temp),
 		}
 	}
 	mu.Unlock()
@@ -105,6 +140,34 @@ func Populate(header *Header) {
 	mu.Unlock()
 }
 
+// PopulatePowerSupply fills in power supply input info
+func PopulatePowerSupply(psu *PowerSupply) {
+	mu.Lock()
+	psuMetrics[psu.Serial] = *psu
+	mu.Unlock()
+}
+
+// PopulateUserProfile fills in active user profile of a device
+func PopulateUserProfile(serial, profile string) {
+	mu.Lock()
+	userProfiles[serial] = profile
+	mu.Unlock()
+}
+
+// RemoveDevice removes all metrics of a disconnected device
+func RemoveDevice(serial string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(productMetrics, serial)
+	delete(psuMetrics, serial)
+	delete(userProfiles, serial)
+	for key, header := range deviceMetrics {
+		if header.Serial == serial {
+			delete(deviceMetrics, key)
+		}
+	}
+}
+
 // GetProductMetrics return product info
 func GetProductMetrics() map[string]Header {
 	mu.RLock()
@@ -149,6 +212,73 @@ func GetDefaultMetrics() map[string]DefaultTemp {
 	return cp
 }
 
+// GetPowerSupplyMetrics return power supply metrics
+func GetPowerSupplyMetrics() map[string]PowerSupply {
+	mu.RLock()
+	defer mu.RUnlock()
+	cp := make(map[string]PowerSupply, len(psuMetrics))
+	for k, v := range psuMetrics {
+		cp[k] = v
+	}
+	return cp
+}
+
+// GetUserProfiles return active user profiles
+func GetUserProfiles() map[string]string {
+	mu.RLock()
+	defer mu.RUnlock()
+	cp := make(map[string]string, len(userProfiles))
+	for k, v := range userProfiles {
+		cp[k] = v
+	}
+	return cp
+}
+
+// writeFamily writes HELP and TYPE metadata followed by samples sorted by labels
+func writeFamily(b *strings.Builder, name, help string, samples []sample) {
+	b.WriteString("# HELP " + name + " " + helpEscaper.Replace(help) + "\n")
+	b.WriteString("# TYPE " + name + " gauge\n")
+
+	lines := make([]string, 0, len(samples))
+	for _, s := range samples {
+		var line strings.Builder
+		line.WriteString(name)
+		if len(s.labels) > 0 {
+			line.WriteByte('{')
+			for i := 0; i+1 < len(s.labels); i += 2 {
+				if i > 0 {
+					line.WriteByte(',')
+				}
+				line.WriteString(s.labels[i] + `="` + labelEscaper.Replace(s.labels[i+1]) + `"`)
+			}
+			line.WriteByte('}')
+		}
+		line.WriteString(" " + strconv.FormatFloat(s.value, 'f', -1, 64) + "\n")
+		lines = append(lines, line.String())
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+}
+
+// channelLabels returns full set of labels describing device channel
+func channelLabels(d Header) []string {
+	return []string{
+		"serial", d.Serial,
+		"channelId", d.ChannelId,
+		"name", d.Name,
+		"description", d.Description,
+		"profile", d.Profile,
+		"label", d.Label,
+		"rgb", d.RGB,
+		"aio", d.AIO,
+		"pump", d.ContainsPump,
+		"probe", d.TemperatureProbe,
+		"led", d.LedChannels,
+	}
+}
+
 // Handler serves metrics in Prometheus exposition format.
 func Handler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -157,53 +287,102 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var b strings.Builder
+	var products, temps, speeds, watts, volts, amps, duty, profiles []sample
 
-	// Device info
-	b.WriteString("# HELP openlinkhub Product information\n")
-	b.WriteString("# TYPE openlinkhub gauge\n")
 	for _, p := range GetProductMetrics() {
-		b.WriteString(fmt.Sprintf(`openlinkhub{product="%s",serial="%s",firmware="%s"} 1`+"\n",
-			p.Product, p.Serial, p.Firmware))
+		products = append(products, sample{labels: []string{"product", p.Product, "serial", p.Serial, "firmware", p.Firmware}, value: 1})
 	}
 
-	// Temperature data
-	b.WriteString("# HELP openlinkhub_temperature Current temperature of devices.\n")
-	b.WriteString("# TYPE openlinkhub_temperature gauge\n")
 	for _, d := range GetDeviceMetrics() {
+		power := []string{"serial", d.Serial, "channelId", d.ChannelId, "name", d.Name}
 		if d.Temperature > 0 {
-			b.WriteString(fmt.Sprintf(`openlinkhub_temperature{serial="%s",channelId="%s",name="%s",description="%s",profile="%s",label="%s",rgb="%s",aio="%s",pump="%s",probe="%s",led="%s"} %.2f`+"\n",
-				d.Serial, d.ChannelId, d.Name, d.Description, d.Profile, d.Label, d.RGB, d.AIO, d.ContainsPump, d.TemperatureProbe, d.LedChannels, d.Temperature))
+			temps = append(temps, sample{labels: channelLabels(d), value: d.Temperature})
+		}
+		if d.Rpm > 0 {
+			speeds = append(speeds, sample{labels: channelLabels(d), value: float64(d.Rpm)})
+		}
+		if d.Watts > 0 {
+			watts = append(watts, sample{labels: power, value: d.Watts})
+		}
+		if d.Volts > 0 {
+			volts = append(volts, sample{labels: power, value: d.Volts})
+		}
+		if d.Amps > 0 {
+			amps = append(amps, sample{labels: power, value: d.Amps})
+		}
+		if channelId, err := strconv.Atoi(d.ChannelId); err == nil {
+			if speed, ok := watchdog.GetSpeed(d.Serial, channelId); ok {
+				duty = append(duty, sample{labels: power, value: float64(speed)})
+			}
+		}
+		if len(d.Profile) > 0 {
+			profiles = append(profiles, sample{labels: []string{"serial", d.Serial, "channelId", d.ChannelId, "type", "speed", "profile", d.Profile}, value: 1})
+		}
+		if len(d.RGB) > 0 {
+			profiles = append(profiles, sample{labels: []string{"serial", d.Serial, "channelId", d.ChannelId, "type", "rgb", "profile", d.RGB}, value: 1})
 		}
 	}
 
-	// RPM data
-	b.WriteString("# HELP openlinkhub_speed Current speed (RPM) of devices.\n")
-	b.WriteString("# TYPE openlinkhub_speed gauge\n")
-	for _, d := range GetDeviceMetrics() {
-		if d.Rpm > 0 {
-			b.WriteString(fmt.Sprintf(`openlinkhub_speed{serial="%s",channelId="%s",name="%s",description="%s",profile="%s",label="%s",rgb="%s",aio="%s",pump="%s",probe="%s",led="%s"} %d`+"\n",
-				d.Serial, d.ChannelId, d.Name, d.Description, d.Profile, d.Label, d.RGB, d.AIO, d.ContainsPump, d.TemperatureProbe, d.LedChannels, d.Rpm))
+	var inputVoltage, inputPower, efficiency []sample
+	for _, p := range GetPowerSupplyMetrics() {
+		labels := []string{"serial", p.Serial, "product", p.Product}
+		if p.InputVoltage > 0 {
+			inputVoltage = append(inputVoltage, sample{labels: labels, value: p.InputVoltage})
+		}
+		if p.InputPower > 0 {
+			inputPower = append(inputPower, sample{labels: labels, value: p.InputPower})
+
+			// Readings taken moments apart can briefly report more power out than in
+			if p.OutputPower > 0 && p.OutputPower <= p.InputPower {
+				efficiency = append(efficiency, sample{labels: labels, value: p.OutputPower / p.InputPower})
+			}
 		}
 	}
 
-	// Storage temps
-	b.WriteString("# HELP openlinkhub_storage_temp Current temperature of storage devices.\n")
-	b.WriteString("# TYPE openlinkhub_storage_temp gauge\n")
+	var userProfile []sample
+	for serial, profile := range GetUserProfiles() {
+		userProfile = append(userProfile, sample{labels: []string{"serial", serial, "profile", profile}, value: 1})
+	}
+
+	var battery []sample
+	for serial, s := range stats.GetBatteryStats() {
+		battery = append(battery, sample{labels: []string{"serial", serial, "device", s.Device}, value: float64(s.Level)})
+	}
+
+	var storage []sample
 	for _, s := range GetStorageMetrics() {
-		b.WriteString(fmt.Sprintf(`openlinkhub_storage_temp{hwmonDevice="%s",model="%s"} %.2f`+"\n",
-			s.HwmonDevice, s.Model, s.Temperature))
+		storage = append(storage, sample{labels: []string{"hwmonDevice", s.HwmonDevice, "model", s.Model}, value: s.Temperature})
 	}
 
-	// Default temps
-	b.WriteString("# HELP openlinkhub_default_temp Current temperature of default devices.\n")
-	b.WriteString("# TYPE openlinkhub_default_temp gauge\n")
+	var defaults []sample
 	for _, d := range GetDefaultMetrics() {
-		b.WriteString(fmt.Sprintf(`openlinkhub_default_temp{model="%s"} %.2f`+"\n",
-			d.Model, d.Temperature))
+		defaults = append(defaults, sample{labels: []string{"model", d.Model}, value: d.Temperature})
+	}
+
+	load := []sample{
+		{labels: []string{"device", "cpu"}, value: systeminfo.GetCpuUtilization()},
+		{labels: []string{"device", "gpu"}, value: float64(systeminfo.GetGPUUtilization())},
 	}
 
+	writeFamily(&b, "openlinkhub", "Product information.", products)
+	writeFamily(&b, "openlinkhub_temperature", "Current temperature of devices.", temps)
+	writeFamily(&b, "openlinkhub_speed", "Current speed (RPM) of devices.", speeds)
+	writeFamily(&b, "openlinkhub_duty_percent", "Last commanded fan or pump speed in percent.", duty)
+	writeFamily(&b, "openlinkhub_power_watts", "Current power of PSU outputs and rails.", watts)
+	writeFamily(&b, "openlinkhub_voltage_volts", "Current voltage of PSU rails.", volts)
+	writeFamily(&b, "openlinkhub_current_amperes", "Current amperage of PSU rails.", amps)
+	writeFamily(&b, "openlinkhub_psu_input_voltage_volts", "Input voltage of PSU.", inputVoltage)
+	writeFamily(&b, "openlinkhub_psu_input_power_watts", "Input power of PSU.", inputPower)
+	writeFamily(&b, "openlinkhub_psu_efficiency", "Efficiency of PSU as ratio of output to input power.", efficiency)
+	writeFamily(&b, "openlinkhub_battery_level_percent", "Battery level of wireless devices.", battery)
+	writeFamily(&b, "openlinkhub_profile_info", "Active speed and RGB profile of device channels.", profiles)
+	writeFamily(&b, "openlinkhub_user_profile_info", "Active user profile of devices.", userProfile)
+	writeFamily(&b, "openlinkhub_storage_temp", "Current temperature of storage devices.", storage)
+	writeFamily(&b, "openlinkhub_default_temp", "Current temperature of default devices.", defaults)
+	writeFamily(&b, "openlinkhub_load_percent", "Current CPU and GPU utilization.", load)
+
 	// Send it
-	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
 	_, err := w.Write([]byte(b.String()))
 	if err != nil {
 		http.Error(w, "Failed to generate metrics", http.StatusInternalServerError)
@@ -9,9 +9,11 @@ import (
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/events"
 	"OpenLinkHub/src/inputmanager"
 	"OpenLinkHub/src/logger"
 	"OpenLinkHub/src/openrgb"
+	"OpenLinkHub/src/scheduler"
 	"github.com/godbus/dbus/v5"
 	"os"
 	"slices"
@@ -89,6 +91,9 @@ func Init() {
 					if isSleeping {
 						logger.Log(logger.Fields{}).Info("Suspend detected. Sending Stop() to all devices")
 
+						// Automation rules
+						scheduler.Suspend()
+
 						// Cleanup
 						if config.GetConfig().EnableOpenRGBTargetServer {
 							openrgb.Close()
@@ -206,6 +211,7 @@ func Init() {
 						}
 						logger.Log(logger.Fields{"vendorId": vid, "productId": pid, "serial": serial}).Info("Init USB device...")
 						devices.InitManual(pid, serial)
+						events.Publish(events.EventDeviceAdded, serial, 0, pid)
 						switchHeadsetAudioSink(pid)
 					}
 				}
@@ -228,6 +234,7 @@ func Init() {
 							logger.Log(logger.Fields{"vendorId": info.VendorID, "productId": info.ProductID, "serial": serial}).Info("Dirty USB removal...")
 
 							devices.StopDirty(serial, info.ProductID)
+							events.Publish(events.EventDeviceRemoved, serial, 0, info.ProductID)
 							delete(cache, devPath)
 							openrgb.NotifyControllerChange(serial)
 							fallbackHeadsetAudioSink(info.ProductID)
@@ -0,0 +1,93 @@
+package mqtt
+
+// Package: mqtt
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/temperatures"
+	"strconv"
+	"strings"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// onCommand will handle messages on <base>/<serial>/<kind>/<channel>/set topics
+func onCommand(_ paho.Client, msg paho.Message) {
+	parts := strings.Split(strings.TrimPrefix(msg.Topic(), settings.BaseTopic+"/"), "/")
+	if len(parts) != 4 {
+		return
+	}
+
+	channelId, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return
+	}
+
+	serial := ""
+	for key := range devices.GetDevices() {
+		if topicSafe(key) == parts[0] {
+			serial = key
+			break
+		}
+	}
+	if len(serial) == 0 {
+		logger.Log(logger.Fields{"topic": msg.Topic()}).Warn("MQTT command for non-existing device")
+		return
+	}
+
+	// Device changes can take a while, keep MQTT client responsive
+	go execute(serial, parts[1], channelId, strings.TrimSpace(string(msg.Payload())))
+}
+
+// execute will apply command and publish new state
+func execute(serial, kind string, channelId int, value string) {
+	device := devices.GetDevice(serial)
+	if device == nil {
+		return
+	}
+
+	result := uint8(0)
+	switch kind {
+	case kindSpeed:
+		if temperatures.GetTemperatureProfile(value) == nil {
+			break
+		}
+		if dev, ok := device.(capabilities.SpeedProfile); ok {
+			result = dev.UpdateSpeedProfile(channelId, value)
+		}
+	case kindRgb:
+		if dev, ok := device.(capabilities.Rgb); ok {
+			if result = dev.UpdateRgbProfile(channelId, value); result == 1 {
+				events.Publish(events.EventRgb, serial, channelId, value)
+			}
+		}
+	case kindBrightness:
+		brightness, err := strconv.ParseFloat(value, 64)
+		if err != nil || brightness < 0 || brightness > 100 {
+			break
+		}
+		if dev, ok := device.(capabilities.BrightnessValue); ok {
+			result = dev.ChangeDeviceBrightnessValue(uint8(brightness))
+		}
+	case kindProfile:
+		if dev, ok := device.(capabilities.UserProfiles); ok {
+			if result = dev.ChangeDeviceProfile(value); result == 1 {
+				events.Publish(events.EventProfile, serial, 0, value)
+			}
+		}
+	default:
+		return
+	}
+
+	if result != 1 {
+		logger.Log(logger.Fields{"serial": serial, "command": kind, "channelId": channelId, "value": value}).Warn("Unable to apply MQTT command")
+	}
+
+	// Publish actual state, which also reverts rejected values in Home Assistant
+	publish()
+}
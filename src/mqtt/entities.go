@@ -0,0 +1,343 @@
+package mqtt
+
+// Package: mqtt
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/metrics"
+	"OpenLinkHub/src/rgb"
+	"OpenLinkHub/src/stats"
+	"OpenLinkHub/src/temperatures"
+	"OpenLinkHub/src/version"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	kindTemperature = "temperature"
+	kindRpm         = "rpm"
+	kindWatts       = "watts"
+	kindVolts       = "volts"
+	kindAmps        = "amps"
+	kindBattery     = "battery"
+	kindSpeed       = "speed"
+	kindRgb         = "rgb"
+	kindBrightness  = "brightness"
+	kindProfile     = "profile"
+)
+
+// entity is a single Home Assistant entity
+type entity struct {
+	component string
+	uniqueId  string
+	path      string
+	name      string
+	device    map[string]interface{}
+	unit      string
+	class     string
+	icon      string
+	command   bool
+	options   []string
+	state     string
+}
+
+// sensorKind defines how a metric value is announced
+type sensorKind struct {
+	kind      string
+	suffix    string
+	unit      string
+	class     string
+	icon      string
+	precision int
+}
+
+var sensorKinds = []sensorKind{
+	{kindTemperature, "temperature", "°C", "temperature", "", 1},
+	{kindRpm, "speed", "RPM", "", "mdi:fan", 0},
+	{kindWatts, "power", "W", "power", "", 1},
+	{kindVolts, "voltage", "V", "voltage", "", 2},
+	{kindAmps, "current", "A", "current", "", 2},
+}
+
+// stateTopic returns topic with entity state
+func (e *entity) stateTopic() string {
+	return settings.BaseTopic + "/" + e.path + "/state"
+}
+
+// commandTopic returns topic entity commands are received on
+func (e *entity) commandTopic() string {
+	return settings.BaseTopic + "/" + e.path + "/set"
+}
+
+// discoveryTopic returns Home Assistant discovery topic of entity
+func (e *entity) discoveryTopic() string {
+	return settings.DiscoveryPrefix + "/" + e.component + "/" + e.uniqueId + "/config"
+}
+
+// discovery will build Home Assistant discovery payload
+func (e *entity) discovery() map[string]interface{} {
+	payload := map[string]interface{}{
+		"name":               e.name,
+		"unique_id":          e.uniqueId,
+		"object_id":          e.uniqueId,
+		"state_topic":        e.stateTopic(),
+		"availability_topic": availabilityTopic(),
+		"device":             e.device,
+	}
+	if len(e.unit) > 0 {
+		payload["unit_of_measurement"] = e.unit
+	}
+	if len(e.class) > 0 {
+		payload["device_class"] = e.class
+	}
+	if len(e.icon) > 0 {
+		payload["icon"] = e.icon
+	}
+	if e.component == "sensor" {
+		payload["state_class"] = "measurement"
+	}
+	if e.command {
+		payload["command_topic"] = e.commandTopic()
+	}
+	switch e.component {
+	case "select":
+		payload["options"] = e.options
+	case "number":
+		payload["min"] = 0
+		payload["max"] = 100
+		payload["step"] = 1
+		payload["mode"] = "slider"
+	}
+	return payload
+}
+
+// sanitize will convert value into lowercase string usable as entity id
+func sanitize(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// topicSafe will replace characters with special meaning in MQTT topics
+func topicSafe(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '+', '#', ' ':
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// formatFloat will format sensor value with given number of decimals
+func formatFloat(value float64, precision int) string {
+	return strconv.FormatFloat(value, 'f', precision, 64)
+}
+
+// hostDevice returns Home Assistant device of this computer
+func hostDevice() map[string]interface{} {
+	device := map[string]interface{}{
+		"identifiers":  []string{"openlinkhub_" + hostId},
+		"name":         "OpenLinkHub " + hostId,
+		"manufacturer": "OpenLinkHub",
+	}
+	if info := version.GetBuildInfo(); info != nil {
+		device["sw_version"] = info.BuildVersion
+	}
+	return device
+}
+
+// deviceInfo returns Home Assistant device of a connected device
+func deviceInfo(device *common.Device, firmware string) map[string]interface{} {
+	info := map[string]interface{}{
+		"identifiers":  []string{"openlinkhub_" + sanitize(device.Serial)},
+		"name":         device.Product,
+		"manufacturer": "Corsair",
+		"model":        device.Product,
+		"via_device":   "openlinkhub_" + hostId,
+	}
+	if len(firmware) > 0 {
+		info["sw_version"] = firmware
+	}
+	return info
+}
+
+// collect will build all entities with their current state
+func collect() []entity {
+	var list []entity
+	host := hostDevice()
+
+	// CPU and GPU
+	for _, value := range metrics.GetDefaultMetrics() {
+		if len(value.Model) == 0 {
+			continue
+		}
+		path := fmt.Sprintf("%s/%s/%s", hostId, kindTemperature, sanitize(value.Model))
+		list = append(list, entity{
+			component: "sensor",
+			uniqueId:  "openlinkhub_" + sanitize(path),
+			path:      path,
+			name:      value.Model + " temperature",
+			device:    host,
+			unit:      "°C",
+			class:     "temperature",
+			state:     formatFloat(value.Temperature, 1),
+		})
+	}
+
+	// Storage
+	for key, value := range metrics.GetStorageMetrics() {
+		path := fmt.Sprintf("%s/%s/%s", hostId, kindTemperature, sanitize(key))
+		list = append(list, entity{
+			component: "sensor",
+			uniqueId:  "openlinkhub_" + sanitize(path),
+			path:      path,
+			name:      value.Model + " temperature",
+			device:    host,
+			unit:      "°C",
+			class:     "temperature",
+			state:     formatFloat(value.Temperature, 1),
+		})
+	}
+
+	connected := devices.GetDevices()
+
+	// Temperatures, fans and PSU rails
+	firmware := make(map[string]string)
+	for _, header := range metrics.GetDeviceMetrics() {
+		device, ok := connected[header.Serial]
+		if !ok || device.Hidden {
+			continue
+		}
+		firmware[header.Serial] = header.Firmware
+
+		values := map[string]float64{
+			kindTemperature: header.Temperature,
+			kindRpm:         float64(header.Rpm),
+			kindWatts:       header.Watts,
+			kindVolts:       header.Volts,
+			kindAmps:        header.Amps,
+		}
+		for _, kind := range sensorKinds {
+			value := values[kind.kind]
+			if value <= 0 {
+				continue
+			}
+			path := fmt.Sprintf("%s/%s/%s", topicSafe(header.Serial), kind.kind, topicSafe(header.ChannelId))
+			list = append(list, entity{
+				component: "sensor",
+				uniqueId:  "openlinkhub_" + sanitize(path),
+				path:      path,
+				name:      header.Name + " " + kind.suffix,
+				device:    deviceInfo(device, header.Firmware),
+				unit:      kind.unit,
+				class:     kind.class,
+				icon:      kind.icon,
+				state:     formatFloat(value, kind.precision),
+			})
+		}
+	}
+
+	// Battery
+	for serial, battery := range stats.GetBatteryStats() {
+		device, ok := connected[serial]
+		if !ok || device.Hidden {
+			continue
+		}
+		path := fmt.Sprintf("%s/%s/0", topicSafe(serial), kindBattery)
+		list = append(list, entity{
+			component: "sensor",
+			uniqueId:  "openlinkhub_" + sanitize(path),
+			path:      path,
+			name:      "Battery",
+			device:    deviceInfo(device, firmware[serial]),
+			unit:      "%",
+			class:     "battery",
+			state:     strconv.Itoa(int(battery.Level)),
+		})
+	}
+
+	// Controls
+	speedProfiles := sortedNames(temperatures.GetTemperatureProfiles())
+	rgbProfiles := sortedNames(rgb.GetRgbProfiles())
+	for serial, device := range connected {
+		if device.Hidden {
+			continue
+		}
+		list = append(list, controls(serial, device, deviceInfo(device, firmware[serial]), speedProfiles, rgbProfiles)...)
+	}
+	return list
+}
+
+// controls will build command entities of a device
+func controls(serial string, device *common.Device, info map[string]interface{}, speedProfiles, rgbProfiles []string) []entity {
+	var list []entity
+	state := capabilities.Snapshot(device.Instance)
+
+	names := map[int]string{}
+	if descriptor := capabilities.Describe(device); descriptor != nil {
+		for _, channel := range descriptor.Channels {
+			names[channel.ChannelId] = channel.Name
+		}
+	}
+	channelName := func(channelId int) string {
+		if name, ok := names[channelId]; ok && len(name) > 0 {
+			return name + " "
+		}
+		if len(names) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("Channel %d ", channelId)
+	}
+
+	add := func(component, kind string, channelId int, name, icon string, options []string, value string) {
+		path := fmt.Sprintf("%s/%s/%d", topicSafe(serial), kind, channelId)
+		list = append(list, entity{
+			component: component,
+			uniqueId:  "openlinkhub_" + sanitize(path),
+			path:      path,
+			name:      name,
+			device:    info,
+			icon:      icon,
+			command:   true,
+			options:   options,
+			state:     value,
+		})
+	}
+
+	for channelId, profile := range state.SpeedProfiles {
+		add("select", kindSpeed, channelId, channelName(channelId)+"fan profile", "mdi:fan", speedProfiles, profile)
+	}
+	for channelId, profile := range state.RgbProfiles {
+		add("select", kindRgb, channelId, channelName(channelId)+"RGB profile", "mdi:palette", rgbProfiles, profile)
+	}
+	if _, ok := device.Instance.(capabilities.BrightnessValue); ok && state.Brightness != nil {
+		add("number", kindBrightness, 0, "Brightness", "mdi:brightness-6", nil, strconv.Itoa(int(*state.Brightness)))
+	}
+	if _, ok := device.Instance.(capabilities.UserProfiles); ok && len(state.UserProfile) > 0 {
+		add("select", kindProfile, 0, "User profile", "mdi:account-switch", capabilities.UserProfileNames(device.Instance), state.UserProfile)
+	}
+	return list
+}
+
+// sortedNames will return sorted keys of a profile map
+func sortedNames[T any](profiles map[string]T) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,168 @@
+package mqtt
+
+// Package: mqtt
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/logger"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	qos            = byte(1)
+	statusOnline   = "online"
+	statusOffline  = "offline"
+	disconnectWait = 250
+)
+
+var (
+	client    paho.Client
+	settings  config.Mqtt
+	hostId    = ""
+	mutex     sync.Mutex
+	announced = map[string]bool{}
+)
+
+// Init will connect to MQTT broker and start publishing device sensors
+func Init() {
+	settings = config.GetConfig().Mqtt
+	if !settings.Enabled {
+		return
+	}
+
+	if settings.Interval < 1 {
+		settings.Interval = 10
+	}
+	settings.BaseTopic = strings.Trim(settings.BaseTopic, "/")
+	if len(settings.BaseTopic) == 0 {
+		settings.BaseTopic = "openlinkhub"
+	}
+	if len(settings.DiscoveryPrefix) == 0 {
+		settings.DiscoveryPrefix = "homeassistant"
+	}
+
+	hostname, _ := os.Hostname()
+	hostId = sanitize(hostname)
+	if len(hostId) == 0 {
+		hostId = "localhost"
+	}
+
+	clientId := settings.ClientId
+	if len(clientId) == 0 {
+		clientId = "openlinkhub-" + hostId
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(settings.Broker).
+		SetClientID(clientId).
+		SetUsername(settings.Username).
+		SetPassword(settings.Password).
+		SetWill(availabilityTopic(), statusOffline, qos, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetOnConnectHandler(onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logger.Log(logger.Fields{"error": err, "broker": settings.Broker}).Warn("MQTT connection lost")
+		})
+
+	client = paho.NewClient(opts)
+	client.Connect()
+	go run()
+}
+
+// Stop will mark service offline and disconnect from broker
+func Stop() {
+	if client == nil || !client.IsConnectionOpen() {
+		return
+	}
+	client.Publish(availabilityTopic(), qos, true, statusOffline).WaitTimeout(time.Second)
+	client.Disconnect(disconnectWait)
+}
+
+// onConnect will announce availability and subscribe to command topics. It runs on every reconnect.
+func onConnect(c paho.Client) {
+	logger.Log(logger.Fields{"broker": settings.Broker}).Info("Connected to MQTT broker")
+
+	mutex.Lock()
+	announced = map[string]bool{}
+	mutex.Unlock()
+
+	c.Publish(availabilityTopic(), qos, true, statusOnline)
+	c.Subscribe(settings.BaseTopic+"/+/+/+/set", qos, onCommand)
+	c.Subscribe(settings.DiscoveryPrefix+"/status", qos, onHomeAssistantStatus)
+	go publish()
+}
+
+// onHomeAssistantStatus will announce all entities again once Home Assistant restarts
+func onHomeAssistantStatus(_ paho.Client, msg paho.Message) {
+	if string(msg.Payload()) != statusOnline {
+		return
+	}
+
+	mutex.Lock()
+	announced = map[string]bool{}
+	mutex.Unlock()
+	go publish()
+}
+
+// run will periodically publish sensor states and react to device changes
+func run() {
+	ticker := time.NewTicker(time.Duration(settings.Interval) * time.Second)
+	defer ticker.Stop()
+
+	_, ch := events.Subscribe()
+	for {
+		select {
+		case <-ticker.C:
+			publish()
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			switch event.Type {
+			case events.EventDeviceAdded, events.EventProfile, events.EventRgb:
+				publish()
+			}
+		}
+	}
+}
+
+// publish will send discovery of new entities and current state of all entities
+func publish() {
+	if client == nil || !client.IsConnectionOpen() {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	devices.UpdateDeviceMetrics()
+	for _, e := range collect() {
+		if !announced[e.uniqueId] {
+			payload, err := json.Marshal(e.discovery())
+			if err != nil {
+				logger.Log(logger.Fields{"error": err, "entity": e.uniqueId}).Error("Unable to encode MQTT discovery")
+				continue
+			}
+			client.Publish(e.discoveryTopic(), qos, true, payload)
+			announced[e.uniqueId] = true
+		}
+		client.Publish(e.stateTopic(), qos, true, e.state)
+	}
+}
+
+// availabilityTopic returns topic holding online state of the service
+func availabilityTopic() string {
+	return settings.BaseTopic + "/" + hostId + "/status"
+}
@@ -0,0 +1,160 @@
+package process
+
+// Package: process
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/logger"
+	"encoding/json"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Profile is a set of profiles applied to a single device while a process is running. KeyLayer toggles
+// key assignment layer, which carries key and macro assignments
+type Profile struct {
+	DeviceId        string `json:"deviceId"`
+	UserProfile     string `json:"userProfile,omitempty"`
+	KeyboardProfile string `json:"keyboardProfile,omitempty"`
+	KeyLayer        *int   `json:"keyLayer,omitempty"`
+}
+
+// Rule binds executable to device profiles
+type Rule struct {
+	Id         string    `json:"id"`
+	Name       string    `json:"name"`
+	Enabled    bool      `json:"enabled"`
+	Executable string    `json:"executable"`
+	Profiles   []Profile `json:"profiles"`
+}
+
+// Activation is a running rule with device profiles which were active before it started
+type Activation struct {
+	RuleId   string             `json:"ruleId"`
+	Previous map[string]Profile `json:"previous"`
+}
+
+type Processes struct {
+	Rules  []Rule       `json:"rules"`
+	Active []Activation `json:"active"`
+}
+
+var (
+	location    = ""
+	processes   Processes
+	mutex       sync.Mutex
+	refreshTime = 2000
+)
+
+// Init will load process rules and start process watcher
+func Init() {
+	location = config.GetConfig().ConfigPath + "/database/processes.json"
+	processes = Processes{
+		Rules:  make([]Rule, 0),
+		Active: make([]Activation, 0),
+	}
+
+	if !common.FileExists(location) {
+		logger.Log(logger.Fields{"file": location}).Info("Process file is missing, creating initial one.")
+		if save(processes) != 1 {
+			return
+		}
+	} else {
+		file, err := os.Open(location)
+		if err != nil {
+			logger.Log(logger.Fields{"error": err, "file": location}).Error("Failed to open process file")
+			return
+		}
+		if err = json.NewDecoder(file).Decode(&processes); err != nil {
+			logger.Log(logger.Fields{"error": err, "file": location}).Error("Failed to decode json")
+		}
+		if err = file.Close(); err != nil {
+			logger.Log(logger.Fields{"error": err, "file": location}).Error("Failed to close file")
+		}
+		if processes.Rules == nil {
+			processes.Rules = make([]Rule, 0)
+		}
+		if processes.Active == nil {
+			processes.Active = make([]Activation, 0)
+		}
+	}
+
+	go watch()
+}
+
+// save will save process rules and active state
+func save(data Processes) uint8 {
+	if err := common.SaveJsonData(location, data); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": location}).Error("Unable to save process data")
+		return 0
+	}
+	return 1
+}
+
+// GetRules will return all process rules
+func GetRules() []Rule {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	rules := make([]Rule, len(processes.Rules))
+	copy(rules, processes.Rules)
+	return rules
+}
+
+// SaveRule will create new or update existing process rule.
+// Returns 0 on save failure, 2 on invalid profile list and 3 on non-existing rule
+func SaveRule(rule Rule) uint8 {
+	if len(rule.Profiles) == 0 {
+		return 2
+	}
+	for _, profile := range rule.Profiles {
+		if len(profile.DeviceId) == 0 || (len(profile.UserProfile) == 0 && len(profile.KeyboardProfile) == 0 && profile.KeyLayer == nil) {
+			return 2
+		}
+		if profile.KeyLayer != nil && *profile.KeyLayer < 0 {
+			return 2
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(rule.Id) == 0 {
+		rule.Id = common.GenerateRandomMD5()
+		processes.Rules = append(processes.Rules, rule)
+	} else {
+		index := slices.IndexFunc(processes.Rules, func(r Rule) bool { return r.Id == rule.Id })
+		if index < 0 {
+			return 3
+		}
+		processes.Rules[index] = rule
+	}
+	return save(processes)
+}
+
+// DeleteRule will delete process rule. Profiles of a running rule are reverted on next process scan
+func DeleteRule(ruleId string) uint8 {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	index := slices.IndexFunc(processes.Rules, func(r Rule) bool { return r.Id == ruleId })
+	if index < 0 {
+		return 2
+	}
+	processes.Rules = slices.Delete(processes.Rules, index, index+1)
+	return save(processes)
+}
+
+// GetActive will return currently running rules
+func GetActive() []Activation {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	active := make([]Activation, len(processes.Active))
+	copy(active, processes.Active)
+	return active
+}
@@ -0,0 +1,317 @@
+package process
+
+// Package: process
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/inputmanager"
+	"OpenLinkHub/src/logger"
+	"bytes"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commLength is maximum length of a process name in /proc/<pid>/comm
+const commLength = 15
+
+// procInfo contains names a single running process can be matched by
+type procInfo struct {
+	comm string
+	arg0 string
+	exe  string
+}
+
+// watch will periodically scan running processes and apply rule profiles
+func watch() {
+	ticker := time.NewTicker(time.Duration(refreshTime) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		scan()
+		<-ticker.C
+	}
+}
+
+// change is a profile switch of a single device
+type change struct {
+	device  interface{}
+	profile Profile
+}
+
+// plan contains profile switches found during a scan, applied once mutex is released
+type plan struct {
+	changes []change
+	pending map[string]Profile
+}
+
+// current will return profiles a device will have once queued changes are applied
+func (p *plan) current(device interface{}, deviceId string) Profile {
+	profile := Profile{
+		DeviceId:        deviceId,
+		UserProfile:     capabilities.ActiveUserProfile(device),
+		KeyboardProfile: capabilities.ActiveKeyboardProfile(device),
+		KeyLayer:        layer(inputmanager.GetToggledLayer(deviceId)),
+	}
+	if pending, ok := p.pending[deviceId]; ok {
+		if len(pending.UserProfile) > 0 {
+			profile.UserProfile = pending.UserProfile
+		}
+		if len(pending.KeyboardProfile) > 0 {
+			profile.KeyboardProfile = pending.KeyboardProfile
+		}
+		if pending.KeyLayer != nil {
+			profile.KeyLayer = pending.KeyLayer
+		}
+	}
+	return profile
+}
+
+// add will queue profile switch of a device
+func (p *plan) add(device interface{}, profile Profile) {
+	p.changes = append(p.changes, change{device: device, profile: profile})
+
+	pending := p.pending[profile.DeviceId]
+	if len(profile.UserProfile) > 0 {
+		pending.UserProfile = profile.UserProfile
+	}
+	if len(profile.KeyboardProfile) > 0 {
+		pending.KeyboardProfile = profile.KeyboardProfile
+	}
+	if profile.KeyLayer != nil {
+		pending.KeyLayer = profile.KeyLayer
+	}
+	p.pending[profile.DeviceId] = pending
+}
+
+// layer will return pointer to key assignment layer id
+func layer(layerId int) *int {
+	return &layerId
+}
+
+// scan will activate rules whose executable started and revert rules whose executable exited.
+// Device profiles are switched after mutex is released, since switching talks to hardware.
+func scan() {
+	running := runningProcesses()
+	changes := &plan{pending: make(map[string]Profile)}
+
+	mutex.Lock()
+	if len(processes.Active) == 0 && !slices.ContainsFunc(processes.Rules, func(r Rule) bool { return r.Enabled }) {
+		mutex.Unlock()
+		return
+	}
+
+	changed := false
+
+	// Newest activations are reverted first
+	for i := len(processes.Active) - 1; i >= 0; i-- {
+		rule := getRule(processes.Active[i].RuleId)
+		if rule != nil && rule.Enabled && isRunning(running, rule.Executable) {
+			continue
+		}
+		deactivate(i, changes)
+		changed = true
+	}
+
+	for _, rule := range processes.Rules {
+		if !rule.Enabled || isActive(rule.Id) || !isRunning(running, rule.Executable) {
+			continue
+		}
+		activate(rule, changes)
+		changed = true
+	}
+
+	if changed {
+		save(processes)
+	}
+	mutex.Unlock()
+
+	for _, c := range changes.changes {
+		apply(c.device, c.profile)
+	}
+}
+
+// getRule will return process rule by id
+func getRule(ruleId string) *Rule {
+	for _, rule := range processes.Rules {
+		if rule.Id == ruleId {
+			return &rule
+		}
+	}
+	return nil
+}
+
+// isActive will return true when rule is currently applied
+func isActive(ruleId string) bool {
+	return slices.ContainsFunc(processes.Active, func(a Activation) bool { return a.RuleId == ruleId })
+}
+
+// activate will remember current device profiles and queue rule profiles
+func activate(rule Rule, changes *plan) {
+	logger.Log(logger.Fields{"rule": rule.Name, "executable": rule.Executable}).Info("Process started, applying profiles")
+
+	activation := Activation{
+		RuleId:   rule.Id,
+		Previous: make(map[string]Profile),
+	}
+	for _, profile := range rule.Profiles {
+		device := devices.GetDevice(profile.DeviceId)
+		if device == nil {
+			logger.Log(logger.Fields{"rule": rule.Name, "serial": profile.DeviceId}).Warn("Process rule device is not connected")
+			continue
+		}
+
+		current := changes.current(device, profile.DeviceId)
+		previous := Profile{DeviceId: profile.DeviceId}
+		if len(profile.UserProfile) > 0 {
+			previous.UserProfile = current.UserProfile
+		}
+		if len(profile.KeyboardProfile) > 0 {
+			previous.KeyboardProfile = current.KeyboardProfile
+		}
+		if profile.KeyLayer != nil {
+			previous.KeyLayer = current.KeyLayer
+		}
+		activation.Previous[profile.DeviceId] = previous
+		changes.add(device, profile)
+	}
+	processes.Active = append(processes.Active, activation)
+}
+
+// deactivate will queue revert of device profiles of activation at given index. When a newer activation
+// changed the same profile, it will take over the original profile instead.
+func deactivate(index int, changes *plan) {
+	activation := processes.Active[index]
+	logger.Log(logger.Fields{"ruleId": activation.RuleId}).Info("Process exited, reverting profiles")
+
+	newer := processes.Active[index+1:]
+	for deviceId, previous := range activation.Previous {
+		restore := Profile{DeviceId: deviceId}
+		if len(previous.UserProfile) > 0 && !handOver(newer, deviceId, func(p *Profile) bool {
+			if len(p.UserProfile) == 0 {
+				return false
+			}
+			p.UserProfile = previous.UserProfile
+			return true
+		}) {
+			restore.UserProfile = previous.UserProfile
+		}
+		if len(previous.KeyboardProfile) > 0 && !handOver(newer, deviceId, func(p *Profile) bool {
+			if len(p.KeyboardProfile) == 0 {
+				return false
+			}
+			p.KeyboardProfile = previous.KeyboardProfile
+			return true
+		}) {
+			restore.KeyboardProfile = previous.KeyboardProfile
+		}
+		if previous.KeyLayer != nil && !handOver(newer, deviceId, func(p *Profile) bool {
+			if p.KeyLayer == nil {
+				return false
+			}
+			p.KeyLayer = previous.KeyLayer
+			return true
+		}) {
+			restore.KeyLayer = previous.KeyLayer
+		}
+		if device := devices.GetDevice(deviceId); device != nil {
+			changes.add(device, restore)
+		}
+	}
+	processes.Active = slices.Delete(processes.Active, index, index+1)
+}
+
+// handOver will pass original profile to the oldest newer activation which changed the same profile
+func handOver(newer []Activation, deviceId string, take func(previous *Profile) bool) bool {
+	for _, activation := range newer {
+		previous, ok := activation.Previous[deviceId]
+		if !ok || !take(&previous) {
+			continue
+		}
+		activation.Previous[deviceId] = previous
+		return true
+	}
+	return false
+}
+
+// apply will change device user profile, keyboard profile and key assignment layer when they differ from the current one
+func apply(device interface{}, profile Profile) {
+	devices.ApplyState(profile.DeviceId, capabilities.State{UserProfile: profile.UserProfile, KeyboardProfile: profile.KeyboardProfile})
+	if len(profile.UserProfile) > 0 && capabilities.ActiveUserProfile(device) != profile.UserProfile {
+		logger.Log(logger.Fields{"serial": profile.DeviceId, "profile": profile.UserProfile}).Warn("Unable to change user profile")
+	}
+	if len(profile.KeyboardProfile) > 0 && capabilities.ActiveKeyboardProfile(device) != profile.KeyboardProfile {
+		logger.Log(logger.Fields{"serial": profile.DeviceId, "profile": profile.KeyboardProfile}).Warn("Unable to change keyboard profile")
+	}
+	if profile.KeyLayer != nil && inputmanager.GetToggledLayer(profile.DeviceId) != *profile.KeyLayer {
+		if inputmanager.SetToggledLayer(profile.DeviceId, *profile.KeyLayer) != 1 {
+			logger.Log(logger.Fields{"serial": profile.DeviceId, "layer": *profile.KeyLayer}).Warn("Unable to change key assignment layer")
+		}
+	}
+}
+
+// runningProcesses will return all processes found in /proc
+func runningProcesses() []procInfo {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		logger.Log(logger.Fields{"error": err}).Error("Unable to read process list")
+		return nil
+	}
+
+	var list []procInfo
+	for _, entry := range entries {
+		if _, err = strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		path := "/proc/" + entry.Name()
+
+		comm, err := os.ReadFile(path + "/comm")
+		if err != nil {
+			// Process exited meanwhile
+			continue
+		}
+		info := procInfo{comm: strings.TrimSpace(string(comm))}
+		if cmdline, err := os.ReadFile(path + "/cmdline"); err == nil {
+			arg0, _, _ := bytes.Cut(cmdline, []byte{0})
+			info.arg0 = string(arg0)
+		}
+		// Only readable for processes of the same user
+		info.exe, _ = os.Readlink(path + "/exe")
+		list = append(list, info)
+	}
+	return list
+}
+
+// baseName will return file name of a Linux or Windows (Wine, Proton) path
+func baseName(path string) string {
+	return path[strings.LastIndexAny(path, `/\`)+1:]
+}
+
+// isRunning will return true when any process matches executable. Executable is either
+// full path or file name, which is compared without case to support Windows games.
+func isRunning(running []procInfo, executable string) bool {
+	if len(executable) == 0 {
+		return false
+	}
+
+	if strings.ContainsAny(executable, `/\`) {
+		return slices.ContainsFunc(running, func(p procInfo) bool {
+			return p.exe == executable || p.arg0 == executable
+		})
+	}
+
+	comm := executable
+	if len(comm) > commLength {
+		comm = comm[:commLength]
+	}
+	return slices.ContainsFunc(running, func(p procInfo) bool {
+		return strings.EqualFold(baseName(p.exe), executable) ||
+			strings.EqualFold(baseName(p.arg0), executable) ||
+			strings.EqualFold(p.comm, comm)
+	})
+}
@@ -45,6 +45,7 @@ type Profile struct {
 	RgbDirection    byte          `json:"rgbDirection"`
 	PerLed          bool          `json:"perLed"`
 	Version         int           `json:"version"`
+	Sensor          string        `json:"sensor,omitempty"`
 }
 
 type LastCycle struct {
@@ -76,6 +77,7 @@ type ActiveRGB struct {
 	IsAIO                  bool
 	MinTemp                float64
 	MaxTemp                float64
+	Sensor                 string
 	Inverted               bool
 	Buffer                 []byte
 	ColorOffset            int
@@ -387,6 +389,18 @@ func SetColor(data map[int][]byte) []byte {
 	return buffer
 }
 
+// Alert will generate red pulsing color buffer of given length, used as hardware failure warning
+func Alert(length int) []byte {
+	pulse := (math.Sin(float64(time.Now().UnixMilli())/1000*2*math.Pi) + 1) / 2
+	red := byte(math.Round(255 * (0.2 + 0.8*pulse)))
+
+	buffer := make([]byte, length)
+	for i := 0; i+2 < length; i += 3 {
+		buffer[i] = red
+	}
+	return buffer
+}
+
 // SetColorInverted will generate byte output for RGB data in inverted state
 func SetColorInverted(data map[int][]byte) []byte {
 	buffer := make([]byte, len(data)*3)
@@ -0,0 +1,212 @@
+package scenes
+
+// Package: scenes
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/inputmanager"
+	"OpenLinkHub/src/logger"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ActionOffset is keyboard action type of the first scene. Scene is bound to a key via
+// key assignment with action type 3 (Keyboard) and action command ActionOffset + scene id.
+const ActionOffset = 10000
+
+// Scene contains state of every device at the time scene was saved
+type Scene struct {
+	Id      int                           `json:"id"`
+	Name    string                        `json:"name"`
+	Devices map[string]capabilities.State `json:"devices"`
+}
+
+var (
+	location = ""
+	scenes   = map[int]Scene{}
+	mutex    sync.Mutex
+)
+
+// Init will load all scenes and register their keyboard actions
+func Init() {
+	location = config.GetConfig().ConfigPath + "/database/scenes/"
+	if !common.FileExists(location) {
+		if err := os.MkdirAll(location, 0755); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": location}).Error("Unable to create scenes directory")
+			return
+		}
+	}
+
+	files, err := os.ReadDir(location)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "location": location}).Error("Unable to read content of a folder")
+		return
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue
+		}
+
+		sceneLocation := location + fi.Name()
+		if !common.IsValidExtension(sceneLocation, ".json") {
+			continue
+		}
+
+		file, err := os.Open(sceneLocation)
+		if err != nil {
+			logger.Log(logger.Fields{"error": err, "location": sceneLocation}).Error("Unable to read scene")
+			continue
+		}
+
+		var scene Scene
+		if err = json.NewDecoder(file).Decode(&scene); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": sceneLocation}).Error("Unable to decode scene")
+		} else {
+			scenes[scene.Id] = scene
+			registerAction(scene)
+		}
+
+		if err = file.Close(); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": sceneLocation}).Warn("Failed to close file handle")
+		}
+	}
+}
+
+// GetScenes will return all scenes
+func GetScenes() map[int]Scene {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := make(map[int]Scene, len(scenes))
+	for id, scene := range scenes {
+		list[id] = scene
+	}
+	return list
+}
+
+// GetScene will return scene by id
+func GetScene(sceneId int) *Scene {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if scene, ok := scenes[sceneId]; ok {
+		return &scene
+	}
+	return nil
+}
+
+// SaveScene will record current state of all connected devices. Existing scene with the same name is overwritten.
+func SaveScene(name string) uint8 {
+	state := make(map[string]capabilities.State)
+	for serial, device := range devices.GetDevices() {
+		if device.Hidden {
+			continue
+		}
+		snapshot := capabilities.Snapshot(device.Instance)
+		if snapshot.IsEmpty() {
+			continue
+		}
+		state[serial] = snapshot
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	scene := Scene{
+		Id:      findSceneId(name),
+		Name:    name,
+		Devices: state,
+	}
+	return save(scene)
+}
+
+// ImportScene will add exported scene. Scene id is always assigned anew.
+func ImportScene(scene Scene) uint8 {
+	if scene.Devices == nil {
+		return 0
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	scene.Id = findSceneId(scene.Name)
+	return save(scene)
+}
+
+// DeleteScene will delete scene
+func DeleteScene(sceneId int) uint8 {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, ok := scenes[sceneId]; !ok {
+		return 2
+	}
+
+	path := fmt.Sprintf("%s%d.json", location, sceneId)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Log(logger.Fields{"error": err, "location": path}).Error("Unable to delete scene")
+		return 0
+	}
+	delete(scenes, sceneId)
+	inputmanager.UnregisterAction(uint16(ActionOffset + sceneId))
+	return 1
+}
+
+// ApplyScene will restore state of every device recorded in a scene
+func ApplyScene(sceneId int) uint8 {
+	scene := GetScene(sceneId)
+	if scene == nil {
+		return 2
+	}
+
+	logger.Log(logger.Fields{"scene": scene.Name}).Info("Applying scene")
+	for serial, state := range scene.Devices {
+		if devices.GetDevice(serial) == nil {
+			logger.Log(logger.Fields{"scene": scene.Name, "serial": serial}).Info("Scene device is not connected")
+			continue
+		}
+		devices.ApplyState(serial, state)
+	}
+	return 1
+}
+
+// findSceneId will return id of a scene with given name, or next free id
+func findSceneId(name string) int {
+	maxId := 0
+	for id, scene := range scenes {
+		if strings.EqualFold(scene.Name, name) {
+			return id
+		}
+		maxId = max(maxId, id)
+	}
+	return maxId + 1
+}
+
+// save will persist scene and register its keyboard action
+func save(scene Scene) uint8 {
+	path := fmt.Sprintf("%s%d.json", location, scene.Id)
+	if err := common.SaveJsonData(path, scene); err != nil {
+		logger.Log(logger.Fields{"error": err, "location": path}).Error("Unable to save scene")
+		return 0
+	}
+	scenes[scene.Id] = scene
+	registerAction(scene)
+	return 1
+}
+
+// registerAction will make scene available as keyboard key action
+func registerAction(scene Scene) {
+	sceneId := scene.Id
+	inputmanager.RegisterAction(uint16(ActionOffset+sceneId), "Scene: "+scene.Name, func() {
+		ApplyScene(sceneId)
+	})
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+// Package: scheduler
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/capabilities"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/macro"
+	"OpenLinkHub/src/temperatures"
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	ActionUserProfile  = "userProfile"
+	ActionSpeedProfile = "speedProfile"
+	ActionRgbProfile   = "rgbProfile"
+	ActionBrightness   = "brightness"
+	ActionMacro        = "macro"
+	ActionCommand      = "command"
+)
+
+var commandTimeout = 30 * time.Second
+
+// Action defines what is done when a rule is triggered
+type Action struct {
+	Type      string `json:"type"`
+	DeviceId  string `json:"deviceId,omitempty"`
+	ChannelId int    `json:"channelId,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Value     uint8  `json:"value,omitempty"`
+	MacroId   int    `json:"macroId,omitempty"`
+	Command   string `json:"command,omitempty"`
+}
+
+// validAction will validate rule action
+func validAction(action *Action) bool {
+	switch action.Type {
+	case ActionUserProfile:
+		return len(action.DeviceId) > 0 && len(action.Profile) > 0
+	case ActionSpeedProfile:
+		return temperatures.GetTemperatureProfile(action.Profile) != nil
+	case ActionRgbProfile:
+		return len(action.Profile) > 0
+	case ActionBrightness:
+		return action.Value <= 100
+	case ActionMacro:
+		return macro.GetProfile(action.MacroId) != nil
+	case ActionCommand:
+		return config.GetConfig().AllowCommands && len(action.Command) > 0
+	}
+	return false
+}
+
+// execute will run all actions of a rule
+func execute(rule Rule, serial string) {
+	logger.Log(logger.Fields{"rule": rule.Name, "trigger": rule.Trigger.Type, "serial": serial}).Info("Running automation rule")
+	for _, action := range rule.Actions {
+		runAction(rule, action, serial)
+	}
+}
+
+// targets will return serials of devices affected by an action
+func targets(deviceId string) []string {
+	if len(deviceId) > 0 {
+		if devices.GetDevice(deviceId) != nil {
+			return []string{deviceId}
+		}
+		return nil
+	}
+
+	var serials []string
+	for serial := range devices.GetDevices() {
+		serials = append(serials, serial)
+	}
+	return serials
+}
+
+// runAction will run single rule action
+func runAction(rule Rule, action Action, serial string) {
+	switch action.Type {
+	case ActionUserProfile:
+		applied := devices.ApplyState(action.DeviceId, capabilities.State{UserProfile: action.Profile})
+		if len(applied.UserProfile) == 0 && capabilities.ActiveUserProfile(devices.GetDevice(action.DeviceId)) != action.Profile {
+			logger.Log(logger.Fields{"rule": rule.Name, "serial": action.DeviceId, "profile": action.Profile}).Warn("Unable to change user profile")
+		}
+	case ActionSpeedProfile:
+		for _, target := range targets(action.DeviceId) {
+			devices.ApplyState(target, capabilities.State{SpeedProfiles: map[int]string{action.ChannelId: action.Profile}})
+		}
+	case ActionRgbProfile:
+		for _, target := range targets(action.DeviceId) {
+			devices.ApplyState(target, capabilities.State{RgbProfiles: map[int]string{action.ChannelId: action.Profile}})
+		}
+	case ActionBrightness:
+		for _, target := range targets(action.DeviceId) {
+			devices.ApplyState(target, capabilities.State{Brightness: &action.Value})
+		}
+	case ActionMacro:
+		runMacro(rule, action.MacroId)
+	case ActionCommand:
+		go runCommand(rule, action.Command, serial)
+	}
+}
+
+// runMacro will play a macro once
+func runMacro(rule Rule, macroId int) {
+	if !macro.Run(macroId) {
+		logger.Log(logger.Fields{"rule": rule.Name, "macroId": macroId}).Warn("Invalid macro profile")
+	}
+}
+
+// runCommand will execute user shell command with rule details passed via environment. Commands are allowed only via allowCommands in config
+func runCommand(rule Rule, command, serial string) {
+	if !config.GetConfig().AllowCommands {
+		logger.Log(logger.Fields{"rule": rule.Name, "command": command}).Warn("Automation commands are disabled. Set allowCommands in config.json to enable them")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"OPENLINKHUB_RULE="+rule.Name,
+		"OPENLINKHUB_TRIGGER="+rule.Trigger.Type,
+		"OPENLINKHUB_SERIAL="+serial,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logger.Log(logger.Fields{"error": err, "command": command, "output": string(output)}).Error("Unable to run automation command")
+	}
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+// Package: scheduler
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is parsed cron expression in minute, hour, day of month, month and day of week format
+type cronSchedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	anyDom     bool
+	anyDow     bool
+}
+
+// cronField defines allowed range of a cron field
+type cronField struct {
+	min int
+	max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // Minute
+	{0, 23}, // Hour
+	{1, 31}, // Day of month
+	{1, 12}, // Month
+	{0, 7},  // Day of week, 0 and 7 are Sunday
+}
+
+// parseCron will parse standard 5-field cron expression
+func parseCron(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields", len(cronFields))
+	}
+
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		value, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	// Sunday can be written as 7
+	if values[4]&(1<<7) != 0 {
+		values[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute:     values[0],
+		hour:       values[1],
+		dayOfMonth: values[2],
+		month:      values[3],
+		dayOfWeek:  values[4],
+		anyDom:     fields[2] == "*",
+		anyDow:     fields[4] == "*",
+	}, nil
+}
+
+// parseCronField will parse comma separated list of values, ranges and steps into a bit set
+func parseCronField(field string, limits cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if before, after, found := strings.Cut(part, "/"); found {
+			value, err := strconv.Atoi(after)
+			if err != nil || value < 1 {
+				return 0, fmt.Errorf("invalid cron step: %s", part)
+			}
+			part, step = before, value
+		}
+
+		start, end := limits.min, limits.max
+		if part != "*" {
+			before, after, found := strings.Cut(part, "-")
+			value, err := strconv.Atoi(before)
+			if err != nil {
+				return 0, fmt.Errorf("invalid cron value: %s", part)
+			}
+			start, end = value, value
+			if found {
+				if end, err = strconv.Atoi(after); err != nil {
+					return 0, fmt.Errorf("invalid cron range: %s", part)
+				}
+			} else if step > 1 {
+				end = limits.max
+			}
+		}
+
+		if start < limits.min || end > limits.max || start > end {
+			return 0, fmt.Errorf("cron value out of range: %s", part)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+// matches will return true when cron schedule matches given time
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 || c.hour&(1<<uint(t.Hour())) == 0 || c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dom := c.dayOfMonth&(1<<uint(t.Day())) != 0
+	dow := c.dayOfWeek&(1<<uint(t.Weekday())) != 0
+
+	// When both day fields are restricted, either of them has to match
+	if !c.anyDom && !c.anyDow {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,342 @@
+package scheduler
+
+// Package: scheduler
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/temperatures"
+	"slices"
+	"syscall"
+	"time"
+)
+
+const (
+	TriggerTime    = "time"
+	TriggerCron    = "cron"
+	TriggerSensor  = "sensor"
+	TriggerDevice  = "device"
+	TriggerBattery = "battery"
+	TriggerSuspend = "suspend"
+	TriggerResume  = "resume"
+)
+
+const (
+	ConditionAbove = "above"
+	ConditionBelow = "below"
+)
+
+const (
+	DeviceConnect    = "connect"
+	DeviceDisconnect = "disconnect"
+)
+
+// sensorHysteresis is amount of degrees a sensor has to move back before its rule can fire again
+const sensorHysteresis = 2
+
+// Trigger defines when a rule is executed
+type Trigger struct {
+	Type      string  `json:"type"`
+	Time      string  `json:"time,omitempty"`
+	Weekdays  []int   `json:"weekdays,omitempty"`
+	Cron      string  `json:"cron,omitempty"`
+	Sensor    uint8   `json:"sensor,omitempty"`
+	DeviceId  string  `json:"deviceId,omitempty"`
+	ChannelId int     `json:"channelId,omitempty"`
+	GpuIndex  int     `json:"gpuIndex,omitempty"`
+	Condition string  `json:"condition,omitempty"`
+	Value     float32 `json:"value,omitempty"`
+	Event     string  `json:"event,omitempty"`
+}
+
+// Rule is a single automation rule
+type Rule struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Enabled bool     `json:"enabled"`
+	Trigger Trigger  `json:"trigger"`
+	Actions []Action `json:"actions"`
+}
+
+var (
+	sensorState  = map[string]bool{}
+	batteryState = map[string]bool{}
+)
+
+// GetRules will return all automation rules
+func GetRules() []Rule {
+	mu.Lock()
+	defer mu.Unlock()
+
+	rules := make([]Rule, len(scheduler.Rules))
+	copy(rules, scheduler.Rules)
+	return rules
+}
+
+// GetRule will return automation rule by id
+func GetRule(ruleId string) *Rule {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, rule := range scheduler.Rules {
+		if rule.Id == ruleId {
+			return &rule
+		}
+	}
+	return nil
+}
+
+// SaveRule will create new or update existing automation rule.
+// Returns 0 on save failure, 2 on invalid trigger, 3 on invalid action and 4 on non-existing rule
+func SaveRule(rule Rule) uint8 {
+	if !validTrigger(&rule.Trigger) {
+		return 2
+	}
+	if len(rule.Actions) == 0 {
+		return 3
+	}
+	for _, action := range rule.Actions {
+		if !validAction(&action) {
+			return 3
+		}
+	}
+
+	mu.Lock()
+	if len(rule.Id) == 0 {
+		rule.Id = common.GenerateRandomMD5()
+		scheduler.Rules = append(scheduler.Rules, rule)
+	} else {
+		index := slices.IndexFunc(scheduler.Rules, func(r Rule) bool { return r.Id == rule.Id })
+		if index < 0 {
+			mu.Unlock()
+			return 4
+		}
+		scheduler.Rules[index] = rule
+	}
+	delete(sensorState, rule.Id)
+	current := scheduler
+	mu.Unlock()
+
+	return SaveSchedulerSettings(current)
+}
+
+// DeleteRule will delete automation rule
+func DeleteRule(ruleId string) uint8 {
+	mu.Lock()
+	index := slices.IndexFunc(scheduler.Rules, func(r Rule) bool { return r.Id == ruleId })
+	if index < 0 {
+		mu.Unlock()
+		return 2
+	}
+	scheduler.Rules = slices.Delete(scheduler.Rules, index, index+1)
+	delete(sensorState, ruleId)
+	current := scheduler
+	mu.Unlock()
+
+	return SaveSchedulerSettings(current)
+}
+
+// RunRule will execute actions of automation rule right away
+func RunRule(ruleId string) uint8 {
+	rule := GetRule(ruleId)
+	if rule == nil {
+		return 2
+	}
+	go execute(*rule, "")
+	return 1
+}
+
+// Suspend will execute suspend rules and remember suspend time, so resume rules can run once process is back
+func Suspend() {
+	mu.Lock()
+	scheduler.Suspended = time.Now().Unix()
+	current := scheduler
+	mu.Unlock()
+
+	SaveSchedulerSettings(current)
+	for _, rule := range matchingRules(TriggerSuspend) {
+		execute(rule, "")
+	}
+}
+
+// checkResume will execute resume rules when process was restarted after system resume
+func checkResume() {
+	mu.Lock()
+	suspended := scheduler.Suspended
+	scheduler.Suspended = 0
+	current := scheduler
+	mu.Unlock()
+
+	if suspended == 0 {
+		return
+	}
+	SaveSchedulerSettings(current)
+
+	// Suspend recorded before last boot means system was shut down, not resumed
+	info := syscall.Sysinfo_t{}
+	if err := syscall.Sysinfo(&info); err != nil {
+		logger.Log(logger.Fields{"error": err}).Warn("Unable to get system uptime")
+		return
+	}
+	if suspended < time.Now().Unix()-int64(info.Uptime) {
+		return
+	}
+
+	for _, rule := range matchingRules(TriggerResume) {
+		go execute(rule, "")
+	}
+}
+
+// validTrigger will validate rule trigger
+func validTrigger(trigger *Trigger) bool {
+	switch trigger.Type {
+	case TriggerTime:
+		if _, err := time.Parse(layout, trigger.Time); err != nil {
+			return false
+		}
+		for _, weekday := range trigger.Weekdays {
+			if weekday < 0 || weekday > 6 {
+				return false
+			}
+		}
+		return true
+	case TriggerCron:
+		_, err := parseCron(trigger.Cron)
+		return err == nil
+	case TriggerSensor:
+		if trigger.Condition != ConditionAbove && trigger.Condition != ConditionBelow {
+			return false
+		}
+		return trigger.Sensor <= temperatures.SensorTypeVirtual
+	case TriggerDevice:
+		return trigger.Event == DeviceConnect || trigger.Event == DeviceDisconnect
+	case TriggerBattery:
+		return trigger.Value > 0 && trigger.Value <= 100
+	case TriggerSuspend, TriggerResume:
+		return true
+	}
+	return false
+}
+
+// matchingRules will return enabled rules of given trigger type
+func matchingRules(triggerType string) []Rule {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var rules []Rule
+	for _, rule := range scheduler.Rules {
+		if rule.Enabled && rule.Trigger.Type == triggerType {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// evaluateTime will execute time and cron rules matching given minute
+func evaluateTime(now time.Time) {
+	for _, rule := range matchingRules(TriggerTime) {
+		at, _ := time.Parse(layout, rule.Trigger.Time)
+		if now.Hour() != at.Hour() || now.Minute() != at.Minute() {
+			continue
+		}
+		if len(rule.Trigger.Weekdays) > 0 && !slices.Contains(rule.Trigger.Weekdays, int(now.Weekday())) {
+			continue
+		}
+		go execute(rule, "")
+	}
+
+	for _, rule := range matchingRules(TriggerCron) {
+		schedule, err := parseCron(rule.Trigger.Cron)
+		if err != nil {
+			continue
+		}
+		if schedule.matches(now) {
+			go execute(rule, "")
+		}
+	}
+}
+
+// evaluateSensors will execute sensor rules once their threshold is crossed
+func evaluateSensors() {
+	for _, rule := range matchingRules(TriggerSensor) {
+		trigger := rule.Trigger
+		temp := temperatures.GetSourceTemperature(temperatures.VirtualSensorSource{
+			Sensor:    trigger.Sensor,
+			Device:    trigger.DeviceId,
+			ChannelId: trigger.ChannelId,
+			GpuIndex:  trigger.GpuIndex,
+		})
+		if temp == 0 {
+			// Failed sensor
+			continue
+		}
+
+		mu.Lock()
+		active := sensorState[rule.Id]
+		fire := false
+		switch trigger.Condition {
+		case ConditionAbove:
+			if !active && temp >= trigger.Value {
+				active, fire = true, true
+			} else if active && temp < trigger.Value-sensorHysteresis {
+				active = false
+			}
+		case ConditionBelow:
+			if !active && temp <= trigger.Value {
+				active, fire = true, true
+			} else if active && temp > trigger.Value+sensorHysteresis {
+				active = false
+			}
+		}
+		sensorState[rule.Id] = active
+		mu.Unlock()
+
+		if fire {
+			go execute(rule, trigger.DeviceId)
+		}
+	}
+}
+
+// evaluateEvent will execute device and battery rules matching given event
+func evaluateEvent(event events.Event) {
+	switch event.Type {
+	case events.EventDeviceAdded, events.EventDeviceRemoved:
+		state := DeviceConnect
+		if event.Type == events.EventDeviceRemoved {
+			state = DeviceDisconnect
+		}
+		for _, rule := range matchingRules(TriggerDevice) {
+			if rule.Trigger.Event != state {
+				continue
+			}
+			if len(rule.Trigger.DeviceId) > 0 && rule.Trigger.DeviceId != event.Serial {
+				continue
+			}
+			go execute(rule, event.Serial)
+		}
+	case events.EventBattery:
+		level, ok := event.Data.(uint16)
+		if !ok {
+			return
+		}
+		for _, rule := range matchingRules(TriggerBattery) {
+			if len(rule.Trigger.DeviceId) > 0 && rule.Trigger.DeviceId != event.Serial {
+				continue
+			}
+
+			key := rule.Id + event.Serial
+			mu.Lock()
+			fired := batteryState[key]
+			fire := !fired && float32(level) <= rule.Trigger.Value
+			batteryState[key] = float32(level) <= rule.Trigger.Value
+			mu.Unlock()
+
+			if fire {
+				go execute(rule, event.Serial)
+			}
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/devices"
+	"OpenLinkHub/src/events"
 	"OpenLinkHub/src/logger"
 	"encoding/json"
 	"os"
@@ -21,26 +22,19 @@ type Scheduler struct {
 	RGBOff     string `json:"rgbOff"`
 	RGBOn      string `json:"rgbOn"`
 	LCDControl bool   `json:"lcdControl"`
+	Rules      []Rule `json:"rules"`
+	Suspended  int64  `json:"suspended"`
 }
 
 var (
 	location    = ""
 	scheduler   Scheduler
-	upgrade     = map[string]any{"lcdControl": false}
+	upgrade     = map[string]any{"lcdControl": false, "rules": []any{}, "suspended": 0}
 	layout      = "15:04"
 	mu          sync.Mutex
-	timer       *time.Ticker
-	stopChan    chan struct{}
 	refreshTime = 5000
 )
 
-// Schedule represents a specific time to execute a task
-type Schedule struct {
-	Hour   int
-	Minute int
-	Action func()
-}
-
 // Init will initialize a new config object
 func Init() {
 	location = config.GetConfig().ConfigPath + "/database/scheduler.json"
@@ -63,15 +57,15 @@ func Init() {
 		logger.Log(logger.Fields{"error": err, "file": location}).Error("Failed to decode json")
 		return
 	}
+	if loaded.Rules == nil {
+		loaded.Rules = make([]Rule, 0)
+	}
 
 	mu.Lock()
 	scheduler = loaded
-	enabled := scheduler.RGBControl
 	mu.Unlock()
 
-	if enabled {
-		startTasks()
-	}
+	go run()
 }
 
 // SaveSchedulerSettings will save dashboard settings
@@ -106,12 +100,6 @@ func UpdateRgbSettings(enabled bool, start, end string, lcdControl bool) uint8 {
 	mu.Unlock()
 
 	SaveSchedulerSettings(current)
-	if current.RGBControl {
-		startTasks()
-	} else {
-		stopTasks()
-	}
-
 	return 1
 }
 
@@ -122,149 +110,80 @@ func GetScheduler() Scheduler {
 	return scheduler
 }
 
-// stopTasks will stop tasks
-func stopTasks() {
-	mu.Lock()
-	defer mu.Unlock()
-
-	if timer != nil {
-		timer.Stop()
-		timer = nil
-	}
-	if stopChan != nil {
-		close(stopChan)
-		stopChan = nil
+// run will evaluate RGB schedule and automation rules until process exits
+func run() {
+	id, ch := events.Subscribe()
+	defer events.Unsubscribe(id)
+
+	ticker := time.NewTicker(time.Duration(refreshTime) * time.Millisecond)
+	defer ticker.Stop()
+
+	// Give devices a moment to settle before applying initial state
+	time.Sleep(time.Duration(refreshTime) * time.Millisecond)
+	now := time.Now()
+	lastMinute := now.Truncate(time.Minute)
+	updateLightsOut(now)
+	checkResume()
+
+	for {
+		select {
+		case now = <-ticker.C:
+			updateLightsOut(now)
+			if minute := now.Truncate(time.Minute); minute.After(lastMinute) {
+				lastMinute = minute
+				evaluateTime(now)
+			}
+			evaluateSensors()
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			evaluateEvent(event)
+		}
 	}
 }
 
-func startTasks() {
-	stopTasks()
-
+// updateLightsOut will turn device brightness off and on based on RGB schedule
+func updateLightsOut(now time.Time) {
 	mu.Lock()
-	rgbOff := scheduler.RGBOff
-	rgbOn := scheduler.RGBOn
-	mu.Unlock()
-
-	scheduledTimeOff, _ := time.Parse("15:04", rgbOff)
-	scheduledTimeOn, _ := time.Parse("15:04", rgbOn)
-
-	isInOffRange := func(now, off, on time.Time) bool {
-		offToday := time.Date(now.Year(), now.Month(), now.Day(), off.Hour(), off.Minute(), 0, 0, now.Location())
-		onToday := time.Date(now.Year(), now.Month(), now.Day(), on.Hour(), on.Minute(), 0, 0, now.Location())
-
-		if onToday.Before(offToday) {
-			return now.After(offToday) || now.Before(onToday)
-		}
-		return now.After(offToday) && now.Before(onToday)
+	if !scheduler.RGBControl {
+		mu.Unlock()
+		return
 	}
 
-	// Define the times you want the task to run
-	schedules := []Schedule{
-		{
-			Hour:   scheduledTimeOff.Hour(),
-			Minute: scheduledTimeOff.Minute(),
-			Action: func() {
-				mu.Lock()
-				if !scheduler.LightsOut {
-					scheduler.LightsOut = true
-					lcdControl := scheduler.LCDControl
-					current := scheduler
-					mu.Unlock()
-
-					devices.ScheduleDeviceBrightness(0)
-					if lcdControl {
-						devices.ScheduleDeviceLcdBrightness(0)
-					}
-					SaveSchedulerSettings(current)
-				} else {
-					mu.Unlock()
-				}
-			},
-		},
-		{
-			Hour:   scheduledTimeOn.Hour(),
-			Minute: scheduledTimeOn.Minute(),
-			Action: func() {
-				mu.Lock()
-				if scheduler.LightsOut {
-					scheduler.LightsOut = false
-					lcdControl := scheduler.LCDControl
-					current := scheduler
-					mu.Unlock()
-
-					devices.ScheduleDeviceBrightness(1)
-					if lcdControl {
-						devices.ScheduleDeviceLcdBrightness(1)
-					}
-					SaveSchedulerSettings(current)
-				} else {
-					mu.Unlock()
-				}
-			},
-		},
+	off, _ := time.Parse(layout, scheduler.RGBOff)
+	on, _ := time.Parse(layout, scheduler.RGBOn)
+	lightsOut := isInOffRange(now, off, on)
+	if lightsOut == scheduler.LightsOut {
+		mu.Unlock()
+		return
 	}
 
-	mu.Lock()
-	localStop := make(chan struct{})
-	localTimer := time.NewTicker(time.Duration(refreshTime) * time.Millisecond)
-	stopChan = localStop
-	timer = localTimer
+	scheduler.LightsOut = lightsOut
+	lcdControl := scheduler.LCDControl
+	current := scheduler
 	mu.Unlock()
 
-	go func(t *time.Ticker, stop <-chan struct{}) {
-		// Check if we started (or settings were updated) while already inside the off
-		// range and apply the initial brightness state after a short settling delay.
-		// This runs in the goroutine so that callers (Init, UpdateRgbSettings) are
-		// not blocked for the duration of the sleep.
-		time.Sleep(time.Duration(refreshTime) * time.Millisecond)
-		timeNow := time.Now()
-		if isInOffRange(timeNow, scheduledTimeOff, scheduledTimeOn) {
-			mu.Lock()
-			if !scheduler.LightsOut {
-				scheduler.LightsOut = true
-				lcdControl := scheduler.LCDControl
-				current := scheduler
-				mu.Unlock()
-
-				devices.ScheduleDeviceBrightness(0)
-				if lcdControl {
-					devices.ScheduleDeviceLcdBrightness(0)
-				}
-				SaveSchedulerSettings(current)
-			} else {
-				mu.Unlock()
-			}
-		} else {
-			mu.Lock()
-			if scheduler.LightsOut {
-				scheduler.LightsOut = false
-				lcdControl := scheduler.LCDControl
-				current := scheduler
-				mu.Unlock()
-
-				devices.ScheduleDeviceBrightness(1)
-				if lcdControl {
-					devices.ScheduleDeviceLcdBrightness(1)
-				}
-				SaveSchedulerSettings(current)
-			} else {
-				mu.Unlock()
-			}
-		}
+	var mode uint8 = 1
+	if lightsOut {
+		mode = 0
+	}
+	devices.ScheduleDeviceBrightness(mode)
+	if lcdControl {
+		devices.ScheduleDeviceLcdBrightness(mode)
+	}
+	SaveSchedulerSettings(current)
+}
 
-		for {
-			select {
-			case now := <-t.C:
-				for _, schedule := range schedules {
-					if now.Hour() == schedule.Hour && now.Minute() == schedule.Minute {
-						go schedule.Action()
-					}
-				}
-			case <-stop:
-				return
-			}
-		}
-	}(localTimer, localStop)
+// isInOffRange will return true when given time is between RGB off and on time
+func isInOffRange(now, off, on time.Time) bool {
+	offToday := time.Date(now.Year(), now.Month(), now.Day(), off.Hour(), off.Minute(), 0, 0, now.Location())
+	onToday := time.Date(now.Year(), now.Month(), now.Day(), on.Hour(), on.Minute(), 0, 0, now.Location())
+
+	if onToday.Before(offToday) {
+		return !now.Before(offToday) || now.Before(onToday)
+	}
+	return !now.Before(offToday) && now.Before(onToday)
 }
 
 // upgradeFile will perform json file upgrade or create initial file
@@ -277,6 +196,7 @@ func upgradeFile() {
 			RGBControl: false,
 			RGBOff:     time.Now().Format("15:04"),
 			RGBOn:      time.Now().Format("15:04"),
+			Rules:      make([]Rule, 0),
 		}
 		if SaveSchedulerSettings(sche) == 1 {
 			logger.Log(logger.Fields{"file": location}).Info("Scheduler file is created.")
@@ -6,25 +6,28 @@ package requests
 
 import (
 	"OpenLinkHub/src/audio"
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dashboard"
 	"OpenLinkHub/src/devices"
 	"OpenLinkHub/src/devices/lcd"
 	"OpenLinkHub/src/display"
+	"OpenLinkHub/src/events"
 	"OpenLinkHub/src/inputmanager"
 	"OpenLinkHub/src/keyboards"
 	"OpenLinkHub/src/language"
 	"OpenLinkHub/src/led"
 	"OpenLinkHub/src/logger"
 	"OpenLinkHub/src/macro"
+	"OpenLinkHub/src/process"
 	"OpenLinkHub/src/rgb"
+	"OpenLinkHub/src/scenes"
 	"OpenLinkHub/src/scheduler"
 	"OpenLinkHub/src/temperatures"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"reflect"
 )
 
 // Payload contains data from a client about device speed change
@@ -143,6 +146,10 @@ type Payload struct {
 	LedProfile                    led.Device            `json:"ledProfile"`
 	Points                        []temperatures.Point  `json:"points"`
 	UpdateType                    uint8                 `json:"updateType"`
+	HysteresisUp                  float32               `json:"hysteresisUp"`
+	HysteresisDown                float32               `json:"hysteresisDown"`
+	RampRate                      float32               `json:"rampRate"`
+	AverageWindow                 int                   `json:"averageWindow"`
 	Data                          interface{}           `json:"data"`
 	PerfWinKey                    bool                  `json:"perf_winKey"`
 	PerfShiftTab                  bool                  `json:"perf_shiftTab"`
@@ -191,9 +198,32 @@ type Payload struct {
 	MousePositionAbsolute         bool                  `json:"mousePositionAbsolute"`
 	MacroRepeat                   int                   `json:"macroRepeat"`
 	MacroRepeatDelay              int                   `json:"macroRepeatDelay"`
+	MacroQuantize                 int                   `json:"macroQuantize"`
+	MacroProfileAlt               string                `json:"macroProfileAlt"`
 	Status                        int
 	Code                          int
 	Message                       string
+
+	// Virtual sensors
+	VirtualSensor string                             `json:"virtualSensor"`
+	Operation     string                             `json:"operation"`
+	Sources       []temperatures.VirtualSensorSource `json:"sources"`
+
+	// Automation rules
+	RuleId string         `json:"ruleId"`
+	Rule   scheduler.Rule `json:"rule"`
+
+	// Process rules
+	ProcessRule process.Rule `json:"processRule"`
+
+	// Scenes
+	SceneId   int          `json:"sceneId"`
+	SceneName string       `json:"sceneName"`
+	Scene     scenes.Scene `json:"scene"`
+
+	// Key assignment layers
+	LayerId int                   `json:"layerId"`
+	Layer   inputmanager.KeyLayer `json:"layer"`
 }
 
 // ProcessDeleteTemperatureProfile will process deletion of temperature profile
@@ -333,6 +363,51 @@ func ProcessUpdateTemperatureProfileGraph(r *http.Request) *Payload {
 	return &Payload{Message: language.GetValue("txtSpeedProfileNotUpdated"), Code: http.StatusOK, Status: 0}
 }
 
+// ProcessUpdateTemperatureProfileCurve will process update of temperature profile hysteresis, ramp rate and averaging
+func ProcessUpdateTemperatureProfileCurve(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	profile := req.Profile
+	if !common.AlphanumericRegex.MatchString(profile) {
+		return &Payload{
+			Message: language.GetValue("txtProfileInvalidName"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if req.HysteresisUp < 0 || req.HysteresisUp > 20 || req.HysteresisDown < 0 || req.HysteresisDown > 20 {
+		return &Payload{Message: language.GetValue("txtInvalidCurveOptions"), Code: http.StatusOK, Status: 0}
+	}
+
+	if req.RampRate < 0 || req.RampRate > 100 || req.AverageWindow < 0 || req.AverageWindow > 60 {
+		return &Payload{Message: language.GetValue("txtInvalidCurveOptions"), Code: http.StatusOK, Status: 0}
+	}
+
+	pf := temperatures.GetTemperatureProfile(profile)
+	if pf == nil {
+		return &Payload{Message: language.GetValue("txtNonExistingSpeedProfile"), Code: http.StatusOK, Status: 0}
+	}
+
+	pf.HysteresisUp = req.HysteresisUp
+	pf.HysteresisDown = req.HysteresisDown
+	pf.RampRate = req.RampRate
+	pf.AverageWindow = req.AverageWindow
+	if temperatures.UpdateTemperatureProfileGraph(profile, *pf) == 1 {
+		return &Payload{Message: language.GetValue("txtSpeedProfileUpdated"), Code: http.StatusOK, Status: 1}
+	}
+	return &Payload{Message: language.GetValue("txtSpeedProfileNotUpdated"), Code: http.StatusOK, Status: 0}
+}
+
 // ProcessNewTemperatureProfile will process the creation of temperature profile
 func ProcessNewTemperatureProfile(r *http.Request) *Payload {
 	req := &Payload{}
@@ -376,7 +451,7 @@ func ProcessNewTemperatureProfile(r *http.Request) *Payload {
 		}
 	}
 
-	if sensor > 11 || sensor < 0 {
+	if sensor > 12 || sensor < 0 {
 		return &Payload{
 			Message: language.GetValue("txtInvalidSensorValue"),
 			Code:    http.StatusOK,
@@ -478,6 +553,17 @@ func ProcessNewTemperatureProfile(r *http.Request) *Payload {
 		deviceId = req.ExternalExecutable
 	}
 
+	if sensor == temperatures.SensorTypeVirtual {
+		if temperatures.GetVirtualSensor(req.VirtualSensor) == nil {
+			return &Payload{
+				Message: language.GetValue("txtNonExistingVirtualSensor"),
+				Code:    http.StatusOK,
+				Status:  0,
+			}
+		}
+		deviceId = req.VirtualSensor
+	}
+
 	gpuIndex := req.GpuIndex
 	if gpuIndex < 0 || gpuIndex > 5 {
 		return &Payload{
@@ -515,6 +601,68 @@ func ProcessNewTemperatureProfile(r *http.Request) *Payload {
 	}
 }
 
+// ProcessNewVirtualSensor will process creation or update of virtual temperature sensor
+func ProcessNewVirtualSensor(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if len(req.VirtualSensor) < 3 {
+		return &Payload{Message: language.GetValue("txtProfileNameTooShort"), Code: http.StatusOK, Status: 0}
+	}
+
+	if !common.AlphanumericRegex.MatchString(req.VirtualSensor) {
+		return &Payload{Message: language.GetValue("txtProfileInvalidName"), Code: http.StatusOK, Status: 0}
+	}
+
+	sensor := temperatures.VirtualSensor{
+		Name:      req.VirtualSensor,
+		Operation: req.Operation,
+		Sources:   req.Sources,
+	}
+
+	if temperatures.SaveVirtualSensor(sensor) == 1 {
+		return &Payload{Message: language.GetValue("txtVirtualSensorSaved"), Code: http.StatusOK, Status: 1}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToSaveVirtualSensor"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessDeleteVirtualSensor will process deletion of virtual temperature sensor
+func ProcessDeleteVirtualSensor(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if !common.AlphanumericRegex.MatchString(req.VirtualSensor) {
+		return &Payload{Message: language.GetValue("txtNonExistingVirtualSensor"), Code: http.StatusOK, Status: 0}
+	}
+
+	for _, profile := range temperatures.GetTemperatureProfiles() {
+		if profile.Sensor == temperatures.SensorTypeVirtual && profile.Device == req.VirtualSensor {
+			return &Payload{Message: language.GetValue("txtVirtualSensorInUse"), Code: http.StatusOK, Status: 0}
+		}
+	}
+
+	if temperatures.DeleteVirtualSensor(req.VirtualSensor) == 1 {
+		return &Payload{Message: language.GetValue("txtVirtualSensorDeleted"), Code: http.StatusOK, Status: 1}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToDeleteVirtualSensor"), Code: http.StatusOK, Status: 0}
+}
+
 // ProcessChangeSpeed will process POST request from a client for fan/pump profile speed change
 func ProcessChangeSpeed(r *http.Request) *Payload {
 	req := &Payload{}
@@ -561,15 +709,20 @@ func ProcessChangeSpeed(r *http.Request) *Payload {
 	}
 
 	// Run it
-	var results []reflect.Value
+	var result uint8
+	var supported bool
 	if len(req.ChannelIds) > 0 {
-		results = devices.CallDeviceMethod(req.DeviceId, "UpdateSpeedProfileBulk", req.ChannelIds, req.Profile)
+		if device, ok := devices.GetDevice(req.DeviceId).(capabilities.SpeedProfileBulk); ok {
+			result, supported = device.UpdateSpeedProfileBulk(req.ChannelIds, req.Profile), true
+		}
 	} else {
-		results = devices.CallDeviceMethod(req.DeviceId, "UpdateSpeedProfile", req.ChannelId, req.Profile)
+		if device, ok := devices.GetDevice(req.DeviceId).(capabilities.SpeedProfile); ok {
+			result, supported = device.UpdateSpeedProfile(req.ChannelId, req.Profile), true
+		}
 	}
 
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if supported {
+		switch result {
 		case 0:
 			return &Payload{Message: language.GetValue("txtNonExistingSpeedProfileSelected"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -619,10 +772,8 @@ func ProcessOperatingMode(r *http.Request) *Payload {
 	}
 
 	// Run it
-	results := devices.CallDeviceMethod(req.DeviceId, "UpdateOperatingMode", req.ChannelId, req.OperatingMode)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.OperatingMode); ok {
+		switch device.UpdateOperatingMode(req.ChannelId, req.OperatingMode) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangePwmMode"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -710,6 +861,11 @@ func ProcessUpdateRgbProfile(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtUnableToValidateRequest"), Code: http.StatusOK, Status: 0}
 	}
 
+	// Virtual sensor used by temperature modes
+	if len(req.VirtualSensor) > 0 && temperatures.GetVirtualSensor(req.VirtualSensor) == nil {
+		return &Payload{Message: language.GetValue("txtNonExistingVirtualSensor"), Code: http.StatusOK, Status: 0}
+	}
+
 	startColor := req.StartColor
 	startColor.Brightness = 1
 
@@ -730,17 +886,11 @@ func ProcessUpdateRgbProfile(r *http.Request) *Payload {
 		AlternateColors: req.AlternateColors,
 		RgbDirection:    req.RgbDirection,
 		Gradients:       req.ColorZones,
+		Sensor:          req.VirtualSensor,
 	}
 
-	results := devices.CallDeviceMethod(
-		deviceId,
-		"UpdateRgbProfileData",
-		profile,
-		rgbProfile,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(deviceId).(capabilities.RgbProfileEditor); ok {
+		switch device.UpdateRgbProfileData(profile, rgbProfile) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtRgbProfileNotUpdated"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -783,15 +933,8 @@ func ProcessLcdChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceLcd",
-		req.ChannelId,
-		req.Mode,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.Lcd); ok {
+		switch device.UpdateDeviceLcd(req.ChannelId, req.Mode) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLcdModeChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -830,14 +973,8 @@ func ProcessLcdProfileChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtInvalidLcdMode"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceLcdProfile",
-		req.Profile,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LcdProfile); ok {
+		switch device.UpdateDeviceLcdProfile(req.Profile) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLcdProfileChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -880,15 +1017,8 @@ func ProcessLcdDeviceChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ChangeDeviceLcd",
-		req.ChannelId,
-		req.LcdSerial,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LcdChanger); ok {
+		switch device.ChangeDeviceLcd(req.ChannelId, req.LcdSerial) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLcdDeviceChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -931,15 +1061,8 @@ func ProcessLcdRotationChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceLcdRotation",
-		req.ChannelId,
-		req.Rotation,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.Lcd); ok {
+		switch device.UpdateDeviceLcdRotation(req.ChannelId, req.Rotation) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLcdRotationChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -982,15 +1105,8 @@ func ProcessLcdBrightnessChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceLcdBrightness",
-		req.ChannelId,
-		req.Brightness,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LcdBrightness); ok {
+		switch device.UpdateDeviceLcdBrightness(req.ChannelId, req.Brightness) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLcdBrightnessChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1037,15 +1153,8 @@ func ProcessLcdImageChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceLcdImage",
-		req.ChannelId,
-		req.Image,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.Lcd); ok {
+		switch device.UpdateDeviceLcdImage(req.ChannelId, req.Image) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLcdImageChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1242,14 +1351,8 @@ func ProcessSaveUserProfile(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SaveUserProfile",
-		req.UserProfileName,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.UserProfiles); ok {
+		switch device.SaveUserProfile(req.UserProfileName) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtUserProfileSaved"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1292,15 +1395,8 @@ func ProcessSaveDeviceProfile(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SaveDeviceProfile",
-		req.KeyboardProfileName,
-		req.New,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyboardProfileSaver); ok {
+		switch device.SaveDeviceProfile(req.KeyboardProfileName, req.New) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtKeyboardProfileSaved"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1343,14 +1439,8 @@ func ProcessChangeKeyboardLayout(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ChangeKeyboardLayout",
-		req.KeyboardLayout,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyboardProfiles); ok {
+		switch device.ChangeKeyboardLayout(req.KeyboardLayout) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtKeyboardLayoutChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1389,14 +1479,8 @@ func ProcessChangeControlDial(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateControlDial",
-		req.KeyboardControlDial,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ControlDial); ok {
+		switch device.UpdateControlDial(req.KeyboardControlDial) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtControlDialChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1435,14 +1519,8 @@ func ProcessChangeSleepMode(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateSleepTimer",
-		req.SleepMode,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.SleepTimer); ok {
+		switch device.UpdateSleepTimer(req.SleepMode) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtSleepModeChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1481,14 +1559,8 @@ func ProcessChangeControllerSleepMode(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateSleepTimer",
-		req.SleepMode,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.SleepTimer); ok {
+		switch device.UpdateSleepTimer(req.SleepMode) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtSleepModeChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1527,14 +1599,8 @@ func ProcessChangePollingRate(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdatePollingRate",
-		req.PollingRate,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.PollingRate); ok {
+		switch device.UpdatePollingRate(req.PollingRate) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtPollingRateChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1573,14 +1639,8 @@ func ProcessChangeAngleSnapping(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateAngleSnapping",
-		req.AngleSnapping,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.AngleSnapping); ok {
+		switch device.UpdateAngleSnapping(req.AngleSnapping) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtAngleSnappingChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1619,14 +1679,8 @@ func ProcessChangeRippleControl(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateRippleControl",
-		req.RippleControl,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RippleControl); ok {
+		switch device.UpdateRippleControl(req.RippleControl) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtRippleControlChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1665,14 +1719,8 @@ func ProcessChangeMotionSync(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateMotionSync",
-		req.MotionSync,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.MotionSync); ok {
+		switch device.UpdateMotionSync(req.MotionSync) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtMotionSyncChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1711,14 +1759,8 @@ func ProcessChangeAutoBrightness(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateAutoBrightness",
-		req.AutoBrightness,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.AutoBrightness); ok {
+		switch device.UpdateAutoBrightness(req.AutoBrightness) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtAutoBrightnessUpdated"), Code: http.StatusOK, Status: 1}
 		case 0:
@@ -1757,14 +1799,8 @@ func ProcessChangeButtonOptimization(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateButtonOptimization",
-		req.ButtonOptimization,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ButtonOptimization); ok {
+		switch device.UpdateButtonOptimization(req.ButtonOptimization) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtButtonOptimizationChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1803,14 +1839,8 @@ func ProcessChangeLeftHandMode(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateLeftHandMode",
-		req.LeftHandMode,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LeftHandMode); ok {
+		switch device.UpdateLeftHandMode(req.LeftHandMode) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLeftHandModeChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1849,14 +1879,8 @@ func ProcessChangeLiftHeight(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateLiftHeight",
-		req.LiftHeight,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LiftHeight); ok {
+		switch device.UpdateLiftHeight(req.LiftHeight) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLiftHeightUpdated"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1895,14 +1919,8 @@ func ProcessChangeSurfaceSelection(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateSurfaceSelection",
-		req.SurfaceSelection,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.SurfaceSelection); ok {
+		switch device.UpdateSurfaceSelection(req.SurfaceSelection) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtSurfaceSelectionUpdated"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -1941,14 +1959,8 @@ func ProcessChangeDebounceTime(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDebounceTime",
-		req.DebounceTime,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.DebounceTime); ok {
+		switch device.UpdateDebounceTime(req.DebounceTime) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtDebounceTimeUpdated"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2019,15 +2031,8 @@ func ProcessChangeKeyAssignment(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtSniperPressAndHold"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceKeyAssignment",
-		req.KeyIndex,
-		keyAssignment,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyAssignment); ok {
+		switch device.UpdateDeviceKeyAssignment(req.KeyIndex, keyAssignment) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtKeyAssigmentUpdated"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2070,15 +2075,8 @@ func ProcessChangeKeyActuation(r *http.Request) *Payload {
 		SecondaryActuationResetPoint:  req.SecondaryActuationResetPoint,
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceKeyActuation",
-		req.KeyIndex,
-		keyActuation,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyActuation); ok {
+		switch device.UpdateDeviceKeyActuation(req.KeyIndex, keyActuation) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtKeyActuationUpdated"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2125,14 +2123,8 @@ func ProcessChangeMuteIndicator(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateMuteIndicator",
-		req.MuteIndicator,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.MuteIndicator); ok {
+		switch device.UpdateMuteIndicator(req.MuteIndicator) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtIndicatorChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2171,14 +2163,8 @@ func ProcessActiveNoiseCancellation(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateActiveNoiseCancellation",
-		req.NoiseCancellation,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.NoiseCancellation); ok {
+		switch device.UpdateActiveNoiseCancellation(req.NoiseCancellation) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtAncUpdated"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2217,14 +2203,8 @@ func ProcessSidetone(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateSidetone",
-		req.SideTone,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.Sidetone); ok {
+		switch device.UpdateSidetone(req.SideTone) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtSidetoneUpdated"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2263,14 +2243,8 @@ func ProcessSidetoneValue(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateSidetoneValue",
-		req.SideToneValue,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.Sidetone); ok {
+		switch device.UpdateSidetoneValue(req.SideToneValue) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtSidetoneUpdated"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2313,15 +2287,8 @@ func ProcessUpdateWheelOption(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateWheelOption",
-		req.WheelId,
-		req.WheelOption,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.WheelOption); ok {
+		switch device.UpdateWheelOption(req.WheelId, req.WheelOption) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtWheelUpdated"), Code: http.StatusOK, Status: 1}
 		}
@@ -2362,14 +2329,8 @@ func ProcessDeleteKeyboardProfile(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"DeleteKeyboardProfile",
-		req.KeyboardProfileName,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyboardProfiles); ok {
+		switch device.DeleteKeyboardProfile(req.KeyboardProfileName) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtKeyboardProfileDeleted"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2414,15 +2375,10 @@ func ProcessChangeKeyboardProfile(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateKeyboardProfile",
-		req.KeyboardProfileName,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyboardProfiles); ok {
+		switch device.UpdateKeyboardProfile(req.KeyboardProfileName) {
 		case 1:
+			events.Publish(events.EventProfile, req.DeviceId, 0, req.KeyboardProfileName)
 			return &Payload{Message: language.GetValue("txtKeyboardProfileChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
 			return &Payload{Message: language.GetValue("txtUnableToChangeKeyboardProfile"), Code: http.StatusOK, Status: 0}
@@ -2464,15 +2420,10 @@ func ProcessChangeUserProfile(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ChangeDeviceProfile",
-		req.UserProfileName,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.UserProfiles); ok {
+		switch device.ChangeDeviceProfile(req.UserProfileName) {
 		case 1:
+			events.Publish(events.EventProfile, req.DeviceId, 0, req.UserProfileName)
 			return &Payload{Message: language.GetValue("txtUserProfileChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
 			return &Payload{Message: language.GetValue("txtUnableToChangeUserProfile"), Code: http.StatusOK, Status: 0}
@@ -2518,14 +2469,8 @@ func ProcessDeleteUserProfile(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtDefaultProfileIsRequired"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"DeleteDeviceProfile",
-		req.UserProfileName,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.UserProfileDeleter); ok {
+		switch device.DeleteDeviceProfile(req.UserProfileName) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtUserProfileDeleted"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2566,14 +2511,8 @@ func ProcessBrightnessChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ChangeDeviceBrightness",
-		req.Brightness,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.Brightness); ok {
+		switch device.ChangeDeviceBrightness(req.Brightness) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtBrightnessChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2612,14 +2551,8 @@ func ProcessBrightnessChangeGradual(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ChangeDeviceBrightnessValue",
-		req.Brightness,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.BrightnessValue); ok {
+		switch device.ChangeDeviceBrightnessValue(req.Brightness) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtBrightnessChanged"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -2658,14 +2591,8 @@ func ProcessPositionChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtUnableToChangePosition"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDevicePosition",
-		req.Positions,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.DevicePositions); ok {
+		switch device.UpdateDevicePosition(req.Positions) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtPositionChanged"), Code: http.StatusOK, Status: 1}
 		}
@@ -2714,15 +2641,20 @@ func ProcessLabelChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	var results []reflect.Value
+	var result uint8
+	var supported bool
 	if req.DeviceType == 0 {
-		results = devices.CallDeviceMethod(req.DeviceId, "UpdateDeviceLabel", req.ChannelId, req.Label)
+		if device, ok := devices.GetDevice(req.DeviceId).(capabilities.DeviceLabel); ok {
+			result, supported = device.UpdateDeviceLabel(req.ChannelId, req.Label), true
+		}
 	} else {
-		results = devices.CallDeviceMethod(req.DeviceId, "UpdateRGBDeviceLabel", req.ChannelId, req.Label)
+		if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbDeviceLabel); ok {
+			result, supported = device.UpdateRGBDeviceLabel(req.ChannelId, req.Label), true
+		}
 	}
 
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if supported {
+		switch result {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToApplyLabel"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -2769,15 +2701,8 @@ func ProcessManualChangeSpeed(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceSpeed",
-		req.ChannelId,
-		req.Value,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ManualSpeed); ok {
+		switch device.UpdateDeviceSpeed(req.ChannelId, req.Value) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtDeviceSpeedProfileChanged"), Code: http.StatusOK, Status: 1}
 		}
@@ -2811,15 +2736,20 @@ func ProcessChangeColor(r *http.Request) *Payload {
 	}
 
 	// Run it
-	var results []reflect.Value
+	var result uint8
+	var supported bool
 	if len(req.ChannelIds) > 0 {
-		results = devices.CallDeviceMethod(req.DeviceId, "UpdateRgbProfileBulk", req.ChannelIds, req.Profile)
+		if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbBulk); ok {
+			result, supported = device.UpdateRgbProfileBulk(req.ChannelIds, req.Profile), true
+		}
 	} else {
-		results = devices.CallDeviceMethod(req.DeviceId, "UpdateRgbProfile", req.ChannelId, req.Profile)
+		if device, ok := devices.GetDevice(req.DeviceId).(capabilities.Rgb); ok {
+			result, supported = device.UpdateRgbProfile(req.ChannelId, req.Profile), true
+		}
 	}
 
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if supported {
+		switch result {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeRgbProfile"), Code: http.StatusOK, Status: 0}
 		case 2:
@@ -2831,6 +2761,13 @@ func ProcessChangeColor(r *http.Request) *Payload {
 		case 5:
 			return &Payload{Message: language.GetValue("txtUnableToChangeRgbProfileCluster"), Code: http.StatusOK, Status: 0}
 		case 1:
+			if len(req.ChannelIds) > 0 {
+				for _, channelId := range req.ChannelIds {
+					events.Publish(events.EventRgb, req.DeviceId, channelId, req.Profile)
+				}
+			} else {
+				events.Publish(events.EventRgb, req.DeviceId, req.ChannelId, req.Profile)
+			}
 			return &Payload{Message: language.GetValue("txtDeviceRgbProfileChanged"), Code: http.StatusOK, Status: 1}
 		}
 	}
@@ -2887,16 +2824,8 @@ func ProcessChangeLinkAdapterColor(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateLinkAdapterRgbProfile",
-		req.ChannelId,
-		req.AdapterId,
-		req.Profile,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LinkAdapter); ok {
+		switch device.UpdateLinkAdapterRgbProfile(req.ChannelId, req.AdapterId, req.Profile) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeRgbProfile"), Code: http.StatusOK, Status: 0}
 		case 2:
@@ -2935,15 +2864,8 @@ func ProcessChangeLinkAdapterColorBulk(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateLinkAdapterRgbProfileBulk",
-		req.ChannelId,
-		req.Profile,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LinkAdapter); ok {
+		switch device.UpdateLinkAdapterRgbProfileBulk(req.ChannelId, req.Profile) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeRgbProfile"), Code: http.StatusOK, Status: 0}
 		case 2:
@@ -2978,14 +2900,8 @@ func ProcessHardwareChangeColor(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateHardwareRgbProfile",
-		req.HardwareLight,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.HardwareRgb); ok {
+		switch device.UpdateHardwareRgbProfile(req.HardwareLight) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeRgbProfile"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3016,15 +2932,8 @@ func ProcessChangeStrip(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateExternalAdapter",
-		req.ChannelId,
-		req.StripId,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ExternalAdapter); ok {
+		switch device.UpdateExternalAdapter(req.ChannelId, req.StripId) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeRgbStrip"), Code: http.StatusOK, Status: 0}
 		case 2:
@@ -3057,15 +2966,8 @@ func ProcessChangeLinkAdapter(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateLinkAdapter",
-		req.ChannelId,
-		req.AdapterId,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LinkAdapter); ok {
+		switch device.UpdateLinkAdapter(req.ChannelId, req.AdapterId) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeRgbStrip"), Code: http.StatusOK, Status: 0}
 		case 2:
@@ -3097,15 +2999,8 @@ func ProcessExternalHubDeviceType(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingLedPort"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateExternalHubDeviceType",
-		req.PortId,
-		req.DeviceType,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ExternalHub); ok {
+		switch device.UpdateExternalHubDeviceType(req.PortId, req.DeviceType) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeExternalHub"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3137,15 +3032,8 @@ func ProcessARGBDevice(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingLedPort"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateARGBDevice",
-		req.PortId,
-		req.DeviceType,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ARGBDevice); ok {
+		switch device.UpdateARGBDevice(req.PortId, req.DeviceType) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeExternalHub"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3194,17 +3082,8 @@ func ProcessKeyboardColor(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtInvalidKeySelected"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceColor",
-		req.KeyId,
-		req.KeyOption,
-		req.Color,
-		req.Keys,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyColor); ok {
+		switch device.UpdateDeviceColor(req.KeyId, req.KeyOption, req.Color, req.Keys) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeDeviceColor"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3249,17 +3128,8 @@ func ProcessMiscColor(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtInvalidAreaOptionSelected"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceColor",
-		req.AreaId,
-		req.AreaOption,
-		req.Color,
-		req.Keys,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyColor); ok {
+		switch device.UpdateDeviceColor(req.AreaId, req.AreaOption, req.Color, req.Keys) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeDeviceColor"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3294,15 +3164,8 @@ func ProcessExternalHubDeviceAmount(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateExternalHubDeviceAmount",
-		req.PortId,
-		req.DeviceAmount,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ExternalHub); ok {
+		switch device.UpdateExternalHubDeviceAmount(req.PortId, req.DeviceAmount) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeDeviceAmount"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3479,6 +3342,298 @@ func ProcessChangeRgbScheduler(r *http.Request) *Payload {
 	return &Payload{Message: language.GetValue("txtUnableToChangeRgbScheduler"), Code: http.StatusOK, Status: 0}
 }
 
+// ProcessSaveSchedulerRule will process creation or update of automation rule
+func ProcessSaveSchedulerRule(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if len(req.Rule.Name) < 3 {
+		return &Payload{Message: language.GetValue("txtProfileNameTooShort"), Code: http.StatusOK, Status: 0}
+	}
+
+	switch scheduler.SaveRule(req.Rule) {
+	case 1:
+		return &Payload{Message: language.GetValue("txtSchedulerRuleSaved"), Code: http.StatusOK, Status: 1}
+	case 2:
+		return &Payload{Message: language.GetValue("txtInvalidSchedulerTrigger"), Code: http.StatusOK, Status: 0}
+	case 3:
+		return &Payload{Message: language.GetValue("txtInvalidSchedulerAction"), Code: http.StatusOK, Status: 0}
+	case 4:
+		return &Payload{Message: language.GetValue("txtNonExistingSchedulerRule"), Code: http.StatusOK, Status: 0}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToSaveSchedulerRule"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessRunSchedulerRule will process manual execution of automation rule
+func ProcessRunSchedulerRule(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if scheduler.RunRule(req.RuleId) == 1 {
+		return &Payload{Message: language.GetValue("txtSchedulerRuleStarted"), Code: http.StatusOK, Status: 1}
+	}
+	return &Payload{Message: language.GetValue("txtNonExistingSchedulerRule"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessDeleteSchedulerRule will process deletion of automation rule
+func ProcessDeleteSchedulerRule(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	switch scheduler.DeleteRule(req.RuleId) {
+	case 1:
+		return &Payload{Message: language.GetValue("txtSchedulerRuleDeleted"), Code: http.StatusOK, Status: 1}
+	case 2:
+		return &Payload{Message: language.GetValue("txtNonExistingSchedulerRule"), Code: http.StatusOK, Status: 0}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToDeleteSchedulerRule"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessSaveProcessRule will process creation or update of process rule
+func ProcessSaveProcessRule(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if len(req.ProcessRule.Name) < 3 {
+		return &Payload{Message: language.GetValue("txtProfileNameTooShort"), Code: http.StatusOK, Status: 0}
+	}
+
+	if len(req.ProcessRule.Executable) == 0 {
+		return &Payload{Message: language.GetValue("txtInvalidProcessExecutable"), Code: http.StatusOK, Status: 0}
+	}
+
+	switch process.SaveRule(req.ProcessRule) {
+	case 1:
+		return &Payload{Message: language.GetValue("txtProcessRuleSaved"), Code: http.StatusOK, Status: 1}
+	case 2:
+		return &Payload{Message: language.GetValue("txtInvalidProcessProfiles"), Code: http.StatusOK, Status: 0}
+	case 3:
+		return &Payload{Message: language.GetValue("txtNonExistingProcessRule"), Code: http.StatusOK, Status: 0}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToSaveProcessRule"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessDeleteProcessRule will process deletion of process rule
+func ProcessDeleteProcessRule(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	switch process.DeleteRule(req.RuleId) {
+	case 1:
+		return &Payload{Message: language.GetValue("txtProcessRuleDeleted"), Code: http.StatusOK, Status: 1}
+	case 2:
+		return &Payload{Message: language.GetValue("txtNonExistingProcessRule"), Code: http.StatusOK, Status: 0}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToDeleteProcessRule"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessSaveScene will process recording of a scene
+func ProcessSaveScene(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if len(req.SceneName) < 3 {
+		return &Payload{Message: language.GetValue("txtProfileNameTooShort"), Code: http.StatusOK, Status: 0}
+	}
+
+	if !common.AlphanumericRegex.MatchString(req.SceneName) {
+		return &Payload{Message: language.GetValue("txtProfileInvalidName"), Code: http.StatusOK, Status: 0}
+	}
+
+	if scenes.SaveScene(req.SceneName) == 1 {
+		return &Payload{Message: language.GetValue("txtSceneSaved"), Code: http.StatusOK, Status: 1}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToSaveScene"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessApplyScene will process applying of a scene
+func ProcessApplyScene(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if scenes.ApplyScene(req.SceneId) == 1 {
+		return &Payload{Message: language.GetValue("txtSceneApplied"), Code: http.StatusOK, Status: 1}
+	}
+	return &Payload{Message: language.GetValue("txtNonExistingScene"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessImportScene will process import of exported scene
+func ProcessImportScene(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if len(req.Scene.Name) < 3 {
+		return &Payload{Message: language.GetValue("txtProfileNameTooShort"), Code: http.StatusOK, Status: 0}
+	}
+
+	if !common.AlphanumericRegex.MatchString(req.Scene.Name) {
+		return &Payload{Message: language.GetValue("txtProfileInvalidName"), Code: http.StatusOK, Status: 0}
+	}
+
+	if scenes.ImportScene(req.Scene) == 1 {
+		return &Payload{Message: language.GetValue("txtSceneImported"), Code: http.StatusOK, Status: 1}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToSaveScene"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessDeleteScene will process deletion of a scene
+func ProcessDeleteScene(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	switch scenes.DeleteScene(req.SceneId) {
+	case 1:
+		return &Payload{Message: language.GetValue("txtSceneDeleted"), Code: http.StatusOK, Status: 1}
+	case 2:
+		return &Payload{Message: language.GetValue("txtNonExistingScene"), Code: http.StatusOK, Status: 0}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToDeleteScene"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessSaveKeyAssignmentLayer will process saving of key assignment layer
+func ProcessSaveKeyAssignmentLayer(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if len(req.DeviceId) == 0 || !common.AlphanumericDashRegex.MatchString(req.DeviceId) {
+		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
+	}
+	if _, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyAssignment); !ok {
+		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
+	}
+
+	if len(req.Layer.Name) > 0 && !common.AlphanumericRegex.MatchString(req.Layer.Name) {
+		return &Payload{Message: language.GetValue("txtProfileInvalidName"), Code: http.StatusOK, Status: 0}
+	}
+
+	if len(req.Layer.RgbProfile) > 0 {
+		device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbProfileReader)
+		if !ok || device.GetRgbProfile(req.Layer.RgbProfile) == nil {
+			return &Payload{Message: language.GetValue("txtNonExistingRgbProfile"), Code: http.StatusOK, Status: 0}
+		}
+	}
+
+	switch inputmanager.SaveLayer(req.DeviceId, req.LayerId, req.Layer) {
+	case 1:
+		return &Payload{Message: language.GetValue("txtKeyLayerSaved"), Code: http.StatusOK, Status: 1}
+	case 2:
+		return &Payload{Message: language.GetValue("txtInvalidKeyLayer"), Code: http.StatusOK, Status: 0}
+	case 3:
+		return &Payload{Message: language.GetValue("txtInvalidKeyLayerKey"), Code: http.StatusOK, Status: 0}
+	case 4:
+		return &Payload{Message: language.GetValue("txtInvalidKeyLayerShift"), Code: http.StatusOK, Status: 0}
+	case 5:
+		return &Payload{Message: language.GetValue("txtInvalidDualFunction"), Code: http.StatusOK, Status: 0}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToSaveKeyLayer"), Code: http.StatusOK, Status: 0}
+}
+
+// ProcessDeleteKeyAssignmentLayer will process deletion of key assignment layer
+func ProcessDeleteKeyAssignmentLayer(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if len(req.DeviceId) == 0 || !common.AlphanumericDashRegex.MatchString(req.DeviceId) {
+		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
+	}
+
+	switch inputmanager.DeleteLayer(req.DeviceId, req.LayerId) {
+	case 1:
+		return &Payload{Message: language.GetValue("txtKeyLayerDeleted"), Code: http.StatusOK, Status: 1}
+	case 2:
+		return &Payload{Message: language.GetValue("txtInvalidKeyLayer"), Code: http.StatusOK, Status: 0}
+	}
+	return &Payload{Message: language.GetValue("txtUnableToSaveKeyLayer"), Code: http.StatusOK, Status: 0}
+}
+
 // ProcessPsuFanModeChange will process a POST request from a client for PSU fan mode change
 func ProcessPsuFanModeChange(r *http.Request) *Payload {
 	req := &Payload{}
@@ -3500,14 +3655,8 @@ func ProcessPsuFanModeChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdatePsuFan",
-		req.FanMode,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.PsuFan); ok {
+		switch device.UpdatePsuFan(req.FanMode) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToChangeFanMode"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3538,14 +3687,8 @@ func ProcessMouseDpiSave(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SaveMouseDPI",
-		req.Stages,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.MouseDpi); ok {
+		switch device.SaveMouseDPI(req.Stages) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToSaveDPI"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3576,15 +3719,8 @@ func ProcessMouseGestureUpdate(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SaveMouseGestures",
-		req.MultiGestures,
-		req.ZoneTilts,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.MouseGestures); ok {
+		switch device.SaveMouseGestures(req.MultiGestures, req.ZoneTilts) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToUpdateDeviceGestures"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3611,26 +3747,20 @@ func ProcessMouseZoneColorsSave(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	var results []reflect.Value
+	var result uint8
+	var supported bool
 	if req.IsSniper {
-		results = devices.CallDeviceMethod(
-			req.DeviceId,
-			"SaveMouseZoneColorsSniper",
-			req.ColorDpi,
-			req.ColorZones,
-			req.ColorSniper,
-		)
+		if device, ok := devices.GetDevice(req.DeviceId).(capabilities.MouseSniperColors); ok {
+			result, supported = device.SaveMouseZoneColorsSniper(req.ColorDpi, req.ColorZones, req.ColorSniper), true
+		}
 	} else {
-		results = devices.CallDeviceMethod(
-			req.DeviceId,
-			"SaveMouseZoneColors",
-			req.ColorDpi,
-			req.ColorZones,
-		)
+		if device, ok := devices.GetDevice(req.DeviceId).(capabilities.MouseZoneColors); ok {
+			result, supported = device.SaveMouseZoneColors(req.ColorDpi, req.ColorZones), true
+		}
 	}
 
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if supported {
+		switch result {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToSaveMouseColors"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3657,15 +3787,8 @@ func ProcessMouseDpiColorsSave(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SaveMouseDpiColors",
-		req.ColorDpi,
-		req.ColorZones,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.MouseDpiColors); ok {
+		switch device.SaveMouseDpiColors(req.ColorDpi, req.ColorZones) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToSaveDPIColors"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3692,14 +3815,8 @@ func ProcessHeadsetZoneColorsSave(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SaveHeadsetZoneColors",
-		req.ColorZones,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.HeadsetZoneColors); ok {
+		switch device.SaveHeadsetZoneColors(req.ColorZones) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToUpdateHeadsetColors"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -3726,14 +3843,8 @@ func ProcessControllerZoneColorsSave(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SaveControllerZoneColors",
-		req.ColorZones,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ControllerZoneColors); ok {
+		switch device.SaveControllerZoneColors(req.ColorZones) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToSaveMouseColors"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -4013,6 +4124,114 @@ func ProcessNewMacroProfile(r *http.Request) *Payload {
 	}
 }
 
+// ProcessStartMacroRecording will process start of macro recording
+func ProcessStartMacroRecording() *Payload {
+	switch macro.StartRecording() {
+	case 1:
+		return &Payload{
+			Message: language.GetValue("txtMacroRecordingStarted"),
+			Code:    http.StatusOK,
+			Status:  1,
+		}
+	case 2:
+		return &Payload{
+			Message: language.GetValue("txtMacroRecordingRunning"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	return &Payload{
+		Message: language.GetValue("txtMacroRecordingNoInputs"),
+		Code:    http.StatusOK,
+		Status:  0,
+	}
+}
+
+// ProcessStopMacroRecording will process stop of macro recording and save it as a new macro profile
+func ProcessStopMacroRecording(r *http.Request) *Payload {
+	req := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log(map[string]interface{}{"error": err}).Error("Unable to decode JSON")
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateRequest"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if len(req.MacroName) < 3 {
+		return &Payload{
+			Message: language.GetValue("txtUnableToValidateMacroName"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if !common.AlphanumericRegex.MatchString(req.MacroName) {
+		return &Payload{
+			Message: language.GetValue("txtProfileInvalidName"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if req.MacroQuantize < 0 || req.MacroQuantize > 10000 {
+		return &Payload{
+			Message: language.GetValue("txtInvalidMacroQuantize"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	macroId, res := macro.StopRecording(req.MacroName, req.MacroQuantize)
+	switch res {
+	case 1:
+		return &Payload{
+			Message: language.GetValue("txtMacroRecordingSaved"),
+			Code:    http.StatusOK,
+			Status:  1,
+			Data:    macroId,
+		}
+	case 2:
+		return &Payload{
+			Message: language.GetValue("txtMacroRecordingNotRunning"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	case 4:
+		return &Payload{
+			Message: language.GetValue("txtMacroRecordingEmpty"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	return &Payload{
+		Message: language.GetValue("txtUnableToCreateMacroProfile"),
+		Code:    http.StatusOK,
+		Status:  0,
+	}
+}
+
+// ProcessCancelMacroRecording will process cancellation of macro recording
+func ProcessCancelMacroRecording() *Payload {
+	if macro.CancelRecording() == 1 {
+		return &Payload{
+			Message: language.GetValue("txtMacroRecordingCancelled"),
+			Code:    http.StatusOK,
+			Status:  1,
+		}
+	}
+
+	return &Payload{
+		Message: language.GetValue("txtMacroRecordingNotRunning"),
+		Code:    http.StatusOK,
+		Status:  0,
+	}
+}
+
 // ProcessNewMacroProfileValue will process creation of new macro profile value
 func ProcessNewMacroProfileValue(r *http.Request) *Payload {
 	req := &Payload{}
@@ -4051,7 +4270,7 @@ func ProcessNewMacroProfileValue(r *http.Request) *Payload {
 		}
 	}
 
-	if macroType < 3 || macroType > 20 {
+	if macroType < 3 || macroType > macro.ActionSpeedToggle {
 		return &Payload{
 			Message: language.GetValue("txtInvalidMacroType"),
 			Code:    http.StatusOK,
@@ -4106,6 +4325,67 @@ func ProcessNewMacroProfileValue(r *http.Request) *Payload {
 		}
 	}
 
+	if (macroType == macro.ActionLaunch || macroType == macro.ActionCommand) && !macro.CommandsAllowed(macroType) {
+		return &Payload{
+			Message: language.GetValue("txtMacroCommandsDisabled"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	if (macroType == macro.ActionLaunch || macroType == macro.ActionCommand) && len(macroText) < 1 {
+		return &Payload{
+			Message: language.GetValue("txtInvalidMacroText"),
+			Code:    http.StatusOK,
+			Status:  0,
+		}
+	}
+
+	switch macroType {
+	case macro.ActionUserProfile, macro.ActionRgbProfile, macro.ActionBrightness, macro.ActionSpeedToggle:
+		if len(req.DeviceId) > 0 && devices.GetDevice(req.DeviceId) == nil {
+			return &Payload{
+				Message: language.GetValue("txtNonExistingDevice"),
+				Code:    http.StatusOK,
+				Status:  0,
+			}
+		}
+	}
+
+	switch macroType {
+	case macro.ActionUserProfile, macro.ActionRgbProfile:
+		if !common.AlphanumericRegex.MatchString(req.Profile) {
+			return &Payload{
+				Message: language.GetValue("txtInvalidProfileName"),
+				Code:    http.StatusOK,
+				Status:  0,
+			}
+		}
+	case macro.ActionBrightness:
+		if req.Brightness > 100 {
+			return &Payload{
+				Message: language.GetValue("txtInvalidBrightness"),
+				Code:    http.StatusOK,
+				Status:  0,
+			}
+		}
+	case macro.ActionSpeedToggle:
+		if temperatures.GetTemperatureProfile(req.Profile) == nil {
+			return &Payload{
+				Message: language.GetValue("txtNonExistingSpeedProfile"),
+				Code:    http.StatusOK,
+				Status:  0,
+			}
+		}
+		if len(req.MacroProfileAlt) > 0 && temperatures.GetTemperatureProfile(req.MacroProfileAlt) == nil {
+			return &Payload{
+				Message: language.GetValue("txtNonExistingSpeedProfile"),
+				Code:    http.StatusOK,
+				Status:  0,
+			}
+		}
+	}
+
 	macroAction := &macro.Actions{
 		ActionType:            macroType,
 		ActionCommand:         macroValue,
@@ -4114,6 +4394,11 @@ func ProcessNewMacroProfileValue(r *http.Request) *Payload {
 		MousePositionX:        mousePositionX,
 		MousePositionY:        mousePositionY,
 		MousePositionAbsolute: mousePositionAbsolute,
+		ActionTarget:          req.DeviceId,
+		ActionChannelId:       req.ChannelId,
+		ActionProfile:         req.Profile,
+		ActionProfileAlt:      req.MacroProfileAlt,
+		ActionValue:           req.Brightness,
 	}
 
 	res := macro.NewMacroProfileValue(macroId, macroAction)
@@ -4157,14 +4442,8 @@ func ProcessLedChange(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateDeviceLedData",
-		req.LedProfile,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LedProfileEditor); ok {
+		switch device.UpdateDeviceLedData(req.LedProfile) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtLedDataChanged"), Code: http.StatusOK, Status: 1}
 		}
@@ -4197,15 +4476,9 @@ func ProcessGetKeyboardKey(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessGetKeyboardKey",
-		req.KeyId,
-	)
-
-	if len(results) > 0 {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyboardKeys); ok {
 		return &Payload{
-			Data:   results[0].Interface(),
+			Data:   device.ProcessGetKeyboardKey(req.KeyId),
 			Code:   http.StatusOK,
 			Status: 1,
 		}
@@ -4243,14 +4516,9 @@ func ProcessGetKeyboardKeys(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessGetKeyboardKeys",
-	)
-
-	if len(results) > 0 {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.FlashTap); ok {
 		return &Payload{
-			Data:   results[0].Interface(),
+			Data:   device.ProcessGetKeyboardKeys(),
 			Code:   http.StatusOK,
 			Status: 1,
 		}
@@ -4288,15 +4556,9 @@ func ProcessGetChannelDevice(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessGetChannelDevice",
-		req.ChannelId,
-	)
-
-	if len(results) > 0 {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ChannelDevices); ok {
 		return &Payload{
-			Data:   results[0].Interface(),
+			Data:   device.ProcessGetChannelDevice(req.ChannelId),
 			Code:   http.StatusOK,
 			Status: 1,
 		}
@@ -4341,14 +4603,8 @@ func ProcessSetKeyboardPerformance(r *http.Request) *Payload {
 		AltF4:    req.PerfAltF4,
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetKeyboardPerformance",
-		performance,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyboardPerformance); ok {
+		switch device.ProcessSetKeyboardPerformance(performance) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtKeyboardPerformanceUpdated"), Code: http.StatusOK, Status: 1}
 		}
@@ -4401,15 +4657,8 @@ func ProcessSetKeyboardFlashTap(r *http.Request) *Payload {
 		Color:  req.FlashTapColor,
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetKeyboardFlashTap",
-		req.FlashTapKeys,
-		flashTap,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.FlashTap); ok {
+		switch device.ProcessSetKeyboardFlashTap(req.FlashTapKeys, flashTap) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtFlashTapUpdated"), Code: http.StatusOK, Status: 1}
 		}
@@ -4450,16 +4699,9 @@ func ProcessGetRgbOverride(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingChannelId"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessGetRgbOverride",
-		req.ChannelId,
-		req.SubDeviceId,
-	)
-
-	if len(results) > 0 {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbOverride); ok {
 		return &Payload{
-			Data:   results[0].Interface(),
+			Data:   device.ProcessGetRgbOverride(req.ChannelId, req.SubDeviceId),
 			Code:   http.StatusOK,
 			Status: 1,
 		}
@@ -4505,20 +4747,8 @@ func ProcessSetRgbOverride(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtInvalidSpeedValue"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetRgbOverride",
-		req.ChannelId,
-		req.SubDeviceId,
-		req.Enabled,
-		req.StartColor,
-		req.EndColor,
-		req.MiddleColor,
-		req.Speed,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbOverride); ok {
+		switch device.ProcessSetRgbOverride(req.ChannelId, req.SubDeviceId, req.Enabled, req.StartColor, req.EndColor, req.MiddleColor, req.Speed) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtRgbOverrideUpdated"), Code: http.StatusOK, Status: 1}
 		}
@@ -4575,18 +4805,8 @@ func ProcessSetRgbTemperatureProbe(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtUnableToValidateRequest"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetRgbTemperatureProfile",
-		req.ChannelId,
-		req.SubDeviceId,
-		req.ProbeChannelId,
-		req.RgbMinTemp,
-		req.RgbMaxTemp,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbTemperature); ok {
+		switch device.ProcessSetRgbTemperatureProfile(req.ChannelId, req.SubDeviceId, req.ProbeChannelId, req.RgbMinTemp, req.RgbMaxTemp) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtRgbOverrideUpdated"), Code: http.StatusOK, Status: 1}
 		}
@@ -4627,16 +4847,9 @@ func ProcessGetLedData(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingChannelId"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessGetLedData",
-		req.ChannelId,
-		req.SubDeviceId,
-	)
-
-	if len(results) > 0 {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LedData); ok {
 		return &Payload{
-			Data:   results[0].Interface(),
+			Data:   device.ProcessGetLedData(req.ChannelId, req.SubDeviceId),
 			Code:   http.StatusOK,
 			Status: 1,
 		}
@@ -4686,17 +4899,8 @@ func ProcessSetLedData(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtInvalidColors"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetLedData",
-		req.ChannelId,
-		req.SubDeviceId,
-		req.ColorZones,
-		req.Save,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LedData); ok {
+		switch device.ProcessSetLedData(req.ChannelId, req.SubDeviceId, req.ColorZones, req.Save) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtRgbPerLedUpdated"), Code: http.StatusOK, Status: 1}
 		}
@@ -4731,14 +4935,8 @@ func ProcessSetOpenRgbIntegration(r *http.Request) *Payload {
 
 	enabled := req.Mode == 1
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetOpenRgbIntegration",
-		enabled,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.OpenRgb); ok {
+		switch device.ProcessSetOpenRgbIntegration(enabled) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtOpenRGBIntegrationEnabled"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -4775,14 +4973,8 @@ func ProcessSetRgbCluster(r *http.Request) *Payload {
 
 	enabled := req.Mode == 1
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetRgbCluster",
-		enabled,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbCluster); ok {
+		switch device.ProcessSetRgbCluster(enabled) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtRgbClusterAdded"), Code: http.StatusOK, Status: 1}
 		case 2:
@@ -4820,14 +5012,8 @@ func ProcessSetLiquidTemperatureSource(r *http.Request) *Payload {
 
 	enabled := req.Mode == 1
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SetLiquidTemperatureSource",
-		enabled,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.LiquidTemperatureSource); ok {
+		switch device.SetLiquidTemperatureSource(enabled) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtTemperatureSourceChanged"), Code: http.StatusOK, Status: 1}
 		}
@@ -4862,8 +5048,7 @@ func ProcessSetKeyboardLiveSync(r *http.Request) *Payload {
 	}
 
 	enabled := req.Mode == 1
-	results := devices.CallDeviceMethod(req.DeviceId, "ProcessSetKeyboardLiveSync", enabled)
-	if len(results) > 0 && results[0].Uint() == 1 {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.KeyboardLiveSync); ok && device.ProcessSetKeyboardLiveSync(enabled) == 1 {
 		return &Payload{Message: language.GetValue("txtKeyboardProfileSaved"), Code: http.StatusOK, Status: 1}
 	}
 	return &Payload{Message: language.GetValue("txtUnableToSaveKeyboardProfile"), Code: http.StatusOK, Status: 0}
@@ -4894,14 +5079,8 @@ func ProcessSetKeyboardControlDialColors(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetKeyboardControlDialColors",
-		req.ColorZones,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ControlDialColors); ok {
+		switch device.ProcessSetKeyboardControlDialColors(req.ColorZones) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtKeyboardControlDialUpdated"), Code: http.StatusOK, Status: 1}
 		}
@@ -4962,15 +5141,8 @@ func ProcessControllerVibration(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessControllerVibration",
-		req.VibrationModule,
-		req.VibrationValue,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ControllerVibration); ok {
+		switch device.ProcessControllerVibration(req.VibrationModule, req.VibrationValue) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtVibrationModuleUpdate"), Code: http.StatusOK, Status: 1}
 		}
@@ -5015,18 +5187,8 @@ func ProcessControllerEmulation(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessControllerEmulation",
-		req.EmulationDevice,
-		req.EmulationMode,
-		req.SensitivityX,
-		req.SensitivityY,
-		req.InvertYAxis,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ControllerEmulation); ok {
+		switch device.ProcessControllerEmulation(req.EmulationDevice, req.EmulationMode, req.SensitivityX, req.SensitivityY, req.InvertYAxis) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtThumbstickModuleUpdate"), Code: http.StatusOK, Status: 1}
 		}
@@ -5059,14 +5221,9 @@ func ProcessGetControllerGraph(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingDevice"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessGetControllerGraph",
-	)
-
-	if len(results) > 0 {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ControllerGraph); ok {
 		return &Payload{
-			Data:   results[0].Interface(),
+			Data:   device.ProcessGetControllerGraph(),
 			Code:   http.StatusOK,
 			Status: 1,
 		}
@@ -5108,17 +5265,8 @@ func ProcessSetControllerGraph(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtInvalidDataPoints"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessSetControllerGraph",
-		req.AnalogDevice,
-		req.DeadZoneMin,
-		req.DeadZoneMax,
-		req.CurveData,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.ControllerGraph); ok {
+		switch device.ProcessSetControllerGraph(req.AnalogDevice, req.DeadZoneMin, req.DeadZoneMax, req.CurveData) {
 		case 1:
 			return &Payload{Message: language.GetValue("txtAnalogModuleUpdated"), Code: http.StatusOK, Status: 1}
 		}
@@ -5160,18 +5308,13 @@ func ProcessNewGradientColor(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingProfile"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessNewGradientColor",
-		profile,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbProfileEditor); ok {
+		status, gradients := device.ProcessNewGradientColor(profile)
+		switch status {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToAddGradientColor"), Code: http.StatusOK, Status: 0}
 		case 1:
-			return &Payload{Message: language.GetValue("txtDeviceRgbProfileChanged"), Code: http.StatusOK, Status: 1, Data: results[1].Uint()}
+			return &Payload{Message: language.GetValue("txtDeviceRgbProfileChanged"), Code: http.StatusOK, Status: 1, Data: gradients}
 		}
 	}
 	return &Payload{Message: language.GetValue("txtUnableToAddGradientColor"), Code: http.StatusOK, Status: 0}
@@ -5211,20 +5354,15 @@ func ProcessDeleteGradientColor(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingProfile"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"ProcessDeleteGradientColor",
-		profile,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.RgbProfileEditor); ok {
+		status, gradients := device.ProcessDeleteGradientColor(profile)
+		switch status {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToDeleteGradientColor"), Code: http.StatusOK, Status: 0}
 		case 2:
 			return &Payload{Message: language.GetValue("txtGradientTooLow"), Code: http.StatusOK, Status: 0}
 		case 1:
-			return &Payload{Message: language.GetValue("txtDeviceRgbProfileChanged"), Code: http.StatusOK, Status: 1, Data: results[1].Uint()}
+			return &Payload{Message: language.GetValue("txtDeviceRgbProfileChanged"), Code: http.StatusOK, Status: 1, Data: gradients}
 		}
 	}
 	return &Payload{Message: language.GetValue("txtUnableToDeleteGradientColor"), Code: http.StatusOK, Status: 0}
@@ -5263,16 +5401,8 @@ func ProcessCommanderDuoOverride(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtNonExistingChannelId"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"SetCommanderDuoOverride",
-		req.ChannelId,
-		req.Enabled,
-		req.LedChannels,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.CommanderDuoOverride); ok {
+		switch device.SetCommanderDuoOverride(req.ChannelId, req.Enabled, req.LedChannels) {
 		case 0:
 			return &Payload{Message: language.GetValue("txtUnableToUpdatedCommanderDuoOverride"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -5381,14 +5511,8 @@ func ProcessUpdateDeviceEqualizer(r *http.Request) *Payload {
 		return &Payload{Message: language.GetValue("txtUnableToUpdateEqualizer"), Code: http.StatusOK, Status: 0}
 	}
 
-	results := devices.CallDeviceMethod(
-		req.DeviceId,
-		"UpdateEqualizer",
-		req.Equalizers,
-	)
-
-	if len(results) > 0 {
-		switch results[0].Uint() {
+	if device, ok := devices.GetDevice(req.DeviceId).(capabilities.Equalizer); ok {
+		switch device.UpdateEqualizer(req.Equalizers) {
 		case 2:
 			return &Payload{Message: language.GetValue("txtNothingToUpdate"), Code: http.StatusOK, Status: 0}
 		case 1:
@@ -7,19 +7,24 @@ package server
 import (
 	"OpenLinkHub/src/audio"
 	"OpenLinkHub/src/backup"
+	"OpenLinkHub/src/capabilities"
 	"OpenLinkHub/src/common"
 	"OpenLinkHub/src/config"
 	"OpenLinkHub/src/dashboard"
 	"OpenLinkHub/src/devices"
 	"OpenLinkHub/src/devices/lcd"
 	"OpenLinkHub/src/display"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/history"
 	"OpenLinkHub/src/inputmanager"
 	"OpenLinkHub/src/language"
 	"OpenLinkHub/src/logger"
 	"OpenLinkHub/src/macro"
 	"OpenLinkHub/src/media"
 	"OpenLinkHub/src/metrics"
+	"OpenLinkHub/src/process"
 	"OpenLinkHub/src/rgb"
+	"OpenLinkHub/src/scenes"
 	"OpenLinkHub/src/scheduler"
 	"OpenLinkHub/src/server/requests"
 	"OpenLinkHub/src/stats"
@@ -27,13 +32,24 @@ import (
 	"OpenLinkHub/src/systray"
 	"OpenLinkHub/src/temperatures"
 	"OpenLinkHub/src/templates"
+	"OpenLinkHub/src/transport"
 	"OpenLinkHub/src/version"
+	"OpenLinkHub/src/watchdog"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 )
 
 // Response contains data what is sent back to a client
@@ -56,6 +72,9 @@ type Header struct {
 var headers []Header
 var server = &http.Server{}
 
+// unixSocketKey marks requests received on unix domain socket
+type unixSocketKey struct{}
+
 // Send will process response and send it back to a client
 func (r *Response) Send(w http.ResponseWriter) {
 	r.Lock()
@@ -182,6 +201,114 @@ func getBatteryStats(w http.ResponseWriter, _ *http.Request) {
 	resp.Send(w)
 }
 
+// getWatchdogAlerts will return active and recently resolved fan watchdog alerts
+func getWatchdogAlerts(w http.ResponseWriter, _ *http.Request) {
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data: map[string]interface{}{
+			"alerts":  watchdog.GetAlerts(),
+			"history": watchdog.GetHistory(),
+		},
+	}
+	resp.Send(w)
+}
+
+// getFailsafe will return temperature failsafe state
+func getFailsafe(w http.ResponseWriter, _ *http.Request) {
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   temperatures.GetFailsafe(),
+	}
+	resp.Send(w)
+}
+
+// getReplays will return progress of replayed HID capture sessions
+func getReplays(w http.ResponseWriter, _ *http.Request) {
+	results := make([]transport.ReplayResult, 0)
+	for _, replay := range transport.GetReplays() {
+		results = append(results, replay.Result())
+	}
+
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   results,
+	}
+	resp.Send(w)
+}
+
+// getHistory returns recorded series, or points of a single series when serial and kind are given
+func getHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	serial, kind := query.Get("serial"), query.Get("kind")
+	if len(serial) == 0 || len(kind) == 0 {
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data: map[string]interface{}{
+				"tiers":  history.GetTiers(),
+				"series": history.GetSeries(),
+			},
+		}
+		resp.Send(w)
+		return
+	}
+
+	channelId := query.Get("channelId")
+	if len(channelId) == 0 {
+		channelId = "0"
+	}
+
+	now := time.Now().Unix()
+	from, to, resolution := now-3600, now, int64(0)
+	var err error
+	if value := query.Get("from"); len(value) > 0 {
+		if from, err = strconv.ParseInt(value, 10, 64); err != nil {
+			from = -1
+		}
+	}
+	if value := query.Get("to"); len(value) > 0 {
+		if to, err = strconv.ParseInt(value, 10, 64); err != nil {
+			to = -1
+		}
+	}
+	if value := query.Get("resolution"); len(value) > 0 {
+		if resolution, err = strconv.ParseInt(value, 10, 64); err != nil {
+			resolution = -1
+		}
+	}
+
+	if from < 0 || to < from || resolution < 0 {
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtInvalidHistoryRange"),
+		}
+		resp.Send(w)
+		return
+	}
+
+	data := history.GetData(serial, channelId, kind, from, to, resolution)
+	if data == nil {
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtNonExistingHistory"),
+		}
+		resp.Send(w)
+		return
+	}
+
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   data,
+	}
+	resp.Send(w)
+}
+
 // getDeviceMetrics will return a list device metrics in prometheus format
 func getDeviceMetrics(w http.ResponseWriter, r *http.Request) {
 	devices.UpdateDeviceMetrics()
@@ -210,6 +337,11 @@ func setSupportedDevices(w http.ResponseWriter, r *http.Request) {
 
 // getDevices returns response on /devices
 func getDevices(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/capabilities") {
+		getDeviceCapabilities(w, r)
+		return
+	}
+
 	deviceId, valid := getVar("/api/devices/", r)
 	if !valid {
 		resp := &Response{
@@ -226,6 +358,92 @@ func getDevices(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getDeviceCapabilities returns response on /api/devices/{id}/capabilities
+func getDeviceCapabilities(w http.ResponseWriter, r *http.Request) {
+	deviceId, valid := getDeviceID("/api/devices/", r)
+	if !valid {
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtNonExistingDevice"),
+		}
+		resp.Send(w)
+		return
+	}
+
+	descriptor := devices.GetDeviceCapabilities(deviceId)
+	if descriptor == nil {
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtNonExistingDevice"),
+		}
+		resp.Send(w)
+		return
+	}
+
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   descriptor,
+	}
+	resp.Send(w)
+}
+
+// getEvents streams device events as Server-Sent Events on /api/events
+func getEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var filter []string
+	if types := r.URL.Query().Get("types"); len(types) > 0 {
+		filter = strings.Split(types, ",")
+	}
+
+	id, ch := events.Subscribe()
+	defer events.Unsubscribe(id)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, open := <-ch:
+			if !open {
+				return
+			}
+			if filter != nil && !slices.Contains(filter, event.Type) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.Log(logger.Fields{"error": err}).Error("Unable to encode event")
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
 // getDeviceLed returns response on /led
 func getDeviceLed(w http.ResponseWriter, r *http.Request) {
 	deviceId, valid := getVar("/api/led/", r)
@@ -236,27 +454,20 @@ func getDeviceLed(w http.ResponseWriter, r *http.Request) {
 			Data:   devices.GetDevicesLedData(),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.LedProfile); ok {
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetDeviceLedData(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(
-			deviceId,
-			"GetDeviceLedData",
-		)
-
-		if len(results) > 0 {
-			resp := &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp := &Response{
-				Code:   http.StatusOK,
-				Status: 0,
-				Data:   nil,
-			}
-			resp.Send(w)
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 0,
+			Data:   nil,
 		}
+		resp.Send(w)
 	}
 }
 
@@ -399,23 +610,20 @@ func getColor(w http.ResponseWriter, r *http.Request) {
 			Data:   devices.GetRgbProfiles(),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.RgbProfiles); ok {
+		resp = &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetRgbProfiles(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "GetRgbProfiles")
-		if len(results) > 0 {
-			resp = &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp = &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtNoSuchRGBProfile"),
-			}
-			resp.Send(w)
+		resp = &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtNoSuchRGBProfile"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -431,23 +639,20 @@ func getZoneColor(w http.ResponseWriter, r *http.Request) {
 			Data:   language.GetValue("txtNonExistingDevice"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.ZoneColors); ok {
+		resp = &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetZoneColors(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "GetZoneColors")
-		if len(results) > 0 {
-			resp = &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp = &Response{
-				Code:   http.StatusOK,
-				Status: 0,
-				Data:   nil,
-			}
-			resp.Send(w)
+		resp = &Response{
+			Code:   http.StatusOK,
+			Status: 0,
+			Data:   nil,
 		}
+		resp.Send(w)
 	}
 }
 
@@ -464,23 +669,20 @@ func getColorData(w http.ResponseWriter, r *http.Request) {
 			Data:   language.GetValue("txtNoSuchRGBProfile"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.RgbProfileReader); ok {
+		resp = &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetRgbProfile(profileName),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "GetRgbProfile", profileName)
-		if len(results) > 0 {
-			resp = &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp = &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtNoSuchRGBProfile"),
-			}
-			resp.Send(w)
+		resp = &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtNoSuchRGBProfile"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -495,23 +697,20 @@ func getPositionData(w http.ResponseWriter, r *http.Request) {
 			Data:   language.GetValue("txtInvalidPosition"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.DevicePositions); ok {
+		resp = &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetDevicePositions(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "GetDevicePositions")
-		if len(results) > 0 {
-			resp = &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp = &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtInvalidPosition"),
-			}
-			resp.Send(w)
+		resp = &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtInvalidPosition"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -526,23 +725,20 @@ func getCommanderDuoOverride(w http.ResponseWriter, r *http.Request) {
 			Data:   language.GetValue("txtUnableToValidateRequest"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.CommanderDuoOverride); ok {
+		resp = &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetCommanderDuoOverride(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "GetCommanderDuoOverride")
-		if len(results) > 0 {
-			resp = &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp = &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtUnableToValidateRequest"),
-			}
-			resp.Send(w)
+		resp = &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtUnableToValidateRequest"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -606,23 +802,20 @@ func getKeyAssignmentTypes(w http.ResponseWriter, r *http.Request) {
 			Message: language.GetValue("txtInvalidDeviceId"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.KeyAssignmentTypes); ok {
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.ProcessGetKeyAssignmentTypes(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "ProcessGetKeyAssignmentTypes")
-		if len(results) > 0 {
-			resp := &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp := &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtUnableToGetAssignmentsTypes"),
-			}
-			resp.Send(w)
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtUnableToGetAssignmentsTypes"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -636,23 +829,20 @@ func getKeyAssignmentModifiers(w http.ResponseWriter, r *http.Request) {
 			Message: language.GetValue("txtInvalidDeviceId"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.KeyAssignmentModifiers); ok {
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.ProcessGetKeyAssignmentModifiers(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "ProcessGetKeyAssignmentModifiers")
-		if len(results) > 0 {
-			resp := &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp := &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtUnableToGetAssignmentsModifiers"),
-			}
-			resp.Send(w)
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtUnableToGetAssignmentsModifiers"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -666,23 +856,20 @@ func getKeyboardPerformance(w http.ResponseWriter, r *http.Request) {
 			Message: language.GetValue("txtInvalidDeviceId"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.KeyboardPerformance); ok {
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.ProcessGetKeyboardPerformance(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "ProcessGetKeyboardPerformance")
-		if len(results) > 0 {
-			resp := &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp := &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtUnableToGetKeyboardPerformance"),
-			}
-			resp.Send(w)
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtUnableToGetKeyboardPerformance"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -696,23 +883,20 @@ func getKeyboardFlashTap(w http.ResponseWriter, r *http.Request) {
 			Message: language.GetValue("txtInvalidDeviceId"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.FlashTap); ok {
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetFlashTap(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "GetFlashTap")
-		if len(results) > 0 {
-			resp := &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp := &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtNoFlashTapData"),
-			}
-			resp.Send(w)
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtNoFlashTapData"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -726,23 +910,20 @@ func getControlDialColors(w http.ResponseWriter, r *http.Request) {
 			Message: language.GetValue("txtInvalidDeviceId"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.ControlDialColors); ok {
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.ProcessGetKeyboardControlDialColors(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "ProcessGetKeyboardControlDialColors")
-		if len(results) > 0 {
-			resp := &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp := &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtUnableToGetControlDialColors"),
-			}
-			resp.Send(w)
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtUnableToGetControlDialColors"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -757,23 +938,20 @@ func getEqualizers(w http.ResponseWriter, r *http.Request) {
 			Data:   language.GetValue("txtInvalidPosition"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.Equalizer); ok {
+		resp = &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetEqualizers(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "GetEqualizers")
-		if len(results) > 0 {
-			resp = &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp = &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtInvalidPosition"),
-			}
-			resp.Send(w)
+		resp = &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtInvalidPosition"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -788,23 +966,20 @@ func getTemperatureProbes(w http.ResponseWriter, r *http.Request) {
 			Data:   language.GetValue("txtInvalidDeviceId"),
 		}
 		resp.Send(w)
+	} else if device, ok := devices.GetDevice(deviceId).(capabilities.TemperatureProbes); ok {
+		resp = &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   device.GetTemperatureProbes(),
+		}
+		resp.Send(w)
 	} else {
-		results := devices.CallDeviceMethod(deviceId, "GetTemperatureProbes")
-		if len(results) > 0 {
-			resp = &Response{
-				Code:   http.StatusOK,
-				Status: 1,
-				Data:   results[0].Interface(),
-			}
-			resp.Send(w)
-		} else {
-			resp = &Response{
-				Code:    http.StatusOK,
-				Status:  0,
-				Message: language.GetValue("txtInvalidDeviceId"),
-			}
-			resp.Send(w)
+		resp = &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtInvalidDeviceId"),
 		}
+		resp.Send(w)
 	}
 }
 
@@ -926,6 +1101,38 @@ func deleteTemperatureProfile(w http.ResponseWriter, r *http.Request) {
 	resp.Send(w)
 }
 
+// getVirtualSensors returns all virtual temperature sensors
+func getVirtualSensors(w http.ResponseWriter, _ *http.Request) {
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   temperatures.GetVirtualSensors(),
+	}
+	resp.Send(w)
+}
+
+// newVirtualSensor handles creation or update of virtual temperature sensor
+func newVirtualSensor(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessNewVirtualSensor(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// deleteVirtualSensor handles deletion of virtual temperature sensor
+func deleteVirtualSensor(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessDeleteVirtualSensor(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
 // updateTemperatureProfile handles update of temperature profile
 func updateTemperatureProfile(w http.ResponseWriter, r *http.Request) {
 	request := requests.ProcessUpdateTemperatureProfile(r)
@@ -948,6 +1155,17 @@ func updateTemperatureProfileGraph(w http.ResponseWriter, r *http.Request) {
 	resp.Send(w)
 }
 
+// updateTemperatureProfileCurve handles temperature profile hysteresis, ramp rate and averaging update
+func updateTemperatureProfileCurve(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessUpdateTemperatureProfileCurve(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
 // setDeviceSpeed handles device speed changes
 func setDeviceSpeed(w http.ResponseWriter, r *http.Request) {
 	request := requests.ProcessChangeSpeed(r)
@@ -1716,6 +1934,203 @@ func changeRgbScheduler(w http.ResponseWriter, r *http.Request) {
 	resp.Send(w)
 }
 
+// getSchedulerRules returns all automation rules
+func getSchedulerRules(w http.ResponseWriter, _ *http.Request) {
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   scheduler.GetRules(),
+	}
+	resp.Send(w)
+}
+
+// saveSchedulerRule handles creation or update of automation rule
+func saveSchedulerRule(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessSaveSchedulerRule(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// runSchedulerRule handles manual execution of automation rule
+func runSchedulerRule(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessRunSchedulerRule(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// deleteSchedulerRule handles deletion of automation rule
+func deleteSchedulerRule(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessDeleteSchedulerRule(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// getProcessRules returns all process rules and currently running ones
+func getProcessRules(w http.ResponseWriter, _ *http.Request) {
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data: map[string]interface{}{
+			"rules":  process.GetRules(),
+			"active": process.GetActive(),
+		},
+	}
+	resp.Send(w)
+}
+
+// saveProcessRule handles creation or update of process rule
+func saveProcessRule(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessSaveProcessRule(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// deleteProcessRule handles deletion of process rule
+func deleteProcessRule(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessDeleteProcessRule(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// getScenes returns all scenes or a single scene for export
+func getScenes(w http.ResponseWriter, r *http.Request) {
+	sceneId, valid := getVar("/api/scenes/", r)
+	if !valid {
+		resp := &Response{
+			Code:   http.StatusOK,
+			Status: 1,
+			Data:   scenes.GetScenes(),
+		}
+		resp.Send(w)
+		return
+	}
+
+	val, err := strconv.Atoi(sceneId)
+	if err != nil {
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtNonExistingScene"),
+		}
+		resp.Send(w)
+		return
+	}
+
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   scenes.GetScene(val),
+	}
+	resp.Send(w)
+}
+
+// saveScene handles recording of a scene
+func saveScene(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessSaveScene(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// applyScene handles applying of a scene
+func applyScene(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessApplyScene(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// importScene handles import of exported scene
+func importScene(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessImportScene(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// deleteScene handles deletion of a scene
+func deleteScene(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessDeleteScene(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// getKeyAssignmentLayers returns key assignment layers of a device
+func getKeyAssignmentLayers(w http.ResponseWriter, r *http.Request) {
+	deviceId, valid := getVar("/api/layers/", r)
+	if !valid {
+		resp := &Response{
+			Code:    http.StatusOK,
+			Status:  0,
+			Message: language.GetValue("txtInvalidDeviceId"),
+		}
+		resp.Send(w)
+		return
+	}
+
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   inputmanager.GetLayers(deviceId),
+	}
+	resp.Send(w)
+}
+
+// saveKeyAssignmentLayer handles saving of key assignment layer
+func saveKeyAssignmentLayer(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessSaveKeyAssignmentLayer(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// deleteKeyAssignmentLayer handles deletion of key assignment layer
+func deleteKeyAssignmentLayer(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessDeleteKeyAssignmentLayer(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
 // deleteKeyboardProfile handles deletion of keyboard profile
 func deleteKeyboardProfile(w http.ResponseWriter, r *http.Request) {
 	request := requests.ProcessDeleteKeyboardProfile(r)
@@ -1881,6 +2296,50 @@ func newMacroProfileValue(w http.ResponseWriter, r *http.Request) {
 	resp.Send(w)
 }
 
+// getMacroRecording returns response on /api/macro/record
+func getMacroRecording(w http.ResponseWriter, _ *http.Request) {
+	resp := &Response{
+		Code:   http.StatusOK,
+		Status: 1,
+		Data:   macro.GetRecordingStatus(),
+	}
+	resp.Send(w)
+}
+
+// startMacroRecording handles start of macro recording
+func startMacroRecording(w http.ResponseWriter, _ *http.Request) {
+	request := requests.ProcessStartMacroRecording()
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
+// stopMacroRecording handles stop of macro recording
+func stopMacroRecording(w http.ResponseWriter, r *http.Request) {
+	request := requests.ProcessStopMacroRecording(r)
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+		Data:    request.Data,
+	}
+	resp.Send(w)
+}
+
+// cancelMacroRecording handles cancellation of macro recording
+func cancelMacroRecording(w http.ResponseWriter, _ *http.Request) {
+	request := requests.ProcessCancelMacroRecording()
+	resp := &Response{
+		Code:    request.Code,
+		Status:  request.Status,
+		Message: request.Message,
+	}
+	resp.Send(w)
+}
+
 // getGetKeyboardKey handles information about keyboard get
 func getGetKeyboardKey(w http.ResponseWriter, r *http.Request) {
 	request := requests.ProcessGetKeyboardKey(r)
@@ -2052,14 +2511,8 @@ func uiDeviceOverview(w http.ResponseWriter, r *http.Request) {
 	device := devices.GetDevice(deviceId)
 	if device == nil {
 		template = "404.html"
-	} else {
-		results := devices.CallDeviceMethod(
-			deviceId,
-			"GetDeviceTemplate",
-		)
-		if len(results) > 0 {
-			template = results[0].String()
-		}
+	} else if templated, ok := device.(capabilities.Templated); ok {
+		template = templated.GetDeviceTemplate()
 	}
 
 	if len(template) == 0 {
@@ -2523,8 +2976,67 @@ func getDeviceID(uri string, r *http.Request) (string, bool) {
 	return value, true
 }
 
+// authorize will validate request credentials. Returns access state and read-only scope
+func authorize(r *http.Request) (bool, bool) {
+	auth := config.GetConfig().Authentication
+	if !auth.Enabled {
+		return true, false
+	}
+
+	// Unix socket access is controlled by file permissions
+	if local, ok := r.Context().Value(unixSocketKey{}).(bool); ok && local {
+		return true, false
+	}
+
+	token := r.Header.Get("X-Api-Token")
+	if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		token = strings.TrimPrefix(bearer, "Bearer ")
+	}
+
+	if len(token) > 0 {
+		for _, value := range auth.Tokens {
+			if len(value) > 0 && subtle.ConstantTimeCompare([]byte(token), []byte(value)) == 1 {
+				return true, false
+			}
+		}
+		for _, value := range auth.ReadOnlyTokens {
+			if len(value) > 0 && subtle.ConstantTimeCompare([]byte(token), []byte(value)) == 1 {
+				return true, true
+			}
+		}
+	}
+
+	if username, password, ok := r.BasicAuth(); ok && len(auth.Username) > 0 && len(auth.Password) > 0 {
+		validUsername := subtle.ConstantTimeCompare([]byte(username), []byte(auth.Username)) == 1
+		validPassword := subtle.ConstantTimeCompare([]byte(password), []byte(auth.Password)) == 1
+		if validUsername && validPassword {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// fullAccessRoutes are GET routes which expose credentials or change device state, and are not available to read-only tokens
+var fullAccessRoutes = map[string]bool{
+	"/api/backup": true, // Backup contains config.json with all credentials
+	"/api/media/": true, // Media playback control
+}
+
 func handleFunc(mux *http.ServeMux, path, method string, handler func(w http.ResponseWriter, r *http.Request)) {
 	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		authorized, readOnly := authorize(r)
+		if !authorized {
+			if len(config.GetConfig().Authentication.Username) > 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="OpenLinkHub", charset="UTF-8"`)
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		if readOnly && (method != http.MethodGet || fullAccessRoutes[path]) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
 		if r.Method == method {
 			handler(w, r)
 		} else {
@@ -2533,6 +3045,26 @@ func handleFunc(mux *http.ServeMux, path, method string, handler func(w http.Res
 	})
 }
 
+// jsonOnly will reject requests which are not JSON or come from a foreign origin. Such requests can be sent by any website open in a browser on the same machine
+func jsonOnly(handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); len(origin) > 0 {
+			u, e := url.Parse(origin)
+			if e != nil || u.Host != r.Host {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
 // setRoutes will set up all routes
 func setRoutes() http.Handler {
 	r := http.NewServeMux()
@@ -2550,13 +3082,22 @@ func setRoutes() http.Handler {
 	handleFunc(r, "/api/gpuLoad", http.MethodGet, getGpuLoad)
 	handleFunc(r, "/api/storageTemp", http.MethodGet, getStorageTemperature)
 	handleFunc(r, "/api/batteryStats", http.MethodGet, getBatteryStats)
+	handleFunc(r, "/api/watchdog", http.MethodGet, getWatchdogAlerts)
+	handleFunc(r, "/api/failsafe", http.MethodGet, getFailsafe)
+	handleFunc(r, "/api/transport/replay", http.MethodGet, getReplays)
+	handleFunc(r, "/api/scheduler/rules", http.MethodGet, getSchedulerRules)
+	handleFunc(r, "/api/processes", http.MethodGet, getProcessRules)
+	handleFunc(r, "/api/scenes/", http.MethodGet, getScenes)
 	handleFunc(r, "/api/devices/", http.MethodGet, getDevices)
+	handleFunc(r, "/api/events", http.MethodGet, getEvents)
+	handleFunc(r, "/api/history", http.MethodGet, getHistory)
 	handleFunc(r, "/api/color/", http.MethodGet, getColor)
 	handleFunc(r, "/api/color/zone/", http.MethodGet, getZoneColor)
 	handleFunc(r, "/api/color/profile/", http.MethodGet, getColorData)
 	handleFunc(r, "/api/color/override/", http.MethodGet, getCommanderDuoOverride)
 	handleFunc(r, "/api/temperatures/", http.MethodGet, getTemperature)
 	handleFunc(r, "/api/temperatures/graph/", http.MethodGet, getTemperatureGraph)
+	handleFunc(r, "/api/temperatures/virtual", http.MethodGet, getVirtualSensors)
 	handleFunc(r, "/api/input/media", http.MethodGet, getMediaKeys)
 	handleFunc(r, "/api/input/keyboard", http.MethodGet, getInputKeys)
 	handleFunc(r, "/api/input/mouse", http.MethodGet, getMouseButtons)
@@ -2564,6 +3105,8 @@ func setRoutes() http.Handler {
 	handleFunc(r, "/api/led/", http.MethodGet, getDeviceLed)
 	handleFunc(r, "/api/macro/", http.MethodGet, getMacro)
 	handleFunc(r, "/api/macro/keyInfo/", http.MethodGet, getKeyName)
+	handleFunc(r, "/api/macro/record", http.MethodGet, getMacroRecording)
+	handleFunc(r, "/api/layers/", http.MethodGet, getKeyAssignmentLayers)
 	handleFunc(r, "/api/dashboard", http.MethodGet, getDashboardSettings)
 	handleFunc(r, "/api/dashboard/devices/get", http.MethodGet, getDashboardDevices)
 	handleFunc(r, "/api/keyboard/assignmentsTypes/", http.MethodGet, getKeyAssignmentTypes)
@@ -2584,6 +3127,7 @@ func setRoutes() http.Handler {
 
 	// POST
 	handleFunc(r, "/api/temperatures/new", http.MethodPost, newTemperatureProfile)
+	handleFunc(r, "/api/temperatures/virtual/new", http.MethodPost, jsonOnly(newVirtualSensor))
 	handleFunc(r, "/api/temperatures/setLiquidTemperatureSource", http.MethodPost, setLiquidTemperatureSource)
 	handleFunc(r, "/api/speed", http.MethodPost, setDeviceSpeed)
 	handleFunc(r, "/api/speed/manual", http.MethodPost, setManualDeviceSpeed)
@@ -2635,6 +3179,12 @@ func setRoutes() http.Handler {
 	handleFunc(r, "/api/keyboard/autoBrightness", http.MethodPost, changeAutoBrightness)
 	handleFunc(r, "/api/keyboard/debounceTime", http.MethodPost, changeDebounceTime)
 	handleFunc(r, "/api/scheduler/rgb", http.MethodPost, changeRgbScheduler)
+	handleFunc(r, "/api/scheduler/rules/save", http.MethodPost, jsonOnly(saveSchedulerRule))
+	handleFunc(r, "/api/scheduler/rules/run", http.MethodPost, jsonOnly(runSchedulerRule))
+	handleFunc(r, "/api/processes/save", http.MethodPost, jsonOnly(saveProcessRule))
+	handleFunc(r, "/api/scenes/save", http.MethodPost, jsonOnly(saveScene))
+	handleFunc(r, "/api/scenes/apply", http.MethodPost, jsonOnly(applyScene))
+	handleFunc(r, "/api/scenes/import", http.MethodPost, jsonOnly(importScene))
 	handleFunc(r, "/api/psu/speed", http.MethodPost, changePsuFanMode)
 	handleFunc(r, "/api/mouse/dpi", http.MethodPost, saveMouseDpi)
 	handleFunc(r, "/api/mouse/gestures", http.MethodPost, saveMouseGestures)
@@ -2655,7 +3205,7 @@ func setRoutes() http.Handler {
 	handleFunc(r, "/api/headset/sleep", http.MethodPost, changeSleepMode)
 	handleFunc(r, "/api/headset/muteIndicator", http.MethodPost, changeMuteIndicator)
 	handleFunc(r, "/api/led/update", http.MethodPost, updateDeviceLed)
-	handleFunc(r, "/api/macro/newValue", http.MethodPost, newMacroProfileValue)
+	handleFunc(r, "/api/macro/newValue", http.MethodPost, jsonOnly(newMacroProfileValue))
 	handleFunc(r, "/api/keyboard/getKey/", http.MethodPost, getGetKeyboardKey)
 	handleFunc(r, "/api/keyboard/getKeys/", http.MethodPost, getGetKeyboardKeys)
 	handleFunc(r, "/api/keyboard/updateKeyAssignment", http.MethodPost, changeKeyAssignment)
@@ -2664,6 +3214,10 @@ func setRoutes() http.Handler {
 	handleFunc(r, "/api/keyboard/setFlashTap", http.MethodPost, setKeyboardFlashTap)
 	handleFunc(r, "/api/macro/updateValue", http.MethodPost, updateMacroValue)
 	handleFunc(r, "/api/macro/updateSettings", http.MethodPost, updateMacroSettings)
+	handleFunc(r, "/api/macro/record/start", http.MethodPost, jsonOnly(startMacroRecording))
+	handleFunc(r, "/api/macro/record/stop", http.MethodPost, jsonOnly(stopMacroRecording))
+	handleFunc(r, "/api/macro/record/cancel", http.MethodPost, jsonOnly(cancelMacroRecording))
+	handleFunc(r, "/api/layers/save", http.MethodPost, jsonOnly(saveKeyAssignmentLayer))
 	handleFunc(r, "/api/keyboard/dial/setColors", http.MethodPost, setKeyboardControlDialColors)
 	handleFunc(r, "/api/setSupportedDevices", http.MethodPost, setSupportedDevices)
 	handleFunc(r, "/api/restore", http.MethodPost, backup.PerformRestore)
@@ -2688,6 +3242,7 @@ func setRoutes() http.Handler {
 	// PUT
 	handleFunc(r, "/api/temperatures/update", http.MethodPut, updateTemperatureProfile)
 	handleFunc(r, "/api/temperatures/updateGraph", http.MethodPut, updateTemperatureProfileGraph)
+	handleFunc(r, "/api/temperatures/updateCurve", http.MethodPut, updateTemperatureProfileCurve)
 	handleFunc(r, "/api/lcd/modes", http.MethodPut, updateLcdProfile)
 	handleFunc(r, "/api/userProfile", http.MethodPut, saveUserProfile)
 	handleFunc(r, "/api/keyboard/profile/new", http.MethodPut, saveDeviceProfile)
@@ -2698,6 +3253,11 @@ func setRoutes() http.Handler {
 	handleFunc(r, "/api/keyboard/profile/delete", http.MethodDelete, deleteKeyboardProfile)
 	handleFunc(r, "/api/macro/value", http.MethodDelete, deleteMacroValue)
 	handleFunc(r, "/api/temperatures/delete", http.MethodDelete, deleteTemperatureProfile)
+	handleFunc(r, "/api/temperatures/virtual/delete", http.MethodDelete, jsonOnly(deleteVirtualSensor))
+	handleFunc(r, "/api/scheduler/rules/delete", http.MethodDelete, jsonOnly(deleteSchedulerRule))
+	handleFunc(r, "/api/processes/delete", http.MethodDelete, jsonOnly(deleteProcessRule))
+	handleFunc(r, "/api/scenes/delete", http.MethodDelete, jsonOnly(deleteScene))
+	handleFunc(r, "/api/layers/delete", http.MethodDelete, jsonOnly(deleteKeyAssignmentLayer))
 	handleFunc(r, "/api/macro/profile", http.MethodDelete, deleteMacroProfile)
 	handleFunc(r, "/api/userProfile/delete", http.MethodDelete, deleteUserProfile)
 	handleFunc(r, "/api/dashboard/devices/delete", http.MethodDelete, removeDashboardDevice)
@@ -2741,28 +3301,96 @@ func Init() {
 		},
 	}
 
-	if config.GetConfig().ListenPort > 0 {
-		templates.Init()
-		server = &http.Server{
-			Addr: fmt.Sprintf(
-				"%s:%v",
-				config.GetConfig().ListenAddress,
-				config.GetConfig().ListenPort,
-			),
-			Handler: setRoutes(),
+	if config.GetConfig().ListenPort < 1 && len(config.GetConfig().UnixSocket) == 0 {
+		logger.Log(logger.Fields{}).Info("REST server is disabled")
+		return
+	}
+
+	templates.Init()
+	handler := setRoutes()
+
+	if len(config.GetConfig().UnixSocket) > 0 {
+		go serveUnixSocket(handler)
+	}
+
+	if config.GetConfig().ListenPort < 1 {
+		select {}
+	}
+
+	server = &http.Server{
+		Addr: fmt.Sprintf(
+			"%s:%v",
+			config.GetConfig().ListenAddress,
+			config.GetConfig().ListenPort,
+		),
+		Handler: handler,
+	}
+
+	if config.GetConfig().Tls.Enabled {
+		tlsConfig, err := getTlsConfig()
+		if err != nil {
+			logger.Log(logger.Fields{"error": err}).Fatal("Unable to load TLS certificate")
 		}
+		server.TLSConfig = tlsConfig
 
 		fmt.Println(
-			fmt.Sprintf("[Server] Running REST and WebUI on %s. WebUI is accessible via: http://%s",
+			fmt.Sprintf("[Server] Running REST and WebUI on %s. WebUI is accessible via: https://%s",
 				server.Addr,
 				server.Addr,
 			),
 		)
-		err := server.ListenAndServe()
+		err = server.ListenAndServeTLS("", "")
 		if err != nil {
 			logger.Log(logger.Fields{"error": err}).Fatal("Unable to start REST server")
 		}
-	} else {
-		logger.Log(logger.Fields{}).Info("REST server is disabled")
+		return
+	}
+
+	fmt.Println(
+		fmt.Sprintf("[Server] Running REST and WebUI on %s. WebUI is accessible via: http://%s",
+			server.Addr,
+			server.Addr,
+		),
+	)
+	err := server.ListenAndServe()
+	if err != nil {
+		logger.Log(logger.Fields{"error": err}).Fatal("Unable to start REST server")
+	}
+}
+
+// serveUnixSocket will serve REST and WebUI on a unix domain socket for local clients
+func serveUnixSocket(handler http.Handler) {
+	path := config.GetConfig().UnixSocket
+	if common.FileExists(path) {
+		// Stale socket from previous run
+		if err := os.Remove(path); err != nil {
+			logger.Log(logger.Fields{"error": err, "path": path}).Error("Unable to remove existing unix socket")
+			return
+		}
+	}
+
+	// Socket is created with 0660, so there is no window with default permissions
+	mask := syscall.Umask(0117)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(mask)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "path": path}).Error("Unable to listen on unix socket")
+		return
+	}
+
+	if err = os.Chmod(path, 0660); err != nil {
+		logger.Log(logger.Fields{"error": err, "path": path}).Warn("Unable to set unix socket permissions")
+	}
+
+	socketServer := &http.Server{
+		Handler: handler,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, unixSocketKey{}, true)
+		},
+	}
+
+	fmt.Println(fmt.Sprintf("[Server] Running REST and WebUI on unix socket %s", path))
+	if err = socketServer.Serve(listener); err != nil {
+		logger.Log(logger.Fields{"error": err, "path": path}).Error("Unable to serve on unix socket")
 	}
 }
@@ -0,0 +1,117 @@
+package server
+
+// Package: server
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/logger"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// getTlsConfig will load configured certificate, or generate a self-signed one when none is defined
+func getTlsConfig() (*tls.Config, error) {
+	certFile := config.GetConfig().Tls.CertFile
+	keyFile := config.GetConfig().Tls.KeyFile
+
+	if len(certFile) == 0 || len(keyFile) == 0 {
+		// Kept outside of database folder, so private key is never part of a backup
+		certFile = config.GetConfig().ConfigPath + "/tls/cert.pem"
+		keyFile = config.GetConfig().ConfigPath + "/tls/key.pem"
+		if !common.FileExists(certFile) || !common.FileExists(keyFile) {
+			logger.Log(logger.Fields{"cert": certFile, "key": keyFile}).Info("TLS certificate is missing, generating self-signed one.")
+			if err := generateCertificate(certFile, keyFile); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}, nil
+}
+
+// generateCertificate will create self-signed certificate valid for listen address and localhost
+func generateCertificate(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"OpenLinkHub"}, CommonName: hostname},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+	}
+	if len(hostname) > 0 {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	if ip := net.ParseIP(config.GetConfig().ListenAddress); ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return err
+	}
+
+	if err = writePem(certFile, "CERTIFICATE", der, 0644); err != nil {
+		return err
+	}
+	return writePem(keyFile, "EC PRIVATE KEY", privateKey, 0600)
+}
+
+// writePem will write PEM encoded block to a file
+func writePem(path, blockType string, data []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if err = pem.Encode(file, &pem.Block{Type: blockType, Bytes: data}); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("unable to encode %s: %v", path, err)
+	}
+	return file.Close()
+}
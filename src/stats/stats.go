@@ -4,7 +4,10 @@ package stats
 // Author: Nikola Jurkovic
 // License: GPL-3.0 or later
 
-import "sync"
+import (
+	"OpenLinkHub/src/events"
+	"sync"
+)
 
 type Device struct {
 	Device            string
@@ -46,11 +49,15 @@ func UpdateBatteryStats(serial, device string, level uint16, deviceType uint8) {
 	defer batteryStatsMutex.Unlock()
 
 	if data, ok := batteryStats[serial]; ok {
+		if data.Level != level {
+			events.Publish(events.EventBattery, serial, 0, level)
+		}
 		data.Level = level
 		data.DeviceType = deviceType
 		data.Device = device
 		batteryStats[serial] = data
 	} else {
+		events.Publish(events.EventBattery, serial, 0, level)
 		batteryStats[serial] = BatteryStats{
 			Device:     device,
 			Level:      level,
@@ -64,6 +71,14 @@ func UpdateDeviceStats(serial, name, temp, speed, label string, channelId int, t
 	statsMutex.Lock()
 	defer statsMutex.Unlock()
 
+	previous, found := stats[serial].Devices[channelId]
+	if !found || previous.Temperature != temperature {
+		events.Publish(events.EventTemperature, serial, channelId, temperature)
+	}
+	if !found || previous.Speed != speed {
+		events.Publish(events.EventRpm, serial, channelId, speed)
+	}
+
 	if data, ok := stats[serial]; ok {
 		data.Devices[channelId] = Device{
 			Device:            name,
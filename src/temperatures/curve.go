@@ -0,0 +1,158 @@
+package temperatures
+
+// Package: temperatures
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	maxAverageWindow = 60 // Seconds
+	maxHysteresis    = 20
+)
+
+type curveKey struct {
+	Serial    string
+	ChannelId int
+}
+
+type rampKey struct {
+	curveKey
+	Pump bool
+}
+
+type curveSample struct {
+	temperature float32
+	time        time.Time
+}
+
+type curveState struct {
+	samples     []curveSample
+	temperature float32
+	initialized bool
+}
+
+type rampState struct {
+	value   float64
+	updated time.Time
+}
+
+var (
+	curveMutex  sync.Mutex
+	curveStates = map[curveKey]*curveState{}
+	rampStates  = map[rampKey]*rampState{}
+	failsafe    = map[string]bool{}
+)
+
+// SmoothTemperature will apply profile averaging window and hysteresis on sensor temperature for given device channel
+func SmoothTemperature(serial string, channelId int, profile *TemperatureProfileData, temp float32) float32 {
+	curveMutex.Lock()
+	defer curveMutex.Unlock()
+
+	key := curveKey{Serial: serial, ChannelId: channelId}
+	state, ok := curveStates[key]
+	if !ok {
+		state = &curveState{}
+		curveStates[key] = state
+	}
+
+	// Moving average over last AverageWindow seconds
+	window := profile.AverageWindow
+	if window > maxAverageWindow {
+		window = maxAverageWindow
+	}
+	if window > 0 {
+		now := time.Now()
+		state.samples = append(state.samples, curveSample{temperature: temp, time: now})
+		cutoff := now.Add(-time.Duration(window) * time.Second)
+		for len(state.samples) > 1 && state.samples[0].time.Before(cutoff) {
+			state.samples = state.samples[1:]
+		}
+		var sum float32 = 0
+		for _, sample := range state.samples {
+			sum += sample.temperature
+		}
+		temp = sum / float32(len(state.samples))
+	} else {
+		state.samples = nil
+	}
+
+	// Hysteresis
+	if state.initialized {
+		up := float32(math.Min(float64(profile.HysteresisUp), maxHysteresis))
+		down := float32(math.Min(float64(profile.HysteresisDown), maxHysteresis))
+		if temp > state.temperature && temp-state.temperature < up {
+			temp = state.temperature
+		} else if temp < state.temperature && state.temperature-temp < down {
+			temp = state.temperature
+		}
+	}
+	state.temperature = temp
+	state.initialized = true
+	return temp
+}
+
+// RampSpeed will limit speed change of given device channel to profile ramp rate in % per second
+func RampSpeed(serial string, channelId int, pump bool, profile *TemperatureProfileData, value int) int {
+	curveMutex.Lock()
+	defer curveMutex.Unlock()
+
+	key := rampKey{curveKey: curveKey{Serial: serial, ChannelId: channelId}, Pump: pump}
+	state, ok := rampStates[key]
+	if !ok || profile.RampRate <= 0 {
+		rampStates[key] = &rampState{value: float64(value), updated: time.Now()}
+		return value
+	}
+
+	maxDelta := float64(profile.RampRate) * time.Since(state.updated).Seconds()
+	target := float64(value)
+	if target > state.value+maxDelta {
+		target = state.value + maxDelta
+	} else if target < state.value-maxDelta {
+		target = state.value - maxDelta
+	}
+
+	state.value = target
+	state.updated = time.Now()
+	return int(math.Round(target))
+}
+
+// RemoveCurveDevice will clear averaging, hysteresis and ramp state of a given device
+func RemoveCurveDevice(serial string) {
+	curveMutex.Lock()
+	defer curveMutex.Unlock()
+
+	for key := range curveStates {
+		if key.Serial == serial {
+			delete(curveStates, key)
+		}
+	}
+	for key := range rampStates {
+		if key.Serial == serial {
+			delete(rampStates, key)
+		}
+	}
+}
+
+// SetFailsafe will enable or disable full fan and pump speed requested by given source
+func SetFailsafe(source string, enabled bool) {
+	curveMutex.Lock()
+	defer curveMutex.Unlock()
+
+	if enabled {
+		failsafe[source] = true
+	} else {
+		delete(failsafe, source)
+	}
+}
+
+// IsFailsafe will return true when any source requested full fan and pump speed
+func IsFailsafe() bool {
+	curveMutex.Lock()
+	defer curveMutex.Unlock()
+	return len(failsafe) > 0
+}
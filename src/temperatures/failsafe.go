@@ -0,0 +1,152 @@
+package temperatures
+
+// Package: temperatures
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/logger"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	FailsafeReasonSensorFailure = "sensorFailure"
+	FailsafeReasonStale         = "stale"
+	FailsafeReasonCritical      = "critical"
+)
+
+// failsafeCriticalHysteresis is amount of °C temperature has to drop under critical threshold to clear failsafe
+const failsafeCriticalHysteresis = 5
+
+type FailsafeSource struct {
+	Serial      string  `json:"serial"`
+	ChannelId   int     `json:"channelId"`
+	Sensor      uint8   `json:"sensor"`
+	Device      string  `json:"device"`
+	Temperature float32 `json:"temperature"`
+	Reason      string  `json:"reason"`
+	Time        int64   `json:"time"`
+}
+
+type Failsafe struct {
+	Active   bool             `json:"active"`
+	Requests []string         `json:"requests"`
+	Sources  []FailsafeSource `json:"sources"`
+}
+
+var (
+	failsafeMutex   sync.Mutex
+	failsafeSources = map[curveKey]*FailsafeSource{}
+)
+
+// IsActiveStep will return true when step of given profile should be applied for temperature. While failsafe is
+// active, the last step is applied regardless of temperature
+func IsActiveStep(profile *TemperatureProfileData, index int, temp float32) bool {
+	if IsFailsafe() {
+		return index == len(profile.Profiles)-1
+	}
+	step := profile.Profiles[index]
+	return common.InBetween(temp, step.Min+0.1, step.Max)
+}
+
+// CheckFailsafe will validate profile sensor temperature of given device channel and switch all fans and pumps to
+// full speed when sensor fails, goes stale or crosses critical temperature
+func CheckFailsafe(serial string, channelId int, profile *TemperatureProfileData, temp float32) {
+	settings := config.GetConfig().Failsafe
+	if !settings.Enabled {
+		return
+	}
+
+	key := curveKey{Serial: serial, ChannelId: channelId}
+
+	failsafeMutex.Lock()
+	defer failsafeMutex.Unlock()
+
+	source, active := failsafeSources[key]
+	reason := ""
+	switch {
+	case temp == 0 && settings.SensorFailure:
+		reason = FailsafeReasonSensorFailure
+	case settings.StaleTimeout > 0 && isSensorStale(GetProfileSensorId(profile), time.Duration(settings.StaleTimeout)*time.Second):
+		reason = FailsafeReasonStale
+	case settings.CriticalTemperature > 0 && temp >= settings.CriticalTemperature:
+		reason = FailsafeReasonCritical
+	case settings.CriticalTemperature > 0 && active && source.Reason == FailsafeReasonCritical && temp > settings.CriticalTemperature-failsafeCriticalHysteresis:
+		reason = FailsafeReasonCritical
+	}
+
+	if len(reason) == 0 {
+		if active {
+			delete(failsafeSources, key)
+			logger.Log(logger.Fields{"serial": serial, "channelId": channelId, "temperature": temp, "reason": source.Reason}).Info("Temperature failsafe condition cleared")
+			events.Publish(events.EventFailsafe, serial, channelId, false)
+			SetFailsafe("temperature", len(failsafeSources) > 0)
+		}
+		return
+	}
+
+	if active {
+		source.Temperature = temp
+		source.Reason = reason
+		return
+	}
+
+	failsafeSources[key] = &FailsafeSource{
+		Serial:      serial,
+		ChannelId:   channelId,
+		Sensor:      profile.Sensor,
+		Device:      profile.Device,
+		Temperature: temp,
+		Reason:      reason,
+		Time:        time.Now().Unix(),
+	}
+	logger.Log(logger.Fields{"serial": serial, "channelId": channelId, "sensor": profile.Sensor, "temperature": temp, "reason": reason}).Error("Temperature failsafe activated. All fans and pumps are set to 100%")
+	events.Publish(events.EventFailsafe, serial, channelId, true)
+	SetFailsafe("temperature", true)
+}
+
+// RemoveFailsafeDevice will clear all failsafe conditions of a given device
+func RemoveFailsafeDevice(serial string) {
+	failsafeMutex.Lock()
+	defer failsafeMutex.Unlock()
+
+	for key := range failsafeSources {
+		if key.Serial == serial {
+			delete(failsafeSources, key)
+		}
+	}
+	SetFailsafe("temperature", len(failsafeSources) > 0)
+}
+
+// GetFailsafe will return current failsafe state
+func GetFailsafe() Failsafe {
+	failsafeMutex.Lock()
+	sources := make([]FailsafeSource, 0, len(failsafeSources))
+	for _, source := range failsafeSources {
+		sources = append(sources, *source)
+	}
+	failsafeMutex.Unlock()
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Time < sources[j].Time
+	})
+
+	curveMutex.Lock()
+	requests := make([]string, 0, len(failsafe))
+	for source := range failsafe {
+		requests = append(requests, source)
+	}
+	curveMutex.Unlock()
+	sort.Strings(requests)
+
+	return Failsafe{
+		Active:   len(requests) > 0,
+		Requests: requests,
+		Sources:  sources,
+	}
+}
@@ -0,0 +1,212 @@
+package temperatures
+
+// Package: temperatures
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/logger"
+	"math"
+	"sync"
+	"time"
+)
+
+// SensorId identifies a single host temperature source
+type SensorId struct {
+	Sensor uint8
+	Device string
+	Index  int
+}
+
+type sensorEntry struct {
+	value    float32
+	accessed time.Time
+	updated  time.Time
+}
+
+const (
+	sensorSampleInterval = 1000 * time.Millisecond
+	sensorExpire         = 30 * time.Second
+)
+
+// sensorNames are used as event serial of host sensors
+var sensorNames = map[uint8]string{
+	SensorTypeCPU:                "cpu",
+	SensorTypeGPU:                "gpu",
+	SensorTypeStorage:            "storage",
+	SensorTypeCpuGpu:             "cpuGpu",
+	SensorTypeExternalHwMon:      "hwmon",
+	SensorTypeExternalExecutable: "executable",
+	SensorTypeMultiGPU:           "gpu",
+	SensorTypeMultiGPUs:          "gpus",
+	SensorTypeVirtual:            "virtual",
+}
+
+var (
+	sensorMutex   sync.Mutex
+	sensorCache   = map[SensorId]*sensorEntry{}
+	sensorSampler sync.Once
+)
+
+// IsHostSensor will return true when sensor is read from the host, and not from a device
+func IsHostSensor(sensor uint8) bool {
+	switch sensor {
+	case SensorTypeGPU, SensorTypeCPU, SensorTypeStorage, SensorTypeCpuGpu, SensorTypeExternalHwMon,
+		SensorTypeExternalExecutable, SensorTypeMultiGPU, SensorTypeMultiGPUs, SensorTypeVirtual:
+		return true
+	}
+	return false
+}
+
+// GetProfileSensorId will return sensor id used by given temperature profile
+func GetProfileSensorId(profile *TemperatureProfileData) SensorId {
+	switch profile.Sensor {
+	case SensorTypeStorage, SensorTypeExternalHwMon, SensorTypeExternalExecutable, SensorTypeVirtual:
+		return SensorId{Sensor: profile.Sensor, Device: profile.Device}
+	case SensorTypeMultiGPU:
+		return SensorId{Sensor: profile.Sensor, Index: int(profile.GPUIndex)}
+	}
+	return SensorId{Sensor: profile.Sensor}
+}
+
+// GetSensorTemperature will return cached sensor temperature. Sensor is sampled on first request and
+// then refreshed in the background for as long as someone keeps asking for it.
+func GetSensorTemperature(id SensorId) float32 {
+	sensorSampler.Do(func() {
+		go sampleSensors()
+	})
+
+	sensorMutex.Lock()
+	if entry, ok := sensorCache[id]; ok {
+		entry.accessed = time.Now()
+		value := entry.value
+		sensorMutex.Unlock()
+		return value
+	}
+	sensorMutex.Unlock()
+
+	value := readSensor(id)
+
+	sensorMutex.Lock()
+	sensorCache[id] = &sensorEntry{value: value, accessed: time.Now(), updated: time.Now()}
+	sensorMutex.Unlock()
+
+	publishSensor(id, value)
+	return value
+}
+
+// publishSensor will publish temperature event of a host sensor. Failed reads are not published.
+func publishSensor(id SensorId, value float32) {
+	if value == 0 {
+		return
+	}
+
+	serial := "host:" + sensorNames[id.Sensor]
+	if len(id.Device) > 0 {
+		serial += ":" + id.Device
+	}
+	events.Publish(events.EventTemperature, serial, id.Index, value)
+}
+
+// isSensorStale will return true when cached sensor was not refreshed successfully within given timeout
+func isSensorStale(id SensorId, timeout time.Duration) bool {
+	sensorMutex.Lock()
+	defer sensorMutex.Unlock()
+
+	if entry, ok := sensorCache[id]; ok {
+		return time.Since(entry.updated) > timeout
+	}
+	return false
+}
+
+// sampleSensors will refresh all requested sensors once per interval
+func sampleSensors() {
+	ticker := time.NewTicker(sensorSampleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sensorMutex.Lock()
+		ids := make([]SensorId, 0, len(sensorCache))
+		for id, entry := range sensorCache {
+			if time.Since(entry.accessed) > sensorExpire {
+				delete(sensorCache, id)
+				continue
+			}
+			ids = append(ids, id)
+		}
+		sensorMutex.Unlock()
+
+		for _, id := range ids {
+			value := readSensor(id)
+			changed := false
+			sensorMutex.Lock()
+			if entry, ok := sensorCache[id]; ok {
+				changed = entry.value != value
+				entry.value = value
+				if value != 0 {
+					entry.updated = time.Now()
+				}
+			}
+			sensorMutex.Unlock()
+
+			if changed {
+				publishSensor(id, value)
+			}
+		}
+	}
+}
+
+// readSensor will read temperature directly from sensor source
+func readSensor(id SensorId) float32 {
+	switch id.Sensor {
+	case SensorTypeCPU:
+		return GetCpuTemperature()
+	case SensorTypeGPU:
+		temp := GetNVIDIAGpuTemperature(0)
+		if temp == 0 {
+			temp = GetAMDGpuTemperature()
+			if temp == 0 {
+				logger.Log(logger.Fields{"temperature": temp}).Warn("Unable to get sensor temperature. Going to fallback to CPU")
+				temp = GetCpuTemperature()
+			}
+		}
+		return temp
+	case SensorTypeStorage:
+		return GetStorageTemperature(id.Device)
+	case SensorTypeCpuGpu:
+		cpuTemp := GetCpuTemperature()
+		gpuTemp := GetNVIDIAGpuTemperature(0)
+		if gpuTemp == 0 {
+			gpuTemp = GetAMDGpuTemperature()
+		}
+		if gpuTemp == 0 {
+			logger.Log(logger.Fields{"cpu": cpuTemp, "gpu": gpuTemp}).Warn("Unable to get GPU temperature. Setting to 50")
+			gpuTemp = 50
+		}
+		return float32(math.Max(float64(cpuTemp), float64(gpuTemp)))
+	case SensorTypeExternalHwMon:
+		return GetHwMonTemperature(id.Device)
+	case SensorTypeExternalExecutable:
+		return GetExternalBinaryTemperature(id.Device)
+	case SensorTypeMultiGPU:
+		return GetGpuTemperatureIndex(id.Index)
+	case SensorTypeMultiGPUs:
+		maxGpuTemp := float32(0)
+		for _, index := range config.GetConfig().NvidiaGpuIndex {
+			gpuTemp := GetNVIDIAGpuTemperature(index)
+			if gpuTemp == 0 {
+				logger.Log(logger.Fields{"temperature": gpuTemp, "gpuIndex": index}).Warn("Unable to get GPU temperature. Setting to 50")
+				gpuTemp = 50
+			}
+			if gpuTemp > maxGpuTemp {
+				maxGpuTemp = gpuTemp
+			}
+		}
+		return maxGpuTemp
+	case SensorTypeVirtual:
+		return readVirtualSensor(id.Device)
+	}
+	return 0
+}
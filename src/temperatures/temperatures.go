@@ -34,6 +34,7 @@ const (
 	SensorTypeGlobalTemperature  = 9
 	SensorTypePSU                = 10
 	SensorTypeMultiGPUs          = 11
+	SensorTypeVirtual            = 12
 )
 
 type UpdateData struct {
@@ -75,6 +76,10 @@ type TemperatureProfileData struct {
 	Linear             bool                 `json:"linear"`
 	GPUIndex           uint8                `json:"gpuIndex"`
 	SensorString       string               `json:"sensorString"`
+	HysteresisUp       float32              `json:"hysteresisUp"`
+	HysteresisDown     float32              `json:"hysteresisDown"`
+	RampRate           float32              `json:"rampRate"`
+	AverageWindow      int                  `json:"averageWindow"`
 	Hidden             bool
 }
 
@@ -135,6 +140,7 @@ var (
 		SensorTypeGlobalTemperature:  "Global Temperature",
 		SensorTypePSU:                "PSU",
 		SensorTypeMultiGPUs:          "Multi GPUs",
+		SensorTypeVirtual:            "Virtual",
 	}
 
 	// Defaults
@@ -300,6 +306,9 @@ func Init() {
 	// Upgrade existing profiles to graph data
 	upgradeGraphProfiles()
 
+	// User-defined virtual sensors
+	loadVirtualSensors()
+
 	// Setup
 	temperatures = &Temperatures{
 		Profiles: profiles,
@@ -492,6 +501,10 @@ func AddTemperatureProfile(newTemperatureProfile *NewTemperatureProfile) bool {
 			{
 				pf = profileNormal
 			}
+		case SensorTypeVirtual:
+			{
+				pf = profileNormal
+			}
 		case SensorTypeGlobalTemperature:
 			{
 				pf = profileNormal
@@ -0,0 +1,282 @@
+package temperatures
+
+// Package: temperatures
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/common"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/stats"
+	"encoding/json"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	VirtualOperationMax      = "max"
+	VirtualOperationMin      = "min"
+	VirtualOperationAverage  = "average"
+	VirtualOperationWeighted = "weighted"
+	VirtualOperationDelta    = "delta"
+)
+
+type VirtualSensorSource struct {
+	Sensor    uint8   `json:"sensor"`
+	Device    string  `json:"device"`
+	ChannelId int     `json:"channelId"`
+	GpuIndex  int     `json:"gpuIndex"`
+	Weight    float32 `json:"weight"`
+}
+
+type VirtualSensor struct {
+	Name      string                `json:"name"`
+	Operation string                `json:"operation"`
+	Sources   []VirtualSensorSource `json:"sources"`
+}
+
+var (
+	virtualLocation = ""
+	virtualMutex    sync.RWMutex
+	virtualSensors  = map[string]VirtualSensor{}
+)
+
+// loadVirtualSensors will load all user-defined virtual sensors
+func loadVirtualSensors() {
+	virtualLocation = location + "virtual/"
+	if !common.FileExists(virtualLocation) {
+		if err := os.MkdirAll(virtualLocation, 0755); err != nil {
+			logger.Log(logger.Fields{"error": err, "location": virtualLocation}).Error("Unable to create virtual sensors folder")
+			return
+		}
+	}
+
+	files, err := os.ReadDir(virtualLocation)
+	if err != nil {
+		logger.Log(logger.Fields{"error": err, "location": virtualLocation}).Error("Unable to read content of a folder")
+		return
+	}
+
+	virtualMutex.Lock()
+	defer virtualMutex.Unlock()
+
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue
+		}
+
+		sensorLocation := virtualLocation + fi.Name()
+		if !common.IsValidExtension(sensorLocation, ".json") {
+			continue
+		}
+
+		file, fe := os.ReadFile(sensorLocation)
+		if fe != nil {
+			logger.Log(logger.Fields{"error": fe, "location": sensorLocation}).Error("Unable to read virtual sensor")
+			continue
+		}
+
+		var sensor VirtualSensor
+		if fe = json.Unmarshal(file, &sensor); fe != nil {
+			logger.Log(logger.Fields{"error": fe, "location": sensorLocation}).Error("Unable to decode virtual sensor")
+			continue
+		}
+		sensor.Name = strings.Split(fi.Name(), ".")[0]
+		setDefaultWeights(&sensor)
+		virtualSensors[sensor.Name] = sensor
+	}
+}
+
+// GetVirtualSensors will return all virtual sensors
+func GetVirtualSensors() map[string]VirtualSensor {
+	virtualMutex.RLock()
+	defer virtualMutex.RUnlock()
+
+	sensors := make(map[string]VirtualSensor, len(virtualSensors))
+	for name, sensor := range virtualSensors {
+		sensors[name] = sensor
+	}
+	return sensors
+}
+
+// GetVirtualSensor will return virtual sensor by name
+func GetVirtualSensor(name string) *VirtualSensor {
+	virtualMutex.RLock()
+	defer virtualMutex.RUnlock()
+
+	if sensor, ok := virtualSensors[name]; ok {
+		return &sensor
+	}
+	return nil
+}
+
+// SaveVirtualSensor will create or update virtual sensor
+func SaveVirtualSensor(sensor VirtualSensor) uint8 {
+	switch sensor.Operation {
+	case VirtualOperationMax, VirtualOperationMin, VirtualOperationAverage, VirtualOperationWeighted:
+		if len(sensor.Sources) < 1 {
+			return 0
+		}
+	case VirtualOperationDelta:
+		if len(sensor.Sources) != 2 {
+			return 0
+		}
+	default:
+		return 0
+	}
+
+	for _, source := range sensor.Sources {
+		switch source.Sensor {
+		case SensorTypeVirtual, SensorTypePSU:
+			return 0
+		}
+		if source.Weight < 0 {
+			return 0
+		}
+	}
+	setDefaultWeights(&sensor)
+
+	if err := common.SaveJsonData(virtualLocation+sensor.Name+".json", sensor); err != nil {
+		logger.Log(logger.Fields{"error": err, "sensor": sensor.Name}).Error("Unable to save virtual sensor")
+		return 0
+	}
+
+	virtualMutex.Lock()
+	virtualSensors[sensor.Name] = sensor
+	virtualMutex.Unlock()
+	return 1
+}
+
+// DeleteVirtualSensor will delete virtual sensor
+func DeleteVirtualSensor(name string) uint8 {
+	virtualMutex.Lock()
+	defer virtualMutex.Unlock()
+
+	if _, ok := virtualSensors[name]; !ok {
+		return 0
+	}
+
+	if err := os.Remove(virtualLocation + name + ".json"); err != nil {
+		logger.Log(logger.Fields{"error": err, "sensor": name}).Error("Unable to delete virtual sensor")
+		return 0
+	}
+	delete(virtualSensors, name)
+	return 1
+}
+
+// setDefaultWeights will set weight of weighted sources without weight to 1
+func setDefaultWeights(sensor *VirtualSensor) {
+	if sensor.Operation != VirtualOperationWeighted {
+		return
+	}
+	for i := range sensor.Sources {
+		if sensor.Sources[i].Weight == 0 {
+			sensor.Sources[i].Weight = 1
+		}
+	}
+}
+
+// GetRgbTemperature will return virtual sensor temperature when sensor is defined, otherwise fallback value
+func GetRgbTemperature(sensor string, fallback float32) float32 {
+	if len(sensor) == 0 {
+		return fallback
+	}
+	return GetSensorTemperature(SensorId{Sensor: SensorTypeVirtual, Device: sensor})
+}
+
+// readVirtualSensor will calculate virtual sensor temperature out of its sources
+func readVirtualSensor(name string) float32 {
+	sensor := GetVirtualSensor(name)
+	if sensor == nil {
+		return 0
+	}
+
+	values := make([]float32, 0, len(sensor.Sources))
+	weights := make([]float32, 0, len(sensor.Sources))
+	for _, source := range sensor.Sources {
+		values = append(values, readVirtualSource(source))
+		weights = append(weights, source.Weight)
+	}
+
+	switch sensor.Operation {
+	case VirtualOperationMax:
+		result := values[0]
+		for _, value := range values[1:] {
+			result = float32(math.Max(float64(result), float64(value)))
+		}
+		return result
+	case VirtualOperationMin:
+		var result float32 = 0
+		for _, value := range values {
+			// Ignore failed sources
+			if value == 0 {
+				continue
+			}
+			if result == 0 || value < result {
+				result = value
+			}
+		}
+		return result
+	case VirtualOperationAverage:
+		var sum float32 = 0
+		count := 0
+		for _, value := range values {
+			if value == 0 {
+				continue
+			}
+			sum += value
+			count++
+		}
+		if count == 0 {
+			return 0
+		}
+		return sum / float32(count)
+	case VirtualOperationWeighted:
+		var sum float32 = 0
+		for i, value := range values {
+			// Failed source would silently lower the result
+			if value == 0 {
+				return 0
+			}
+			sum += value * weights[i]
+		}
+		return sum
+	case VirtualOperationDelta:
+		if values[0] == 0 || values[1] == 0 {
+			return 0
+		}
+		// Fan curves treat 0 as failed sensor
+		return float32(math.Max(float64(values[0]-values[1]), 0.1))
+	}
+	return 0
+}
+
+// readVirtualSource will return temperature of a single virtual sensor source
+func readVirtualSource(source VirtualSensorSource) float32 {
+	switch source.Sensor {
+	case SensorTypeLiquidTemperature, SensorTypeGlobalTemperature:
+		return stats.GetDeviceTemperature(source.Device, source.ChannelId)
+	case SensorTypeTemperatureProbe:
+		if strings.HasPrefix(source.Device, i2cPrefix) {
+			return GetMemoryTemperature(source.ChannelId)
+		}
+		return stats.GetDeviceTemperature(source.Device, source.ChannelId)
+	case SensorTypeStorage, SensorTypeExternalHwMon, SensorTypeExternalExecutable:
+		return GetSensorTemperature(SensorId{Sensor: source.Sensor, Device: source.Device})
+	case SensorTypeMultiGPU:
+		return GetSensorTemperature(SensorId{Sensor: source.Sensor, Index: source.GpuIndex})
+	case SensorTypeVirtual, SensorTypePSU:
+		return 0
+	}
+	return GetSensorTemperature(SensorId{Sensor: source.Sensor})
+}
+
+// GetSourceTemperature will return temperature of a single sensor source, including virtual sensors
+func GetSourceTemperature(source VirtualSensorSource) float32 {
+	if source.Sensor == SensorTypeVirtual {
+		return GetSensorTemperature(SensorId{Sensor: SensorTypeVirtual, Device: source.Device})
+	}
+	return readVirtualSource(source)
+}
@@ -0,0 +1,187 @@
+package transport
+
+// Package: transport
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sstallion/go-hid"
+)
+
+var (
+	captureCounter uint64
+	fileNameRegex  = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+	responseWindow = time.Second
+)
+
+// Capture is HID device which logs every outgoing and incoming report to a file
+type Capture struct {
+	*hid.Device
+	file      *os.File
+	encoder   *json.Encoder
+	path      string
+	start     time.Time
+	lastWrite time.Time
+	input     bool
+	keyboard  bool
+	mutex     sync.Mutex
+	failed    bool
+}
+
+// newCapture will create new capture file for given device. Unless input is set, input reports which
+// are not a response to an outgoing report, and all input reports of keyboard interfaces, are not logged
+func newCapture(dev *hid.Device, directory string, input bool) (*Capture, error) {
+	info, err := dev.GetDeviceInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	name := info.SerialNbr
+	if len(name) == 0 {
+		name = filepath.Base(info.Path)
+	}
+	name = fmt.Sprintf(
+		"%04x-%s-%s-%d.jsonl",
+		info.ProductID,
+		fileNameRegex.ReplaceAllString(name, ""),
+		time.Now().Format("20060102-150405"),
+		atomic.AddUint64(&captureCounter, 1),
+	)
+	path := filepath.Join(directory, name)
+
+	// Captures may contain typed keys, keep them private to the service user
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Capture{
+		Device:   dev,
+		file:     file,
+		encoder:  json.NewEncoder(file),
+		path:     path,
+		start:    time.Now(),
+		input:    input,
+		keyboard: info.UsagePage == usagePageGenericDesktop && info.Usage == usageKeyboard,
+	}
+
+	header := Header{
+		Device:  info,
+		Created: c.start.Format(time.RFC3339),
+	}
+	if err = c.encoder.Encode(header); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// record will append single report to capture file
+func (c *Capture) record(packetType string, data []byte, err error) {
+	if err != nil && errors.Is(err, hid.ErrTimeout) {
+		// Timeouts of polling readers are not part of the protocol
+		return
+	}
+	if err == nil && len(data) == 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.failed {
+		return
+	}
+
+	switch packetType {
+	case PacketWrite, PacketSendFeature:
+		c.lastWrite = time.Now()
+	case PacketRead:
+		if !c.input && (c.keyboard || time.Since(c.lastWrite) > responseWindow) {
+			// Unsolicited input report, e.g. key press
+			return
+		}
+	}
+
+	packet := Packet{
+		Time: time.Since(c.start).Microseconds(),
+		Type: packetType,
+		Data: hex.EncodeToString(data),
+	}
+	if err != nil {
+		packet.Error = err.Error()
+	}
+
+	if e := c.encoder.Encode(packet); e != nil {
+		// Stop capturing, device keeps working
+		c.failed = true
+		reportError(e, c.path)
+	}
+}
+
+// received will return part of a buffer filled by a device
+func received(p []byte, n int) []byte {
+	if n <= 0 {
+		return nil
+	}
+	return p[:n]
+}
+
+// Write will send output report to a device
+func (c *Capture) Write(p []byte) (int, error) {
+	n, err := c.Device.Write(p)
+	c.record(PacketWrite, p, err)
+	return n, err
+}
+
+// Read will read input report from a device
+func (c *Capture) Read(p []byte) (int, error) {
+	n, err := c.Device.Read(p)
+	c.record(PacketRead, received(p, n), err)
+	return n, err
+}
+
+// ReadWithTimeout will read input report from a device with a timeout
+func (c *Capture) ReadWithTimeout(p []byte, timeout time.Duration) (int, error) {
+	n, err := c.Device.ReadWithTimeout(p, timeout)
+	c.record(PacketRead, received(p, n), err)
+	return n, err
+}
+
+// SendFeatureReport will send feature report to a device
+func (c *Capture) SendFeatureReport(p []byte) (int, error) {
+	n, err := c.Device.SendFeatureReport(p)
+	c.record(PacketSendFeature, p, err)
+	return n, err
+}
+
+// GetFeatureReport will read feature report from a device
+func (c *Capture) GetFeatureReport(p []byte) (int, error) {
+	n, err := c.Device.GetFeatureReport(p)
+	c.record(PacketGetFeature, received(p, n), err)
+	return n, err
+}
+
+// Close will close capture file and device
+func (c *Capture) Close() error {
+	c.mutex.Lock()
+	if !c.failed {
+		c.failed = true
+		if err := c.file.Close(); err != nil {
+			reportError(err, c.path)
+		}
+	}
+	c.mutex.Unlock()
+	return c.Device.Close()
+}
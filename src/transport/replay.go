@@ -0,0 +1,332 @@
+package transport
+
+// Package: transport
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sstallion/go-hid"
+)
+
+// Replay is HID device which feeds recorded capture session back to a driver
+type Replay struct {
+	info         *hid.DeviceInfo
+	path         string
+	unsolicited  exchange
+	exchanges    map[string][]*exchange
+	used         map[string]int
+	incoming     []Packet
+	features     []Packet
+	total        int
+	outIndex     int
+	inIndex      int
+	featureIndex int
+	mismatches   int
+	nonblocking  bool
+	closed       chan struct{}
+	closeOnce    *sync.Once
+	mutex        sync.Mutex
+}
+
+// exchange is a recorded outgoing report and input and feature reports which followed it
+type exchange struct {
+	write    Packet
+	reads    []Packet
+	features []Packet
+}
+
+// ReplayResult contains replay session progress
+type ReplayResult struct {
+	Path       string `json:"path"`
+	Serial     string `json:"serial"`
+	ProductId  uint16 `json:"productId"`
+	Writes     int    `json:"writes"`
+	Reads      int    `json:"reads"`
+	Mismatches int    `json:"mismatches"`
+	Remaining  int    `json:"remaining"`
+}
+
+// LoadReplay will load capture file as a replay session
+func LoadReplay(path string) (*Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("capture file %s is empty", path)
+	}
+	header := Header{}
+	if err = json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, err
+	}
+	if header.Device == nil {
+		return nil, fmt.Errorf("capture file %s has no device header", path)
+	}
+
+	r := &Replay{
+		info:      header.Device,
+		path:      path,
+		exchanges: make(map[string][]*exchange),
+	}
+
+	// Every response belongs to the outgoing report which preceded it
+	current := &r.unsolicited
+	for scanner.Scan() {
+		packet := Packet{}
+		if err = json.Unmarshal(scanner.Bytes(), &packet); err != nil {
+			return nil, err
+		}
+		switch packet.Type {
+		case PacketWrite, PacketSendFeature:
+			current = &exchange{write: packet}
+			r.exchanges[packet.Data] = append(r.exchanges[packet.Data], current)
+			r.total++
+		case PacketRead:
+			current.reads = append(current.reads, packet)
+			r.total++
+		case PacketGetFeature:
+			current.features = append(current.features, packet)
+			r.total++
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	r.reset()
+	return r, nil
+}
+
+// AddReplay will load capture file and serve it instead of real hardware
+func AddReplay(path string) (*Replay, error) {
+	r, err := LoadReplay(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	replays = append(replays, r)
+	return r, nil
+}
+
+// GetReplays will return all registered replay sessions
+func GetReplays() []*Replay {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	cp := make([]*Replay, len(replays))
+	copy(cp, replays)
+	return cp
+}
+
+// findReplay will return first registered replay session matching device info
+func findReplay(match func(info *hid.DeviceInfo) bool) *Replay {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, r := range replays {
+		if match(r.info) {
+			r.reset()
+			return r
+		}
+	}
+	return nil
+}
+
+// reset will rewind replay session to the beginning
+func (r *Replay) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.outIndex, r.inIndex, r.featureIndex, r.mismatches = 0, 0, 0, 0
+	r.used = make(map[string]int)
+	r.incoming = append([]Packet(nil), r.unsolicited.reads...)
+	r.features = append([]Packet(nil), r.unsolicited.features...)
+	r.nonblocking = false
+	r.closed = make(chan struct{})
+	r.closeOnce = &sync.Once{}
+}
+
+// Info will return recorded device info
+func (r *Replay) Info() *hid.DeviceInfo {
+	return r.info
+}
+
+// Result will return replay session progress
+func (r *Replay) Result() ReplayResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return ReplayResult{
+		Path:       r.path,
+		Serial:     r.info.SerialNbr,
+		ProductId:  r.info.ProductID,
+		Writes:     r.outIndex,
+		Reads:      r.inIndex + r.featureIndex,
+		Mismatches: r.mismatches,
+		Remaining:  r.total - r.outIndex - r.inIndex - r.featureIndex,
+	}
+}
+
+// packetError will return recorded error of a packet, if any
+func packetError(packet Packet) error {
+	if len(packet.Error) > 0 {
+		return errors.New(packet.Error)
+	}
+	return nil
+}
+
+// send will match outgoing report with recorded one and queue responses recorded after it
+func (r *Replay) send(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := hex.EncodeToString(p)
+	recorded := r.exchanges[key]
+	index := r.used[key]
+	if index >= len(recorded) {
+		r.mismatches++
+		reportError(fmt.Errorf("outgoing report %d was not recorded: %x", r.outIndex, p), r.path)
+		return len(p), nil
+	}
+
+	ex := recorded[index]
+	r.used[key]++
+	r.outIndex++
+	r.incoming = append(r.incoming, ex.reads...)
+	r.features = append(r.features, ex.features...)
+
+	if err := packetError(ex.write); err != nil {
+		return -1, err
+	}
+	return len(p), nil
+}
+
+// receive will copy next recorded input report to a buffer
+func (r *Replay) receive(p []byte, timeout time.Duration) (int, error) {
+	r.mutex.Lock()
+	if len(r.incoming) > 0 {
+		packet := r.incoming[0]
+		r.incoming = r.incoming[1:]
+		r.inIndex++
+		r.mutex.Unlock()
+
+		if err := packetError(packet); err != nil {
+			return -1, err
+		}
+		data, _ := hex.DecodeString(packet.Data)
+		return copy(p, data), nil
+	}
+	nonblocking, closed := r.nonblocking, r.closed
+	r.mutex.Unlock()
+
+	// Nothing left to replay, behave like a silent device
+	if nonblocking {
+		return 0, hid.ErrTimeout
+	}
+	if timeout >= 0 {
+		select {
+		case <-time.After(timeout):
+		case <-closed:
+		}
+		return 0, hid.ErrTimeout
+	}
+	<-closed
+	return -1, ErrReplayExhausted
+}
+
+// Write will compare output report with recorded session
+func (r *Replay) Write(p []byte) (int, error) {
+	return r.send(p)
+}
+
+// Read will return next input report recorded after previous outgoing reports, blocking like a silent device when session is exhausted
+func (r *Replay) Read(p []byte) (int, error) {
+	return r.receive(p, -1)
+}
+
+// ReadWithTimeout will return next recorded input report
+func (r *Replay) ReadWithTimeout(p []byte, timeout time.Duration) (int, error) {
+	return r.receive(p, timeout)
+}
+
+// SetNonblock will change Read behaviour when session is exhausted
+func (r *Replay) SetNonblock(nonblocking bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.nonblocking = nonblocking
+	return nil
+}
+
+// SendFeatureReport will compare feature report with recorded session
+func (r *Replay) SendFeatureReport(p []byte) (int, error) {
+	return r.send(p)
+}
+
+// GetFeatureReport will return next recorded feature report
+func (r *Replay) GetFeatureReport(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.features) == 0 {
+		return -1, ErrReplayExhausted
+	}
+	packet := r.features[0]
+	r.features = r.features[1:]
+	r.featureIndex++
+
+	if err := packetError(packet); err != nil {
+		return -1, err
+	}
+	data, _ := hex.DecodeString(packet.Data)
+	return copy(p, data), nil
+}
+
+// GetMfrStr will return recorded manufacturer
+func (r *Replay) GetMfrStr() (string, error) {
+	return r.info.MfrStr, nil
+}
+
+// GetProductStr will return recorded product name
+func (r *Replay) GetProductStr() (string, error) {
+	return r.info.ProductStr, nil
+}
+
+// GetSerialNbr will return recorded serial number
+func (r *Replay) GetSerialNbr() (string, error) {
+	return r.info.SerialNbr, nil
+}
+
+// GetDeviceInfo will return recorded device info
+func (r *Replay) GetDeviceInfo() (*hid.DeviceInfo, error) {
+	info := *r.info
+	return &info, nil
+}
+
+// Close will unblock pending reads
+func (r *Replay) Close() error {
+	r.mutex.Lock()
+	once, closed := r.closeOnce, r.closed
+	r.mutex.Unlock()
+
+	once.Do(func() {
+		close(closed)
+	})
+	return nil
+}
@@ -0,0 +1,153 @@
+package transport
+
+// Package: transport
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sstallion/go-hid"
+)
+
+const (
+	PacketWrite       = "write"
+	PacketRead        = "read"
+	PacketSendFeature = "sendFeature"
+	PacketGetFeature  = "getFeature"
+
+	usagePageGenericDesktop = 0x01
+	usageKeyboard           = 0x06
+)
+
+// ErrReplayExhausted is returned when replay session has no more recorded input reports
+var ErrReplayExhausted = errors.New("replay session has no more recorded reports")
+
+// Device is HID transport used by device drivers to exchange reports with hardware
+type Device interface {
+	Write(p []byte) (int, error)
+	Read(p []byte) (int, error)
+	ReadWithTimeout(p []byte, timeout time.Duration) (int, error)
+	SetNonblock(nonblocking bool) error
+	SendFeatureReport(p []byte) (int, error)
+	GetFeatureReport(p []byte) (int, error)
+	GetMfrStr() (string, error)
+	GetProductStr() (string, error)
+	GetSerialNbr() (string, error)
+	GetDeviceInfo() (*hid.DeviceInfo, error)
+	Close() error
+}
+
+// Header is the first line of a capture file and describes captured device
+type Header struct {
+	Device  *hid.DeviceInfo `json:"device"`
+	Created string          `json:"created"`
+}
+
+// Packet is a single captured HID report
+type Packet struct {
+	Time  int64  `json:"time"`
+	Type  string `json:"type"`
+	Data  string `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+var (
+	mutex        sync.Mutex
+	captureDir   = ""
+	capture      = false
+	captureInput = false
+	replays      = make([]*Replay, 0)
+	errorHandler func(err error, path string)
+)
+
+// Init will set packet capture location and state. When input is set, unsolicited input reports, such as key presses, are captured too
+func Init(directory string, enabled, input bool) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	captureDir = directory
+	capture = enabled
+	captureInput = input
+	if capture {
+		if err := os.MkdirAll(captureDir, 0700); err != nil {
+			return err
+		}
+		return os.Chmod(captureDir, 0700)
+	}
+	return nil
+}
+
+// SetErrorHandler will set function used to report capture and replay errors
+func SetErrorHandler(handler func(err error, path string)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	errorHandler = handler
+}
+
+// reportError will pass error to error handler, if any
+func reportError(err error, path string) {
+	mutex.Lock()
+	handler := errorHandler
+	mutex.Unlock()
+
+	if handler != nil {
+		handler(err, path)
+	}
+}
+
+// Open will open HID device by vendor id, product id and serial number
+func Open(vid, pid uint16, serial string) (Device, error) {
+	if replay := findReplay(func(info *hid.DeviceInfo) bool {
+		return info.VendorID == vid && info.ProductID == pid && (len(serial) == 0 || info.SerialNbr == serial)
+	}); replay != nil {
+		return replay, nil
+	}
+
+	dev, err := hid.Open(vid, pid, serial)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(dev), nil
+}
+
+// OpenFirst will open first HID device matching vendor and product id
+func OpenFirst(vid, pid uint16) (Device, error) {
+	return Open(vid, pid, "")
+}
+
+// OpenPath will open HID device by its path
+func OpenPath(path string) (Device, error) {
+	if replay := findReplay(func(info *hid.DeviceInfo) bool {
+		return info.Path == path
+	}); replay != nil {
+		return replay, nil
+	}
+
+	dev, err := hid.OpenPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(dev), nil
+}
+
+// wrap will attach packet capture to device when capture is enabled
+func wrap(dev *hid.Device) Device {
+	mutex.Lock()
+	enabled, directory, input := capture, captureDir, captureInput
+	mutex.Unlock()
+
+	if !enabled {
+		return dev
+	}
+
+	c, err := newCapture(dev, directory, input)
+	if err != nil {
+		reportError(err, directory)
+		return dev
+	}
+	return c
+}
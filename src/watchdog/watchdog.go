@@ -0,0 +1,284 @@
+package watchdog
+
+// Package: watchdog
+// Author: Nikola Jurkovic
+// License: GPL-3.0 or later
+
+import (
+	"OpenLinkHub/src/config"
+	"OpenLinkHub/src/events"
+	"OpenLinkHub/src/logger"
+	"OpenLinkHub/src/temperatures"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ActionNone    = "none"
+	ActionMaxFans = "maxFans"
+	ActionCommand = "command"
+	ActionRgb     = "rgb"
+)
+
+// historySize is amount of resolved alerts kept in memory
+const historySize = 50
+
+var commandTimeout = 30 * time.Second
+
+type Alert struct {
+	Serial    string `json:"serial"`
+	ChannelId int    `json:"channelId"`
+	Device    string `json:"device"`
+	Pump      bool   `json:"pump"`
+	Speed     int    `json:"speed"`
+	Rpm       int    `json:"rpm"`
+	Critical  bool   `json:"critical"`
+	Active    bool   `json:"active"`
+	Time      int64  `json:"time"`
+	Resolved  int64  `json:"resolved"`
+}
+
+type channelKey struct {
+	Serial    string
+	ChannelId int
+}
+
+type channel struct {
+	speed   int
+	seen    bool
+	stalled time.Time
+	alert   *Alert
+}
+
+var (
+	mutex      sync.Mutex
+	channels   = map[channelKey]*channel{}
+	history    = make([]Alert, 0)
+	rgbActive  = false
+	rgbHandler func(active bool)
+)
+
+// SetRgbHandler will set function used to flash RGB warning on devices
+func SetRgbHandler(handler func(active bool)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	rgbHandler = handler
+}
+
+// SetSpeed will record speed in % commanded to device channel
+func SetSpeed(serial string, channelId int, speed int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	key := channelKey{Serial: serial, ChannelId: channelId}
+	ch, ok := channels[key]
+	if !ok {
+		channels[key] = &channel{speed: speed}
+		return
+	}
+
+	// Spin-up starts when channel crosses minimum speed
+	if ch.speed < config.GetConfig().Watchdog.MinSpeed {
+		ch.stalled = time.Time{}
+	}
+	ch.speed = speed
+}
+
+// GetSpeed will return speed in % last commanded to device channel
+func GetSpeed(serial string, channelId int) (int, bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	ch, ok := channels[channelKey{Serial: serial, ChannelId: channelId}]
+	if !ok {
+		return 0, false
+	}
+	return ch.speed, true
+}
+
+// UpdateRpm will compare reported channel RPM to commanded speed and raise or resolve stall alerts
+func UpdateRpm(serial, name string, channelId int, pump bool, rpm int) {
+	settings := config.GetConfig().Watchdog
+	if !settings.Enabled {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	ch, ok := channels[channelKey{Serial: serial, ChannelId: channelId}]
+	if !ok {
+		// Speed was never commanded by us, nothing to compare with
+		return
+	}
+
+	if rpm > 0 {
+		// Empty headers and ports are never reported as stalled
+		ch.seen = true
+	}
+
+	if rpm > 0 || !ch.seen || ch.speed < settings.MinSpeed {
+		ch.stalled = time.Time{}
+		if ch.alert != nil {
+			resolve(ch, rpm)
+		}
+		return
+	}
+
+	if ch.stalled.IsZero() {
+		ch.stalled = time.Now()
+		return
+	}
+
+	if ch.alert == nil && time.Since(ch.stalled) >= time.Duration(settings.Delay)*time.Second {
+		ch.alert = &Alert{
+			Serial:    serial,
+			ChannelId: channelId,
+			Device:    name,
+			Pump:      pump,
+			Speed:     ch.speed,
+			Rpm:       rpm,
+			Critical:  pump,
+			Active:    true,
+			Time:      time.Now().Unix(),
+		}
+		raise(ch.alert)
+	}
+}
+
+// RemoveDevice will remove all channels and alerts of a given device
+func RemoveDevice(serial string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for key, ch := range channels {
+		if key.Serial != serial {
+			continue
+		}
+		if ch.alert != nil {
+			resolve(ch, 0)
+		}
+		delete(channels, key)
+	}
+}
+
+// GetAlerts will return all active alerts
+func GetAlerts() []Alert {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	alerts := make([]Alert, 0)
+	for _, ch := range channels {
+		if ch.alert != nil {
+			alerts = append(alerts, *ch.alert)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Time < alerts[j].Time
+	})
+	return alerts
+}
+
+// GetHistory will return recently resolved alerts
+func GetHistory() []Alert {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	cp := make([]Alert, len(history))
+	copy(cp, history)
+	return cp
+}
+
+// raise will report new alert and run configured action
+func raise(alert *Alert) {
+	fields := logger.Fields{"serial": alert.Serial, "channelId": alert.ChannelId, "device": alert.Device, "speed": alert.Speed, "rpm": alert.Rpm}
+	if alert.Critical {
+		logger.Log(fields).Error("Pump failure detected. Pump is not spinning")
+	} else {
+		logger.Log(fields).Warn("Fan stall detected. Fan is not spinning")
+	}
+	events.Publish(events.EventAlert, alert.Serial, alert.ChannelId, *alert)
+	runAction(alert)
+}
+
+// resolve will close channel alert and revert configured action when no alert is left
+func resolve(ch *channel, rpm int) {
+	alert := ch.alert
+	ch.alert = nil
+
+	alert.Active = false
+	alert.Rpm = rpm
+	alert.Resolved = time.Now().Unix()
+
+	history = append(history, *alert)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+
+	logger.Log(logger.Fields{"serial": alert.Serial, "channelId": alert.ChannelId, "device": alert.Device, "rpm": rpm}).Info("Fan alert resolved")
+	events.Publish(events.EventAlert, alert.Serial, alert.ChannelId, *alert)
+	runAction(alert)
+}
+
+// isActive will return true if any channel has an active alert
+func isActive() bool {
+	for _, ch := range channels {
+		if ch.alert != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// runAction will run configured watchdog action for given alert state
+func runAction(alert *Alert) {
+	active := isActive()
+	settings := config.GetConfig().Watchdog
+
+	switch settings.Action {
+	case ActionMaxFans:
+		temperatures.SetFailsafe("watchdog", active)
+	case ActionRgb:
+		if rgbHandler != nil && rgbActive != active {
+			rgbActive = active
+			go rgbHandler(active)
+		}
+	case ActionCommand:
+		if len(settings.Command) > 0 {
+			go runCommand(settings.Command, *alert)
+		}
+	}
+}
+
+// runCommand will execute user command with alert details passed via environment. Commands are allowed only via allowCommands in config
+func runCommand(command string, alert Alert) {
+	if !config.GetConfig().AllowCommands {
+		logger.Log(logger.Fields{"command": command}).Warn("Watchdog commands are disabled. Set allowCommands in config.json to enable them")
+		return
+	}
+
+	state := "recovered"
+	if alert.Active {
+		state = "failed"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Env = append(os.Environ(),
+		"OPENLINKHUB_ALERT="+state,
+		"OPENLINKHUB_SERIAL="+alert.Serial,
+		"OPENLINKHUB_DEVICE="+alert.Device,
+		fmt.Sprintf("OPENLINKHUB_CHANNEL=%d", alert.ChannelId),
+		fmt.Sprintf("OPENLINKHUB_PUMP=%t", alert.Pump),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logger.Log(logger.Fields{"error": err, "command": command, "output": string(output)}).Error("Unable to run watchdog command")
+	}
+}
@@ -64,6 +64,7 @@ $(document).ready(function () {
         $(".macroDelayId").hide();
         $(".macroTextId").hide();
         $(".macroMousePosition").hide();
+        $(".macroDeviceAction, .macroDeviceChannel, .macroDeviceProfile, .macroDeviceProfileAlt, .macroDeviceBrightness").hide();
     });
 
     $('#updateMacroSettingsModal').on('shown.bs.modal', function () {
@@ -304,6 +305,24 @@ $(document).ready(function () {
                             case 20:
                                 actionType = 'Mouse Position';
                                 break;
+                            case 21:
+                                actionType = i18n.t('txtLaunchApplication', 'Launch Application');
+                                break;
+                            case 22:
+                                actionType = i18n.t('txtShellCommand', 'Shell Command');
+                                break;
+                            case 23:
+                                actionType = i18n.t('txtUserProfile', 'User Profile');
+                                break;
+                            case 24:
+                                actionType = i18n.t('txtRgbProfile', 'RGB Profile');
+                                break;
+                            case 25:
+                                actionType = i18n.t('txtBrightness', 'Brightness');
+                                break;
+                            case 26:
+                                actionType = i18n.t('txtToggleSpeedProfile', 'Toggle Speed Profile');
+                                break;
                             default:
                                 actionType = 'n/a';
                                 break;
@@ -359,6 +378,35 @@ $(document).ready(function () {
                                     </div>
                                 </div>`,
                             ]).draw();
+                        } else if (item.actionType >= 21 && item.actionType <= 26) {
+                            let description = item.actionText;
+                            if (item.actionType >= 23) {
+                                const target = item.actionTarget.length > 0 ? item.actionTarget : i18n.t('txtMacroAllDevices', 'All devices');
+                                switch (item.actionType) {
+                                    case 25:
+                                        description = `${target}: ${item.actionValue} %`;
+                                        break;
+                                    case 26:
+                                        description = `${target} [${item.actionChannelId}]: ${item.actionProfile} / ${item.actionProfileAlt}`;
+                                        break;
+                                    default:
+                                        description = `${target}: ${item.actionProfile}`;
+                                        break;
+                                }
+                            }
+                            dt.row.add([
+                                i,
+                                actionType,
+                                $('<span>').addClass('settings-label text-ellipsis').text(description).prop('outerHTML'),
+                                'N/A',
+                                'N/A',
+                                'N/A',
+                                'N/A',
+                                'N/A',
+                                'N/A',
+                                '' +
+                                `<input class="system-button danger deleteMacroValue" id="deleteMacroValue" data-id="${pf};${i}" type="button" value="DELETE" style="width: 100%;">`
+                            ]).draw();
                         } else if (item.actionType === 20) {
                             dt.row.add([
                                 i,
@@ -399,6 +447,9 @@ $(document).ready(function () {
                         } else {
                             // Render row if we have actual key
                             getKeyName(item.actionCommand, function (result) {
+                                if (item.actionRelease === true) {
+                                    result += ` (${i18n.t('txtMacroRelease', 'Release')})`;
+                                }
                                 dt.row.add([
                                     i,
                                     actionType,
@@ -586,11 +637,21 @@ $(document).ready(function () {
         pf["mousePositionX"] = mousePositionX;
         pf["mousePositionY"] = mousePositionY;
         pf["mousePositionAbsolute"] = mousePositionAbsolute;
+        if (parseInt(macroType) >= 23) {
+            // Device actions
+            const channelId = parseInt($('#macroChannelId').val());
+            pf["deviceId"] = $('#macroTarget').val();
+            pf["channelId"] = isNaN(channelId) ? -1 : channelId;
+            pf["profile"] = $('#macroProfile').val();
+            pf["macroProfileAlt"] = $('#macroProfileAlt').val();
+            pf["brightness"] = parseInt($('#macroBrightness').val()) || 0;
+        }
         const json = JSON.stringify(pf, null, 2);
 
         $.ajax({
             url: '/api/macro/newValue',
             type: 'POST',
+            contentType: 'application/json',
             data: json,
             cache: false,
             success: function (response) {
@@ -659,6 +720,7 @@ $(document).ready(function () {
         mdi.hide();
         mti.hide();
         mmp.hide();
+        $(".macroDeviceAction, .macroDeviceChannel, .macroDeviceProfile, .macroDeviceProfileAlt, .macroDeviceBrightness").hide();
 
         switch (selectedValue) {
             case 3:
@@ -676,6 +738,22 @@ $(document).ready(function () {
             case 20:
                 mmp.show();
                 break;
+            case 21:
+            case 22:
+                mti.show();
+                break;
+            case 23:
+                $(".macroDeviceAction, .macroDeviceProfile").show();
+                break;
+            case 24:
+                $(".macroDeviceAction, .macroDeviceChannel, .macroDeviceProfile").show();
+                break;
+            case 25:
+                $(".macroDeviceAction, .macroDeviceBrightness").show();
+                break;
+            case 26:
+                $(".macroDeviceAction, .macroDeviceChannel, .macroDeviceProfile, .macroDeviceProfileAlt").show();
+                break;
         }
     });
 